@@ -0,0 +1,485 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// forkRequestTimeout is the time allowed for retrieving a single value from the
+// remote node of a forked backend.
+const forkRequestTimeout = 30 * time.Second
+
+var (
+	// forkTombstone marks accounts and storage slots deleted locally, which must
+	// not be retrieved from the remote node anymore. It is the RLP encoding of an
+	// empty string, which is neither a valid account nor a stored slot value.
+	forkTombstone = []byte{0x80}
+
+	emptyCodeHash = crypto.Keccak256Hash(nil)
+)
+
+// NewForkedSimulatedBackend creates a new binding backend using a simulated
+// blockchain forked off the chain of a remote node at the given block number,
+// or at its latest block if number is nil. The chain configuration must match
+// the one of the remote chain, and its chain ID is checked against the node.
+//
+// Accounts, contract code and storage are retrieved lazily from the remote node
+// as the simulated chain accesses them, so contracts deployed on the remote chain
+// can be called and transacted with. The accounts in alloc are set up on top of
+// the remote state, replacing the balance, nonce and code of existing ones. The
+// gas limit of the remote block is used if gasLimit is zero.
+func NewForkedSimulatedBackend(client *ethclient.Client, config ctypes.ChainConfigurator, number *big.Int, alloc genesisT.GenesisAlloc, gasLimit uint64) (*SimulatedBackend, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), forkRequestTimeout)
+	defer cancel()
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve chain ID: %v", err)
+	}
+	if have := config.GetChainID(); have == nil || have.Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("chain ID mismatch: have %v, remote %v", have, chainID)
+	}
+	header, err := client.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve fork block: %v", err)
+	}
+	genesis, err := client.HeaderByNumber(ctx, common.Big0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve genesis block: %v", err)
+	}
+	if header.Number.Sign() == 0 {
+		return nil, errors.New("cannot fork at the genesis block")
+	}
+	var (
+		database = rawdb.NewMemoryDatabase()
+		fork     = newForkState(client, header)
+	)
+	// Set up the test accounts on top of the remote state
+	statedb, err := state.New(header.Root, fork.database(database), nil)
+	if err != nil {
+		return nil, err
+	}
+	for addr, account := range alloc {
+		statedb.SetBalance(addr, account.Balance)
+		statedb.SetNonce(addr, account.Nonce)
+		statedb.SetCode(addr, account.Code)
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up accounts: %v", err)
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false, nil); err != nil {
+		return nil, fmt.Errorf("failed to set up accounts: %v", err)
+	}
+	// Write the remote genesis and the fork block as the local chain. The fork
+	// block has no transactions of its own and holds the modified state.
+	if gasLimit == 0 {
+		gasLimit = header.GasLimit
+	}
+	base := &types.Header{
+		ParentHash:  header.ParentHash,
+		UncleHash:   types.EmptyUncleHash,
+		Coinbase:    header.Coinbase,
+		Root:        root,
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+		Difficulty:  header.Difficulty,
+		Number:      header.Number,
+		GasLimit:    gasLimit,
+		Time:        header.Time,
+		Extra:       header.Extra,
+	}
+	for _, block := range []*types.Block{types.NewBlockWithHeader(genesis), types.NewBlockWithHeader(base)} {
+		rawdb.WriteTd(database, block.Hash(), block.NumberU64(), block.Difficulty())
+		rawdb.WriteBlock(database, block)
+		rawdb.WriteReceipts(database, block.Hash(), block.NumberU64(), nil)
+		rawdb.WriteCanonicalHash(database, block.Hash(), block.NumberU64())
+	}
+	rawdb.WriteHeadBlockHash(database, base.Hash())
+	rawdb.WriteHeadFastBlockHash(database, base.Hash())
+	rawdb.WriteHeadHeaderHash(database, base.Hash())
+	rawdb.WriteChainConfig(database, genesis.Hash(), config)
+
+	// The ancestors of the fork block are missing, so skip the header verification.
+	// State snapshots would only cover the local changes, so they are disabled.
+	cacheConfig := &core.CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  5 * time.Minute,
+	}
+	blockchain, err := core.NewBlockChainWithStateDatabase(database, fork.database(database), cacheConfig, config, ethash.NewFullFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	backend := &SimulatedBackend{
+		database:   database,
+		blockchain: blockchain,
+		config:     config,
+		fork:       fork,
+		events:     filters.NewEventSystem(&filterBackend{database, blockchain}, false),
+	}
+	backend.rollback()
+	return backend, nil
+}
+
+// forkState retrieves the state of a remote chain at a fixed block, caching all
+// values retrieved. It is shared by the state databases of a forked backend.
+type forkState struct {
+	client *ethclient.Client
+	number *big.Int
+
+	lock     sync.Mutex
+	addrs    map[common.Hash]common.Address            // Preimages of the hashed account addresses
+	accounts map[common.Address][]byte                 // RLP encoded remote accounts, nil if nonexistent
+	storage  map[common.Address]map[common.Hash][]byte // RLP encoded remote storage slots, nil if empty
+	roots    map[common.Hash]bool                      // Remote state and storage roots, not available locally
+	locals   map[common.Address]map[common.Hash]bool   // Storage roots of accounts created locally
+}
+
+func newForkState(client *ethclient.Client, header *types.Header) *forkState {
+	return &forkState{
+		client:   client,
+		number:   new(big.Int).Set(header.Number),
+		addrs:    make(map[common.Hash]common.Address),
+		accounts: make(map[common.Address][]byte),
+		storage:  make(map[common.Address]map[common.Hash][]byte),
+		roots:    map[common.Hash]bool{header.Root: true},
+		locals:   make(map[common.Address]map[common.Hash]bool),
+	}
+}
+
+// database returns a state database storing local changes in db, retrieving
+// everything else from the remote node.
+func (f *forkState) database(db ethdb.Database) state.Database {
+	return &forkDatabase{Database: state.NewDatabase(db), fork: f}
+}
+
+// address records the preimage of a hashed account address.
+func (f *forkState) address(addr common.Address) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.addrs[crypto.Keccak256Hash(addr[:])] = addr
+}
+
+// preimage returns the account address of an address hash, if known.
+func (f *forkState) preimage(addrHash common.Hash) (common.Address, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	addr, ok := f.addrs[addrHash]
+	return addr, ok
+}
+
+// remoteRoot reports whether a state or storage root belongs to the remote chain.
+func (f *forkState) remoteRoot(root common.Hash) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.roots[root]
+}
+
+// localStorage records the storage root of an account created locally, whose
+// storage must not be retrieved from the remote node.
+func (f *forkState) localStorage(addr common.Address, root common.Hash) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.locals[addr] == nil {
+		f.locals[addr] = make(map[common.Hash]bool)
+	}
+	f.locals[addr][root] = true
+}
+
+// isLocalStorage reports whether a storage root belongs to an account created
+// locally. Accounts start out with an empty storage root when created, whereas
+// the storage of remote accounts is either non-empty or has nothing to retrieve.
+func (f *forkState) isLocalStorage(addr common.Address, root common.Hash) bool {
+	if root == types.EmptyRootHash {
+		return true
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.locals[addr][root]
+}
+
+// account retrieves the RLP encoded remote account, or nil if it doesn't exist.
+func (f *forkState) account(addr common.Address) ([]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if enc, ok := f.accounts[addr]; ok {
+		return enc, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), forkRequestTimeout)
+	defer cancel()
+
+	result, err := f.client.GetProof(ctx, addr, nil, f.number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve remote account %x: %v", addr, err)
+	}
+	var enc []byte
+	if result.Nonce != 0 || result.Balance.Sign() != 0 || (result.CodeHash != (common.Hash{}) && result.CodeHash != emptyCodeHash) {
+		enc, err = rlp.EncodeToBytes(&state.Account{
+			Nonce:    result.Nonce,
+			Balance:  result.Balance,
+			Root:     result.StorageHash,
+			CodeHash: result.CodeHash.Bytes(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		f.roots[result.StorageHash] = true
+	}
+	f.accounts[addr] = enc
+	return enc, nil
+}
+
+// slot retrieves the RLP encoded value of a remote storage slot, or nil if it
+// is empty.
+func (f *forkState) slot(addr common.Address, key common.Hash) ([]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if enc, ok := f.storage[addr][key]; ok {
+		return enc, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), forkRequestTimeout)
+	defer cancel()
+
+	value, err := f.client.StorageAt(ctx, addr, key, f.number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve remote storage %x of %x: %v", key, addr, err)
+	}
+	var enc []byte
+	if value = common.TrimLeftZeroes(value); len(value) > 0 {
+		if enc, err = rlp.EncodeToBytes(value); err != nil {
+			return nil, err
+		}
+	}
+	if f.storage[addr] == nil {
+		f.storage[addr] = make(map[common.Hash][]byte)
+	}
+	f.storage[addr][key] = enc
+	return enc, nil
+}
+
+// code retrieves the contract code of a remote account, checking it against the
+// expected code hash.
+func (f *forkState) code(addrHash, codeHash common.Hash) ([]byte, error) {
+	addr, ok := f.preimage(addrHash)
+	if !ok {
+		return nil, fmt.Errorf("unknown account %x", addrHash)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), forkRequestTimeout)
+	defer cancel()
+
+	code, err := f.client.CodeAt(ctx, addr, f.number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve remote code of %x: %v", addr, err)
+	}
+	if hash := crypto.Keccak256Hash(code); hash != codeHash {
+		return nil, fmt.Errorf("remote code hash mismatch for %x: have %x, want %x", addr, hash, codeHash)
+	}
+	return code, nil
+}
+
+// forkDatabase is a state database which keeps the changes to the remote state
+// in local tries, retrieving any values not present locally from the remote node.
+type forkDatabase struct {
+	state.Database
+	fork *forkState
+}
+
+// openTrie opens a local trie, starting with an empty one for remote roots.
+func (db *forkDatabase) openTrie(root common.Hash) (*trie.SecureTrie, error) {
+	if db.fork.remoteRoot(root) {
+		root = common.Hash{}
+	}
+	return trie.NewSecure(root, db.TrieDB())
+}
+
+// OpenTrie opens the main account trie.
+func (db *forkDatabase) OpenTrie(root common.Hash) (state.Trie, error) {
+	tr, err := db.openTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	return &forkTrie{local: tr, fork: db.fork, remote: true}, nil
+}
+
+// OpenStorageTrie opens the storage trie of an account. The storage of accounts
+// created locally, including ones destroyed and recreated, is never retrieved
+// from the remote node.
+func (db *forkDatabase) OpenStorageTrie(addrHash, root common.Hash) (state.Trie, error) {
+	tr, err := db.openTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	addr, ok := db.fork.preimage(addrHash)
+	if !ok {
+		return nil, fmt.Errorf("unknown account %x", addrHash)
+	}
+	return &forkTrie{local: tr, fork: db.fork, owner: &addr, remote: !db.fork.isLocalStorage(addr, root)}, nil
+}
+
+// CopyTrie returns an independent copy of the given trie.
+func (db *forkDatabase) CopyTrie(t state.Trie) state.Trie {
+	if t, ok := t.(*forkTrie); ok {
+		return &forkTrie{local: t.local.Copy(), fork: t.fork, owner: t.owner, remote: t.remote}
+	}
+	return db.Database.CopyTrie(t)
+}
+
+// ContractCode retrieves a particular contract's code, from the remote node if
+// it isn't available locally.
+func (db *forkDatabase) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
+	if code, err := db.Database.ContractCode(addrHash, codeHash); err == nil {
+		return code, nil
+	}
+	code, err := db.fork.code(addrHash, codeHash)
+
+	if err != nil {
+		return nil, err
+	}
+	rawdb.WriteCode(db.TrieDB().DiskDB(), codeHash, code)
+	return code, nil
+}
+
+// ContractCodeWithPrefix retrieves a particular contract's code like ContractCode.
+func (db *forkDatabase) ContractCodeWithPrefix(addrHash, codeHash common.Hash) ([]byte, error) {
+	return db.ContractCode(addrHash, codeHash)
+}
+
+// ContractCodeSize retrieves a particular contracts code's size.
+func (db *forkDatabase) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(addrHash, codeHash)
+	return len(code), err
+}
+
+// forkTrie is an account or storage trie holding the local changes to the remote
+// state. Values missing from the local trie are retrieved from the remote node,
+// while deleted ones are kept as tombstones to hide the remote values.
+//
+// The root hash and iterators of the trie only cover the local changes.
+type forkTrie struct {
+	local  *trie.SecureTrie
+	fork   *forkState
+	owner  *common.Address // Account of a storage trie, nil for the account trie
+	remote bool            // Whether values missing locally are retrieved remotely
+}
+
+// GetKey returns the sha3 preimage of a hashed key.
+func (t *forkTrie) GetKey(key []byte) []byte {
+	return t.local.GetKey(key)
+}
+
+// TryGet returns the value for key, retrieving it from the remote node if it
+// isn't available locally.
+func (t *forkTrie) TryGet(key []byte) ([]byte, error) {
+	if t.owner == nil {
+		t.fork.address(common.BytesToAddress(key))
+	}
+	enc, err := t.local.TryGet(key)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.Equal(enc, forkTombstone):
+		return nil, nil
+	case len(enc) > 0 || !t.remote:
+		return enc, nil
+	case t.owner == nil:
+		return t.fork.account(common.BytesToAddress(key))
+	default:
+		return t.fork.slot(*t.owner, common.BytesToHash(key))
+	}
+}
+
+// TryUpdate associates key with value in the local trie.
+func (t *forkTrie) TryUpdate(key, value []byte) error {
+	if len(value) == 0 {
+		return t.TryDelete(key)
+	}
+	return t.local.TryUpdate(key, value)
+}
+
+// TryDelete replaces the value of key with a tombstone, hiding the remote value.
+func (t *forkTrie) TryDelete(key []byte) error {
+	return t.local.TryUpdate(key, forkTombstone)
+}
+
+// Hash returns the root hash of the local trie.
+func (t *forkTrie) Hash() common.Hash {
+	root := t.local.Hash()
+	t.track(root)
+	return root
+}
+
+// Commit writes the local trie to the trie database.
+func (t *forkTrie) Commit(onleaf trie.LeafCallback) (common.Hash, error) {
+	root, err := t.local.Commit(onleaf)
+	if err == nil {
+		t.track(root)
+	}
+	return root, err
+}
+
+// track records the root of a locally created storage trie, so it isn't mistaken
+// for modified remote storage when reopened.
+func (t *forkTrie) track(root common.Hash) {
+	if t.owner != nil && !t.remote {
+		t.fork.localStorage(*t.owner, root)
+	}
+}
+
+// NodeIterator returns an iterator over the nodes of the local trie.
+func (t *forkTrie) NodeIterator(start []byte) trie.NodeIterator {
+	return t.local.NodeIterator(start)
+}
+
+// Prove constructs a Merkle proof for key in the local trie.
+func (t *forkTrie) Prove(key []byte, fromLevel uint, proofDb ethdb.KeyValueWriter) error {
+	return t.local.Prove(key, fromLevel, proofDb)
+}
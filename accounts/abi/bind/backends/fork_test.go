@@ -0,0 +1,291 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/vars"
+)
+
+// Tests that a forked backend reads the remote state at the fork block, and
+// transacts against remote contracts without modifying the remote chain.
+func TestForkedSimulatedBackend(t *testing.T) {
+	var (
+		remoteKey, _ = crypto.GenerateKey()
+		remoteAddr   = crypto.PubkeyToAddress(remoteKey.PublicKey)
+		localKey, _  = crypto.GenerateKey()
+		localAddr    = crypto.PubkeyToAddress(localKey.PublicKey)
+		contract     = common.Address{0xc0}
+		// Stores the first word of the call data in slot 0
+		code   = common.FromHex("0x600035600055")
+		config = params.AllEthashProtocolChanges
+		signer = types.NewEIP155Signer(config.GetChainID())
+	)
+	genesis := &genesisT.Genesis{
+		Config: config,
+		Alloc: genesisT.GenesisAlloc{
+			remoteAddr: {Balance: big.NewInt(vars.Ether)},
+			contract: {Balance: new(big.Int), Code: code, Storage: map[common.Hash]common.Hash{
+				{0x00}:                          common.BigToHash(big.NewInt(42)),
+				common.BigToHash(big.NewInt(1)): common.BigToHash(big.NewInt(7)),
+			}},
+		},
+	}
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(config, core.GenesisToBlock(genesis, db), ethash.NewFaker(), db, 2, func(i int, gen *core.BlockGen) {
+		switch i {
+		case 0:
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(remoteAddr), common.Address{0x01}, big.NewInt(1000), 21000, big.NewInt(1), nil), signer, remoteKey)
+			gen.AddTx(tx)
+		case 1:
+			// Modify the contract storage after the fork block
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(remoteAddr), contract, new(big.Int), 100000, big.NewInt(1), common.BigToHash(big.NewInt(99)).Bytes()), signer, remoteKey)
+			gen.AddTx(tx)
+		}
+	})
+	client, stop := startForkRemote(t, genesis, blocks)
+	defer stop()
+
+	// Fork the chain at the first block and check the remote state
+	sim, err := NewForkedSimulatedBackend(client, config, big.NewInt(1), genesisT.GenesisAlloc{localAddr: {Balance: big.NewInt(vars.Ether)}}, 0)
+	if err != nil {
+		t.Fatalf("can't fork chain: %v", err)
+	}
+	defer sim.Close()
+
+	ctx := context.Background()
+	if head := sim.Blockchain().CurrentBlock(); head.NumberU64() != 1 {
+		t.Fatalf("fork block number mismatch: have %d, want 1", head.NumberU64())
+	}
+	remoteBalance, _ := client.BalanceAt(ctx, remoteAddr, big.NewInt(1))
+	if balance, err := sim.BalanceAt(ctx, remoteAddr, nil); err != nil || balance.Cmp(remoteBalance) != 0 {
+		t.Errorf("remote balance mismatch: have %v (%v), want %v", balance, err, remoteBalance)
+	}
+	if nonce, err := sim.NonceAt(ctx, remoteAddr, nil); err != nil || nonce != 1 {
+		t.Errorf("remote nonce mismatch: have %d (%v), want 1", nonce, err)
+	}
+	if balance, err := sim.BalanceAt(ctx, localAddr, nil); err != nil || balance.Cmp(big.NewInt(vars.Ether)) != 0 {
+		t.Errorf("local balance mismatch: have %v (%v), want %v", balance, err, vars.Ether)
+	}
+	if have, err := sim.CodeAt(ctx, contract, nil); err != nil || common.Bytes2Hex(have) != common.Bytes2Hex(code) {
+		t.Errorf("remote code mismatch: have %x (%v), want %x", have, err, code)
+	}
+	checkStorage := func(slot int64, want int64) {
+		t.Helper()
+		value, err := sim.StorageAt(ctx, contract, common.BigToHash(big.NewInt(slot)), nil)
+		if err != nil {
+			t.Fatalf("can't retrieve slot %d: %v", slot, err)
+		}
+		if have := new(big.Int).SetBytes(value); have.Int64() != want {
+			t.Errorf("slot %d mismatch: have %v, want %d", slot, have, want)
+		}
+	}
+	checkStorage(0, 42)
+	checkStorage(1, 7)
+
+	// Clear and then set the remote slot by transacting against the contract
+	for i, value := range []int64{0, 5} {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), contract, new(big.Int), 100000, big.NewInt(1), common.BigToHash(big.NewInt(value)).Bytes()), signer, localKey)
+		if err := sim.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("can't send transaction %d: %v", i, err)
+		}
+		sim.Commit()
+
+		receipt, err := sim.TransactionReceipt(ctx, tx.Hash())
+		if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+			t.Fatalf("transaction %d failed: %v", i, err)
+		}
+		checkStorage(0, value)
+		checkStorage(1, 7)
+	}
+	if head := sim.Blockchain().CurrentBlock(); head.NumberU64() != 3 {
+		t.Fatalf("head block number mismatch: have %d, want 3", head.NumberU64())
+	}
+	// The remote chain must not be affected
+	if value, _ := client.StorageAt(ctx, contract, common.Hash{}, big.NewInt(1)); new(big.Int).SetBytes(value).Int64() != 42 {
+		t.Errorf("remote slot modified: have %x", value)
+	}
+}
+
+// Tests that a forked backend runs with the configuration of the remote chain,
+// rejecting configurations with a different chain ID.
+func TestForkedSimulatedBackendChainID(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.Address{0xc0}
+		// Returns the result of the CHAINID opcode
+		code = common.FromHex("0x4660005260206000f3")
+	)
+	config := *params.AllEthashProtocolChanges
+	config.ChainID = big.NewInt(61)
+
+	genesis := &genesisT.Genesis{
+		Config: &config,
+		Alloc: genesisT.GenesisAlloc{
+			addr:     {Balance: big.NewInt(vars.Ether)},
+			contract: {Balance: new(big.Int), Code: code},
+		},
+	}
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(&config, core.GenesisToBlock(genesis, db), ethash.NewFaker(), db, 1, nil)
+
+	client, stop := startForkRemote(t, genesis, blocks)
+	defer stop()
+
+	if _, err := NewForkedSimulatedBackend(client, params.AllEthashProtocolChanges, nil, nil, 0); err == nil {
+		t.Fatal("forked with mismatching chain ID")
+	}
+	sim, err := NewForkedSimulatedBackend(client, &config, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("can't fork chain: %v", err)
+	}
+	defer sim.Close()
+
+	ctx := context.Background()
+	result, err := sim.CallContract(ctx, ethereum.CallMsg{From: addr, To: &contract}, nil)
+	if err != nil {
+		t.Fatalf("can't call contract: %v", err)
+	}
+	if have := new(big.Int).SetBytes(result); have.Cmp(config.ChainID) != 0 {
+		t.Errorf("chain ID mismatch: have %v, want %v", have, config.ChainID)
+	}
+	// Transactions signed for the remote chain must be accepted
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{0x01}, big.NewInt(1000), 21000, big.NewInt(1), nil), types.NewEIP155Signer(config.ChainID), key)
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("can't send transaction: %v", err)
+	}
+	sim.Commit()
+	if receipt, err := sim.TransactionReceipt(ctx, tx.Hash()); err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("transaction failed: %v", err)
+	}
+}
+
+// Tests that the storage of a remote account destroyed and recreated locally is
+// not retrieved from the remote node anymore.
+func TestForkedSimulatedBackendRecreate(t *testing.T) {
+	var (
+		contract = common.Address{0xc0}
+		config   = params.AllEthashProtocolChanges
+	)
+	genesis := &genesisT.Genesis{
+		Config: config,
+		Alloc: genesisT.GenesisAlloc{
+			contract: {Balance: new(big.Int), Code: []byte{0x00}, Storage: map[common.Hash]common.Hash{
+				{0x01}: {0x01},
+				{0x02}: {0x02},
+			}},
+		},
+	}
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(config, core.GenesisToBlock(genesis, db), ethash.NewFaker(), db, 1, nil)
+
+	client, stop := startForkRemote(t, genesis, blocks)
+	defer stop()
+
+	sim, err := NewForkedSimulatedBackend(client, config, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("can't fork chain: %v", err)
+	}
+	defer sim.Close()
+
+	// Destroy the contract, recreate it and commit the new state
+	sdb := sim.fork.database(sim.database)
+	statedb, err := state.New(sim.Blockchain().CurrentBlock().Root(), sdb, nil)
+	if err != nil {
+		t.Fatalf("can't open state: %v", err)
+	}
+	if have := statedb.GetState(contract, common.Hash{0x01}); have != (common.Hash{0x01}) {
+		t.Fatalf("remote slot mismatch: have %x, want %x", have, common.Hash{0x01})
+	}
+	statedb.Suicide(contract)
+	statedb.Finalise(true)
+	statedb.CreateAccount(contract)
+	statedb.SetBalance(contract, big.NewInt(1))
+	if have := statedb.GetState(contract, common.Hash{0x01}); have != (common.Hash{}) {
+		t.Errorf("recreated slot mismatch: have %x, want empty", have)
+	}
+	statedb.SetState(contract, common.Hash{0x03}, common.Hash{0x03})
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatalf("can't commit state: %v", err)
+	}
+	// Reopen the state and check that only the local storage is present
+	if statedb, err = state.New(root, sdb, nil); err != nil {
+		t.Fatalf("can't reopen state: %v", err)
+	}
+	for slot, want := range map[common.Hash]common.Hash{
+		{0x01}: {},
+		{0x02}: {},
+		{0x03}: {0x03},
+	} {
+		if have := statedb.GetState(contract, slot); have != want {
+			t.Errorf("slot %x mismatch: have %x, want %x", slot, have, want)
+		}
+	}
+}
+
+// startForkRemote starts a node serving the given chain to fork off, returning
+// a client connected to it and a function stopping the node.
+func startForkRemote(t *testing.T, genesis *genesisT.Genesis, blocks []*types.Block) (*ethclient.Client, func()) {
+	t.Helper()
+
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("can't create node: %v", err)
+	}
+	ethconf := &eth.Config{Genesis: genesis}
+	ethconf.Ethash.PowMode = ethash.ModeFake
+	ethservice, err := eth.New(stack, ethconf)
+	if err != nil {
+		stack.Close()
+		t.Fatalf("can't create ethereum service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		stack.Close()
+		t.Fatalf("can't start node: %v", err)
+	}
+	if _, err := ethservice.BlockChain().InsertChain(blocks); err != nil {
+		stack.Close()
+		t.Fatalf("can't import blocks: %v", err)
+	}
+	rpcClient, err := stack.Attach()
+	if err != nil {
+		stack.Close()
+		t.Fatalf("can't attach to node: %v", err)
+	}
+	return ethclient.NewClient(rpcClient), func() {
+		rpcClient.Close()
+		stack.Close()
+	}
+}
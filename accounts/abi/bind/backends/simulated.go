@@ -73,6 +73,7 @@ type SimulatedBackend struct {
 	events *filters.EventSystem // Event system for filtering log events live
 
 	config ctypes.ChainConfigurator
+	fork   *forkState // Remote state the chain was forked off, nil if not forked
 }
 
 // NewSimulatedBackendWithDatabase creates a new binding backend based on the given database
@@ -124,8 +125,17 @@ func (b *SimulatedBackend) Rollback() {
 	b.rollback()
 }
 
+// generateBlock generates a new block on top of the current head, reading and
+// writing the state through the remote state if the chain is forked.
+func (b *SimulatedBackend) generateBlock(gen func(int, *core.BlockGen)) ([]*types.Block, []types.Receipts) {
+	if b.fork != nil {
+		return core.GenerateChainWithStateDatabase(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.fork.database(b.database), 1, gen)
+	}
+	return core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, gen)
+}
+
 func (b *SimulatedBackend) rollback() {
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(int, *core.BlockGen) {})
+	blocks, _ := b.generateBlock(func(int, *core.BlockGen) {})
 	stateDB, _ := b.blockchain.State()
 
 	b.pendingBlock = blocks[0]
@@ -569,7 +579,7 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
 	}
 
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
+	blocks, _ := b.generateBlock(func(number int, block *core.BlockGen) {
 		for _, tx := range b.pendingBlock.Transactions() {
 			block.AddTxWithChain(b.blockchain, tx)
 		}
@@ -687,7 +697,7 @@ func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 		return errors.New("Could not adjust time on non-empty block")
 	}
 
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
+	blocks, _ := b.generateBlock(func(number int, block *core.BlockGen) {
 		block.OffsetTime(int64(adjustment.Seconds()))
 	})
 	stateDB, _ := b.blockchain.State()
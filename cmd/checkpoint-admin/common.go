@@ -17,6 +17,7 @@
 package main
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/ethereum/go-ethereum/accounts"
@@ -77,25 +78,33 @@ func getCheckpoint(ctx *cli.Context, client *rpc.Client) *ctypes.TrustedCheckpoi
 			BloomRoot:    common.HexToHash(result[2]),
 		}
 	} else {
-		var result [4]string
-		err := client.Call(&result, "les_latestCheckpoint")
-		if err != nil {
+		var err error
+		if checkpoint, err = latestCheckpoint(client); err != nil {
 			utils.Fatalf("Failed to get local checkpoint %v, please ensure the les API is exposed", err)
 		}
-		index, err := strconv.ParseUint(result[0], 0, 64)
-		if err != nil {
-			utils.Fatalf("Failed to parse checkpoint index %v", err)
-		}
-		checkpoint = &ctypes.TrustedCheckpoint{
-			SectionIndex: index,
-			SectionHead:  common.HexToHash(result[1]),
-			CHTRoot:      common.HexToHash(result[2]),
-			BloomRoot:    common.HexToHash(result[3]),
-		}
 	}
 	return checkpoint
 }
 
+// latestCheckpoint retrieves the latest checkpoint generated by the local
+// indexers of the remote node.
+func latestCheckpoint(client *rpc.Client) (*ctypes.TrustedCheckpoint, error) {
+	var result [4]string
+	if err := client.Call(&result, "les_latestCheckpoint"); err != nil {
+		return nil, err
+	}
+	index, err := strconv.ParseUint(result[0], 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint index: %v", err)
+	}
+	return &ctypes.TrustedCheckpoint{
+		SectionIndex: index,
+		SectionHead:  common.HexToHash(result[1]),
+		CHTRoot:      common.HexToHash(result[2]),
+		BloomRoot:    common.HexToHash(result[3]),
+	}, nil
+}
+
 // newContract creates a registrar contract instance with specified
 // contract address or the default contracts for mainnet or testnet.
 func newContract(client *rpc.Client) (common.Address, *checkpointoracle.CheckpointOracle) {
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/contracts/checkpointoracle"
 	"github.com/ethereum/go-ethereum/contracts/checkpointoracle/contract"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
@@ -210,21 +211,35 @@ func sign(ctx *cli.Context) error {
 		}
 	}
 	clef := newRPCClient(ctx.String(clefURLFlag.Name))
-	p := make(map[string]string)
-	buf := make([]byte, 8)
-	binary.BigEndian.PutUint64(buf, cindex)
-	p["address"] = address.Hex()
-	p["message"] = hexutil.Encode(append(buf, chash.Bytes()...))
 
 	fmt.Println("Sending signing request to Clef...")
-	if err := clef.Call(&signature, "account_signData", accounts.MimetypeDataWithValidator, signer, p); err != nil {
+	sig, err := signCheckpoint(clef, common.HexToAddress(signer), address, cindex, chash)
+	if err != nil {
 		utils.Fatalf("Failed to sign checkpoint, err %v", err)
 	}
+	signature = hexutil.Encode(sig)
+
 	fmt.Printf("Signer     => %s\n", signer)
 	fmt.Printf("Signature  => %s\n", signature)
 	return nil
 }
 
+// signCheckpoint requests clef to sign the checkpoint with the given index and
+// hash on behalf of the oracle contract.
+func signCheckpoint(clef *rpc.Client, signer common.Address, oracle common.Address, index uint64, hash common.Hash) ([]byte, error) {
+	p := make(map[string]string)
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, index)
+	p["address"] = oracle.Hex()
+	p["message"] = hexutil.Encode(append(buf, hash.Bytes()...))
+
+	var signature hexutil.Bytes
+	if err := clef.Call(&signature, "account_signData", accounts.MimetypeDataWithValidator, signer, p); err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
+
 // sighash calculates the hash of the data to sign for the checkpoint oracle.
 func sighash(index uint64, oracle common.Address, hash common.Hash) []byte {
 	buf := make([]byte, 8)
@@ -236,14 +251,30 @@ func sighash(index uint64, oracle common.Address, hash common.Hash) []byte {
 
 // ecrecover calculates the sender address from a sighash and signature combo.
 func ecrecover(sighash []byte, sig []byte) common.Address {
+	signer, err := recoverSigner(sighash, sig)
+	if err != nil {
+		utils.Fatalf("Failed to recover sender from signature %x: %v", sig, err)
+	}
+	return signer
+}
+
+// recoverSigner calculates the sender address from a sighash and an "ethereum
+// style" signature, whose recovery id is 27 or 28.
+func recoverSigner(sighash []byte, sig []byte) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("invalid signature length %d", len(sig))
+	}
+	if sig[64] != 27 && sig[64] != 28 {
+		return common.Address{}, fmt.Errorf("invalid signature recovery id %d", sig[64])
+	}
+	sig = common.CopyBytes(sig)
 	sig[64] -= 27
-	defer func() { sig[64] += 27 }()
 
 	signer, err := crypto.SigToPub(sighash, sig)
 	if err != nil {
-		utils.Fatalf("Failed to recover sender from signature %x: %v", sig, err)
+		return common.Address{}, err
 	}
-	return crypto.PubkeyToAddress(*signer)
+	return crypto.PubkeyToAddress(*signer), nil
 }
 
 // publish registers the specified checkpoint which generated by connected node
@@ -270,25 +301,13 @@ func publish(ctx *cli.Context) error {
 		checkpoint   = getCheckpoint(ctx, client)
 		sighash      = sighash(checkpoint.SectionIndex, addr, checkpoint.Hash())
 	)
-	for i := 0; i < len(sigs); i++ {
-		for j := i + 1; j < len(sigs); j++ {
-			signerA := ecrecover(sighash, sigs[i])
-			signerB := ecrecover(sighash, sigs[j])
-			if bytes.Compare(signerA.Bytes(), signerB.Bytes()) > 0 {
-				sigs[i], sigs[j] = sigs[j], sigs[i]
-			}
-		}
-	}
+	sortSignatures(sighash, sigs)
+
 	// Retrieve recent header info to protect replay attack
 	reqCtx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelFn()
 
-	head, err := ethclient.NewClient(client).HeaderByNumber(reqCtx, nil)
-	if err != nil {
-		return err
-	}
-	num := head.Number.Uint64()
-	recent, err := ethclient.NewClient(client).HeaderByNumber(reqCtx, big.NewInt(int64(num-128)))
+	recent, err := sentryHeader(reqCtx, client)
 	if err != nil {
 		return err
 	}
@@ -309,3 +328,28 @@ func publish(ctx *cli.Context) error {
 	log.Info("Successfully registered checkpoint", "tx", tx.Hash().Hex())
 	return nil
 }
+
+// sortSignatures sorts the checkpoint signatures by the address of their
+// signers, as required by the oracle contract.
+func sortSignatures(sighash []byte, sigs [][]byte) {
+	for i := 0; i < len(sigs); i++ {
+		for j := i + 1; j < len(sigs); j++ {
+			signerA := ecrecover(sighash, sigs[i])
+			signerB := ecrecover(sighash, sigs[j])
+			if bytes.Compare(signerA.Bytes(), signerB.Bytes()) > 0 {
+				sigs[i], sigs[j] = sigs[j], sigs[i]
+			}
+		}
+	}
+}
+
+// sentryHeader retrieves the recent header referenced by a checkpoint
+// registration to protect against replaying it on another chain.
+func sentryHeader(ctx context.Context, client *rpc.Client) (*types.Header, error) {
+	head, err := ethclient.NewClient(client).HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	num := head.Number.Uint64()
+	return ethclient.NewClient(client).HeaderByNumber(ctx, big.NewInt(int64(num-128)))
+}
@@ -43,6 +43,7 @@ func init() {
 		commandDeploy,
 		commandSign,
 		commandPublish,
+		commandServe,
 	}
 	app.Flags = []cli.Flag{
 		oracleFlag,
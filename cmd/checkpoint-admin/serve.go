@@ -0,0 +1,382 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/vars"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	confirmationsFlag = cli.Uint64Flag{
+		Name:  "confirmations",
+		Value: 2048,
+		Usage: "Number of blocks on top of a checkpoint's section before it is considered final and signed",
+	}
+	intervalFlag = cli.DurationFlag{
+		Name:  "interval",
+		Value: time.Minute,
+		Usage: "Interval between checks for new checkpoints",
+	}
+	listenFlag = cli.StringFlag{
+		Name:  "listen",
+		Usage: "Address to serve the local checkpoint signature to the other signers on (e.g. localhost:8555)",
+	}
+	peersFlag = cli.StringFlag{
+		Name:  "peers",
+		Usage: "Comma separated URLs of the other signers' serve endpoints to collect signatures from",
+	}
+	publishFlag = cli.BoolFlag{
+		Name:  "publish",
+		Usage: "Publish checkpoints into the oracle once enough signatures were collected",
+	}
+)
+
+var commandServe = cli.Command{
+	Name:  "serve",
+	Usage: "Automatically sign and publish new checkpoints",
+	Flags: []cli.Flag{
+		nodeURLFlag,
+		clefURLFlag,
+		signerFlag,
+		thresholdFlag,
+		confirmationsFlag,
+		intervalFlag,
+		listenFlag,
+		peersFlag,
+		publishFlag,
+	},
+	Action: utils.MigrateFlags(serve),
+	Description: `
+The serve command runs a long-lived checkpoint signing service. It watches the
+checkpoints created by the LES server of the connected node and signs each of
+them with clef once its section is buried under the configured number of
+confirmations.
+
+The signatures are exchanged between the signers over HTTP: every service
+serves its own latest signature on --listen and polls the signatures of the
+signers listed in --peers. If --publish is set, the checkpoint is registered in
+the oracle as soon as the signatures of --threshold admins were collected.`,
+}
+
+// publishRetry is the time to wait before registering a checkpoint again if a
+// previous registration did not make it into the oracle.
+const publishRetry = 10 * time.Minute
+
+// signedCheckpoint is a checkpoint signature exchanged between the signers.
+type signedCheckpoint struct {
+	Index     uint64         `json:"index"`
+	Hash      common.Hash    `json:"hash"`
+	Signer    common.Address `json:"signer"`
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+// checkpointService signs final checkpoints and collects the signatures of the
+// other oracle admins until the checkpoint can be published.
+type checkpointService struct {
+	node          *rpc.Client
+	clef          *rpc.Client
+	oracle        *checkpointoracle.CheckpointOracle
+	address       common.Address     // Address of the oracle contract
+	signer        common.Address     // Admin account used for signing
+	opts          *bind.TransactOpts // Transactor for publishing, nil if disabled
+	threshold     int
+	confirmations uint64
+	peers         []string
+	client        *http.Client
+
+	lock      sync.Mutex
+	signed    *signedCheckpoint         // Local signature of the latest final checkpoint
+	sigs      map[common.Address][]byte // Signatures collected for the same checkpoint
+	published time.Time                 // Time the checkpoint was last registered
+}
+
+// serve runs the automatic checkpoint signing service.
+func serve(ctx *cli.Context) error {
+	if !ctx.IsSet(signerFlag.Name) || !common.IsHexAddress(ctx.String(signerFlag.Name)) {
+		utils.Fatalf("Please specify the signer account (--signer)")
+	}
+	threshold := ctx.Int(thresholdFlag.Name)
+	if threshold <= 0 {
+		utils.Fatalf("Invalid signature threshold %d", threshold)
+	}
+	node := newRPCClient(ctx.GlobalString(nodeURLFlag.Name))
+	address, oracle := newContract(node)
+
+	s := &checkpointService{
+		node:          node,
+		clef:          newRPCClient(ctx.String(clefURLFlag.Name)),
+		oracle:        oracle,
+		address:       address,
+		signer:        common.HexToAddress(ctx.String(signerFlag.Name)),
+		threshold:     threshold,
+		confirmations: ctx.Uint64(confirmationsFlag.Name),
+		client:        &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, peer := range strings.Split(ctx.String(peersFlag.Name), ",") {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			s.peers = append(s.peers, strings.TrimSuffix(peer, "/"))
+		}
+	}
+	if ctx.Bool(publishFlag.Name) {
+		s.opts = newClefSigner(ctx)
+	}
+	admins, err := oracle.Contract().GetAllAdmin(nil)
+	if err != nil {
+		return err
+	}
+	if !isAdmin(admins, s.signer) {
+		utils.Fatalf("Signer %s is not an admin of oracle %s", s.signer.Hex(), address.Hex())
+	}
+	if threshold > len(admins) {
+		utils.Fatalf("Signature threshold %d exceeds the number of admins %d", threshold, len(admins))
+	}
+	if listen := ctx.String(listenFlag.Name); listen != "" {
+		go func() {
+			if err := http.ListenAndServe(listen, s); err != nil {
+				utils.Fatalf("Failed to serve signatures: %v", err)
+			}
+		}()
+		log.Info("Serving checkpoint signatures", "address", listen)
+	}
+	log.Info("Started checkpoint signing service", "oracle", address, "signer", s.signer,
+		"threshold", threshold, "peers", len(s.peers), "publish", s.opts != nil)
+
+	interval := ctx.Duration(intervalFlag.Name)
+	for {
+		reqCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if err := s.step(reqCtx); err != nil {
+			log.Warn("Checkpoint signing round failed", "err", err)
+		}
+		cancel()
+		time.Sleep(interval)
+	}
+}
+
+// step runs a single round of the service: it signs the latest checkpoint if it
+// is final, collects the other admins' signatures and publishes it if enough
+// signatures are available.
+func (s *checkpointService) step(ctx context.Context) error {
+	checkpoint, err := latestCheckpoint(s.node)
+	if err != nil {
+		return err
+	}
+	if checkpoint.Empty() {
+		return nil
+	}
+	head, err := ethclient.NewClient(s.node).HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if final := (checkpoint.SectionIndex+1)*vars.CheckpointFrequency + s.confirmations; head.Number.Uint64() < final {
+		log.Debug("Checkpoint not final yet", "index", checkpoint.SectionIndex, "head", head.Number, "final", final)
+		return nil
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	latest, _, height, err := s.oracle.Contract().GetLatestCheckpoint(opts)
+	if err != nil {
+		return err
+	}
+	if checkpoint.SectionIndex < latest || (checkpoint.SectionIndex == latest && (latest != 0 || height.Uint64() != 0)) {
+		log.Debug("Checkpoint already registered", "index", checkpoint.SectionIndex, "latest", latest)
+		return nil
+	}
+	admins, err := s.oracle.Contract().GetAllAdmin(opts)
+	if err != nil {
+		return err
+	}
+	if err := s.sign(checkpoint); err != nil {
+		return err
+	}
+	for _, peer := range s.peers {
+		signed, err := s.fetch(ctx, peer)
+		if err != nil {
+			log.Warn("Failed to fetch checkpoint signature", "peer", peer, "err", err)
+			continue
+		}
+		if err := s.addSignature(signed, admins); err != nil {
+			log.Warn("Rejected checkpoint signature", "peer", peer, "err", err)
+		}
+	}
+	if s.opts == nil {
+		return nil
+	}
+	return s.publish(ctx, checkpoint)
+}
+
+// sign signs the given checkpoint with clef, unless it was signed already.
+func (s *checkpointService) sign(checkpoint *ctypes.TrustedCheckpoint) error {
+	hash := checkpoint.Hash()
+
+	s.lock.Lock()
+	signed := s.signed
+	s.lock.Unlock()
+	if signed != nil && signed.Index == checkpoint.SectionIndex && signed.Hash == hash {
+		return nil
+	}
+	sig, err := signCheckpoint(s.clef, s.signer, s.address, checkpoint.SectionIndex, hash)
+	if err != nil {
+		return fmt.Errorf("failed to sign checkpoint: %v", err)
+	}
+	if signer, err := recoverSigner(sighash(checkpoint.SectionIndex, s.address, hash), sig); err != nil || signer != s.signer {
+		return fmt.Errorf("clef returned invalid checkpoint signature %x", sig)
+	}
+	s.lock.Lock()
+	s.signed = &signedCheckpoint{Index: checkpoint.SectionIndex, Hash: hash, Signer: s.signer, Signature: sig}
+	s.sigs = map[common.Address][]byte{s.signer: sig}
+	s.published = time.Time{}
+	s.lock.Unlock()
+
+	log.Info("Signed checkpoint", "index", checkpoint.SectionIndex, "hash", hash)
+	return nil
+}
+
+// addSignature verifies a signature received from another signer and adds it to
+// the collected ones if it signs the same checkpoint.
+func (s *checkpointService) addSignature(signed *signedCheckpoint, admins []common.Address) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.signed == nil || signed.Index != s.signed.Index {
+		return nil // Signer is ahead or behind us, try again later
+	}
+	if signed.Hash != s.signed.Hash {
+		return fmt.Errorf("checkpoint %d hash mismatch: have %x, want %x", signed.Index, signed.Hash, s.signed.Hash)
+	}
+	signer, err := recoverSigner(sighash(signed.Index, s.address, signed.Hash), signed.Signature)
+	if err != nil {
+		return err
+	}
+	if signer != signed.Signer {
+		return fmt.Errorf("signature by %x claims to be from %x", signer, signed.Signer)
+	}
+	if !isAdmin(admins, signer) {
+		return fmt.Errorf("signer %x is not an admin", signer)
+	}
+	if _, ok := s.sigs[signer]; !ok {
+		log.Info("Collected checkpoint signature", "index", signed.Index, "signer", signer, "have", len(s.sigs)+1, "need", s.threshold)
+	}
+	s.sigs[signer] = signed.Signature
+	return nil
+}
+
+// publish registers the checkpoint in the oracle if enough signatures were
+// collected.
+func (s *checkpointService) publish(ctx context.Context, checkpoint *ctypes.TrustedCheckpoint) error {
+	s.lock.Lock()
+	var sigs [][]byte
+	for _, sig := range s.sigs {
+		sigs = append(sigs, sig)
+	}
+	recent := time.Since(s.published) < publishRetry
+	s.lock.Unlock()
+
+	if len(sigs) < s.threshold {
+		log.Info("Waiting for checkpoint signatures", "index", checkpoint.SectionIndex, "have", len(sigs), "need", s.threshold)
+		return nil
+	}
+	if recent {
+		return nil
+	}
+	hash := checkpoint.Hash()
+	sortSignatures(sighash(checkpoint.SectionIndex, s.address, hash), sigs)
+
+	sentry, err := sentryHeader(ctx, s.node)
+	if err != nil {
+		return err
+	}
+	tx, err := s.oracle.RegisterCheckpoint(s.opts, checkpoint.SectionIndex, hash.Bytes(), sentry.Number, sentry.Hash(), sigs)
+	if err != nil {
+		return fmt.Errorf("failed to register checkpoint: %v", err)
+	}
+	s.lock.Lock()
+	s.published = time.Now()
+	s.lock.Unlock()
+
+	log.Info("Published checkpoint", "index", checkpoint.SectionIndex, "hash", hash, "signatures", len(sigs), "tx", tx.Hash())
+	return nil
+}
+
+// fetch retrieves the latest checkpoint signature of another signer.
+func (s *checkpointService) fetch(ctx context.Context, peer string) (*signedCheckpoint, error) {
+	req, err := http.NewRequest(http.MethodGet, peer+"/checkpoint", nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	signed := new(signedCheckpoint)
+	if err := json.NewDecoder(res.Body).Decode(signed); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+// ServeHTTP serves the local signature of the latest final checkpoint.
+func (s *checkpointService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/checkpoint" || r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	s.lock.Lock()
+	signed := s.signed
+	s.lock.Unlock()
+
+	if signed == nil {
+		http.Error(w, errNoCheckpoint.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(signed)
+}
+
+var errNoCheckpoint = errors.New("no checkpoint signed yet")
+
+// isAdmin checks whether the given address is in the list of oracle admins.
+func isAdmin(admins []common.Address, addr common.Address) bool {
+	for _, admin := range admins {
+		if admin == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,382 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle"
+	"github.com/ethereum/go-ethereum/contracts/checkpointoracle/contract"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/vars"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testCheckpoint is the checkpoint generated by the fake node of the tests.
+var testCheckpoint = &ctypes.TrustedCheckpoint{
+	SectionIndex: 0,
+	SectionHead:  common.HexToHash("0x01"),
+	CHTRoot:      common.HexToHash("0x02"),
+	BloomRoot:    common.HexToHash("0x03"),
+}
+
+// testLesAPI serves the checkpoint of the fake node.
+type testLesAPI struct{}
+
+func (api *testLesAPI) LatestCheckpoint() [4]string {
+	return [4]string{
+		hexutil.EncodeUint64(testCheckpoint.SectionIndex),
+		testCheckpoint.SectionHead.Hex(),
+		testCheckpoint.CHTRoot.Hex(),
+		testCheckpoint.BloomRoot.Hex(),
+	}
+}
+
+// testEthAPI serves the headers of the simulated chain, optionally reporting a
+// fake head number to make checkpoints final.
+type testEthAPI struct {
+	backend *backends.SimulatedBackend
+	head    uint64
+}
+
+func (api *testEthAPI) GetBlockByNumber(number rpc.BlockNumber, full bool) *types.Header {
+	if number >= 0 {
+		return api.backend.Blockchain().GetHeaderByNumber(uint64(number))
+	}
+	head := types.CopyHeader(api.backend.Blockchain().CurrentHeader())
+	if api.head != 0 {
+		head.Number = new(big.Int).SetUint64(api.head)
+	}
+	return head
+}
+
+// testClefAPI signs checkpoints with the keys of the oracle admins.
+type testClefAPI struct {
+	keys map[common.Address]*ecdsa.PrivateKey
+}
+
+func (api *testClefAPI) SignData(contentType string, addr common.Address, data map[string]string) (hexutil.Bytes, error) {
+	key, ok := api.keys[addr]
+	if !ok {
+		return nil, fmt.Errorf("unknown account %x", addr)
+	}
+	message, err := hexutil.Decode(data["message"])
+	if err != nil || len(message) != 8+common.HashLength {
+		return nil, fmt.Errorf("invalid message %q", data["message"])
+	}
+	index := binary.BigEndian.Uint64(message[:8])
+	hash := common.BytesToHash(message[8:])
+
+	sig, err := crypto.Sign(sighash(index, common.HexToAddress(data["address"]), hash), key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+// testOracle is a checkpoint oracle deployed on a simulated chain together with
+// the fake node and clef endpoints the service talks to.
+type testOracle struct {
+	backend *backends.SimulatedBackend
+	address common.Address
+	oracle  *checkpointoracle.CheckpointOracle
+	admins  []*ecdsa.PrivateKey
+
+	eth  *testEthAPI
+	node *rpc.Client
+	clef *rpc.Client
+}
+
+// newTestOracle deploys an oracle with the given number of admins and signature
+// threshold, then mines enough blocks to publish the first checkpoint.
+func newTestOracle(t *testing.T, admins int, threshold int64) *testOracle {
+	t.Helper()
+
+	var (
+		alloc = make(genesisT.GenesisAlloc)
+		keys  = make(map[common.Address]*ecdsa.PrivateKey)
+		addrs []common.Address
+		o     = new(testOracle)
+	)
+	for i := 0; i < admins+1; i++ {
+		key, _ := crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		alloc[addr] = genesisT.GenesisAccount{Balance: big.NewInt(1000000000)}
+		keys[addr] = key
+		if i < admins {
+			o.admins = append(o.admins, key)
+			addrs = append(addrs, addr)
+		}
+	}
+	o.backend = backends.NewSimulatedBackend(alloc, 10000000)
+
+	address, _, _, err := contract.DeployCheckpointOracle(bind.NewKeyedTransactor(o.admins[0]), o.backend, addrs, big.NewInt(1), big.NewInt(1), big.NewInt(threshold))
+	if err != nil {
+		t.Fatalf("failed to deploy oracle: %v", err)
+	}
+	// The sentry header of a registration is 128 blocks behind the head
+	for i := 0; i < 130; i++ {
+		o.backend.Commit()
+	}
+	o.address = address
+	if o.oracle, err = checkpointoracle.NewCheckPointOracle(address, o.backend); err != nil {
+		t.Fatalf("failed to bind oracle: %v", err)
+	}
+	node := rpc.NewServer()
+	node.RegisterName("les", new(testLesAPI))
+	o.eth = &testEthAPI{backend: o.backend}
+	node.RegisterName("eth", o.eth)
+	o.node = rpc.DialInProc(node)
+
+	clef := rpc.NewServer()
+	clef.RegisterName("account", &testClefAPI{keys: keys})
+	o.clef = rpc.DialInProc(clef)
+
+	return o
+}
+
+func (o *testOracle) close() {
+	o.node.Close()
+	o.clef.Close()
+	o.backend.Close()
+}
+
+// adminAddrs returns the addresses of the oracle admins.
+func (o *testOracle) adminAddrs() []common.Address {
+	var addrs []common.Address
+	for _, key := range o.admins {
+		addrs = append(addrs, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	return addrs
+}
+
+// newService creates a signing service for the given admin key.
+func (o *testOracle) newService(key *ecdsa.PrivateKey, threshold int, peers ...string) *checkpointService {
+	return &checkpointService{
+		node:          o.node,
+		clef:          o.clef,
+		oracle:        o.oracle,
+		address:       o.address,
+		signer:        crypto.PubkeyToAddress(key.PublicKey),
+		threshold:     threshold,
+		confirmations: 16,
+		peers:         peers,
+		client:        &http.Client{Timeout: time.Second},
+	}
+}
+
+// signed returns a checkpoint signature made with the given key.
+func (o *testOracle) signed(key *ecdsa.PrivateKey, index uint64, hash common.Hash) *signedCheckpoint {
+	sig, _ := crypto.Sign(sighash(index, o.address, hash), key)
+	sig[64] += 27
+	return &signedCheckpoint{Index: index, Hash: hash, Signer: crypto.PubkeyToAddress(key.PublicKey), Signature: sig}
+}
+
+func TestServeHTTP(t *testing.T) {
+	o := newTestOracle(t, 1, 1)
+	defer o.close()
+
+	s := o.newService(o.admins[0], 1)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	get := func(method, path string) *http.Response {
+		req, _ := http.NewRequest(method, srv.URL+path, nil)
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		return res
+	}
+	// Nothing is served until the first checkpoint is signed
+	if res := get(http.MethodGet, "/checkpoint"); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("unsigned checkpoint status mismatch: have %d, want %d", res.StatusCode, http.StatusNotFound)
+	}
+	if err := s.sign(testCheckpoint); err != nil {
+		t.Fatalf("failed to sign checkpoint: %v", err)
+	}
+	if res := get(http.MethodGet, "/other"); res.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown path status mismatch: have %d, want %d", res.StatusCode, http.StatusNotFound)
+	}
+	if res := get(http.MethodPost, "/checkpoint"); res.StatusCode != http.StatusNotFound {
+		t.Errorf("POST status mismatch: have %d, want %d", res.StatusCode, http.StatusNotFound)
+	}
+	res := get(http.MethodGet, "/checkpoint")
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("signed checkpoint status mismatch: have %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	var signed signedCheckpoint
+	if err := json.NewDecoder(res.Body).Decode(&signed); err != nil {
+		t.Fatalf("failed to decode signature: %v", err)
+	}
+	if signed.Index != testCheckpoint.SectionIndex || signed.Hash != testCheckpoint.Hash() || signed.Signer != s.signer {
+		t.Errorf("served checkpoint mismatch: have %+v", signed)
+	}
+	if signer, err := recoverSigner(sighash(signed.Index, o.address, signed.Hash), signed.Signature); err != nil || signer != s.signer {
+		t.Errorf("served signature mismatch: have signer %x (%v), want %x", signer, err, s.signer)
+	}
+}
+
+func TestAddSignature(t *testing.T) {
+	o := newTestOracle(t, 3, 2)
+	defer o.close()
+
+	var (
+		admins      = o.adminAddrs()
+		s           = o.newService(o.admins[0], 2)
+		hash        = testCheckpoint.Hash()
+		outsider, _ = crypto.GenerateKey()
+	)
+	// Signatures are ignored until a checkpoint is signed locally
+	if err := s.addSignature(o.signed(o.admins[1], 0, hash), admins); err != nil {
+		t.Fatalf("signature before local checkpoint rejected: %v", err)
+	}
+	if err := s.sign(testCheckpoint); err != nil {
+		t.Fatalf("failed to sign checkpoint: %v", err)
+	}
+	if len(s.sigs) != 1 {
+		t.Fatalf("signature collected before local checkpoint")
+	}
+	// Signatures of other sections are ignored, mismatching ones rejected
+	if err := s.addSignature(o.signed(o.admins[1], 1, hash), admins); err != nil {
+		t.Errorf("signature of other section rejected: %v", err)
+	}
+	if err := s.addSignature(o.signed(o.admins[1], 0, common.HexToHash("0xdead")), admins); err == nil {
+		t.Errorf("signature with hash mismatch accepted")
+	}
+	if err := s.addSignature(o.signed(outsider, 0, hash), admins); err == nil {
+		t.Errorf("signature of non-admin accepted")
+	}
+	impersonated := o.signed(o.admins[2], 0, hash)
+	impersonated.Signer = admins[1]
+	if err := s.addSignature(impersonated, admins); err == nil {
+		t.Errorf("signature impersonating an admin accepted")
+	}
+	if len(s.sigs) != 1 {
+		t.Fatalf("invalid signatures collected: have %d, want 1", len(s.sigs))
+	}
+	// Valid signatures are collected once per signer
+	for i := 0; i < 2; i++ {
+		if err := s.addSignature(o.signed(o.admins[1], 0, hash), admins); err != nil {
+			t.Fatalf("valid signature rejected: %v", err)
+		}
+	}
+	if len(s.sigs) != 2 {
+		t.Errorf("collected signature count mismatch: have %d, want 2", len(s.sigs))
+	}
+}
+
+func TestStep(t *testing.T) {
+	o := newTestOracle(t, 3, 2)
+	defer o.close()
+
+	// Start two peers, one of which has signed the checkpoint already
+	signer := o.newService(o.admins[1], 2)
+	if err := signer.sign(testCheckpoint); err != nil {
+		t.Fatalf("failed to sign checkpoint: %v", err)
+	}
+	peer := httptest.NewServer(signer)
+	defer peer.Close()
+	idle := httptest.NewServer(o.newService(o.admins[2], 2))
+	defer idle.Close()
+
+	s := o.newService(o.admins[0], 2, peer.URL, idle.URL)
+
+	// Checkpoints that are not final yet are not signed
+	final := (testCheckpoint.SectionIndex+1)*vars.CheckpointFrequency + s.confirmations
+	o.eth.head = final - 1
+	if err := s.step(context.Background()); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	if s.signed != nil {
+		t.Fatalf("checkpoint signed before being final")
+	}
+	// Final checkpoints are signed and the signatures of the peers collected
+	o.eth.head = final
+	if err := s.step(context.Background()); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	if s.signed == nil || s.signed.Index != testCheckpoint.SectionIndex || s.signed.Hash != testCheckpoint.Hash() {
+		t.Fatalf("signed checkpoint mismatch: have %+v", s.signed)
+	}
+	if len(s.sigs) != 2 {
+		t.Fatalf("collected signature count mismatch: have %d, want 2", len(s.sigs))
+	}
+	if _, ok := s.sigs[signer.signer]; !ok {
+		t.Errorf("peer signature missing")
+	}
+}
+
+func TestPublishThreshold(t *testing.T) {
+	o := newTestOracle(t, 3, 2)
+	defer o.close()
+
+	s := o.newService(o.admins[0], 2)
+	s.opts = bind.NewKeyedTransactor(o.admins[0])
+	if err := s.sign(testCheckpoint); err != nil {
+		t.Fatalf("failed to sign checkpoint: %v", err)
+	}
+	latest := func() (uint64, common.Hash) {
+		o.backend.Commit()
+		index, hash, _, err := o.oracle.Contract().GetLatestCheckpoint(nil)
+		if err != nil {
+			t.Fatalf("failed to retrieve latest checkpoint: %v", err)
+		}
+		return index, hash
+	}
+	// A single signature is below the threshold, nothing may be published
+	if err := s.publish(context.Background(), testCheckpoint); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if !s.published.IsZero() {
+		t.Fatalf("checkpoint published below threshold")
+	}
+	if _, hash := latest(); hash != (common.Hash{}) {
+		t.Fatalf("checkpoint registered below threshold: %x", hash)
+	}
+	// Reaching the threshold registers the checkpoint
+	if err := s.addSignature(o.signed(o.admins[1], 0, testCheckpoint.Hash()), o.adminAddrs()); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if err := s.publish(context.Background(), testCheckpoint); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if s.published.IsZero() {
+		t.Fatalf("checkpoint not published at threshold")
+	}
+	if index, hash := latest(); index != testCheckpoint.SectionIndex || hash != testCheckpoint.Hash() {
+		t.Errorf("registered checkpoint mismatch: have %d/%x, want %d/%x", index, hash, testCheckpoint.SectionIndex, testCheckpoint.Hash())
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/storage"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	auditorFlag = cli.StringFlag{
+		Name:  "auditor",
+		Usage: "Address of the audit key expected to have signed the audit log",
+	}
+	auditHeadFlag = cli.StringFlag{
+		Name:  "head",
+		Usage: "Previously recorded head (<seq>:<hash>) the audit log must contain",
+	}
+	fromVaultFlag = cli.BoolFlag{
+		Name:  "from-vault",
+		Usage: "Read the audit key and the last recorded head from the clef vault (requires the master seed)",
+	}
+	auditOutputFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "File to write the exported entries to (default = stdout)",
+	}
+	auditCommand = cli.Command{
+		Name:  "audit",
+		Usage: "Verify and export the audit log",
+		Subcommands: []cli.Command{
+			auditVerifyCommand,
+			auditExportCommand,
+		},
+	}
+	auditVerifyCommand = cli.Command{
+		Action: utils.MigrateFlags(verifyAudit),
+		Name:   "verify",
+		Usage:  "Verify the hash chain and signatures of the audit log",
+		Flags: []cli.Flag{
+			logLevelFlag,
+			configdirFlag,
+			signerSecretFlag,
+			auditLogFlag,
+			auditorFlag,
+			auditHeadFlag,
+			fromVaultFlag,
+		},
+		Description: `
+The audit verify command checks that the entries of the audit log form an unbroken
+hash chain signed by the audit key of the auditor, and prints the head of the log.
+Entries modified, removed or reordered are detected. The auditor is given with
+--auditor, or read from the clef vault using --from-vault.
+
+To detect the truncation of the end of the log, the log needs to be checked against
+a head recorded earlier, given with --head, or the head kept in the clef vault
+using --from-vault.`,
+	}
+	auditExportCommand = cli.Command{
+		Action: utils.MigrateFlags(exportAudit),
+		Name:   "export",
+		Usage:  "Export the verified audit log as JSON lines",
+		Flags: []cli.Flag{
+			logLevelFlag,
+			configdirFlag,
+			signerSecretFlag,
+			auditLogFlag,
+			auditorFlag,
+			auditHeadFlag,
+			fromVaultFlag,
+			auditOutputFlag,
+		},
+		Description: `
+The audit export command verifies the audit log like audit verify, and writes its
+entries as flat JSON objects, one per line, for ingestion by log management systems.`,
+	}
+)
+
+// auditExport is the exported form of an audit entry.
+type auditExport struct {
+	Timestamp string            `json:"@timestamp"`
+	Event     string            `json:"event"`
+	Seq       uint64            `json:"seq"`
+	Hash      common.Hash       `json:"hash"`
+	Prev      common.Hash       `json:"prev"`
+	Auditor   common.Address    `json:"auditor"`
+	Fields    map[string]string `json:"fields"`
+}
+
+// verifyAuditLog verifies the audit log configured by the command line flags,
+// returning its path, head and auditor.
+func verifyAuditLog(ctx *cli.Context) (string, *core.AuditHead, common.Address) {
+	path := ctx.String(auditLogFlag.Name)
+	if path == "" {
+		utils.Fatalf("Missing audit log, use --%s", auditLogFlag.Name)
+	}
+	var (
+		auditor *common.Address
+		known   *core.AuditHead
+	)
+	if s := ctx.String(auditorFlag.Name); s != "" {
+		if !common.IsHexAddress(s) {
+			utils.Fatalf("Invalid auditor address: %q", s)
+		}
+		addr := common.HexToAddress(s)
+		auditor = &addr
+	}
+	if s := ctx.String(auditHeadFlag.Name); s != "" {
+		head, err := core.ParseAuditHead(s)
+		if err != nil {
+			utils.Fatalf(err.Error())
+		}
+		known = &head
+	}
+	if ctx.Bool(fromVaultFlag.Name) {
+		if err := initialize(ctx); err != nil {
+			utils.Fatalf(err.Error())
+		}
+		stretchedKey, err := readMasterKey(ctx, nil)
+		if err != nil {
+			utils.Fatalf(err.Error())
+		}
+		key, err := crypto.ToECDSA(crypto.Keccak256([]byte("audit"), stretchedKey))
+		if err != nil {
+			utils.Fatalf("Could not derive audit key: %v", err)
+		}
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		auditor = &addr
+
+		configDir := ctx.GlobalString(configdirFlag.Name)
+		vaultLocation := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), stretchedKey)[:10]))
+		confKey := crypto.Keccak256([]byte("config"), stretchedKey)
+		configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confKey)
+
+		stored, err := core.StoredAuditHead(configStorage)
+		if err != nil {
+			utils.Fatalf(err.Error())
+		}
+		if stored != nil && (known == nil || stored.Seq > known.Seq) {
+			known = stored
+		}
+	}
+	if auditor == nil {
+		utils.Fatalf("Missing auditor, use --%s or --%s", auditorFlag.Name, fromVaultFlag.Name)
+	}
+	head, err := core.VerifyAuditLog(path, *auditor, known)
+	if err != nil {
+		utils.Fatalf("Audit log verification failed: %v", err)
+	}
+	return path, head, *auditor
+}
+
+func verifyAudit(ctx *cli.Context) error {
+	path, head, auditor := verifyAuditLog(ctx)
+	fmt.Printf("Audit log %s verified\n", path)
+	fmt.Printf("  entries: %d\n", head.Seq+1)
+	fmt.Printf("  auditor: %s\n", auditor.Hex())
+	fmt.Printf("  head:    %s\n", head)
+	return nil
+}
+
+func exportAudit(ctx *cli.Context) error {
+	path, head, auditor := verifyAuditLog(ctx)
+
+	var out io.Writer = os.Stdout
+	if file := ctx.String(auditOutputFlag.Name); file != "" {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			utils.Fatalf(err.Error())
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	return core.ReadAuditLog(path, func(entry *core.AuditEntry) error {
+		// Skip entries appended since the verification
+		if entry.Seq > head.Seq {
+			return nil
+		}
+		return enc.Encode(&auditExport{
+			Timestamp: entry.Time,
+			Event:     entry.Msg,
+			Seq:       entry.Seq,
+			Hash:      entry.Hash,
+			Prev:      entry.Prev,
+			Auditor:   auditor,
+			Fields:    entry.Data,
+		})
+	})
+}
@@ -19,6 +19,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
@@ -130,6 +131,32 @@ var (
 		Name:  "rules",
 		Usage: "Path to the rule file to auto-authorize requests with",
 	}
+	policyFlag = cli.StringFlag{
+		Name:  "policy",
+		Usage: "Path to the policy file (YAML or JSON) to auto-approve or reject transactions with",
+	}
+	simulateRPCFlag = cli.StringFlag{
+		Name:  "simulate-rpc",
+		Usage: "URL of a node (with the debug API enabled) to simulate transactions on before approval",
+	}
+	approversFlag = cli.StringFlag{
+		Name:  "approvers",
+		Usage: "Comma separated addresses of the keys of the approvers who need to reach a quorum to sign requests",
+	}
+	quorumFlag = cli.IntFlag{
+		Name:  "quorum",
+		Usage: "Number of approvers required to approve a signing request",
+		Value: 1,
+	}
+	quorumTimeoutFlag = cli.DurationFlag{
+		Name:  "quorum-timeout",
+		Usage: "Time after which signing requests lacking a quorum of approvers are rejected",
+		Value: 10 * time.Minute,
+	}
+	quorumAccountsFlag = cli.StringFlag{
+		Name:  "quorum-accounts",
+		Usage: "Comma separated accounts whose signing requests require a quorum of approvers (default = all)",
+	}
 	stdiouiFlag = cli.BoolFlag{
 		Name: "stdio-ui",
 		Usage: "Use STDIN/STDOUT as a channel for an external UI. " +
@@ -244,6 +271,12 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			customDBFlag,
 			auditLogFlag,
 			ruleFlag,
+			policyFlag,
+			simulateRPCFlag,
+			approversFlag,
+			quorumFlag,
+			quorumTimeoutFlag,
+			quorumAccountsFlag,
 			stdiouiFlag,
 			testFlag,
 			advancedMode,
@@ -279,6 +312,12 @@ func init() {
 		customDBFlag,
 		auditLogFlag,
 		ruleFlag,
+		policyFlag,
+		simulateRPCFlag,
+		approversFlag,
+		quorumFlag,
+		quorumTimeoutFlag,
+		quorumAccountsFlag,
 		stdiouiFlag,
 		testFlag,
 		advancedMode,
@@ -288,6 +327,8 @@ func init() {
 	app.Action = signer
 	app.Commands = []cli.Command{initCommand,
 		attestCommand,
+		policyCommand,
+		auditCommand,
 		setCredentialCommand,
 		delCredentialCommand,
 		newAccountCommand,
@@ -586,6 +627,9 @@ func signer(c *cli.Context) error {
 	var (
 		api       core.ExternalAPI
 		pwStorage storage.Storage = &storage.NoStorage{}
+
+		auditKey     *ecdsa.PrivateKey
+		auditStorage storage.Storage
 	)
 	configDir := c.GlobalString(configdirFlag.Name)
 	if stretchedKey, err := readMasterKey(c, ui); err != nil {
@@ -603,6 +647,12 @@ func signer(c *cli.Context) error {
 		jsStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "jsstorage.json"), jskey)
 		configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confkey)
 
+		// Sign the audit log with a key dedicated to it
+		if auditKey, err = crypto.ToECDSA(crypto.Keccak256([]byte("audit"), stretchedKey)); err != nil {
+			utils.Fatalf("Could not derive audit key: %v", err)
+		}
+		auditStorage = configStorage
+
 		// Do we have a rule-file?
 		if ruleFile := c.GlobalString(ruleFlag.Name); ruleFile != "" {
 			ruleJS, err := ioutil.ReadFile(ruleFile)
@@ -626,6 +676,36 @@ func signer(c *cli.Context) error {
 				}
 			}
 		}
+		// Do we have a policy-file? It is evaluated before the rules.
+		if policyFile := c.GlobalString(policyFlag.Name); policyFile != "" {
+			policyKey := crypto.Keccak256([]byte("policy"), stretchedKey)
+			policyStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "policy.json"), policyKey)
+
+			policyUI, err := loadPolicy(policyFile, configStorage, policyStorage, ui, big.NewInt(c.GlobalInt64(chainIdFlag.Name)), db)
+			if err != nil {
+				log.Warn("Could not load policy, disabling", "file", policyFile, "err", err)
+			} else {
+				ui = policyUI
+				log.Info("Policy engine configured", "file", policyFile)
+			}
+		}
+	}
+	// Do we require a quorum of approvers? It is only asked once the rules, policies
+	// or the user approved a request, and both need to approve, so automated
+	// approvals can't bypass it.
+	var quorumUI *core.QuorumUI
+	if approvers := c.GlobalString(approversFlag.Name); approvers != "" {
+		config := core.QuorumConfig{
+			Approvers: parseAddresses(approvers),
+			Threshold: c.GlobalInt(quorumFlag.Name),
+			Timeout:   c.GlobalDuration(quorumTimeoutFlag.Name),
+			Accounts:  parseAddresses(c.GlobalString(quorumAccountsFlag.Name)),
+		}
+		if quorumUI, err = core.NewQuorumUI(ui, config); err != nil {
+			utils.Fatalf("Could not configure approvers: %v", err)
+		}
+		ui = quorumUI
+		log.Info("Approver quorum configured", "quorum", config.Threshold, "approvers", len(config.Approvers), "timeout", config.Timeout)
 	}
 	var (
 		chainId  = c.GlobalInt64(chainIdFlag.Name)
@@ -639,6 +719,14 @@ func signer(c *cli.Context) error {
 		"light-kdf", lightKdf, "advanced", advanced)
 	am := core.StartClefAccountManager(ksLoc, nousb, lightKdf, scpath)
 	apiImpl := core.NewSignerAPI(am, chainId, nousb, ui, db, advanced, pwStorage)
+	if url := c.GlobalString(simulateRPCFlag.Name); url != "" {
+		client, err := rpc.Dial(url)
+		if err != nil {
+			utils.Fatalf("Could not connect to simulation node: %v", err)
+		}
+		apiImpl.SetSimulator(core.NewNodeSimulator(client))
+		log.Info("Transaction simulation configured", "url", url)
+	}
 
 	// Establish the bidirectional communication, by creating a new UI backend and registering
 	// it with the UI.
@@ -646,11 +734,22 @@ func signer(c *cli.Context) error {
 	api = apiImpl
 	// Audit logging
 	if logfile := c.GlobalString(auditLogFlag.Name); logfile != "" {
-		api, err = core.NewAuditLogger(logfile, api)
+		if auditKey == nil {
+			log.Warn("Master seed unavailable, audit log entries will not be signed")
+		}
+		auditLogger, err := core.NewAuditLogger(logfile, api, auditKey, auditStorage)
 		if err != nil {
 			utils.Fatalf(err.Error())
 		}
-		log.Info("Audit logs configured", "file", logfile)
+		api = auditLogger
+		if auditKey != nil {
+			log.Info("Audit logs configured", "file", logfile, "auditor", crypto.PubkeyToAddress(auditKey.PublicKey))
+		} else {
+			log.Info("Audit logs configured", "file", logfile)
+		}
+		if quorumUI != nil {
+			quorumUI.SetAuditLog(auditLogger.Logger())
+		}
 	}
 	// register signer API with server
 	var (
@@ -664,12 +763,22 @@ func signer(c *cli.Context) error {
 			Service:   api,
 			Version:   "1.0"},
 	}
+	exposed := []string{"account"}
+	if quorumUI != nil {
+		rpcAPI = append(rpcAPI, rpc.API{
+			Namespace: "approver",
+			Public:    true,
+			Service:   core.NewApproverAPI(quorumUI),
+			Version:   "1.0",
+		})
+		exposed = append(exposed, "approver")
+	}
 	if c.GlobalBool(utils.HTTPEnabledFlag.Name) {
 		vhosts := utils.SplitAndTrim(c.GlobalString(utils.HTTPVirtualHostsFlag.Name))
 		cors := utils.SplitAndTrim(c.GlobalString(utils.HTTPCORSDomainFlag.Name))
 
 		srv := rpc.NewServer()
-		err := node.RegisterApisFromWhitelist(rpcAPI, []string{"account"}, srv, false)
+		err := node.RegisterApisFromWhitelist(rpcAPI, exposed, srv, false)
 		if err != nil {
 			utils.Fatalf("Could not register API: %w", err)
 		}
@@ -758,6 +867,18 @@ func DefaultConfigDir() string {
 	return ""
 }
 
+// parseAddresses parses a comma separated list of addresses.
+func parseAddresses(list string) []common.Address {
+	var addrs []common.Address
+	for _, s := range utils.SplitAndTrim(list) {
+		if !common.IsHexAddress(s) {
+			utils.Fatalf("Invalid address: %q", s)
+		}
+		addrs = append(addrs, common.HexToAddress(s))
+	}
+	return addrs
+}
+
 func readMasterKey(ctx *cli.Context, ui core.UIClientAPI) ([]byte, error) {
 	var (
 		file      string
@@ -0,0 +1,175 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/fourbyte"
+	"github.com/ethereum/go-ethereum/signer/rules"
+	"github.com/ethereum/go-ethereum/signer/storage"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	policyCommand = cli.Command{
+		Name:  "policy",
+		Usage: "Manage declarative transaction policies",
+		Subcommands: []cli.Command{
+			policyAttestCommand,
+			policyTestCommand,
+		},
+	}
+	policyAttestCommand = cli.Command{
+		Action:    utils.MigrateFlags(attestPolicy),
+		Name:      "attest",
+		Usage:     "Attest that a policy file is to be used",
+		ArgsUsage: "<sha256sum>",
+		Flags: []cli.Flag{
+			logLevelFlag,
+			configdirFlag,
+			signerSecretFlag,
+		},
+		Description: `
+The policy attest command stores the sha256 of the policy file that you want to use
+for automatic processing of incoming transactions.
+
+Whenever you make an edit to the policy file, you need to attest it again.`,
+	}
+	policyTestCommand = cli.Command{
+		Action:    utils.MigrateFlags(testPolicy),
+		Name:      "test",
+		Usage:     "Dry-run a policy against sample transactions",
+		ArgsUsage: "<transactions.json>",
+		Flags: []cli.Flag{
+			logLevelFlag,
+			policyFlag,
+			chainIdFlag,
+			customDBFlag,
+		},
+		Description: `
+The policy test command evaluates a list of sample transactions against a policy
+file, printing the decision taken for each. The value of approved transactions
+counts towards the limits of later ones. The samples are a JSON array of objects
+holding a "transaction" in the format of account_signTransaction, and optionally
+the RFC3339 "time" at which it is sent and its predicted outcome "simulation".`,
+	}
+)
+
+// policySample is a transaction evaluated by the policy test command.
+type policySample struct {
+	Transaction core.SendTxArgs        `json:"transaction"`
+	Time        *time.Time             `json:"time,omitempty"`
+	Simulation  *core.SimulationResult `json:"simulation,omitempty"`
+}
+
+// loadPolicy reads an attested policy file and creates the policy engine for it.
+func loadPolicy(file string, configStorage, policyStorage storage.Storage, next core.UIClientAPI, chainID *big.Int, db *fourbyte.Database) (core.UIClientAPI, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	shasum := sha256.Sum256(data)
+	foundShaSum := hex.EncodeToString(shasum[:])
+	if storedShaSum, _ := configStorage.Get("policy_sha256"); storedShaSum != foundShaSum {
+		return nil, fmt.Errorf("policy hash %s not attested, attested %q", foundShaSum, storedShaSum)
+	}
+	policy, err := rules.ParsePolicy(data)
+	if err != nil {
+		return nil, err
+	}
+	return rules.NewPolicyEvaluator(next, policy, chainID, db, policyStorage)
+}
+
+func attestPolicy(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	if err := initialize(ctx); err != nil {
+		return err
+	}
+
+	stretchedKey, err := readMasterKey(ctx, nil)
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	configDir := ctx.GlobalString(configdirFlag.Name)
+	vaultLocation := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), stretchedKey)[:10]))
+	confKey := crypto.Keccak256([]byte("config"), stretchedKey)
+
+	configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confKey)
+	val := ctx.Args().First()
+	configStorage.Put("policy_sha256", val)
+	log.Info("Policy attestation updated", "sha256", val)
+	return nil
+}
+
+func testPolicy(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	policyFile := ctx.String(policyFlag.Name)
+	if policyFile == "" {
+		utils.Fatalf("Missing policy file, use --%s", policyFlag.Name)
+	}
+	policy, err := rules.LoadPolicy(policyFile)
+	if err != nil {
+		utils.Fatalf("Could not load policy: %v", err)
+	}
+	data, err := ioutil.ReadFile(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Could not read samples: %v", err)
+	}
+	var samples []policySample
+	if err := json.Unmarshal(data, &samples); err != nil {
+		utils.Fatalf("Could not parse samples: %v", err)
+	}
+	db, err := fourbyte.NewWithFile(ctx.String(customDBFlag.Name))
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	// Spending is tracked in memory only, nothing is persisted by a dry run
+	engine, err := rules.NewPolicyEvaluator(core.NewCommandlineUI(), policy, big.NewInt(ctx.Int64(chainIdFlag.Name)), db, storage.NewEphemeralStorage())
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	for i, sample := range samples {
+		at := time.Now()
+		if sample.Time != nil {
+			at = *sample.Time
+		}
+		verdict, reason := engine.DryRun(&core.SignTxRequest{Transaction: sample.Transaction, Simulation: sample.Simulation}, at)
+		if reason != "" {
+			fmt.Printf("%d: %s from %s: %s\n", i, verdict, sample.Transaction.From.Address().Hex(), reason)
+		} else {
+			fmt.Printf("%d: %s from %s\n", i, verdict, sample.Transaction.From.Address().Hex())
+		}
+	}
+	return nil
+}
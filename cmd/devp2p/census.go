@@ -0,0 +1,264 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params/confp"
+	"github.com/ethereum/go-ethereum/params/confp/generic"
+	"github.com/ethereum/go-ethereum/params/confp/tconvert"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/types/parity"
+	"github.com/ethereum/go-ethereum/params/vars"
+)
+
+// Census classes of nodes, relative to the chain configuration of the census.
+const (
+	classReady        = "ready"         // Node announces the fork ID of the configuration
+	classOutdated     = "outdated"      // Node does not know about the upcoming forks
+	classIncompatible = "incompatible"  // Node announces forks not in the configuration
+	classNoForkID     = "no-fork-id"    // Node speaks eth/63, which lacks fork IDs
+	classOtherNetwork = "other-network" // Node has a different genesis or network ID
+	classUnreachable  = "unreachable"   // Handshakes with the node failed or were not tried
+)
+
+var censusCSVHeader = []string{
+	"id", "ip", "client", "version", "caps", "eth", "network", "td", "head", "genesis", "forkHash", "forkNext", "class", "error",
+}
+
+// censusNetwork is a chain configuration the nodes are classified against.
+type censusNetwork struct {
+	config    ctypes.ChainConfigurator
+	genesis   common.Hash
+	networkID uint64
+	stages    []forkid.ID // fork ID after passing each number of forks
+}
+
+func newCensusNetwork(config ctypes.ChainConfigurator, genesis common.Hash) *censusNetwork {
+	n := &censusNetwork{config: config, genesis: genesis, networkID: vars.DefaultNetworkID}
+	if id := config.GetNetworkID(); id != nil && *id != 0 {
+		n.networkID = *id
+	}
+	n.stages = append(n.stages, forkid.NewID(config, genesis, 0))
+	for _, fork := range confp.Forks(config) {
+		if fork == 0 {
+			continue
+		}
+		n.stages = append(n.stages, forkid.NewID(config, genesis, fork))
+	}
+	return n
+}
+
+// loadCensusNetwork returns the network of a known name, or reads its genesis
+// from a file. The genesis may use any of the supported configuration formats.
+func loadCensusNetwork(arg string) (*censusNetwork, error) {
+	if config, genesis, err := ethtest.NetworkConfig(arg); err == nil {
+		return newCensusNetwork(config, genesis), nil
+	}
+	input, err := ioutil.ReadFile(arg)
+	if err != nil {
+		return nil, fmt.Errorf("neither a known network nor a readable genesis file: %v", err)
+	}
+	config, err := generic.UnmarshalChainConfiguratorOrGenesis(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain configuration: %v", err)
+	}
+	// Fork IDs commit to the genesis hash, so a bare chain configuration is
+	// not enough to classify nodes
+	var genesis *genesisT.Genesis
+	if spec, ok := config.(*parity.ParityChainSpec); ok {
+		genesis, err = tconvert.ParityConfigToCoreGethGenesis(spec)
+	} else {
+		genesis = new(genesisT.Genesis)
+		err = json.Unmarshal(input, genesis)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid genesis file: %v", err)
+	}
+	return newCensusNetwork(genesis.Config, core.GenesisToBlock(genesis, nil).Hash()), nil
+}
+
+// classify determines the census class of a node. Nodes which are still syncing
+// are classified by the forks they announce, so nodes which did not reach the
+// last fork they know about count as ready.
+func (n *censusNetwork) classify(info *ethInfo) string {
+	if info == nil || info.Error != "" {
+		return classUnreachable
+	}
+	if info.Genesis != n.genesis || info.NetworkID != n.networkID {
+		return classOtherNetwork
+	}
+	id, ok := info.forkID()
+	if !ok {
+		return classNoForkID
+	}
+	for _, stage := range n.stages {
+		if stage.Hash != id.Hash {
+			continue
+		}
+		switch id.Next {
+		case stage.Next:
+			return classReady
+		case 0:
+			return classOutdated
+		default:
+			return classIncompatible
+		}
+	}
+	return classIncompatible
+}
+
+// censusEntry is the census record of a node.
+type censusEntry struct {
+	ID      enode.ID `json:"id"`
+	IP      string   `json:"ip,omitempty"`
+	Client  string   `json:"client,omitempty"`
+	Version string   `json:"version,omitempty"`
+	Class   string   `json:"class"`
+	Eth     *ethInfo `json:"eth,omitempty"`
+}
+
+// censusReport summarizes the clients and fork readiness of a node set.
+type censusReport struct {
+	Genesis   common.Hash    `json:"genesis"`
+	NetworkID uint64         `json:"networkId"`
+	ForkID    string         `json:"forkId"`   // Latest fork ID of the configuration
+	NextFork  uint64         `json:"nextFork"` // Next fork after the most advanced ready node, zero if none
+	Time      time.Time      `json:"time"`
+	Nodes     int            `json:"nodes"`
+	Classes   map[string]int `json:"classes"`
+	Clients   map[string]int `json:"clients"`  // Client names of the nodes on the network
+	Versions  map[string]int `json:"versions"` // Client names and versions of the nodes on the network
+	Entries   []censusEntry  `json:"entries"`
+}
+
+// makeCensus classifies all nodes of a set against the network.
+func makeCensus(ns nodeSet, network *censusNetwork) *censusReport {
+	latest := network.stages[len(network.stages)-1]
+	report := &censusReport{
+		Genesis:   network.genesis,
+		NetworkID: network.networkID,
+		ForkID:    fmt.Sprintf("%#x", latest.Hash),
+		Time:      truncNow(),
+		Nodes:     len(ns),
+		Classes:   make(map[string]int),
+		Clients:   make(map[string]int),
+		Versions:  make(map[string]int),
+	}
+	passed := 0 // highest number of forks passed by any node
+	for _, n := range ns.nodes() {
+		info := ns[n.ID()].Eth
+		entry := censusEntry{ID: n.ID(), Class: network.classify(info), Eth: info}
+		if n.IP() != nil {
+			entry.IP = n.IP().String()
+		}
+		if info != nil {
+			entry.Client, entry.Version = parseClientName(info.Client)
+		}
+		report.Classes[entry.Class]++
+		if entry.Class != classUnreachable && entry.Class != classOtherNetwork {
+			report.Clients[entry.Client]++
+			report.Versions[entry.Client+"/"+entry.Version]++
+		}
+		if entry.Class == classReady {
+			id, _ := info.forkID()
+			for i, stage := range network.stages {
+				if stage.Hash == id.Hash && i > passed {
+					passed = i
+				}
+			}
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+	report.NextFork = network.stages[passed].Next
+	return report
+}
+
+// writeCSV writes the entries of the report as CSV.
+func (r *censusReport) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(censusCSVHeader)
+	for _, e := range r.Entries {
+		record := []string{e.ID.String(), e.IP, e.Client, e.Version}
+		if e.Eth != nil {
+			var td, forkHash, forkNext string
+			if e.Eth.TD != nil {
+				td = e.Eth.TD.ToInt().String()
+			}
+			if id, ok := e.Eth.forkID(); ok {
+				forkHash, forkNext = fmt.Sprintf("%#x", id.Hash), strconv.FormatUint(id.Next, 10)
+			}
+			record = append(record,
+				strings.Join(e.Eth.Caps, " "),
+				strconv.FormatUint(uint64(e.Eth.Version), 10),
+				strconv.FormatUint(e.Eth.NetworkID, 10),
+				td, e.Eth.Head.Hex(), e.Eth.Genesis.Hex(), forkHash, forkNext, e.Class, e.Eth.Error,
+			)
+		} else {
+			record = append(record, "", "", "", "", "", "", "", "", e.Class, "")
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// clientVersionRE matches the version component of a client identifier.
+var clientVersionRE = regexp.MustCompile(`^v?\d+\.\d+`)
+
+// parseClientName splits a client identifier like "CoreGeth/v1.11.17-stable/linux-amd64/go1.15"
+// into the client name and version. Custom node names between the two are skipped.
+func parseClientName(name string) (client, version string) {
+	parts := strings.Split(name, "/")
+	for _, part := range parts[1:] {
+		if clientVersionRE.MatchString(part) {
+			return parts[0], part
+		}
+	}
+	return parts[0], ""
+}
+
+// sortedCounts returns the keys of a count map, ordered by descending count.
+func sortedCounts(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,219 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/confp"
+	"github.com/ethereum/go-ethereum/params/confp/tconvert"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/types/multigeth"
+)
+
+func TestCensusClassify(t *testing.T) {
+	network := newCensusNetwork(params.ClassicChainConfig, params.MainnetGenesisHash)
+	if len(network.stages) < 3 {
+		t.Fatalf("too few fork stages: %d", len(network.stages))
+	}
+	var (
+		current  = network.stages[1]
+		previous = network.stages[0]
+		latest   = network.stages[len(network.stages)-1]
+	)
+	info := func(id forkid.ID, modify func(*ethInfo)) *ethInfo {
+		info := &ethInfo{
+			NetworkID: 1,
+			Genesis:   params.MainnetGenesisHash,
+			ForkHash:  id.Hash[:],
+			ForkNext:  id.Next,
+		}
+		if modify != nil {
+			modify(info)
+		}
+		return info
+	}
+	tests := []struct {
+		info *ethInfo
+		want string
+	}{
+		{nil, classUnreachable},
+		{&ethInfo{Error: "too many peers"}, classUnreachable},
+		{info(current, nil), classReady},
+		{info(latest, nil), classReady},
+		{info(previous, nil), classReady}, // still syncing
+		{info(forkid.ID{Hash: current.Hash}, nil), classOutdated},
+		{info(forkid.ID{Hash: current.Hash, Next: current.Next + 1}, nil), classIncompatible},
+		{info(forkid.ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}}, nil), classIncompatible},
+		{info(current, func(i *ethInfo) { i.ForkHash = nil }), classNoForkID},
+		{info(current, func(i *ethInfo) { i.NetworkID = 2 }), classOtherNetwork},
+		{info(current, func(i *ethInfo) { i.Genesis = params.MordorGenesisHash }), classOtherNetwork},
+	}
+	for i, tt := range tests {
+		if have := network.classify(tt.info); have != tt.want {
+			t.Errorf("test %d: wrong class: have %q, want %q", i, have, tt.want)
+		}
+	}
+}
+
+func TestLoadCensusNetwork(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devp2p-census-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Write a Ropsten based genesis in all the supported configuration formats
+	genesis := params.DefaultRopstenGenesisBlock()
+	genesis.Alloc = genesisT.GenesisAlloc{common.Address{1}: {Balance: big.NewInt(1)}}
+	parityspec, err := tconvert.NewParityChainSpec("ropsten", genesis, nil)
+	if err != nil {
+		t.Fatalf("failed to convert to parity: %v", err)
+	}
+	multigethspec := *genesis
+	multigethspec.Config = new(multigeth.ChainConfig)
+	if err := confp.Convert(genesis.Config, multigethspec.Config); err != nil {
+		t.Fatalf("failed to convert to multi-geth: %v", err)
+	}
+	files := map[string]interface{}{
+		"coregeth.json":  genesis,
+		"multigeth.json": &multigethspec,
+		"parity.json":    parityspec,
+		"config.json":    genesis.Config,
+	}
+	for name, spec := range files {
+		blob, err := json.Marshal(spec)
+		if err != nil {
+			t.Fatalf("%s: failed to encode: %v", name, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), blob, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := newCensusNetwork(genesis.Config, core.GenesisToBlock(genesis, nil).Hash())
+	for _, name := range []string{"coregeth.json", "multigeth.json", "parity.json"} {
+		have, err := loadCensusNetwork(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("%s: failed to load: %v", name, err)
+			continue
+		}
+		if have.genesis != want.genesis {
+			t.Errorf("%s: genesis mismatch: have %x, want %x", name, have.genesis, want.genesis)
+		}
+		if have.networkID != want.networkID {
+			t.Errorf("%s: network ID mismatch: have %d, want %d", name, have.networkID, want.networkID)
+		}
+		if !reflect.DeepEqual(have.stages, want.stages) {
+			t.Errorf("%s: fork stages mismatch:\nhave %v\nwant %v", name, have.stages, want.stages)
+		}
+	}
+	// A bare chain configuration lacks the genesis needed for fork IDs
+	if _, err := loadCensusNetwork(filepath.Join(dir, "config.json")); err == nil {
+		t.Errorf("chain configuration without genesis accepted")
+	}
+	if _, err := loadCensusNetwork(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("missing genesis file accepted")
+	}
+}
+
+func TestParseClientName(t *testing.T) {
+	tests := []struct {
+		name, client, version string
+	}{
+		{"CoreGeth/v1.11.17-stable-c3a56b8e/linux-amd64/go1.15.5", "CoreGeth", "v1.11.17-stable-c3a56b8e"},
+		{"Geth/mynode/v1.9.24-stable/linux-amd64/go1.15.5", "Geth", "v1.9.24-stable"},
+		{"besu/v20.10.1/linux-x86_64/oracle_openjdk-java-11", "besu", "v20.10.1"},
+		{"OpenEthereum/v3.1.0-stable-ae9d5bb/x86_64-linux-gnu/rustc1.47.0", "OpenEthereum", "v3.1.0-stable-ae9d5bb"},
+		{"custom", "custom", ""},
+	}
+	for _, tt := range tests {
+		client, version := parseClientName(tt.name)
+		if client != tt.client || version != tt.version {
+			t.Errorf("%q: have %q %q, want %q %q", tt.name, client, version, tt.client, tt.version)
+		}
+	}
+}
+
+// Tests that the RLPx crawler learns the client and status of a node.
+func TestRLPxCrawlerHandshake(t *testing.T) {
+	status := &ethtest.Status{
+		ProtocolVersion: 65,
+		NetworkID:       1,
+		TD:              big.NewInt(1000),
+		Head:            common.Hash{1},
+		Genesis:         params.MainnetGenesisHash,
+		ForkID:          forkid.NewID(params.ClassicChainConfig, params.MainnetGenesisHash, 0),
+	}
+	key, _ := crypto.GenerateKey()
+	srv := &p2p.Server{Config: p2p.Config{
+		PrivateKey:  key,
+		MaxPeers:    10,
+		ListenAddr:  "127.0.0.1:0",
+		NoDiscovery: true,
+		Name:        "CoreGeth/v1.11.17-stable/linux-amd64/go1.15.5",
+		Protocols: []p2p.Protocol{{
+			Name:    "eth",
+			Version: 65,
+			Length:  17,
+			Run: func(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+				if err := p2p.Send(rw, 0, status); err != nil {
+					return err
+				}
+				_, err := rw.ReadMsg()
+				return err
+			},
+		}},
+	}}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("can't start server: %v", err)
+	}
+	defer srv.Stop()
+
+	crawlerKey, _ := crypto.GenerateKey()
+	crawler := &rlpxCrawler{key: crawlerKey, workers: 1, timeout: 5 * time.Second}
+	info := crawler.handshake(srv.Self())
+	if info.Error != "" {
+		t.Fatalf("handshake failed: %v", info.Error)
+	}
+	if info.Client != srv.Name || info.Version != 65 || len(info.Caps) != 1 || info.Caps[0] != "eth/65" {
+		t.Errorf("wrong handshake info: %+v", info)
+	}
+	if info.NetworkID != 1 || info.TD.ToInt().Cmp(status.TD) != 0 || info.Head != status.Head || info.Genesis != status.Genesis {
+		t.Errorf("wrong status info: %+v", info)
+	}
+	if id, ok := info.forkID(); !ok || id != status.ForkID {
+		t.Errorf("wrong fork ID: have %v, want %v", id, status.ForkID)
+	}
+	network := newCensusNetwork(params.ClassicChainConfig, params.MainnetGenesisHash)
+	if class := network.classify(info); class != classReady {
+		t.Errorf("wrong class: %q", class)
+	}
+}
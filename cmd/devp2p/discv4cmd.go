@@ -19,14 +19,12 @@ package main
 import (
 	"fmt"
 	"net"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/v4test"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/internal/utesting"
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/params"
@@ -82,7 +80,7 @@ var (
 		Name:   "test",
 		Usage:  "Runs tests against a node",
 		Action: discv4Test,
-		Flags:  []cli.Flag{remoteEnodeFlag, testPatternFlag, testListen1Flag, testListen2Flag},
+		Flags:  []cli.Flag{remoteEnodeFlag, testPatternFlag, testJUnitFlag, testListen1Flag, testListen2Flag},
 	}
 )
 
@@ -117,6 +115,10 @@ var (
 		Name:  "run",
 		Usage: "Pattern of test suite(s) to run",
 	}
+	testJUnitFlag = cli.StringFlag{
+		Name:  "junit",
+		Usage: "Write a JUnit XML report of the test results to the given file",
+	}
 	testListen1Flag = cli.StringFlag{
 		Name:  "listen1",
 		Usage: "IP address of the first tester",
@@ -222,17 +224,7 @@ func discv4Test(ctx *cli.Context) error {
 	v4test.Listen1 = ctx.String(testListen1Flag.Name)
 	v4test.Listen2 = ctx.String(testListen2Flag.Name)
 
-	// Filter and run test cases.
-	tests := v4test.AllTests
-	if ctx.IsSet(testPatternFlag.Name) {
-		tests = utesting.MatchTests(tests, ctx.String(testPatternFlag.Name))
-	}
-	results := utesting.RunTests(tests, os.Stdout)
-	if fails := utesting.CountFailures(results); fails > 0 {
-		return fmt.Errorf("%v/%v tests passed.", len(tests)-fails, len(tests))
-	}
-	fmt.Printf("%v/%v passed\n", len(tests), len(tests))
-	return nil
+	return runTests(ctx, "discv4", v4test.AllTests)
 }
 
 // startV4 starts an ephemeral discovery V4 node.
@@ -18,7 +18,6 @@ package ethtest
 
 import (
 	"compress/gzip"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -26,10 +25,13 @@ import (
 	"os"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/confp/generic"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
-	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/vars"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -62,6 +64,14 @@ func (c *Chain) TD(height int) *big.Int { // TODO later on channge scheme so tha
 	return sum
 }
 
+// NetworkID returns the network ID of the chain.
+func (c *Chain) NetworkID() uint64 {
+	if id := c.chainConfig.GetNetworkID(); id != nil && *id != 0 {
+		return *id
+	}
+	return vars.DefaultNetworkID
+}
+
 // ForkID gets the fork id of the chain.
 func (c *Chain) ForkID() forkid.ID {
 	return forkid.NewID(c.chainConfig, c.blocks[0].Hash(), uint64(c.Len()))
@@ -149,18 +159,56 @@ func loadChain(chainfile string, genesis string) (*Chain, error) {
 		blocks = append(blocks, &b)
 	}
 
-	// Open the file handle and potentially unwrap the gzip stream
-	chainConfig, err := ioutil.ReadFile(genesis)
-	if err != nil {
-		return nil, err
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no blocks in %s", chainfile)
 	}
-	var gen genesisT.Genesis
-	if err := json.Unmarshal(chainConfig, &gen); err != nil {
+	chainConfig, err := loadChainConfig(genesis)
+	if err != nil {
 		return nil, err
 	}
-
 	return &Chain{
 		blocks:      blocks,
-		chainConfig: gen.Config,
+		chainConfig: chainConfig,
 	}, nil
 }
+
+// NetworkConfig returns the chain configuration and genesis hash of a network
+// known by name.
+func NetworkConfig(name string) (ctypes.ChainConfigurator, common.Hash, error) {
+	switch name {
+	case "mainnet":
+		return params.MainnetChainConfig, params.MainnetGenesisHash, nil
+	case "rinkeby":
+		return params.RinkebyChainConfig, params.RinkebyGenesisHash, nil
+	case "goerli":
+		return params.GoerliChainConfig, params.GoerliGenesisHash, nil
+	case "ropsten":
+		return params.RopstenChainConfig, params.RopstenGenesisHash, nil
+	case "classic":
+		return params.ClassicChainConfig, params.MainnetGenesisHash, nil
+	case "kotti":
+		return params.KottiChainConfig, params.KottiGenesisHash, nil
+	case "mordor":
+		return params.MordorChainConfig, params.MordorGenesisHash, nil
+	default:
+		return nil, common.Hash{}, fmt.Errorf("unknown network %q", name)
+	}
+}
+
+// loadChainConfig returns the chain configuration of a known network, or reads
+// it from a file. The file may hold a genesis or a bare chain configuration in
+// any of the supported configuration formats.
+func loadChainConfig(genesis string) (ctypes.ChainConfigurator, error) {
+	if config, _, err := NetworkConfig(genesis); err == nil {
+		return config, nil
+	}
+	input, err := ioutil.ReadFile(genesis)
+	if err != nil {
+		return nil, err
+	}
+	config, err := generic.UnmarshalChainConfiguratorOrGenesis(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain configuration in %s: %v", genesis, err)
+	}
+	return config, nil
+}
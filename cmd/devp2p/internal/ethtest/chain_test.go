@@ -17,11 +17,20 @@
 package ethtest
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
 	"path/filepath"
 	"strconv"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -148,3 +157,57 @@ func TestChain_GetHeaders(t *testing.T) {
 		})
 	}
 }
+
+// TestLoadChainConfig tests whether chain configurations are loaded by
+// network name, from genesis files and from bare configuration files.
+func TestLoadChainConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mordor, err := json.Marshal(params.MordorChainConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mordorFile := filepath.Join(dir, "mordor.json")
+	if err := ioutil.WriteFile(mordorFile, mordor, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		genesis   string
+		genHash   common.Hash
+		networkID uint64
+		want      ctypes.ChainConfigurator
+	}{
+		{"classic", params.MainnetGenesisHash, 1, params.ClassicChainConfig},
+		{"mordor", params.MordorGenesisHash, 7, params.MordorChainConfig},
+		{mordorFile, params.MordorGenesisHash, 7, params.MordorChainConfig},
+		{"./testdata/genesis.json", common.Hash{}, 1, nil},
+	}
+	for _, tt := range tests {
+		config, err := loadChainConfig(tt.genesis)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.genesis, err)
+		}
+		genesis := types.NewBlockWithHeader(&types.Header{Number: new(big.Int)})
+		chain := &Chain{blocks: []*types.Block{genesis}, chainConfig: config}
+		if id := chain.NetworkID(); id != tt.networkID {
+			t.Errorf("%s: wrong network ID: have %d, want %d", tt.genesis, id, tt.networkID)
+		}
+		if tt.want == nil {
+			continue
+		}
+		for _, head := range []uint64{0, 2000000, 5000000, 15000000} {
+			have := forkid.NewID(config, tt.genHash, head)
+			want := forkid.NewID(tt.want, tt.genHash, head)
+			if have != want {
+				t.Errorf("%s: fork ID mismatch at %d: have %x, want %x", tt.genesis, head, have, want)
+			}
+		}
+	}
+	if _, err := loadChainConfig("unknown"); err == nil {
+		t.Errorf("unknown network name accepted")
+	}
+}
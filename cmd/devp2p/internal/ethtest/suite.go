@@ -18,10 +18,15 @@ package ethtest
 
 import (
 	"fmt"
+	"math/big"
 	"net"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/utesting"
+	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/rlpx"
 	"github.com/stretchr/testify/assert"
@@ -36,27 +41,41 @@ type Suite struct {
 	fullChain *Chain
 }
 
+// testChainHeight is the height of the chain the node under test is expected
+// to have imported. The block following it is used for announcements.
+const testChainHeight = 1000
+
 // NewSuite creates and returns a new eth-test suite that can
 // be used to test the given node against the given blockchain
-// data.
-func NewSuite(dest *enode.Node, chainfile string, genesisfile string) *Suite {
-	chain, err := loadChain(chainfile, genesisfile)
+// data. The chain configuration is read from the genesis file,
+// or selected by network name.
+func NewSuite(dest *enode.Node, chainfile string, genesis string) (*Suite, error) {
+	chain, err := loadChain(chainfile, genesis)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if chain.Len() <= testChainHeight {
+		return nil, fmt.Errorf("chain too short: have %d blocks, need more than %d", chain.Len(), testChainHeight)
 	}
 	return &Suite{
 		Dest:      dest,
-		chain:     chain.Shorten(1000),
+		chain:     chain.Shorten(testChainHeight),
 		fullChain: chain,
-	}
+	}, nil
 }
 
 func (s *Suite) AllTests() []utesting.Test {
 	return []utesting.Test{
 		{Name: "Status", Fn: s.TestStatus},
+		{Name: "StatusMismatch", Fn: s.TestStatusMismatch},
 		{Name: "GetBlockHeaders", Fn: s.TestGetBlockHeaders},
 		{Name: "Broadcast", Fn: s.TestBroadcast},
 		{Name: "GetBlockBodies", Fn: s.TestGetBlockBodies},
+		{Name: "LargeAnnounce", Fn: s.TestLargeAnnounce},
+		{Name: "MalformedRLP", Fn: s.TestMalformedRLP},
+		{Name: "NewPooledTxs", Fn: s.TestNewPooledTxs},
+		{Name: "GetPooledTxs", Fn: s.TestGetPooledTxs},
+		{Name: "LargePooledTxAnnounce", Fn: s.TestLargePooledTxAnnounce},
 	}
 }
 
@@ -71,7 +90,7 @@ func (s *Suite) TestStatus(t *utesting.T) {
 	// get protoHandshake
 	conn.handshake(t)
 	// get status
-	switch msg := conn.statusExchange(t, s.chain).(type) {
+	switch msg := conn.statusExchange(t, s.chain, nil).(type) {
 	case *Status:
 		t.Logf("%+v\n", msg)
 	default:
@@ -88,7 +107,7 @@ func (s *Suite) TestGetBlockHeaders(t *utesting.T) {
 	}
 
 	conn.handshake(t)
-	conn.statusExchange(t, s.chain)
+	conn.statusExchange(t, s.chain, nil)
 
 	// get block headers
 	req := &GetBlockHeaders{
@@ -126,7 +145,7 @@ func (s *Suite) TestGetBlockBodies(t *utesting.T) {
 	}
 
 	conn.handshake(t)
-	conn.statusExchange(t, s.chain)
+	conn.statusExchange(t, s.chain, nil)
 	// create block bodies request
 	req := &GetBlockBodies{s.chain.blocks[54].Hash(), s.chain.blocks[75].Hash()}
 	if err := conn.Write(req); err != nil {
@@ -161,13 +180,13 @@ func (s *Suite) TestBroadcast(t *utesting.T) {
 	sendConn.handshake(t)
 	receiveConn.handshake(t)
 
-	sendConn.statusExchange(t, s.chain)
-	receiveConn.statusExchange(t, s.chain)
+	sendConn.statusExchange(t, s.chain, nil)
+	receiveConn.statusExchange(t, s.chain, nil)
 
 	// sendConn sends the block announcement
 	blockAnnouncement := &NewBlock{
-		Block: s.fullChain.blocks[1000],
-		TD:    s.fullChain.TD(1001),
+		Block: s.fullChain.blocks[testChainHeight],
+		TD:    s.fullChain.TD(testChainHeight + 1),
 	}
 	if err := sendConn.Write(blockAnnouncement); err != nil {
 		t.Fatalf("could not write to connection: %v", err)
@@ -187,13 +206,151 @@ func (s *Suite) TestBroadcast(t *utesting.T) {
 		t.Fatalf("unexpected: %#v", msg)
 	}
 	// update test suite chain
-	s.chain.blocks = append(s.chain.blocks, s.fullChain.blocks[1000])
+	s.chain.blocks = append(s.chain.blocks, s.fullChain.blocks[testChainHeight])
 	// wait for client to update its chain
 	if err := receiveConn.waitForBlock(s.chain.Head()); err != nil {
 		t.Fatal(err)
 	}
 }
 
+// TestStatusMismatch tests whether the given node disconnects peers
+// whose status does not match its chain, with an appropriate reason.
+func (s *Suite) TestStatusMismatch(t *utesting.T) {
+	tests := []struct {
+		name   string
+		modify func(*Status)
+	}{
+		{"protocol version", func(st *Status) { st.ProtocolVersion = 1 }},
+		{"network ID", func(st *Status) { st.NetworkID++ }},
+		{"genesis", func(st *Status) { st.Genesis = common.Hash{0xde, 0xad} }},
+		{"fork ID", func(st *Status) { st.ForkID = forkid.ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}} }},
+	}
+	for _, tt := range tests {
+		conn, err := s.dial()
+		if err != nil {
+			t.Fatalf("could not dial: %v", err)
+		}
+		conn.handshake(t)
+		status := &Status{
+			ProtocolVersion: uint32(conn.ethProtocolVersion),
+			NetworkID:       s.chain.NetworkID(),
+			TD:              s.chain.TD(s.chain.Len()),
+			Head:            s.chain.Head().Hash(),
+			Genesis:         s.chain.blocks[0].Hash(),
+			ForkID:          s.chain.ForkID(),
+		}
+		tt.modify(status)
+		conn.statusExchange(t, s.chain, status)
+
+		reason := conn.expectDisconnect(t, s.chain)
+		switch reason {
+		case p2p.DiscSubprotocolError, p2p.DiscUselessPeer, p2p.DiscProtocolError, p2p.DiscIncompatibleVersion:
+			t.Logf("%s mismatch: disconnected: %v", tt.name, reason)
+		default:
+			t.Errorf("%s mismatch: wrong disconnect reason: %v", tt.name, reason)
+		}
+		conn.Close()
+	}
+}
+
+// TestLargeAnnounce tests whether the given node disconnects peers
+// announcing blocks with oversized fields.
+func (s *Suite) TestLargeAnnounce(t *utesting.T) {
+	var (
+		head  = s.chain.Head()
+		large = new(big.Int).Lsh(common.Big1, 128)
+		td    = s.chain.TD(s.chain.Len())
+	)
+	header := func(modify func(*types.Header)) *types.Block {
+		h := &types.Header{
+			ParentHash: head.Hash(),
+			UncleHash:  types.EmptyUncleHash,
+			TxHash:     types.EmptyRootHash,
+			Root:       head.Root(),
+			Number:     new(big.Int).Add(head.Number(), common.Big1),
+			Difficulty: head.Difficulty(),
+			GasLimit:   head.GasLimit(),
+			Time:       head.Time() + 1,
+		}
+		modify(h)
+		return types.NewBlockWithHeader(h)
+	}
+	tests := []struct {
+		name  string
+		block *NewBlock
+	}{
+		{"total difficulty", &NewBlock{Block: head, TD: large}},
+		{"block number", &NewBlock{Block: header(func(h *types.Header) { h.Number = large }), TD: td}},
+		{"difficulty", &NewBlock{Block: header(func(h *types.Header) { h.Difficulty = large }), TD: td}},
+		{"extra data", &NewBlock{Block: header(func(h *types.Header) { h.Extra = make([]byte, 100*1024+1) }), TD: td}},
+	}
+	for _, tt := range tests {
+		conn := s.setupConnection(t)
+		if err := conn.Write(tt.block); err != nil {
+			t.Fatalf("could not write to connection: %v", err)
+		}
+		t.Logf("large %s: disconnected: %v", tt.name, conn.expectDisconnect(t, s.chain))
+		conn.Close()
+	}
+}
+
+// TestMalformedRLP tests whether the given node disconnects peers
+// sending messages which can not be decoded.
+func (s *Suite) TestMalformedRLP(t *utesting.T) {
+	tests := []struct {
+		name    string
+		code    int
+		payload []byte
+		eth65   bool // message is only defined in eth/65
+	}{
+		{"Status", Status{}.Code(), []byte{0xc3, 0x01}, false},
+		{"GetBlockHeaders", GetBlockHeaders{}.Code(), []byte{0xc3, 0x01}, false},
+		{"GetBlockBodies", GetBlockBodies{}.Code(), []byte{0xc2, 0x01, 0x02}, false},
+		{"NewBlock", NewBlock{}.Code(), []byte{0xc1, 0xc0}, false},
+		{"Transactions", Transactions{}.Code(), []byte{0xc2, 0x01, 0x02}, false},
+		{"NewPooledTransactionHashes", NewPooledTransactionHashes{}.Code(), []byte{0xc2, 0x01, 0x02}, true},
+		{"GetPooledTransactions", GetPooledTransactions{}.Code(), []byte{0xc2, 0x01, 0x02}, true},
+	}
+	for _, tt := range tests {
+		conn, err := s.dial()
+		if err != nil {
+			t.Fatalf("could not dial: %v", err)
+		}
+		conn.handshake(t)
+		if tt.eth65 && conn.ethProtocolVersion < 65 {
+			t.Logf("malformed %s: skipped, eth/65 not supported", tt.name)
+			conn.Close()
+			continue
+		}
+		// Malformed statuses replace the status of the handshake,
+		// all other messages are sent after it
+		if tt.code == (Status{}).Code() {
+			if msg, ok := conn.ReadAndServe(s.chain).(*Status); !ok {
+				t.Fatalf("malformed %s: bad status message: %#v", tt.name, msg)
+			}
+		} else {
+			conn.statusExchange(t, s.chain, nil)
+		}
+		if err := conn.WriteRaw(tt.code, tt.payload); err != nil {
+			t.Fatalf("could not write to connection: %v", err)
+		}
+		t.Logf("malformed %s: disconnected: %v", tt.name, conn.expectDisconnect(t, s.chain))
+		conn.Close()
+	}
+}
+
+// setupConnection dials the given node and performs the protocol
+// handshake and status exchange with it.
+func (s *Suite) setupConnection(t *utesting.T) *Conn {
+	conn, err := s.dial()
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	conn.handshake(t)
+	conn.statusExchange(t, s.chain, nil)
+	return conn
+}
+
 // dial attempts to dial the given node and perform a handshake,
 // returning the created Conn if successful.
 func (s *Suite) dial() (*Conn, error) {
@@ -0,0 +1,153 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethtest
+
+import (
+	"crypto/rand"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/internal/utesting"
+)
+
+// largeTxAnnounce is the number of transaction hashes announced at once by
+// the large announcement test, exceeding the number of announcements nodes
+// are expected to track per peer.
+const largeTxAnnounce = 5000
+
+// TestNewPooledTxs tests whether the given node requests the transactions
+// announced to it with `NewPooledTransactionHashes` (eth/65).
+func (s *Suite) TestNewPooledTxs(t *utesting.T) {
+	conn := s.setupConnection(t)
+	defer conn.Close()
+	if conn.ethProtocolVersion < 65 {
+		t.Logf("skipped, eth/65 not supported")
+		return
+	}
+	hashes := randomHashes(10)
+	if err := conn.Write(NewPooledTransactionHashes(hashes)); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	pending := make(map[common.Hash]bool)
+	for _, hash := range hashes {
+		pending[hash] = true
+	}
+	for len(pending) > 0 {
+		switch msg := conn.ReadAndServe(s.chain).(type) {
+		case *GetPooledTransactions:
+			for _, hash := range *msg {
+				if !pending[hash] {
+					t.Fatalf("requested transaction %x which was not announced or already requested", hash)
+				}
+				delete(pending, hash)
+			}
+			if err := conn.Write(PooledTransactions{}); err != nil {
+				t.Fatalf("could not write to connection: %v", err)
+			}
+		case *NewBlockHashes, *Transactions, *NewPooledTransactionHashes:
+			continue
+		default:
+			t.Fatalf("unexpected: %#v, wanted transaction request for %d hashes", msg, len(pending))
+		}
+	}
+}
+
+// TestGetPooledTxs tests whether the given node responds to a
+// `GetPooledTransactions` request (eth/65) without returning transactions
+// which were not requested.
+func (s *Suite) TestGetPooledTxs(t *utesting.T) {
+	conn := s.setupConnection(t)
+	defer conn.Close()
+	if conn.ethProtocolVersion < 65 {
+		t.Logf("skipped, eth/65 not supported")
+		return
+	}
+	hashes := randomHashes(10)
+	if err := conn.Write(GetPooledTransactions(hashes)); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	for {
+		switch msg := conn.ReadAndServe(s.chain).(type) {
+		case *PooledTransactions:
+			// The transactions are unknown to the node, so none may be returned
+			if len(*msg) > 0 {
+				t.Fatalf("received %d unknown transactions, first %x", len(*msg), (*msg)[0].Hash())
+			}
+			return
+		case *NewBlockHashes, *Transactions, *NewPooledTransactionHashes:
+			continue
+		default:
+			t.Fatalf("unexpected: %#v", msg)
+		}
+	}
+}
+
+// TestLargePooledTxAnnounce tests whether the given node stays connected
+// to a peer announcing more transactions than it tracks, only requesting
+// transactions which were announced.
+func (s *Suite) TestLargePooledTxAnnounce(t *utesting.T) {
+	conn := s.setupConnection(t)
+	defer conn.Close()
+	if conn.ethProtocolVersion < 65 {
+		t.Logf("skipped, eth/65 not supported")
+		return
+	}
+	hashes := randomHashes(largeTxAnnounce)
+	if err := conn.Write(NewPooledTransactionHashes(hashes)); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	announced := make(map[common.Hash]bool)
+	for _, hash := range hashes {
+		announced[hash] = true
+	}
+	// Check that the node is still responsive after the announcement
+	req := &GetBlockHeaders{Origin: hashOrNumber{Hash: s.chain.Head().Hash()}, Amount: 1}
+	if err := conn.Write(req); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	var requested int
+	for {
+		switch msg := conn.ReadAndServe(s.chain).(type) {
+		case *BlockHeaders:
+			t.Logf("node requested %d of %d announced transactions", requested, len(hashes))
+			return
+		case *GetPooledTransactions:
+			for _, hash := range *msg {
+				if !announced[hash] {
+					t.Fatalf("requested transaction %x which was not announced", hash)
+				}
+			}
+			requested += len(*msg)
+			if err := conn.Write(PooledTransactions{}); err != nil {
+				t.Fatalf("could not write to connection: %v", err)
+			}
+		case *NewBlockHashes, *Transactions, *NewPooledTransactionHashes:
+			continue
+		default:
+			t.Fatalf("unexpected: %#v", msg)
+		}
+	}
+}
+
+// randomHashes returns n random hashes, standing in for transactions unknown
+// to the node.
+func randomHashes(n int) []common.Hash {
+	hashes := make([]common.Hash, n)
+	for i := range hashes {
+		rand.Read(hashes[i][:])
+	}
+	return hashes
+}
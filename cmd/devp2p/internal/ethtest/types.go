@@ -34,6 +34,9 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
+// timeout is the time to wait for a message from the node.
+const timeout = 20 * time.Second
+
 type Message interface {
 	Code() int
 }
@@ -88,6 +91,11 @@ type Status struct {
 
 func (s Status) Code() int { return 16 }
 
+// Transactions is the network packet for transaction propagation.
+type Transactions []*types.Transaction
+
+func (t Transactions) Code() int { return 18 }
+
 // NewBlockHashes is the network packet for the block announcements.
 type NewBlockHashes []struct {
 	Hash   common.Hash // Hash of one particular block being announced
@@ -164,6 +172,22 @@ type BlockBodies []*types.Body
 
 func (bb BlockBodies) Code() int { return 22 }
 
+// NewPooledTransactionHashes is the network packet for the transaction
+// announcements of eth/65.
+type NewPooledTransactionHashes []common.Hash
+
+func (nptp NewPooledTransactionHashes) Code() int { return 24 }
+
+// GetPooledTransactions represents a transaction query of eth/65.
+type GetPooledTransactions []common.Hash
+
+func (gpt GetPooledTransactions) Code() int { return 25 }
+
+// PooledTransactions is the network packet for transaction retrieval in eth/65.
+type PooledTransactions []*types.Transaction
+
+func (pt PooledTransactions) Code() int { return 26 }
+
 // Conn represents an individual connection with a peer
 type Conn struct {
 	*rlpx.Conn
@@ -172,6 +196,7 @@ type Conn struct {
 }
 
 func (c *Conn) Read() Message {
+	c.SetReadDeadline(time.Now().Add(timeout))
 	code, rawData, _, err := c.Conn.Read()
 	if err != nil {
 		return &Error{fmt.Errorf("could not read from connection: %v", err)}
@@ -201,6 +226,14 @@ func (c *Conn) Read() Message {
 		msg = new(NewBlock)
 	case (NewBlockHashes{}).Code():
 		msg = new(NewBlockHashes)
+	case (Transactions{}).Code():
+		msg = new(Transactions)
+	case (NewPooledTransactionHashes{}).Code():
+		msg = new(NewPooledTransactionHashes)
+	case (GetPooledTransactions{}).Code():
+		msg = new(GetPooledTransactions)
+	case (PooledTransactions{}).Code():
+		msg = new(PooledTransactions)
 	default:
 		return &Error{fmt.Errorf("invalid message code: %d", code)}
 	}
@@ -242,7 +275,13 @@ func (c *Conn) Write(msg Message) error {
 	}
 	_, err = c.Conn.Write(uint64(msg.Code()), payload)
 	return err
+}
 
+// WriteRaw sends a message with the given code and an arbitrary, possibly
+// malformed, payload.
+func (c *Conn) WriteRaw(code int, payload []byte) error {
+	_, err := c.Conn.Write(uint64(code), payload)
+	return err
 }
 
 // handshake checks to make sure a `HELLO` is received.
@@ -295,8 +334,9 @@ func (c *Conn) negotiateEthProtocol(caps []p2p.Cap) {
 }
 
 // statusExchange performs a `Status` message exchange with the given
-// node.
-func (c *Conn) statusExchange(t *utesting.T, chain *Chain) Message {
+// node. If status is non-nil, it is sent instead of the status matching
+// the chain.
+func (c *Conn) statusExchange(t *utesting.T, chain *Chain, status *Status) Message {
 	// read status message from client
 	var message Message
 
@@ -304,6 +344,12 @@ loop:
 	for {
 		switch msg := c.Read().(type) {
 		case *Status:
+			if msg.Genesis != chain.blocks[0].Hash() {
+				t.Fatalf("wrong genesis in status: %v", msg.Genesis)
+			}
+			if msg.NetworkID != chain.NetworkID() {
+				t.Fatalf("wrong network ID in status: %d", msg.NetworkID)
+			}
 			if msg.Head != chain.blocks[chain.Len()-1].Hash() {
 				t.Fatalf("wrong head in status: %v", msg.Head)
 			}
@@ -329,13 +375,15 @@ loop:
 		t.Fatalf("eth protocol version must be set in Conn")
 	}
 	// write status message to client
-	status := Status{
-		ProtocolVersion: uint32(c.ethProtocolVersion),
-		NetworkID:       1,
-		TD:              chain.TD(chain.Len()),
-		Head:            chain.blocks[chain.Len()-1].Hash(),
-		Genesis:         chain.blocks[0].Hash(),
-		ForkID:          chain.ForkID(),
+	if status == nil {
+		status = &Status{
+			ProtocolVersion: uint32(c.ethProtocolVersion),
+			NetworkID:       chain.NetworkID(),
+			TD:              chain.TD(chain.Len()),
+			Head:            chain.blocks[chain.Len()-1].Hash(),
+			Genesis:         chain.blocks[0].Hash(),
+			ForkID:          chain.ForkID(),
+		}
 	}
 	if err := c.Write(status); err != nil {
 		t.Fatalf("could not write to connection: %v", err)
@@ -344,6 +392,27 @@ loop:
 	return message
 }
 
+// expectDisconnect reads from the connection until the node disconnects,
+// returning the reason. Announcements received meanwhile are ignored, the
+// test fails on any other message, if the connection is closed without a
+// disconnect message or if the node does not disconnect in time.
+func (c *Conn) expectDisconnect(t *utesting.T, chain *Chain) p2p.DiscReason {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+		switch msg := c.ReadAndServe(chain).(type) {
+		case *Disconnect:
+			return msg.Reason
+		case *NewBlockHashes, *NewBlock, *Transactions, *NewPooledTransactionHashes:
+			continue
+		case *Error:
+			t.Fatalf("connection closed without disconnect: %v", msg)
+		default:
+			t.Fatalf("unexpected: %#v, wanted disconnect", msg)
+		}
+	}
+	t.Fatalf("timeout waiting for disconnect")
+	return 0
+}
+
 // waitForBlock waits for confirmation from the client that it has
 // imported the given block.
 func (c *Conn) waitForBlock(block *types.Block) error {
@@ -47,6 +47,8 @@ type nodeJSON struct {
 	LastResponse  time.Time `json:"lastResponse,omitempty"`
 	// This one tracks the time of our last attempt to contact the node.
 	LastCheck time.Time `json:"lastCheck,omitempty"`
+	// The result of the last RLPx crawl, if any.
+	Eth *ethInfo `json:"eth,omitempty"`
 }
 
 func loadNodesJSON(file string) nodeSet {
@@ -21,9 +21,9 @@ import (
 	"net"
 	"time"
 
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
 	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/p2p/enr"
-	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -155,25 +155,11 @@ func minAgeFilter(args []string) (nodeFilter, error) {
 }
 
 func ethFilter(args []string) (nodeFilter, error) {
-	var filter forkid.Filter
-	switch args[0] {
-	case "mainnet":
-		filter = forkid.NewStaticFilter(params.MainnetChainConfig, params.MainnetGenesisHash)
-	case "rinkeby":
-		filter = forkid.NewStaticFilter(params.RinkebyChainConfig, params.RinkebyGenesisHash)
-	case "goerli":
-		filter = forkid.NewStaticFilter(params.GoerliChainConfig, params.GoerliGenesisHash)
-	case "ropsten":
-		filter = forkid.NewStaticFilter(params.RopstenChainConfig, params.RopstenGenesisHash)
-	case "classic":
-		filter = forkid.NewStaticFilter(params.ClassicChainConfig, params.MainnetGenesisHash)
-	case "kotti":
-		filter = forkid.NewStaticFilter(params.KottiChainConfig, params.KottiGenesisHash)
-	case "mordor":
-		filter = forkid.NewStaticFilter(params.MordorChainConfig, params.MordorGenesisHash)
-	default:
-		return nil, fmt.Errorf("unknown network %q", args[0])
+	config, genesis, err := ethtest.NetworkConfig(args[0])
+	if err != nil {
+		return nil, err
 	}
+	filter := forkid.NewStaticFilter(config, genesis)
 
 	f := func(n nodeJSON) bool {
 		var eth struct {
@@ -17,13 +17,15 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/internal/utesting"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/rlpx"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -37,6 +39,8 @@ var (
 		Subcommands: []cli.Command{
 			rlpxPingCommand,
 			rlpxEthTestCommand,
+			rlpxCrawlCommand,
+			rlpxCensusCommand,
 		},
 	}
 	rlpxPingCommand = cli.Command{
@@ -47,9 +51,44 @@ var (
 	rlpxEthTestCommand = cli.Command{
 		Name:      "eth-test",
 		Usage:     "Runs tests against a node",
-		ArgsUsage: "<node> <path_to_chain.rlp_file>",
+		ArgsUsage: "<node> <path_to_chain.rlp_file> <genesis_file_or_network>",
 		Action:    rlpxEthTest,
-		Flags:     []cli.Flag{testPatternFlag},
+		Flags:     []cli.Flag{testPatternFlag, testJUnitFlag},
+	}
+	rlpxCrawlCommand = cli.Command{
+		Name:      "crawl",
+		Usage:     "Performs RLPx and eth handshakes with the nodes of a node set",
+		ArgsUsage: "<nodes.json>",
+		Action:    rlpxCrawl,
+		Flags:     []cli.Flag{crawlWorkersFlag, handshakeTimeoutFlag},
+	}
+	rlpxCensusCommand = cli.Command{
+		Name:      "census",
+		Usage:     "Reports the clients and fork readiness of a crawled node set",
+		ArgsUsage: "<nodes.json> <genesis_file_or_network>",
+		Action:    rlpxCensus,
+		Flags:     []cli.Flag{censusJSONFlag, censusCSVFlag},
+	}
+)
+
+var (
+	crawlWorkersFlag = cli.IntFlag{
+		Name:  "workers",
+		Usage: "Number of concurrent handshakes",
+		Value: 32,
+	}
+	handshakeTimeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "Time limit for the handshakes with a node",
+		Value: 10 * time.Second,
+	}
+	censusJSONFlag = cli.StringFlag{
+		Name:  "json",
+		Usage: "Write the census report as JSON to the given file ('-' for stdout)",
+	}
+	censusCSVFlag = cli.StringFlag{
+		Name:  "csv",
+		Usage: "Write the census entries as CSV to the given file ('-' for stdout)",
 	}
 )
 
@@ -90,20 +129,94 @@ func rlpxPing(ctx *cli.Context) error {
 
 func rlpxEthTest(ctx *cli.Context) error {
 	if ctx.NArg() < 3 {
-		exit("missing path to chain.rlp as command-line argument")
+		exit("missing path to chain.rlp and genesis file or network name as command-line arguments")
+	}
+	suite, err := ethtest.NewSuite(getNodeArg(ctx), ctx.Args()[1], ctx.Args()[2])
+	if err != nil {
+		exit(err)
+	}
+	return runTests(ctx, "eth", suite.AllTests())
+}
+
+func rlpxCrawl(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("need nodes file as argument")
+	}
+	nodesFile := ctx.Args().First()
+	ns := loadNodesJSON(nodesFile)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return err
+	}
+	c := &rlpxCrawler{
+		key:     key,
+		workers: ctx.Int(crawlWorkersFlag.Name),
+		timeout: ctx.Duration(handshakeTimeoutFlag.Name),
 	}
+	if c.workers < 1 {
+		c.workers = 1
+	}
+	c.run(ns)
+	writeNodesJSON(nodesFile, ns)
+	return nil
+}
 
-	suite := ethtest.NewSuite(getNodeArg(ctx), ctx.Args()[1], ctx.Args()[2])
+func rlpxCensus(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("need nodes file and genesis file or network name as arguments")
+	}
+	ns := loadNodesJSON(ctx.Args().First())
+	network, err := loadCensusNetwork(ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	report := makeCensus(ns, network)
 
-	// Filter and run test cases.
-	tests := suite.AllTests()
-	if ctx.IsSet(testPatternFlag.Name) {
-		tests = utesting.MatchTests(tests, ctx.String(testPatternFlag.Name))
+	if file := ctx.String(censusJSONFlag.Name); file != "" {
+		if err := writeOutput(file, func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", jsonIndent)
+			return enc.Encode(report)
+		}); err != nil {
+			return err
+		}
+	}
+	if file := ctx.String(censusCSVFlag.Name); file != "" {
+		if err := writeOutput(file, report.writeCSV); err != nil {
+			return err
+		}
 	}
-	results := utesting.RunTests(tests, os.Stdout)
-	if fails := utesting.CountFailures(results); fails > 0 {
-		return fmt.Errorf("%v of %v tests passed.", len(tests)-fails, len(tests))
+	if ctx.String(censusJSONFlag.Name) != "-" && ctx.String(censusCSVFlag.Name) != "-" {
+		printCensus(report)
 	}
-	fmt.Printf("all tests passed\n")
 	return nil
 }
+
+// printCensus prints a summary of a census report.
+func printCensus(r *censusReport) {
+	fmt.Printf("Census of %d nodes, fork ID %s, next fork %d\n", r.Nodes, r.ForkID, r.NextFork)
+	fmt.Println("\nClasses:")
+	for _, class := range sortedCounts(r.Classes) {
+		fmt.Printf("  %-16s %d\n", class, r.Classes[class])
+	}
+	fmt.Println("\nClient versions on the network:")
+	for _, version := range sortedCounts(r.Versions) {
+		fmt.Printf("  %-48s %d\n", version, r.Versions[version])
+	}
+}
+
+// writeOutput writes to the given file, or to stdout if the file is "-".
+func writeOutput(file string, write func(io.Writer) error) error {
+	if file == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
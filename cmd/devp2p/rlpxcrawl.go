@@ -0,0 +1,241 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/rlpx"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ethVersions are the eth protocol versions offered in the crawler handshake.
+var ethVersions = []uint{63, 64, 65}
+
+// ethInfo is what a node reported about itself in the RLPx and eth protocol
+// handshakes.
+type ethInfo struct {
+	Client    string        `json:"client,omitempty"`    // Client identifier from the RLPx handshake
+	Caps      []string      `json:"caps,omitempty"`      // Capabilities from the RLPx handshake
+	Version   uint32        `json:"version,omitempty"`   // Negotiated eth protocol version
+	NetworkID uint64        `json:"networkId,omitempty"` // Network ID from the eth status
+	TD        *hexutil.Big  `json:"td,omitempty"`        // Total difficulty from the eth status
+	Head      common.Hash   `json:"head"`                // Head hash from the eth status
+	Genesis   common.Hash   `json:"genesis"`             // Genesis hash from the eth status
+	ForkHash  hexutil.Bytes `json:"forkHash,omitempty"`  // Fork ID from the eth status, missing before eth/64
+	ForkNext  uint64        `json:"forkNext,omitempty"`
+	Error     string        `json:"error,omitempty"` // Reason the handshakes failed
+	Checked   time.Time     `json:"checked"`
+}
+
+// forkID returns the fork ID reported by the node, if any.
+func (info *ethInfo) forkID() (forkid.ID, bool) {
+	var id forkid.ID
+	if len(info.ForkHash) != len(id.Hash) {
+		return id, false
+	}
+	copy(id.Hash[:], info.ForkHash)
+	id.Next = info.ForkNext
+	return id, true
+}
+
+// ethStatus is the eth protocol status message. The fork ID is decoded from
+// the tail, as it is missing before eth/64.
+type ethStatus struct {
+	ProtocolVersion uint32
+	NetworkID       uint64
+	TD              *big.Int
+	Head            common.Hash
+	Genesis         common.Hash
+	Rest            []rlp.RawValue `rlp:"tail"`
+}
+
+// rlpxCrawler performs RLPx and eth protocol handshakes with the nodes of a node
+// set, recording what they report about themselves.
+type rlpxCrawler struct {
+	key     *ecdsa.PrivateKey
+	workers int
+	timeout time.Duration // handshake timeout per node
+}
+
+// run performs the handshakes with all nodes of the set and stores the results.
+func (c *rlpxCrawler) run(ns nodeSet) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		nodes   = make(chan nodeJSON)
+		reached int
+	)
+	// Queue the nodes before starting, the workers store their results in ns.
+	queue := make([]nodeJSON, 0, len(ns))
+	for _, n := range ns {
+		queue = append(queue, n)
+	}
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range nodes {
+				info := c.handshake(n.N)
+				mu.Lock()
+				n.Eth = info
+				ns[n.N.ID()] = n
+				if info.Error == "" {
+					reached++
+				}
+				mu.Unlock()
+				log.Debug("Crawled node", "id", n.N.ID(), "client", info.Client, "err", info.Error)
+			}
+		}()
+	}
+	for _, n := range queue {
+		nodes <- n
+	}
+	close(nodes)
+	wg.Wait()
+	log.Info("RLPx crawl done", "nodes", len(ns), "reached", reached)
+}
+
+// handshake connects to a node and performs the RLPx and eth protocol handshakes.
+func (c *rlpxCrawler) handshake(n *enode.Node) *ethInfo {
+	info := &ethInfo{Checked: truncNow()}
+	if err := c.doHandshake(n, info); err != nil {
+		info.Error = err.Error()
+	}
+	return info
+}
+
+func (c *rlpxCrawler) doHandshake(n *enode.Node, info *ethInfo) error {
+	if n.IP() == nil || n.TCP() == 0 {
+		return errors.New("no TCP endpoint")
+	}
+	fd, err := net.DialTimeout("tcp", fmt.Sprintf("%v:%d", n.IP(), n.TCP()), c.timeout)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	fd.SetDeadline(time.Now().Add(c.timeout))
+
+	conn := rlpx.NewConn(fd, n.Pubkey())
+	if _, err := conn.Handshake(c.key); err != nil {
+		return err
+	}
+	hello := &ethtest.Hello{
+		Version: 5,
+		Name:    "devp2p-crawler",
+		ID:      crypto.FromECDSAPub(&c.key.PublicKey)[1:],
+	}
+	for _, version := range ethVersions {
+		hello.Caps = append(hello.Caps, p2p.Cap{Name: "eth", Version: version})
+	}
+	if err := writeMsg(conn, hello.Code(), hello); err != nil {
+		return err
+	}
+	defer writeMsg(conn, (ethtest.Disconnect{}).Code(), []p2p.DiscReason{p2p.DiscQuitting})
+
+	for {
+		code, data, _, err := conn.Read()
+		if err != nil {
+			return err
+		}
+		switch {
+		case code == uint64((ethtest.Hello{}).Code()):
+			var h ethtest.Hello
+			if err := rlp.DecodeBytes(data, &h); err != nil {
+				return fmt.Errorf("invalid handshake: %v", err)
+			}
+			info.Client = h.Name
+			for _, cap := range h.Caps {
+				info.Caps = append(info.Caps, cap.String())
+			}
+			if h.Version >= 5 {
+				conn.SetSnappy(true)
+			}
+			if info.Version = highestEthVersion(h.Caps); info.Version == 0 {
+				return errors.New("no matching eth protocol version")
+			}
+		case code == uint64((ethtest.Disconnect{}).Code()):
+			var reason []p2p.DiscReason
+			if rlp.DecodeBytes(data, &reason); len(reason) == 0 {
+				return errors.New("disconnected")
+			}
+			return fmt.Errorf("disconnected: %v", reason[0])
+		case code == uint64((ethtest.Ping{}).Code()):
+			writeMsg(conn, (ethtest.Pong{}).Code(), []interface{}{})
+		case code == uint64((ethtest.Status{}).Code()):
+			if info.Version == 0 {
+				return errors.New("status before handshake")
+			}
+			var status ethStatus
+			if err := rlp.DecodeBytes(data, &status); err != nil {
+				return fmt.Errorf("invalid status: %v", err)
+			}
+			info.NetworkID = status.NetworkID
+			info.TD = (*hexutil.Big)(status.TD)
+			info.Head = status.Head
+			info.Genesis = status.Genesis
+			if len(status.Rest) > 0 {
+				var id forkid.ID
+				if err := rlp.DecodeBytes(status.Rest[0], &id); err != nil {
+					return fmt.Errorf("invalid fork ID: %v", err)
+				}
+				info.ForkHash, info.ForkNext = id.Hash[:], id.Next
+			}
+			return nil
+		}
+	}
+}
+
+// highestEthVersion returns the highest eth protocol version supported by both
+// the crawler and a node with the given capabilities.
+func highestEthVersion(caps []p2p.Cap) uint32 {
+	var highest uint32
+	for _, cap := range caps {
+		if cap.Name != "eth" {
+			continue
+		}
+		for _, version := range ethVersions {
+			if cap.Version == version && uint32(version) > highest {
+				highest = uint32(version)
+			}
+		}
+	}
+	return highest
+}
+
+func writeMsg(conn *rlpx.Conn, code int, msg interface{}) error {
+	payload, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(uint64(code), payload)
+	return err
+}
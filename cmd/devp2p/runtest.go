@@ -0,0 +1,48 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/internal/utesting"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// runTests runs the test cases selected by the test pattern flag, writing a
+// JUnit report of the results if requested.
+func runTests(ctx *cli.Context, suite string, tests []utesting.Test) error {
+	if ctx.IsSet(testPatternFlag.Name) {
+		tests = utesting.MatchTests(tests, ctx.String(testPatternFlag.Name))
+	}
+	results := utesting.RunTests(tests, os.Stdout)
+	if file := ctx.String(testJUnitFlag.Name); file != "" {
+		err := writeOutput(file, func(w io.Writer) error {
+			return utesting.WriteJUnitReport(w, suite, results)
+		})
+		if err != nil {
+			return fmt.Errorf("can't write JUnit report: %v", err)
+		}
+	}
+	if fails := utesting.CountFailures(results); fails > 0 {
+		return fmt.Errorf("%v/%v tests passed.", len(tests)-fails, len(tests))
+	}
+	fmt.Printf("%v/%v passed\n", len(tests), len(tests))
+	return nil
+}
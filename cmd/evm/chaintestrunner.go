@@ -0,0 +1,245 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params/confp"
+	"github.com/ethereum/go-ethereum/params/confp/generic"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/tests"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var chainTestCommand = cli.Command{
+	Action:    chainTestCmd,
+	Name:      "chaintest",
+	Usage:     "executes the given state or blockchain tests against a chain configuration",
+	ArgsUsage: "<file>",
+	Description: `
+The chaintest command runs ethereum/tests fixtures using an arbitrary chain configuration.
+
+Each fixture fork name (eg. 'Istanbul') is mapped to a configuration derived from the given
+chain configuration which enables an equivalent set of features. Fixtures filled for forks
+which have no equivalent in the chain configuration are reported as skipped.
+
+Results are reported per test, and aggregated per feature (EIP/ECIP) enabled by the
+derived configuration.`,
+	Flags: []cli.Flag{
+		ChainConfigFlag,
+		BlockTestFlag,
+	},
+}
+
+var (
+	ChainConfigFlag = cli.StringFlag{
+		Name:  "chainconfig",
+		Usage: "Chain configuration file (core-geth, multi-geth, go-ethereum or Parity format; plain or genesis)",
+	}
+	BlockTestFlag = cli.BoolFlag{
+		Name:  "blocktest",
+		Usage: "Run the fixtures as blockchain tests instead of state tests",
+	}
+)
+
+// ChaintestResult contains the execution status after running a state or blockchain test
+// against a derived chain configuration.
+type ChaintestResult struct {
+	Name     string   `json:"name"`
+	Fork     string   `json:"fork"`
+	Block    uint64   `json:"block"` // block number in the chain configuration at which the fork features begin
+	Pass     bool     `json:"pass"`
+	Skipped  bool     `json:"skipped,omitempty"`
+	Features []string `json:"features,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// ChaintestFeatureResult aggregates the results of all tests run with a feature enabled.
+type ChaintestFeatureResult struct {
+	Pass int `json:"pass"`
+	Fail int `json:"fail"`
+}
+
+// ChaintestReport contains all test results, and their aggregation by feature.
+type ChaintestReport struct {
+	Results  []ChaintestResult                  `json:"results"`
+	Features map[string]*ChaintestFeatureResult `json:"features"`
+}
+
+func (r *ChaintestReport) add(result ChaintestResult) {
+	r.Results = append(r.Results, result)
+	if result.Skipped {
+		return
+	}
+	for _, f := range result.Features {
+		fr, ok := r.Features[f]
+		if !ok {
+			fr = new(ChaintestFeatureResult)
+			r.Features[f] = fr
+		}
+		if result.Pass {
+			fr.Pass++
+		} else {
+			fr.Fail++
+		}
+	}
+}
+
+// readChainConfig reads a chain configuration of any supported format from a file.
+func readChainConfig(path string) (ctypes.ChainConfigurator, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return generic.UnmarshalChainConfiguratorOrGenesis(data)
+}
+
+// chainTestFeatures returns the features enabled by the configuration at block n,
+// including those of the consensus engine.
+func chainTestFeatures(conf ctypes.ChainConfigurator, n uint64) []string {
+	features := tests.ActiveFeatures(conf, n)
+	fns, names := confp.Transitions(conf)
+	for i, fn := range fns {
+		if !strings.HasPrefix(names[i], "GetEthash") || confp.NameSignalsCompatibility(names[i]) {
+			continue
+		}
+		if v := fn(); v != nil && *v <= n {
+			features = append(features, tests.TransitionFeatureName(names[i]))
+		}
+	}
+	sort.Strings(features)
+	return features
+}
+
+func chainTestCmd(ctx *cli.Context) error {
+	if len(ctx.Args().First()) == 0 {
+		return errors.New("path-to-test argument required")
+	}
+	if !ctx.IsSet(ChainConfigFlag.Name) {
+		return errors.New("--chainconfig flag required")
+	}
+	// Configure the go-ethereum logger
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(ctx.GlobalInt(VerbosityFlag.Name)))
+	log.Root().SetHandler(glogger)
+
+	conf, err := readChainConfig(ctx.String(ChainConfigFlag.Name))
+	if err != nil {
+		return err
+	}
+	// Load the test content from the input file
+	src, err := ioutil.ReadFile(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	report := &ChaintestReport{
+		Results:  []ChaintestResult{},
+		Features: make(map[string]*ChaintestFeatureResult),
+	}
+	if ctx.Bool(BlockTestFlag.Name) {
+		err = runChainBlockTests(conf, src, report)
+	} else {
+		err = runChainStateTests(conf, src, report)
+	}
+	if err != nil {
+		return err
+	}
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+	return nil
+}
+
+func runChainStateTests(conf ctypes.ChainConfigurator, src []byte, report *ChaintestReport) error {
+	var stateTests map[string]tests.StateTest
+	if err := json.Unmarshal(src, &stateTests); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(stateTests))
+	for k := range stateTests {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		test := stateTests[key]
+		for _, st := range test.Subtests(nil) {
+			result := ChaintestResult{Name: key, Fork: st.Fork, Pass: true}
+
+			base, eips, err := tests.GetChainConfigName(st.Fork)
+			if err != nil {
+				result.Pass, result.Skipped, result.Error = false, true, err.Error()
+				report.add(result)
+				continue
+			}
+			derived, block, err := tests.ConfiguratorForFork(conf, base)
+			if err != nil {
+				result.Pass, result.Skipped, result.Error = false, true, err.Error()
+				report.add(result)
+				continue
+			}
+			result.Block = block
+			result.Features = chainTestFeatures(derived, 0)
+			for _, eip := range eips {
+				result.Features = append(result.Features, fmt.Sprintf("EIP%d", eip))
+			}
+			if _, _, err := test.RunWithConfig(st, derived, vm.Config{ExtraEips: eips}, false); err != nil {
+				result.Pass, result.Error = false, err.Error()
+			}
+			report.add(result)
+		}
+	}
+	return nil
+}
+
+func runChainBlockTests(conf ctypes.ChainConfigurator, src []byte, report *ChaintestReport) error {
+	var blockTests map[string]tests.BlockTest
+	if err := json.Unmarshal(src, &blockTests); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(blockTests))
+	for k := range blockTests {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		test := blockTests[key]
+		result := ChaintestResult{Name: key, Fork: test.Network(), Pass: true}
+
+		derived, block, err := tests.ConfiguratorForFork(conf, test.Network())
+		if err != nil {
+			result.Pass, result.Skipped, result.Error = false, true, err.Error()
+			report.add(result)
+			continue
+		}
+		result.Block = block
+		result.Features = chainTestFeatures(derived, math.MaxUint64-1)
+		if err := test.RunWithConfig(derived, false); err != nil {
+			result.Pass, result.Error = false, err.Error()
+		}
+		report.add(result)
+	}
+	return nil
+}
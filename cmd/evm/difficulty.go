@@ -0,0 +1,153 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of core-geth.
+//
+// core-geth is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// core-geth is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with core-geth. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params/confp/generic"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/tests"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var difficultyCommand = cli.Command{
+	Action:    difficultyCmd,
+	Name:      "difficulty",
+	Usage:     "calculates block difficulties for a chain configuration",
+	ArgsUsage: "[<file>]",
+	Description: `
+The difficulty command calculates block difficulties using the difficulty rules
+of the given (Ethash) chain configuration.
+
+The input file contains a JSON list of objects with the fields 'parentTimestamp',
+'parentDifficulty', 'parentUncles' (optional; the parent uncle hash, defaulting to the empty uncle hash), 'currentBlockNumber'
+and 'currentTimestamp'. The list is output with 'currentDifficulty' set for each element.
+
+With --generate, ethereum/tests-compatible DifficultyTests are output instead, keyed by test name.
+If no input file is given, tests are generated for the blocks around each of the
+configured forks.`,
+	Flags: []cli.Flag{
+		ChainConfigFlag,
+		DifficultyGenerateFlag,
+		DifficultyNameFlag,
+	},
+}
+
+var (
+	DifficultyGenerateFlag = cli.BoolFlag{
+		Name:  "generate",
+		Usage: "Output ethereum/tests-compatible DifficultyTests",
+	}
+	DifficultyNameFlag = cli.StringFlag{
+		Name:  "name",
+		Usage: "Name prefix for generated tests (default: chain configuration file name)",
+	}
+)
+
+// difficultyResult is the calculated difficulty for a block.
+type difficultyResult struct {
+	Number     math.HexOrDecimal64   `json:"currentBlockNumber"`
+	Timestamp  math.HexOrDecimal64   `json:"currentTimestamp"`
+	Difficulty *math.HexOrDecimal256 `json:"currentDifficulty"`
+}
+
+func difficultyCmd(ctx *cli.Context) error {
+	if !ctx.IsSet(ChainConfigFlag.Name) {
+		return errors.New("--chainconfig flag required")
+	}
+	configPath := ctx.String(ChainConfigFlag.Name)
+	configData, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	conf, err := generic.UnmarshalChainConfiguratorOrGenesis(configData)
+	if err != nil {
+		return err
+	}
+	if conf.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+		return errors.New("difficulty calculation requires an Ethash chain configuration")
+	}
+	name := ctx.String(DifficultyNameFlag.Name)
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(configPath), filepath.Ext(configPath))
+	}
+
+	var inputs []*tests.DifficultyTest
+	if len(ctx.Args().First()) != 0 {
+		src, err := ioutil.ReadFile(ctx.Args().First())
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(src, &inputs); err != nil {
+			return err
+		}
+		for i, input := range inputs {
+			if input.ParentDifficulty == nil || input.CurrentBlockNumber == 0 {
+				return fmt.Errorf("input %d: parentDifficulty and currentBlockNumber > 0 required", i)
+			}
+			if input.UncleHash == (common.Hash{}) {
+				input.UncleHash = types.EmptyUncleHash
+			}
+			input.Fill(conf)
+		}
+	} else if !ctx.Bool(DifficultyGenerateFlag.Name) {
+		return errors.New("input file argument required (or use --generate)")
+	}
+
+	var out interface{}
+	if !ctx.Bool(DifficultyGenerateFlag.Name) {
+		results := make([]difficultyResult, len(inputs))
+		for i, input := range inputs {
+			results[i] = difficultyResult{
+				Number:     math.HexOrDecimal64(input.CurrentBlockNumber),
+				Timestamp:  math.HexOrDecimal64(input.CurrentTimestamp),
+				Difficulty: (*math.HexOrDecimal256)(input.CurrentDifficulty),
+			}
+		}
+		out = results
+	} else {
+		var generated map[string]*tests.DifficultyTest
+		if inputs == nil {
+			generated = tests.GenerateDifficultyTests(conf, name)
+		} else {
+			generated = make(map[string]*tests.DifficultyTest, len(inputs))
+			for i, input := range inputs {
+				input.Name = fmt.Sprintf("%s_%d", name, i)
+				generated[input.Name] = input
+			}
+		}
+		for _, t := range generated {
+			t.SetChainspec(filepath.Base(configPath), configData)
+		}
+		out = generated
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -54,6 +55,10 @@ type ExecutionResult struct {
 	Bloom       types.Bloom    `json:"logsBloom"        gencodec:"required"`
 	Receipts    types.Receipts `json:"receipts"`
 	Rejected    []int          `json:"rejected,omitempty"`
+
+	// Difficulty is only set if the difficulty was calculated from the parent block values
+	// given in the environment.
+	Difficulty *math.HexOrDecimal256 `json:"currentDifficulty,omitempty"`
 }
 
 type ommer struct {
@@ -63,26 +68,33 @@ type ommer struct {
 
 //go:generate gencodec -type stEnv -field-override stEnvMarshaling -out gen_stenv.go
 type stEnv struct {
-	Coinbase    common.Address                      `json:"currentCoinbase"   gencodec:"required"`
-	Difficulty  *big.Int                            `json:"currentDifficulty" gencodec:"required"`
-	GasLimit    uint64                              `json:"currentGasLimit"   gencodec:"required"`
-	Number      uint64                              `json:"currentNumber"     gencodec:"required"`
-	Timestamp   uint64                              `json:"currentTimestamp"  gencodec:"required"`
-	BlockHashes map[math.HexOrDecimal64]common.Hash `json:"blockHashes,omitempty"`
-	Ommers      []ommer                             `json:"ommers,omitempty"`
+	Coinbase         common.Address                      `json:"currentCoinbase"   gencodec:"required"`
+	Difficulty       *big.Int                            `json:"currentDifficulty"`
+	ParentDifficulty *big.Int                            `json:"parentDifficulty"`
+	ParentTimestamp  uint64                              `json:"parentTimestamp,omitempty"`
+	ParentUncleHash  common.Hash                         `json:"parentUncleHash"`
+	GasLimit         uint64                              `json:"currentGasLimit"   gencodec:"required"`
+	Number           uint64                              `json:"currentNumber"     gencodec:"required"`
+	Timestamp        uint64                              `json:"currentTimestamp"  gencodec:"required"`
+	BlockHashes      map[math.HexOrDecimal64]common.Hash `json:"blockHashes,omitempty"`
+	Ommers           []ommer                             `json:"ommers,omitempty"`
 }
 
 type stEnvMarshaling struct {
-	Coinbase   common.UnprefixedAddress
-	Difficulty *math.HexOrDecimal256
-	GasLimit   math.HexOrDecimal64
-	Number     math.HexOrDecimal64
-	Timestamp  math.HexOrDecimal64
+	Coinbase         common.UnprefixedAddress
+	Difficulty       *math.HexOrDecimal256
+	ParentDifficulty *math.HexOrDecimal256
+	ParentTimestamp  math.HexOrDecimal64
+	GasLimit         math.HexOrDecimal64
+	Number           math.HexOrDecimal64
+	Timestamp        math.HexOrDecimal64
 }
 
-// Apply applies a set of transactions to a pre-state
+// Apply applies a set of transactions to a pre-state.
+// If ethashRewards is set, the miner and ommers are rewarded as defined by the
+// chain configuration (ie. block reward schedule, ECIP-1017 eras), and miningReward is ignored.
 func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig ctypes.ChainConfigurator,
-	txs types.Transactions, miningReward int64,
+	txs types.Transactions, miningReward int64, ethashRewards bool,
 	getTracerFn func(txIndex int, txHash common.Hash) (tracer vm.Tracer, err error)) (*state.StateDB, *ExecutionResult, error) {
 
 	// Capture errors for BLOCKHASH operation, if we haven't been supplied the
@@ -193,7 +205,24 @@ func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig ctypes.ChainConfigura
 	}
 	statedb.IntermediateRoot(chainConfig.IsEnabled(chainConfig.GetEIP161dTransition, vmContext.BlockNumber))
 	// Add mining reward?
-	if miningReward > 0 {
+	if ethashRewards {
+		header := &types.Header{
+			Number:   new(big.Int).SetUint64(pre.Env.Number),
+			Coinbase: pre.Env.Coinbase,
+		}
+		uncles := make([]*types.Header, len(pre.Env.Ommers))
+		for i, ommer := range pre.Env.Ommers {
+			uncles[i] = &types.Header{
+				Number:   new(big.Int).SetUint64(pre.Env.Number - ommer.Delta),
+				Coinbase: ommer.Address,
+			}
+		}
+		minerReward, uncleRewards := ethash.GetRewards(chainConfig, header, uncles)
+		for i, uncle := range uncles {
+			statedb.AddBalance(uncle.Coinbase, uncleRewards[i])
+		}
+		statedb.AddBalance(header.Coinbase, minerReward)
+	} else if miningReward > 0 {
 		// Add mining reward. The mining reward may be `0`, which only makes a difference in the cases
 		// where
 		// - the coinbase suicided, or
@@ -234,6 +263,23 @@ func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig ctypes.ChainConfigura
 	return statedb, execRs, nil
 }
 
+// calcDifficulty calculates the difficulty of the block described by the environment
+// from the parent block values given in it.
+func calcDifficulty(config ctypes.ChainConfigurator, env *stEnv) *big.Int {
+	uncleHash := env.ParentUncleHash
+	if uncleHash == (common.Hash{}) {
+		uncleHash = types.EmptyUncleHash
+	}
+	parent := &types.Header{
+		ParentHash: common.Hash{},
+		UncleHash:  uncleHash,
+		Difficulty: env.ParentDifficulty,
+		Number:     new(big.Int).SetUint64(env.Number - 1),
+		Time:       env.ParentTimestamp,
+	}
+	return ethash.CalcDifficulty(config, env.Timestamp, parent)
+}
+
 func MakePreState(db ethdb.Database, accounts genesisT.GenesisAlloc) *state.StateDB {
 	sdb := state.NewDatabase(db)
 	statedb, _ := state.New(common.Hash{}, sdb, nil)
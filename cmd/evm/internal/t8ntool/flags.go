@@ -100,6 +100,13 @@ var (
 			strings.Join(vm.ActivateableEips(), ", ")),
 		Value: "Istanbul",
 	}
+	ChainConfigFlag = cli.StringFlag{
+		Name: "state.chainconfig",
+		Usage: "File containing a chain configuration to use instead of state.fork.\n" +
+			"\tAny supported format (core-geth, multi-geth, go-ethereum, Parity), either plain or as genesis, is accepted.\n" +
+			"\tFor Ethash configurations, miner and ommer rewards are applied as defined by the configuration\n" +
+			"\t(block reward schedule, ECIP-1017 eras) unless state.reward is set.",
+	}
 	VerbosityFlag = cli.IntFlag{
 		Name:  "verbosity",
 		Usage: "sets the verbosity level",
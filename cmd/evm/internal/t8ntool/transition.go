@@ -18,6 +18,7 @@ package t8ntool
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -25,10 +26,12 @@ import (
 	"path"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params/confp/generic"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
 	"github.com/ethereum/go-ethereum/params/types/genesisT"
 	"github.com/ethereum/go-ethereum/tests"
@@ -190,23 +193,54 @@ func Main(ctx *cli.Context) error {
 		Debug:  (tracer != nil),
 	}
 	// Construct the chainconfig
-	var chainConfig ctypes.ChainConfigurator
-	if cConf, extraEips, err := tests.GetChainConfig(ctx.String(ForknameFlag.Name)); err != nil {
+	var (
+		chainConfig   ctypes.ChainConfigurator
+		ethashRewards bool
+	)
+	if ctx.IsSet(ChainConfigFlag.Name) {
+		data, err := ioutil.ReadFile(ctx.String(ChainConfigFlag.Name))
+		if err != nil {
+			return NewError(ErrorIO, fmt.Errorf("failed reading chain configuration file: %v", err))
+		}
+		chainConfig, err = generic.UnmarshalChainConfiguratorOrGenesis(data)
+		if err != nil {
+			return NewError(ErrorJson, fmt.Errorf("Failed unmarshaling chain configuration: %v", err))
+		}
+		ethashRewards = chainConfig.GetConsensusEngineType() == ctypes.ConsensusEngineT_Ethash &&
+			!ctx.IsSet(RewardFlag.Name)
+	} else if cConf, extraEips, err := tests.GetChainConfig(ctx.String(ForknameFlag.Name)); err != nil {
 		return NewError(ErrorVMConfig, fmt.Errorf("Failed constructing chain configuration: %v", err))
 	} else {
 		chainConfig = cConf
 		vmConfig.ExtraEips = extraEips
 	}
-	// Set the chain id
-	if err := chainConfig.SetChainID(big.NewInt(ctx.Int64(ChainIDFlag.Name))); err != nil {
-		return err
+	// Set the chain id. A chain configuration file defines its own, unless overridden.
+	if !ctx.IsSet(ChainConfigFlag.Name) || ctx.IsSet(ChainIDFlag.Name) {
+		if err := chainConfig.SetChainID(big.NewInt(ctx.Int64(ChainIDFlag.Name))); err != nil {
+			return err
+		}
+	}
+	// Calculate the block difficulty if only the parent values were given.
+	var calculatedDifficulty *big.Int
+	if prestate.Env.Difficulty == nil {
+		if prestate.Env.ParentDifficulty == nil || prestate.Env.Number == 0 {
+			return NewError(ErrorVMConfig, errors.New("currentDifficulty or parentDifficulty (with currentNumber > 0) must be given"))
+		}
+		if chainConfig.GetConsensusEngineType() != ctypes.ConsensusEngineT_Ethash {
+			return NewError(ErrorVMConfig, errors.New("difficulty calculation requires an Ethash chain configuration"))
+		}
+		calculatedDifficulty = calcDifficulty(chainConfig, &prestate.Env)
+		prestate.Env.Difficulty = calculatedDifficulty
 	}
 
 	// Run the test and aggregate the result
-	state, result, err := prestate.Apply(vmConfig, chainConfig, txs, ctx.Int64(RewardFlag.Name), getTracer)
+	state, result, err := prestate.Apply(vmConfig, chainConfig, txs, ctx.Int64(RewardFlag.Name), ethashRewards, getTracer)
 	if err != nil {
 		return err
 	}
+	if calculatedDifficulty != nil {
+		result.Difficulty = (*math.HexOrDecimal256)(calculatedDifficulty)
+	}
 	// Dump the excution result
 	//postAlloc := state.DumpGenesisFormat(false, false, false)
 	collector := make(Alloc)
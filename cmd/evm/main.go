@@ -21,9 +21,11 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/cmd/evm/internal/t8ntool"
 	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -134,6 +136,11 @@ var (
 		Usage: "External EVM configuration (default = built-in interpreter)",
 		Value: "",
 	}
+	ExtraEipsFlag = cli.StringFlag{
+		Name: "vm.eips",
+		Usage: fmt.Sprintf("Comma separated list of extra EIPs to enable on top of the configured chain (available: %s)",
+			strings.Join(vm.ActivateableEips(), ", ")),
+	}
 )
 
 var stateTransitionCommand = cli.Command{
@@ -153,6 +160,7 @@ var stateTransitionCommand = cli.Command{
 		t8ntool.InputEnvFlag,
 		t8ntool.InputTxsFlag,
 		t8ntool.ForknameFlag,
+		t8ntool.ChainConfigFlag,
 		t8ntool.ChainIDFlag,
 		t8ntool.RewardFlag,
 		t8ntool.VerbosityFlag,
@@ -185,9 +193,12 @@ func init() {
 		DisableStorageFlag,
 		DisableReturnDataFlag,
 		EVMInterpreterFlag,
+		ExtraEipsFlag,
 	}
 	app.Commands = []cli.Command{
+		chainTestCommand,
 		compileCommand,
+		difficultyCommand,
 		disasmCommand,
 		runCommand,
 		stateTestCommand,
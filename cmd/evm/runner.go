@@ -25,6 +25,8 @@ import (
 	"os"
 	goruntime "runtime"
 	"runtime/pprof"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -198,6 +200,16 @@ func runCmd(ctx *cli.Context) error {
 		}
 		code = common.Hex2Bytes(bin)
 	}
+	var extraEips []int
+	if eips := ctx.GlobalString(ExtraEipsFlag.Name); eips != "" {
+		for _, eip := range strings.Split(eips, ",") {
+			eipNum, err := strconv.Atoi(strings.TrimSpace(eip))
+			if err != nil || !vm.ValidEip(eipNum) {
+				utils.Fatalf("Invalid extra EIP %q, available: %s", eip, strings.Join(vm.ActivateableEips(), ", "))
+			}
+			extraEips = append(extraEips, eipNum)
+		}
+	}
 	initialGas := ctx.GlobalUint64(GasFlag.Name)
 	if genesisConfig.GasLimit != 0 {
 		initialGas = genesisConfig.GasLimit
@@ -216,6 +228,7 @@ func runCmd(ctx *cli.Context) error {
 			Tracer:         tracer,
 			Debug:          ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name),
 			EVMInterpreter: ctx.GlobalString(EVMInterpreterFlag.Name),
+			ExtraEips:      extraEips,
 		},
 	}
 
@@ -0,0 +1,89 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/pkg/reexec"
+	"github.com/ethereum/go-ethereum/internal/cmdtest"
+)
+
+type testEvm struct {
+	*cmdtest.TestCmd
+}
+
+// spawns evm with the given command line args.
+func runEvm(t *testing.T, args ...string) *testEvm {
+	tt := new(testEvm)
+	tt.TestCmd = cmdtest.NewTestCmd(t, tt)
+	tt.Run("evm-test", args...)
+	return tt
+}
+
+func TestMain(m *testing.M) {
+	// Run the app if we've been exec'd as "evm-test" in runEvm.
+	reexec.Register("evm-test", func() {
+		if err := app.Run(os.Args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	})
+	// check if we have been reexec'd
+	if reexec.Init() {
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// Tests state transitions of the t8n tool against the expected output of the
+// testdata directories.
+func TestT8n(t *testing.T) {
+	tests := []struct {
+		dir         string
+		chainConfig string // chain configuration file in dir, if any
+	}{
+		// Classic chain configuration, with ECIP-1017 era rewards and the
+		// difficulty calculated from the parent values.
+		{dir: "8", chainConfig: "classic.json"},
+	}
+	for _, tt := range tests {
+		dir := filepath.Join("testdata", tt.dir)
+		want, err := ioutil.ReadFile(filepath.Join(dir, "exp.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		args := []string{"t8n",
+			"--input.alloc", filepath.Join(dir, "alloc.json"),
+			"--input.txs", filepath.Join(dir, "txs.json"),
+			"--input.env", filepath.Join(dir, "env.json"),
+			"--output.result", "stdout",
+			"--output.alloc", "stdout",
+		}
+		if tt.chainConfig != "" {
+			args = append(args, "--state.chainconfig", filepath.Join(dir, tt.chainConfig))
+		}
+		evm := runEvm(t, args...)
+		evm.Expect(string(want))
+		evm.ExpectExit()
+	}
+}
@@ -28,15 +28,17 @@ import (
 	"flag"
 	"fmt"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"math"
 	"math/big"
+	"net"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -62,6 +64,7 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
 	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/gorilla/websocket"
 )
 
@@ -88,12 +91,20 @@ var (
 	payoutFlag  = flag.Int("faucet.amount", 1, "Number of Ethers to pay out per user request")
 	minutesFlag = flag.Int("faucet.minutes", 1440, "Number of minutes to wait between funding rounds")
 	tiersFlag   = flag.Int("faucet.tiers", 3, "Number of funding tiers to enable (x3 time, x2.5 funds)")
+	addrQuota   = flag.Int("faucet.addrquota", 1, "Number of fundings allowed per address within a funding round (0 = unlimited)")
+	ipQuota     = flag.Int("faucet.ipquota", 0, "Number of fundings allowed per IP address within a funding round (0 = unlimited)")
+	proxiesFlag = flag.Int("faucet.proxies", 0, "Number of trusted reverse proxies whose X-Forwarded-For entries identify requesters")
 
-	accJSONFlag = flag.String("account.json", "", "Key json file to fund user requests with")
-	accPassFlag = flag.String("account.pass", "", "Decryption password to access faucet funds")
+	accJSONFlag = flag.String("account.json", "", "Comma separated key json files to fund user requests with")
+	accPassFlag = flag.String("account.pass", "", "Decryption passwords to access faucet funds (one line per account, or one for all)")
 
 	captchaToken  = flag.String("captcha.token", "", "Recaptcha site key to authenticate client side")
 	captchaSecret = flag.String("captcha.secret", "", "Recaptcha secret key to authenticate server side")
+	captchaURL    = flag.String("captcha.verify", "https://www.google.com/recaptcha/api/siteverify", "Captcha verification endpoint (reCAPTCHA compatible)")
+
+	githubClientID = flag.String("github.clientid", "", "GitHub OAuth application client ID to authenticate requests with")
+	githubSecret   = flag.String("github.secret", "", "GitHub OAuth application client secret to authenticate requests with")
+	allowlistFlag  = flag.String("allowlist", "", "File of addresses (one per line) allowed to request funds without further authentication")
 
 	noauthFlag = flag.Bool("noauth", false, "Enables funding requests without authentication")
 	logFlag    = flag.Int("loglevel", 3, "Log level to use for Ethereum and the faucet")
@@ -280,28 +291,71 @@ func main() {
 		}
 	}
 
-	// Load up the account key and decrypt its password
+	// Load up the account keys and decrypt their passwords
 	if blob, err = ioutil.ReadFile(*accPassFlag); err != nil {
 		log.Crit("Failed to read account password contents", "file", *accPassFlag, "err", err)
 	}
-	pass := strings.TrimSuffix(string(blob), "\n")
-
-	ks := keystore.NewKeyStore(filepath.Join(faucetDirFromConfig(genesis.Config), "keys"), keystore.StandardScryptN, keystore.StandardScryptP)
-	if blob, err = ioutil.ReadFile(*accJSONFlag); err != nil {
-		log.Crit("Failed to read account key contents", "file", *accJSONFlag, "err", err)
+	passes := strings.Split(strings.TrimSuffix(string(blob), "\n"), "\n")
+	keys := strings.Split(*accJSONFlag, ",")
+	if len(passes) != 1 && len(passes) != len(keys) {
+		log.Crit("Account password count mismatch", "accounts", len(keys), "passwords", len(passes))
+	}
+	datadir := faucetDirFromConfig(genesis.Config)
+
+	ks := keystore.NewKeyStore(filepath.Join(datadir, "keys"), keystore.StandardScryptN, keystore.StandardScryptP)
+	var funders []accounts.Account
+	for i, key := range keys {
+		pass := passes[0]
+		if len(passes) > 1 {
+			pass = passes[i]
+		}
+		if blob, err = ioutil.ReadFile(key); err != nil {
+			log.Crit("Failed to read account key contents", "file", key, "err", err)
+		}
+		acc, err := ks.Import(blob, pass, pass)
+		if err == keystore.ErrAccountAlreadyExists {
+			acc, err = ks.Find(acc)
+		}
+		if err != nil {
+			log.Crit("Failed to import faucet signer account", "file", key, "err", err)
+		}
+		if err := ks.Unlock(acc, pass); err != nil {
+			log.Crit("Failed to unlock faucet signer account", "address", acc.Address, "err", err)
+		}
+		funders = append(funders, acc)
+	}
+	// Load the funding history and set up the identity providers
+	window := time.Duration(*minutesFlag) * time.Minute
+	history, err := newHistory(filepath.Join(datadir, "history.json"), window, *addrQuota, *ipQuota)
+	if err != nil {
+		log.Crit("Failed to load faucet history", "err", err)
 	}
-	acc, err := ks.Import(blob, pass, pass)
-	if err != nil && err != keystore.ErrAccountAlreadyExists {
-		log.Crit("Failed to import faucet signer account", "err", err)
+	var captcha *captchaVerifier
+	if *captchaToken != "" {
+		captcha = newCaptchaVerifier(*captchaURL, *captchaSecret)
 	}
-	if err := ks.Unlock(acc, pass); err != nil {
-		log.Crit("Failed to unlock faucet signer account", "err", err)
+	providers := map[string]identityProvider{
+		"social": &socialProvider{noauth: *noauthFlag},
+	}
+	if captcha != nil {
+		providers["captcha"] = &captchaProvider{verifier: captcha}
+	}
+	if *githubClientID != "" {
+		providers["github"] = newGithubProvider(*githubClientID, *githubSecret)
+	}
+	if *allowlistFlag != "" {
+		allowlist, err := newAllowlistProvider(*allowlistFlag)
+		if err != nil {
+			log.Crit("Failed to load allowlist", "file", *allowlistFlag, "err", err)
+		}
+		providers["allowlist"] = allowlist
 	}
 	// Assemble and start the faucet light service
-	faucet, err := newFaucet(genesis, *ethPortFlag, enodes, *netFlag, *statsFlag, ks, website.Bytes())
+	faucet, err := newFaucet(genesis, *ethPortFlag, enodes, *netFlag, *statsFlag, ks, funders, website.Bytes())
 	if err != nil {
 		log.Crit("Failed to start faucet", "err", err)
 	}
+	faucet.history, faucet.providers, faucet.captcha = history, providers, captcha
 	defer faucet.close()
 
 	if err := faucet.listenAndServe(*apiPortFlag); err != nil {
@@ -315,6 +369,15 @@ type request struct {
 	Account common.Address     `json:"account"` // Ethereum address being funded
 	Time    time.Time          `json:"time"`    // Timestamp when the request was accepted
 	Tx      *types.Transaction `json:"tx"`      // Transaction funding the account
+
+	funder common.Address // Faucet account the funds are sent from
+}
+
+// fundingAccount is a faucet account user requests are funded from.
+type fundingAccount struct {
+	account accounts.Account // Keystore account to sign funding transactions with
+	balance *big.Int         // Current balance, minus the pending fundings
+	nonce   uint64           // Current pending nonce
 }
 
 // faucet represents a crypto faucet backed by an Ethereum light client.
@@ -324,22 +387,26 @@ type faucet struct {
 	client *ethclient.Client        // Client connection to the Ethereum chain
 	index  []byte                   // Index page to serve up on the web
 
-	keystore *keystore.KeyStore // Keystore containing the single signer
-	account  accounts.Account   // Account funding user faucet requests
-	head     *types.Header      // Current head header of the faucet
-	balance  *big.Int           // Current balance of the faucet
-	nonce    uint64             // Current pending nonce of the faucet
-	price    *big.Int           // Current gas price to issue funds with
+	rpc      *rpc.Client             // Raw RPC connection for non-standard APIs
+	keystore *keystore.KeyStore      // Keystore containing the funding signers
+	accounts []*fundingAccount       // Accounts funding user faucet requests
+	signers  map[common.Address]bool // Current clique signers, if running on a clique network
+	head     *types.Header           // Current head header of the faucet
+	price    *big.Int                // Current gas price to issue funds with
+	synced   bool                    // Whether the account stats were retrieved yet
+
+	history   *history                    // Persistent funding history to enforce quotas
+	providers map[string]identityProvider // Identity providers to authenticate requests with
+	captcha   *captchaVerifier            // Captcha verifier, nil if captchas are disabled
 
-	conns    []*websocket.Conn    // Currently live websocket connections
-	timeouts map[string]time.Time // History of users and their funding timeouts
-	reqs     []*request           // Currently pending funding requests
-	update   chan struct{}        // Channel to signal request updates
+	conns  []*websocket.Conn // Currently live websocket connections
+	reqs   []*request        // Currently pending funding requests
+	update chan struct{}     // Channel to signal request updates
 
 	lock sync.RWMutex // Lock protecting the faucet's internals
 }
 
-func newFaucet(genesis *genesisT.Genesis, port int, enodes []*discv5.Node, network uint64, stats string, ks *keystore.KeyStore, index []byte) (*faucet, error) {
+func newFaucet(genesis *genesisT.Genesis, port int, enodes []*discv5.Node, network uint64, stats string, ks *keystore.KeyStore, funders []accounts.Account, index []byte) (*faucet, error) {
 	// Assemble the raw devp2p protocol stack
 	stack, err := node.New(&node.Config{
 		Name:    "MultiFaucet",
@@ -402,14 +469,18 @@ func newFaucet(genesis *genesisT.Genesis, port int, enodes []*discv5.Node, netwo
 	}
 	client := ethclient.NewClient(api)
 
+	accs := make([]*fundingAccount, len(funders))
+	for i, funder := range funders {
+		accs[i] = &fundingAccount{account: funder}
+	}
 	return &faucet{
 		config:   genesis.Config,
 		stack:    stack,
 		client:   client,
 		index:    index,
+		rpc:      api,
 		keystore: ks,
-		account:  ks.Accounts()[0],
-		timeouts: make(map[string]time.Time),
+		accounts: accs,
 		update:   make(chan struct{}, 1),
 	}, nil
 }
@@ -426,6 +497,8 @@ func (f *faucet) listenAndServe(port int) error {
 
 	http.HandleFunc("/", f.webHandler)
 	http.HandleFunc("/api", f.apiHandler)
+	http.HandleFunc("/api/status", f.statusHandler)
+	http.HandleFunc("/api/fund", f.fundHandler)
 	return http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
 }
 
@@ -472,10 +545,9 @@ func (f *faucet) apiHandler(w http.ResponseWriter, r *http.Request) {
 		if f.head != nil {
 			head = types.CopyHeader(f.head)
 		}
-		if f.balance != nil {
-			balance = new(big.Int).Set(f.balance)
+		if f.synced {
+			balance, nonce = f.totals()
 		}
-		nonce = f.nonce
 		f.lock.RUnlock()
 
 		if head == nil || balance == nil {
@@ -535,36 +607,9 @@ func (f *faucet) apiHandler(w http.ResponseWriter, r *http.Request) {
 		log.Info("Faucet funds requested", "url", msg.URL, "tier", msg.Tier)
 
 		// If captcha verifications are enabled, make sure we're not dealing with a robot
-		if *captchaToken != "" {
-			form := url.Values{}
-			form.Add("secret", *captchaSecret)
-			form.Add("response", msg.Captcha)
-
-			res, err := http.PostForm("https://www.google.com/recaptcha/api/siteverify", form)
-			if err != nil {
+		if f.captcha != nil {
+			if err = f.captcha.verify(msg.Captcha); err != nil {
 				if err = sendError(conn, err); err != nil {
-					log.Warn("Failed to send captcha post error to client", "err", err)
-					return
-				}
-				continue
-			}
-			var result struct {
-				Success bool            `json:"success"`
-				Errors  json.RawMessage `json:"error-codes"`
-			}
-			err = json.NewDecoder(res.Body).Decode(&result)
-			res.Body.Close()
-			if err != nil {
-				if err = sendError(conn, err); err != nil {
-					log.Warn("Failed to send captcha decode error to client", "err", err)
-					return
-				}
-				continue
-			}
-			if !result.Success {
-				log.Warn("Captcha verification failed", "err", string(result.Errors))
-				//lint:ignore ST1005 it's funny and the robot won't mind
-				if err = sendError(conn, errors.New("Beep-bop, you're a robot!")); err != nil {
 					log.Warn("Failed to send captcha failure to client", "err", err)
 					return
 				}
@@ -572,35 +617,7 @@ func (f *faucet) apiHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		// Retrieve the Ethereum address to fund, the requesting user and a profile picture
-		var (
-			username string
-			avatar   string
-			address  common.Address
-		)
-		switch {
-		case strings.HasPrefix(msg.URL, "https://gist.github.com/"):
-			if err = sendError(conn, errors.New("GitHub authentication discontinued at the official request of GitHub")); err != nil {
-				log.Warn("Failed to send GitHub deprecation to client", "err", err)
-				return
-			}
-			continue
-		case strings.HasPrefix(msg.URL, "https://plus.google.com/"):
-			//lint:ignore ST1005 Google is a company name and should be capitalized.
-			if err = sendError(conn, errors.New("Google+ authentication discontinued as the service was sunset")); err != nil {
-				log.Warn("Failed to send Google+ deprecation to client", "err", err)
-				return
-			}
-			continue
-		case strings.HasPrefix(msg.URL, "https://twitter.com/"):
-			username, avatar, address, err = authTwitter(msg.URL)
-		case strings.HasPrefix(msg.URL, "https://www.facebook.com/"):
-			username, avatar, address, err = authFacebook(msg.URL)
-		case *noauthFlag:
-			username, avatar, address, err = authNoAuth(msg.URL)
-		default:
-			//lint:ignore ST1005 This error is to be displayed in the browser
-			err = errors.New("Something funky happened, please open an issue at https://github.com/ethereum/go-ethereum/issues")
-		}
+		id, err := f.providers["social"].authenticate(&fundRequest{URL: msg.URL, Tier: msg.Tier})
 		if err != nil {
 			if err = sendError(conn, err); err != nil {
 				log.Warn("Failed to send prefix error to client", "err", err)
@@ -608,62 +625,17 @@ func (f *faucet) apiHandler(w http.ResponseWriter, r *http.Request) {
 			}
 			continue
 		}
-		log.Info("Faucet request valid", "url", msg.URL, "tier", msg.Tier, "user", username, "address", address)
+		log.Info("Faucet request valid", "url", msg.URL, "tier", msg.Tier, "user", id.ID, "address", id.Address)
 
-		// Ensure the user didn't request funds too recently
-		f.lock.Lock()
-		var (
-			fund    bool
-			timeout time.Time
-		)
-		if timeout = f.timeouts[username]; time.Now().After(timeout) {
-			// User wasn't funded recently, create the funding transaction
-			amount := new(big.Int).Mul(big.NewInt(int64(*payoutFlag)), ether)
-			amount = new(big.Int).Mul(amount, new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(msg.Tier)), nil))
-			amount = new(big.Int).Div(amount, new(big.Int).Exp(big.NewInt(2), big.NewInt(int64(msg.Tier)), nil))
-
-			tx := types.NewTransaction(f.nonce+uint64(len(f.reqs)), address, amount, 21000, f.price, nil)
-			signed, err := f.keystore.SignTx(f.account, tx, f.config.GetChainID())
-			if err != nil {
-				f.lock.Unlock()
-				if err = sendError(conn, err); err != nil {
-					log.Warn("Failed to send transaction creation error to client", "err", err)
-					return
-				}
-				continue
-			}
-			// Submit the transaction and mark as funded if successful
-			if err := f.client.SendTransaction(context.Background(), signed); err != nil {
-				f.lock.Unlock()
-				if err = sendError(conn, err); err != nil {
-					log.Warn("Failed to send transaction transmission error to client", "err", err)
-					return
-				}
-				continue
-			}
-			f.reqs = append(f.reqs, &request{
-				Avatar:  avatar,
-				Account: address,
-				Time:    time.Now(),
-				Tx:      signed,
-			})
-			timeout := time.Duration(*minutesFlag*int(math.Pow(3, float64(msg.Tier)))) * time.Minute
-			grace := timeout / 288 // 24h timeout => 5m grace
-
-			f.timeouts[username] = time.Now().Add(timeout - grace)
-			fund = true
-		}
-		f.lock.Unlock()
-
-		// Send an error if too frequent funding, othewise a success
-		if !fund {
-			if err = sendError(conn, fmt.Errorf("%s left until next allowance", common.PrettyDuration(time.Until(timeout)))); err != nil { // nolint: gosimple
+		// Ensure the user didn't request funds too recently and fund them
+		if _, err = f.fund(id, msg.Tier, remoteIP(r, *proxiesFlag)); err != nil {
+			if err = sendError(conn, err); err != nil {
 				log.Warn("Failed to send funding error to client", "err", err)
 				return
 			}
 			continue
 		}
-		if err = sendSuccess(conn, fmt.Sprintf("Funding request accepted for %s into %s", username, address.Hex())); err != nil {
+		if err = sendSuccess(conn, fmt.Sprintf("Funding request accepted for %s into %s", id.ID, id.Address.Hex())); err != nil {
 			log.Warn("Failed to send funding success to client", "err", err)
 			return
 		}
@@ -674,6 +646,234 @@ func (f *faucet) apiHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// pruneRequests drops the pending requests already included in the chain. The
+// requests are filtered into a new slice, as the old one may still be encoded
+// for clients outside of the lock. The caller must hold the write lock.
+func (f *faucet) pruneRequests() {
+	reqs := make([]*request, 0, len(f.reqs))
+	for _, req := range f.reqs {
+		for _, acc := range f.accounts {
+			if req.funder == acc.account.Address && req.Tx.Nonce() >= acc.nonce {
+				reqs = append(reqs, req)
+				break
+			}
+		}
+	}
+	f.reqs = reqs
+}
+
+// statusHandler reports the current state of the faucet as JSON.
+func (f *faucet) statusHandler(w http.ResponseWriter, r *http.Request) {
+	type accountStatus struct {
+		Address common.Address `json:"address"`
+		Balance *big.Int       `json:"balance"`
+		Nonce   uint64         `json:"nonce"`
+		Signer  bool           `json:"signer"`
+	}
+	f.lock.RLock()
+	var (
+		accs      = make([]accountStatus, 0, len(f.accounts))
+		providers = make([]string, 0, len(f.providers))
+		number    *big.Int
+	)
+	for _, acc := range f.accounts {
+		accs = append(accs, accountStatus{
+			Address: acc.account.Address,
+			Balance: acc.balance,
+			Nonce:   acc.nonce,
+			Signer:  f.signers[acc.account.Address],
+		})
+	}
+	for name := range f.providers {
+		providers = append(providers, name)
+	}
+	if f.head != nil {
+		number = f.head.Number
+	}
+	status := map[string]interface{}{
+		"network":   *netnameFlag,
+		"head":      number,
+		"peers":     f.stack.Server().PeerCount(),
+		"accounts":  accs,
+		"providers": providers,
+		"tiers":     *tiersFlag,
+		"requests":  f.reqs,
+	}
+	f.lock.RUnlock()
+
+	sort.Strings(providers)
+	writeJSON(w, http.StatusOK, status)
+}
+
+// fundHandler accepts funding requests as JSON, authenticating them with the
+// identity provider requested.
+func (f *faucet) fundHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Funding requests must be POSTed"})
+		return
+	}
+	var req fundRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1024*1024)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if req.Tier >= uint(*tiersFlag) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid funding tier requested"})
+		return
+	}
+	provider, ok := f.providers[req.Provider]
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Unknown identity provider %q", req.Provider)})
+		return
+	}
+	// The captcha provider verifies the captcha itself, don't burn it twice
+	if f.captcha != nil && req.Provider != "captcha" {
+		if err := f.captcha.verify(req.Captcha); err != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	id, err := provider.authenticate(&req)
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+	log.Info("Faucet request valid", "provider", req.Provider, "tier", req.Tier, "user", id.ID, "address", id.Address)
+
+	tx, err := f.fund(id, req.Tier, remoteIP(r, *proxiesFlag))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if _, ok := err.(*quotaError); ok {
+			status = http.StatusTooManyRequests
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	select {
+	case f.update <- struct{}{}:
+	default:
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"identity": id.ID,
+		"address":  id.Address,
+		"amount":   tx.Value(),
+		"tx":       tx.Hash(),
+	})
+}
+
+// fund checks the funding history of the requester and if allowed, sends the
+// funds of the requested tier from one of the faucet accounts.
+func (f *faucet) fund(id *identity, tier uint, ip string) (*types.Transaction, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	now := time.Now()
+	if err := f.history.check(id.ID, id.Address, ip, now); err != nil {
+		return nil, err
+	}
+	if f.price == nil {
+		//lint:ignore ST1005 This error is to be displayed in the browser
+		return nil, errors.New("Faucet offline")
+	}
+	amount := new(big.Int).Mul(big.NewInt(int64(*payoutFlag)), ether)
+	amount = new(big.Int).Mul(amount, new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(tier)), nil))
+	amount = new(big.Int).Div(amount, new(big.Int).Exp(big.NewInt(2), big.NewInt(int64(tier)), nil))
+
+	cost := new(big.Int).Add(amount, new(big.Int).Mul(big.NewInt(21000), f.price))
+	acc := pickFunder(f.accounts, f.signers, cost)
+	if acc == nil {
+		//lint:ignore ST1005 This error is to be displayed in the browser
+		return nil, errors.New("Faucet is out of funds")
+	}
+	tx := types.NewTransaction(acc.nonce+uint64(f.pending(acc.account.Address)), id.Address, amount, 21000, f.price, nil)
+	signed, err := f.keystore.SignTx(acc.account, tx, f.config.GetChainID())
+	if err != nil {
+		return nil, err
+	}
+	// Submit the transaction and mark as funded if successful
+	if err := f.client.SendTransaction(context.Background(), signed); err != nil {
+		return nil, err
+	}
+	acc.balance = new(big.Int).Sub(acc.balance, cost)
+	f.reqs = append(f.reqs, &request{
+		Avatar:  id.Avatar,
+		Account: id.Address,
+		Time:    now,
+		Tx:      signed,
+		funder:  acc.account.Address,
+	})
+	timeout := time.Duration(*minutesFlag*int(math.Pow(3, float64(tier)))) * time.Minute
+	grace := timeout / 288 // 24h timeout => 5m grace
+
+	if err := f.history.add(&grant{
+		Identity: id.ID,
+		Address:  id.Address,
+		IP:       ip,
+		Tier:     tier,
+		Time:     now,
+		Until:    now.Add(timeout - grace),
+		Tx:       signed.Hash(),
+	}); err != nil {
+		log.Error("Failed to persist faucet history", "err", err)
+	}
+	return signed, nil
+}
+
+// pending returns the number of pending funding requests sent from the given
+// faucet account. The caller must hold the faucet lock.
+func (f *faucet) pending(funder common.Address) int {
+	var count int
+	for _, req := range f.reqs {
+		if req.funder == funder {
+			count++
+		}
+	}
+	return count
+}
+
+// totals returns the cumulative balance and nonce of all the faucet accounts.
+// The caller must hold the faucet lock.
+func (f *faucet) totals() (*big.Int, uint64) {
+	var (
+		balance = new(big.Int)
+		nonce   uint64
+	)
+	for _, acc := range f.accounts {
+		if acc.balance != nil {
+			balance.Add(balance, acc.balance)
+		}
+		nonce += acc.nonce
+	}
+	return balance, nonce
+}
+
+// pickFunder selects the account to fund a request costing the given amount
+// from: the richest one able to cover it. On clique networks the accounts of the
+// authorized signers are only used if no other account can cover the request,
+// keeping their funds in reserve for running the network.
+func pickFunder(accs []*fundingAccount, signers map[common.Address]bool, cost *big.Int) *fundingAccount {
+	var best *fundingAccount
+	for _, acc := range accs {
+		if acc.balance == nil || acc.balance.Cmp(cost) < 0 {
+			continue
+		}
+		if best == nil {
+			best = acc
+			continue
+		}
+		if signer, bestSigner := signers[acc.account.Address], signers[best.account.Address]; signer != bestSigner {
+			if !signer {
+				best = acc
+			}
+			continue
+		}
+		if acc.balance.Cmp(best.balance) > 0 {
+			best = acc
+		}
+	}
+	return best
+}
+
 // refresh attempts to retrieve the latest header from the chain and extract the
 // associated faucet balance and nonce for connectivity caching.
 func (f *faucet) refresh(head *types.Header) error {
@@ -688,27 +888,57 @@ func (f *faucet) refresh(head *types.Header) error {
 			return err
 		}
 	}
-	// Retrieve the balance, nonce and gas price from the current head
+	// Retrieve the balances, nonces and gas price from the current head
 	var (
-		balance *big.Int
-		nonce   uint64
-		price   *big.Int
+		balances = make([]*big.Int, len(f.accounts))
+		nonces   = make([]uint64, len(f.accounts))
+		price    *big.Int
+		signers  map[common.Address]bool
 	)
-	if balance, err = f.client.BalanceAt(ctx, f.account.Address, head.Number); err != nil {
-		return err
-	}
-	if nonce, err = f.client.NonceAt(ctx, f.account.Address, head.Number); err != nil {
-		return err
+	for i, acc := range f.accounts {
+		if balances[i], err = f.client.BalanceAt(ctx, acc.account.Address, head.Number); err != nil {
+			return err
+		}
+		if nonces[i], err = f.client.NonceAt(ctx, acc.account.Address, head.Number); err != nil {
+			return err
+		}
 	}
 	if price, err = f.client.SuggestGasPrice(ctx); err != nil {
 		return err
 	}
+	// On clique networks, track the signers to keep their funds in reserve
+	if f.config.GetConsensusEngineType().IsClique() {
+		var addrs []common.Address
+		if err := f.rpc.CallContext(ctx, &addrs, "clique_getSigners"); err != nil {
+			log.Debug("Failed to retrieve clique signers", "err", err)
+		} else {
+			signers = make(map[common.Address]bool)
+			for _, addr := range addrs {
+				signers[addr] = true
+			}
+		}
+	}
 	// Everything succeeded, update the cached stats and eject old requests
 	f.lock.Lock()
-	f.head, f.balance = head, balance
-	f.price, f.nonce = price, nonce
-	for len(f.reqs) > 0 && f.reqs[0].Tx.Nonce() < f.nonce {
-		f.reqs = f.reqs[1:]
+	f.head, f.price = head, price
+	for i, acc := range f.accounts {
+		acc.balance, acc.nonce = balances[i], nonces[i]
+	}
+	if signers != nil {
+		f.signers = signers
+	}
+	f.synced = true
+
+	f.pruneRequests()
+
+	// Deduct the funds of the still pending requests from the balances
+	for _, req := range f.reqs {
+		for _, acc := range f.accounts {
+			if req.funder == acc.account.Address {
+				cost := new(big.Int).Mul(req.Tx.GasPrice(), new(big.Int).SetUint64(req.Tx.Gas()))
+				acc.balance = new(big.Int).Sub(acc.balance, cost.Add(cost, req.Tx.Value()))
+			}
+		}
 	}
 	f.lock.Unlock()
 
@@ -743,15 +973,16 @@ func (f *faucet) loop() {
 			}
 			// Faucet state retrieved, update locally and send to clients
 			f.lock.RLock()
-			log.Info("Updated faucet state", "number", head.Number, "hash", head.Hash(), "age", common.PrettyAge(timestamp), "balance", f.balance, "nonce", f.nonce, "price", f.price)
+			balance, nonce := f.totals()
+			log.Info("Updated faucet state", "number", head.Number, "hash", head.Hash(), "age", common.PrettyAge(timestamp), "balance", balance, "nonce", nonce, "price", f.price)
 
-			balance := new(big.Int).Div(f.balance, ether)
+			balance = new(big.Int).Div(balance, ether)
 			peers := f.stack.Server().PeerCount()
 
 			for _, conn := range f.conns {
 				if err := send(conn, map[string]interface{}{
 					"funds":    balance,
-					"funded":   f.nonce,
+					"funded":   nonce,
 					"peers":    peers,
 					"requests": f.reqs,
 				}, time.Second); err != nil {
@@ -807,6 +1038,36 @@ func sendError(conn *websocket.Conn, err error) error {
 	return send(conn, map[string]string{"error": err.Error()}, time.Second)
 }
 
+// writeJSON replies to a JSON API request with the given status and value.
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// remoteIP returns the IP address of the requester. If the faucet is running
+// behind trusted reverse proxies, the address is taken from the X-Forwarded-For
+// entry appended by the outermost one, as any earlier entries are supplied by
+// the requester and may be spoofed.
+func remoteIP(r *http.Request, proxies int) string {
+	if proxies > 0 {
+		var hops []string
+		for _, fwd := range r.Header["X-Forwarded-For"] {
+			hops = append(hops, strings.Split(fwd, ",")...)
+		}
+		if len(hops) >= proxies {
+			if ip := strings.TrimSpace(hops[len(hops)-proxies]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // sendSuccess transmits a success message to the remote end of the websocket, also
 // setting the write deadline to 1 second to prevent waiting forever.
 func sendSuccess(conn *websocket.Conn, msg string) error {
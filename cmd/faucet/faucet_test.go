@@ -0,0 +1,305 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/node"
+)
+
+// Tests that the funding history enforces the identity timeouts and the address
+// and IP quotas, and that it survives restarts.
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faucet-history-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		path  = filepath.Join(dir, "history.json")
+		now   = time.Now()
+		addr1 = common.HexToAddress("0x01")
+		addr2 = common.HexToAddress("0x02")
+	)
+	h, err := newHistory(path, time.Hour, 1, 2)
+	if err != nil {
+		t.Fatalf("failed to create history: %v", err)
+	}
+	if err := h.check("alice@github", addr1, "1.2.3.4", now); err != nil {
+		t.Fatalf("fresh request rejected: %v", err)
+	}
+	if err := h.add(&grant{Identity: "alice@github", Address: addr1, IP: "1.2.3.4", Time: now, Until: now.Add(3 * time.Hour)}); err != nil {
+		t.Fatalf("failed to add grant: %v", err)
+	}
+	// Reopen the history and check that the grant is remembered
+	if h, err = newHistory(path, time.Hour, 1, 2); err != nil {
+		t.Fatalf("failed to reopen history: %v", err)
+	}
+	tests := []struct {
+		identity string
+		address  common.Address
+		ip       string
+		time     time.Time
+		fail     bool
+	}{
+		{"alice@github", addr2, "5.6.7.8", now.Add(time.Minute), true},      // identity timeout
+		{"alice@github", addr2, "5.6.7.8", now.Add(4 * time.Hour), false},   // identity timeout passed
+		{"bob@github", addr1, "5.6.7.8", now.Add(time.Minute), true},        // address quota
+		{"bob@github", addr1, "5.6.7.8", now.Add(2 * time.Hour), false},     // address quota window passed
+		{"bob@github", addr2, "1.2.3.4", now.Add(time.Minute), false},       // IP quota not reached
+		{"bob@github", addr2, "", now.Add(time.Minute), false},              // unknown IP
+		{"carol@twitter", addr2, "1.2.3.4", now.Add(2 * time.Minute), true}, // IP quota reached (added below)
+	}
+	for i, tt := range tests {
+		if i == len(tests)-1 {
+			h.add(&grant{Identity: "bob@github", Address: addr2, IP: "1.2.3.4", Time: now.Add(time.Minute), Until: now.Add(time.Minute)})
+			h.addrQuota = 0
+		}
+		err := h.check(tt.identity, tt.address, tt.ip, tt.time)
+		if tt.fail && err == nil {
+			t.Errorf("test %d: request accepted, expected rejection", i)
+		}
+		if !tt.fail && err != nil {
+			t.Errorf("test %d: request rejected: %v", i, err)
+		}
+		if _, ok := err.(*quotaError); err != nil && !ok {
+			t.Errorf("test %d: unexpected error type %T", i, err)
+		}
+	}
+	// Check that expired grants are pruned
+	h.prune(now.Add(4 * time.Hour))
+	if len(h.grants) != 0 {
+		t.Errorf("expired grants not pruned: %d left", len(h.grants))
+	}
+}
+
+// Tests the GitHub OAuth provider against a local stand-in of the GitHub API.
+func TestGithubProvider(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "id" || r.FormValue("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("code") != "good" {
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "token"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"login": "alice", "avatar_url": "https://avatars/alice"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := newGithubProvider("id", "secret")
+	p.tokenURL, p.apiURL = server.URL+"/login/oauth/access_token", server.URL
+
+	addr := common.HexToAddress("0x01")
+	id, err := p.authenticate(&fundRequest{Address: addr, Token: "good"})
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if id.ID != "alice@github" || id.Avatar != "https://avatars/alice" || id.Address != addr {
+		t.Errorf("identity mismatch: %+v", id)
+	}
+	if _, err := p.authenticate(&fundRequest{Address: addr, Token: "bad"}); err == nil {
+		t.Errorf("invalid authorization code accepted")
+	}
+	if _, err := p.authenticate(&fundRequest{Token: "good"}); err == nil {
+		t.Errorf("request without address accepted")
+	}
+}
+
+// Tests the captcha provider against a local stand-in of a verification service.
+func TestCaptchaProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok := r.FormValue("secret") == "secret" && r.FormValue("response") == "human"
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": ok})
+	}))
+	defer server.Close()
+
+	p := &captchaProvider{verifier: newCaptchaVerifier(server.URL, "secret")}
+
+	addr := common.HexToAddress("0x01")
+	id, err := p.authenticate(&fundRequest{Address: addr, Captcha: "human"})
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if id.Address != addr {
+		t.Errorf("address mismatch: have %x, want %x", id.Address, addr)
+	}
+	if _, err := p.authenticate(&fundRequest{Address: addr, Captcha: "robot"}); err == nil {
+		t.Errorf("failed captcha accepted")
+	}
+}
+
+// Tests that the allowlist provider only accepts the listed addresses.
+func TestAllowlistProvider(t *testing.T) {
+	file, err := ioutil.TempFile("", "faucet-allowlist-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	file.WriteString("# Testnet operators\n0x0000000000000000000000000000000000000001\n\n")
+	file.Close()
+
+	p, err := newAllowlistProvider(file.Name())
+	if err != nil {
+		t.Fatalf("failed to load allowlist: %v", err)
+	}
+	if _, err := p.authenticate(&fundRequest{Address: common.HexToAddress("0x01")}); err != nil {
+		t.Errorf("allowed address rejected: %v", err)
+	}
+	if _, err := p.authenticate(&fundRequest{Address: common.HexToAddress("0x02")}); err == nil {
+		t.Errorf("unlisted address accepted")
+	}
+}
+
+// Tests that funding accounts are picked by balance, keeping clique signers in
+// reserve.
+func TestPickFunder(t *testing.T) {
+	newAccount := func(addr byte, balance int64) *fundingAccount {
+		return &fundingAccount{
+			account: accounts.Account{Address: common.BytesToAddress([]byte{addr})},
+			balance: big.NewInt(balance),
+		}
+	}
+	var (
+		poor   = newAccount(1, 10)
+		rich   = newAccount(2, 100)
+		signer = newAccount(3, 1000)
+		accs   = []*fundingAccount{poor, rich, signer}
+	)
+	tests := []struct {
+		signers map[common.Address]bool
+		cost    int64
+		want    *fundingAccount
+	}{
+		{nil, 5, signer},
+		{map[common.Address]bool{signer.account.Address: true}, 5, rich},
+		{map[common.Address]bool{signer.account.Address: true}, 50, rich},
+		{map[common.Address]bool{signer.account.Address: true}, 500, signer},
+		{nil, 5000, nil},
+	}
+	for i, tt := range tests {
+		if have := pickFunder(accs, tt.signers, big.NewInt(tt.cost)); have != tt.want {
+			t.Errorf("test %d: funder mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that requesters behind reverse proxies are identified by the forwarding
+// entry appended by the outermost trusted proxy, not by the spoofable ones.
+func TestRemoteIP(t *testing.T) {
+	tests := []struct {
+		forwarded []string
+		proxies   int
+		want      string
+	}{
+		{nil, 0, "10.0.0.1"},
+		{[]string{"1.1.1.1"}, 0, "10.0.0.1"},
+		{nil, 1, "10.0.0.1"},
+		{[]string{"1.1.1.1"}, 1, "1.1.1.1"},
+		{[]string{"6.6.6.6, 1.1.1.1"}, 1, "1.1.1.1"},
+		{[]string{"6.6.6.6", "1.1.1.1"}, 1, "1.1.1.1"},
+		{[]string{"6.6.6.6, 1.1.1.1, 2.2.2.2"}, 2, "1.1.1.1"},
+		{[]string{"2.2.2.2"}, 2, "10.0.0.1"},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		for _, fwd := range tt.forwarded {
+			req.Header.Add("X-Forwarded-For", fwd)
+		}
+		if have := remoteIP(req, tt.proxies); have != tt.want {
+			t.Errorf("test %d: remote IP mismatch: have %s, want %s", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that pruning the pending requests doesn't modify the request lists
+// handed out to clients before, which are encoded outside of the lock.
+func TestPruneRequests(t *testing.T) {
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("can't create node: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("can't start node: %v", err)
+	}
+	defer stack.Close()
+
+	funder := &fundingAccount{account: accounts.Account{Address: common.Address{0x01}}, balance: new(big.Int)}
+	f := &faucet{stack: stack, accounts: []*fundingAccount{funder}}
+	for i := 0; i < 8; i++ {
+		f.reqs = append(f.reqs, &request{
+			Account: common.Address{byte(i)},
+			Tx:      types.NewTransaction(uint64(i), common.Address{byte(i)}, new(big.Int), 21000, new(big.Int), nil),
+			funder:  funder.account.Address,
+		})
+	}
+	f.lock.RLock()
+	snapshot := f.reqs
+	f.lock.RUnlock()
+	want, _ := json.Marshal(snapshot)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for nonce := uint64(1); nonce <= 8; nonce += 2 {
+			f.lock.Lock()
+			funder.nonce = nonce
+			f.pruneRequests()
+			f.lock.Unlock()
+		}
+	}()
+	for i := 0; i < 16; i++ {
+		rec := httptest.NewRecorder()
+		f.statusHandler(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status code mismatch: have %d, want %d", rec.Code, http.StatusOK)
+		}
+	}
+	<-done
+
+	if have, _ := json.Marshal(snapshot); string(have) != string(want) {
+		t.Errorf("handed out requests modified:\nhave %s\nwant %s", have, want)
+	}
+	if len(f.reqs) != 1 || f.reqs[0].Tx.Nonce() != 7 {
+		t.Errorf("pruned requests mismatch: %v", f.reqs)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// grant is a single funding request accepted by the faucet.
+type grant struct {
+	Identity string         `json:"identity"` // Authenticated identity of the requester
+	Address  common.Address `json:"address"`  // Ethereum address funded
+	IP       string         `json:"ip"`       // Remote IP address of the requester
+	Tier     uint           `json:"tier"`     // Funding tier requested
+	Time     time.Time      `json:"time"`     // Timestamp when the request was accepted
+	Until    time.Time      `json:"until"`    // Time before which the identity may not be funded again
+	Tx       common.Hash    `json:"tx"`       // Hash of the funding transaction
+}
+
+// quotaError is returned if a funding request is rejected due to the requester
+// having been funded too recently.
+type quotaError struct {
+	msg string
+}
+
+func (e *quotaError) Error() string { return e.msg }
+
+// history is the persistent record of funding requests, used to enforce the
+// per identity timeouts and the per address and per IP quotas across restarts.
+type history struct {
+	path      string        // File the history is persisted into
+	window    time.Duration // Time window within which the quotas are enforced
+	addrQuota int           // Maximum number of grants per address within the window (0 = unlimited)
+	ipQuota   int           // Maximum number of grants per IP within the window (0 = unlimited)
+
+	grants []*grant
+	lock   sync.Mutex
+}
+
+// newHistory loads the funding history from the given file, or creates an empty
+// one if it does not exist yet.
+func newHistory(path string, window time.Duration, addrQuota, ipQuota int) (*history, error) {
+	h := &history{
+		path:      path,
+		window:    window,
+		addrQuota: addrQuota,
+		ipQuota:   ipQuota,
+	}
+	blob, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return h, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(blob, &h.grants); err != nil {
+		return nil, fmt.Errorf("corrupt faucet history %s: %v", path, err)
+	}
+	h.prune(time.Now())
+	return h, nil
+}
+
+// check verifies that a new grant for the given requester is allowed at the
+// given time, returning a quotaError if not.
+func (h *history) check(identity string, address common.Address, ip string, now time.Time) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var addrs, ips int
+	for _, g := range h.grants {
+		if g.Identity == identity && now.Before(g.Until) {
+			return &quotaError{fmt.Sprintf("%s left until next allowance", common.PrettyDuration(g.Until.Sub(now)))}
+		}
+		if now.Sub(g.Time) >= h.window {
+			continue
+		}
+		if g.Address == address {
+			addrs++
+		}
+		if ip != "" && g.IP == ip {
+			ips++
+		}
+	}
+	if h.addrQuota > 0 && addrs >= h.addrQuota {
+		return &quotaError{fmt.Sprintf("Address %s exceeded its funding quota", address.Hex())}
+	}
+	if h.ipQuota > 0 && ips >= h.ipQuota {
+		return &quotaError{"Too many funding requests from your IP address"}
+	}
+	return nil
+}
+
+// add records a new grant and persists the history to disk.
+func (h *history) add(g *grant) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.grants = append(h.grants, g)
+	h.prune(g.Time)
+
+	return h.flush()
+}
+
+// prune drops all grants which don't influence any timeout or quota any more.
+func (h *history) prune(now time.Time) {
+	grants := h.grants[:0]
+	for _, g := range h.grants {
+		if now.Before(g.Until) || now.Sub(g.Time) < h.window {
+			grants = append(grants, g)
+		}
+	}
+	h.grants = grants
+}
+
+// flush atomically writes the history to disk.
+func (h *history) flush() error {
+	blob, err := json.Marshal(h.grants)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return err
+	}
+	tmp := h.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.path)
+}
@@ -0,0 +1,279 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// fundRequest is a funding request received through the JSON API or the
+// websocket UI.
+type fundRequest struct {
+	Provider string         `json:"provider"` // Identity provider to authenticate with
+	Address  common.Address `json:"address"`  // Ethereum address to fund, unless contained in the URL
+	Tier     uint           `json:"tier"`     // Funding tier requested
+	URL      string         `json:"url"`      // Social media post, for the social provider
+	Token    string         `json:"token"`    // OAuth authorization code, for the github provider
+	Captcha  string         `json:"captcha"`  // Captcha response, if captchas are enabled
+}
+
+// identity is an authenticated requester of funds.
+type identity struct {
+	ID      string         // Unique identifier of the requester, used for timeouts
+	Avatar  string         // Avatar URL to make the UI nicer
+	Address common.Address // Ethereum address to fund
+}
+
+// identityProvider authenticates funding requests.
+type identityProvider interface {
+	authenticate(req *fundRequest) (*identity, error)
+}
+
+// socialProvider authenticates requests by scraping public social media posts
+// containing the address to fund.
+type socialProvider struct {
+	noauth bool // Whether to accept any URL containing an address
+}
+
+func (p *socialProvider) authenticate(req *fundRequest) (*identity, error) {
+	var (
+		username string
+		avatar   string
+		address  common.Address
+		err      error
+	)
+	switch {
+	case strings.HasPrefix(req.URL, "https://gist.github.com/"):
+		return nil, errors.New("GitHub authentication discontinued at the official request of GitHub")
+	case strings.HasPrefix(req.URL, "https://plus.google.com/"):
+		//lint:ignore ST1005 Google is a company name and should be capitalized.
+		return nil, errors.New("Google+ authentication discontinued as the service was sunset")
+	case strings.HasPrefix(req.URL, "https://twitter.com/"):
+		username, avatar, address, err = authTwitter(req.URL)
+	case strings.HasPrefix(req.URL, "https://www.facebook.com/"):
+		username, avatar, address, err = authFacebook(req.URL)
+	case p.noauth:
+		username, avatar, address, err = authNoAuth(req.URL)
+	default:
+		//lint:ignore ST1005 This error is to be displayed in the browser
+		err = errors.New("URL doesn't link to supported services")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity{ID: username, Avatar: avatar, Address: address}, nil
+}
+
+// githubProvider authenticates requests through the GitHub OAuth web flow. The
+// requester's client obtains an authorization code from GitHub, which the faucet
+// exchanges for an access token to look up the GitHub user.
+type githubProvider struct {
+	clientID string
+	secret   string
+	tokenURL string // Endpoint to exchange authorization codes at
+	apiURL   string // Base URL of the GitHub REST API
+	client   *http.Client
+}
+
+// newGithubProvider creates a GitHub OAuth identity provider for the given OAuth
+// application credentials.
+func newGithubProvider(clientID, secret string) *githubProvider {
+	return &githubProvider{
+		clientID: clientID,
+		secret:   secret,
+		tokenURL: "https://github.com/login/oauth/access_token",
+		apiURL:   "https://api.github.com",
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *githubProvider) authenticate(req *fundRequest) (*identity, error) {
+	if req.Token == "" {
+		return nil, errors.New("No GitHub authorization code provided")
+	}
+	if req.Address == (common.Address{}) {
+		//lint:ignore ST1005 This error is to be displayed in the browser
+		return nil, errors.New("No Ethereum address found to fund")
+	}
+	// Exchange the authorization code for an access token
+	form := url.Values{}
+	form.Add("client_id", p.clientID)
+	form.Add("client_secret", p.secret)
+	form.Add("code", req.Token)
+
+	tokenReq, err := http.NewRequest(http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tokenReq.Header.Set("Accept", "application/json")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := p.do(tokenReq, &token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		log.Warn("GitHub authorization failed", "err", token.Error, "description", token.Description)
+		return nil, errors.New("GitHub authorization failed")
+	}
+	// Look up the user the access token belongs to
+	userReq, err := http.NewRequest(http.MethodGet, p.apiURL+"/user", nil)
+	if err != nil {
+		return nil, err
+	}
+	userReq.Header.Set("Authorization", "token "+token.AccessToken)
+	userReq.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	var user struct {
+		Login  string `json:"login"`
+		Avatar string `json:"avatar_url"`
+	}
+	if err := p.do(userReq, &user); err != nil {
+		return nil, err
+	}
+	if user.Login == "" {
+		return nil, errors.New("GitHub user lookup failed")
+	}
+	return &identity{ID: user.Login + "@github", Avatar: user.Avatar, Address: req.Address}, nil
+}
+
+// do executes an HTTP request against GitHub and decodes the JSON reply.
+func (p *githubProvider) do(req *http.Request, result interface{}) error {
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub request failed: %s", res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+// captchaVerifier checks captcha responses against a reCAPTCHA compatible
+// verification service (e.g. reCAPTCHA or hCaptcha).
+type captchaVerifier struct {
+	url    string // Verification endpoint of the captcha service
+	secret string // Secret key to authenticate with the service
+	client *http.Client
+}
+
+// newCaptchaVerifier creates a captcha verifier for the given service endpoint.
+func newCaptchaVerifier(url, secret string) *captchaVerifier {
+	return &captchaVerifier{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// verify checks whether the captcha response was solved by a human.
+func (c *captchaVerifier) verify(response string) error {
+	form := url.Values{}
+	form.Add("secret", c.secret)
+	form.Add("response", response)
+
+	res, err := c.client.PostForm(c.url, form)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var result struct {
+		Success bool            `json:"success"`
+		Errors  json.RawMessage `json:"error-codes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.Success {
+		log.Warn("Captcha verification failed", "err", string(result.Errors))
+		//lint:ignore ST1005 it's funny and the robot won't mind
+		return errors.New("Beep-bop, you're a robot!")
+	}
+	return nil
+}
+
+// captchaProvider authenticates requests by a solved captcha alone, using the
+// funded address as the identity of the requester.
+type captchaProvider struct {
+	verifier *captchaVerifier
+}
+
+func (p *captchaProvider) authenticate(req *fundRequest) (*identity, error) {
+	if req.Address == (common.Address{}) {
+		//lint:ignore ST1005 This error is to be displayed in the browser
+		return nil, errors.New("No Ethereum address found to fund")
+	}
+	if err := p.verifier.verify(req.Captcha); err != nil {
+		return nil, err
+	}
+	return &identity{ID: req.Address.Hex() + "@captcha", Address: req.Address}, nil
+}
+
+// allowlistProvider authenticates requests funding a static set of addresses.
+type allowlistProvider struct {
+	allowed map[common.Address]bool
+}
+
+// newAllowlistProvider loads the allowed addresses from a file containing one
+// address per line. Empty lines and lines starting with # are ignored.
+func newAllowlistProvider(path string) (*allowlistProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	p := &allowlistProvider{allowed: make(map[common.Address]bool)}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !common.IsHexAddress(line) {
+			return nil, fmt.Errorf("invalid allowlist address %q", line)
+		}
+		p.allowed[common.HexToAddress(line)] = true
+	}
+	return p, scanner.Err()
+}
+
+func (p *allowlistProvider) authenticate(req *fundRequest) (*identity, error) {
+	if !p.allowed[req.Address] {
+		return nil, fmt.Errorf("Address %s is not allowed to request funds", req.Address.Hex())
+	}
+	return &identity{ID: req.Address.Hex() + "@allowlist", Address: req.Address}, nil
+}
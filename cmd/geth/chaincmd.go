@@ -34,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
@@ -234,6 +235,35 @@ Use "ethereum dump 0" to dump the genesis block.`,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 	}
+	backfillAddressIndexCommand = cli.Command{
+		Action:    utils.MigrateFlags(backfillAddressIndex),
+		Name:      "backfill-addressindex",
+		Usage:     "Build the address index for the locally stored chain",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.CacheFlag,
+			utils.ClassicFlag,
+			utils.MordorFlag,
+			utils.KottiFlag,
+			utils.SocialFlag,
+			utils.EthersocialFlag,
+			utils.LegacyTestnetFlag,
+			utils.RopstenFlag,
+			utils.RinkebyFlag,
+			utils.GoerliFlag,
+			utils.YoloV1Flag,
+			utils.SyncModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The backfill-addressindex command indexes the transactions of the locally stored
+chain by the addresses taking part in them, as served by eth_getTransactionsByAddress
+on nodes running with --addressindex. Indexing continues from the last indexed
+section. Internal value transfers are only indexed for blocks whose parent state
+is available, so backfilling them for the whole chain requires an archive node.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
@@ -622,6 +652,46 @@ func inspect(ctx *cli.Context) error {
 	return rawdb.InspectDatabase(chainDb)
 }
 
+// backfillAddressIndex runs the address indexer over the locally stored chain
+// until all complete sections are indexed.
+func backfillAddressIndex(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, chainDb := utils.MakeChain(ctx, stack, true)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	head := chain.CurrentBlock().NumberU64()
+	if head+1 < eth.AddressIndexConfirms+eth.AddressIndexSectionSize {
+		fmt.Println("Chain too short to index")
+		return nil
+	}
+	target := (head + 1 - eth.AddressIndexConfirms) / eth.AddressIndexSectionSize
+
+	indexer := eth.NewAddressIndexer(chainDb, chain, eth.AddressIndexSectionSize, eth.AddressIndexConfirms, 0)
+	defer indexer.Close()
+
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	indexer.Start(chain)
+	for {
+		sections, _, _ := indexer.Sections()
+		if sections >= target {
+			break
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing addresses", "sections", sections, "target", target, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	fmt.Printf("Indexed %d blocks in %v\n", target*eth.AddressIndexSectionSize, time.Since(start))
+	return nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
@@ -30,10 +30,12 @@ import (
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethstats"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/statediff"
 	"github.com/naoina/toml"
 )
 
@@ -72,7 +74,10 @@ var tomlSettings = toml.Config{
 }
 
 type ethstatsConfig struct {
-	URL string `toml:",omitempty"`
+	URL     string `toml:",omitempty"`
+	TLSCert string `toml:",omitempty"`
+	TLSKey  string `toml:",omitempty"`
+	TLSCA   string `toml:",omitempty"`
 }
 
 // whisper has been deprecated, but clients out there might still have [Shh]
@@ -146,6 +151,15 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	if ctx.GlobalIsSet(utils.EthStatsURLFlag.Name) {
 		cfg.Ethstats.URL = ctx.GlobalString(utils.EthStatsURLFlag.Name)
 	}
+	if ctx.GlobalIsSet(utils.EthStatsTLSCertFlag.Name) {
+		cfg.Ethstats.TLSCert = ctx.GlobalString(utils.EthStatsTLSCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(utils.EthStatsTLSKeyFlag.Name) {
+		cfg.Ethstats.TLSKey = ctx.GlobalString(utils.EthStatsTLSKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(utils.EthStatsTLSCAFlag.Name) {
+		cfg.Ethstats.TLSCA = ctx.GlobalString(utils.EthStatsTLSCAFlag.Name)
+	}
 	utils.SetShhConfig(ctx, stack)
 
 	return stack, cfg
@@ -180,7 +194,19 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 	}
 	// Add the Ethereum Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
-		utils.RegisterEthStatsService(stack, backend, cfg.Ethstats.URL)
+		utils.RegisterEthStatsService(stack, backend, ethstats.Config{
+			URL:     cfg.Ethstats.URL,
+			TLSCert: cfg.Ethstats.TLSCert,
+			TLSKey:  cfg.Ethstats.TLSKey,
+			TLSCA:   cfg.Ethstats.TLSCA,
+		})
+	}
+	// Stream the state diffs of imported blocks if requested.
+	if ctx.GlobalBool(utils.StateDiffFlag.Name) {
+		utils.RegisterStateDiffService(stack, backend, statediff.Config{
+			File:              ctx.GlobalString(utils.StateDiffFileFlag.Name),
+			IntermediateNodes: ctx.GlobalBool(utils.StateDiffNodesFlag.Name),
+		})
 	}
 	return stack, backend
 }
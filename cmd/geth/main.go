@@ -97,6 +97,7 @@ var (
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.AddressIndexFlag,
 		utils.LightServeFlag,
 		utils.LegacyLightServFlag,
 		utils.LightIngressFlag,
@@ -139,6 +140,9 @@ var (
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.MaxPeersPerSubnetFlag,
+		utils.NetGroupsFlag,
+		utils.MaxPeersPerNetGroupFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.DNSDiscoveryFlag,
@@ -159,6 +163,12 @@ var (
 		utils.VMEnableDebugFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
+		utils.EthStatsTLSCertFlag,
+		utils.EthStatsTLSKeyFlag,
+		utils.EthStatsTLSCAFlag,
+		utils.StateDiffFlag,
+		utils.StateDiffFileFlag,
+		utils.StateDiffNodesFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
@@ -186,6 +196,7 @@ var (
 		utils.GraphQLEnabledFlag,
 		utils.GraphQLCORSDomainFlag,
 		utils.GraphQLVirtualHostsFlag,
+		utils.GraphQLWSOriginsFlag,
 		utils.HTTPApiFlag,
 		utils.LegacyRPCApiFlag,
 		utils.WSEnabledFlag,
@@ -242,6 +253,7 @@ func init() {
 		dumpCommand,
 		dumpGenesisCommand,
 		inspectCommand,
+		backfillAddressIndexCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -56,7 +56,14 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
 			utils.TxLookupLimitFlag,
+			utils.AddressIndexFlag,
 			utils.EthStatsURLFlag,
+			utils.EthStatsTLSCertFlag,
+			utils.EthStatsTLSKeyFlag,
+			utils.EthStatsTLSCAFlag,
+			utils.StateDiffFlag,
+			utils.StateDiffFileFlag,
+			utils.StateDiffNodesFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
 			utils.WhitelistFlag,
@@ -153,6 +160,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.GraphQLEnabledFlag,
 			utils.GraphQLCORSDomainFlag,
 			utils.GraphQLVirtualHostsFlag,
+			utils.GraphQLWSOriginsFlag,
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalTxFeeCapFlag,
 			utils.JSpathFlag,
@@ -175,6 +183,9 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
+			utils.MaxPeersPerSubnetFlag,
+			utils.NetGroupsFlag,
+			utils.MaxPeersPerNetGroupFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
 		},
@@ -34,6 +34,11 @@
 //     $ p2psim node connect node01 node02
 //     Connected node01 to node02
 //
+// Scenario files describing reproducible experiments can be run on a local
+// in-process network, independently of the simulation HTTP API:
+//
+//     $ p2psim scenario partition.json
+//
 package main
 
 import (
@@ -42,14 +47,17 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/simulations"
 	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"github.com/ethereum/go-ethereum/p2p/simulations/scenario"
 	"github.com/ethereum/go-ethereum/rpc"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -103,6 +111,19 @@ func main() {
 			Usage:  "load a network snapshot from stdin",
 			Action: loadSnapshot,
 		},
+		{
+			Name:      "scenario",
+			ArgsUsage: "<file>",
+			Usage:     "run a scenario file on a local in-process network",
+			Action:    runScenario,
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "timeout",
+					Value: 10 * time.Minute,
+					Usage: "maximum duration of the scenario run",
+				},
+			},
+		},
 		{
 			Name:   "node",
 			Usage:  "manage simulation nodes",
@@ -249,6 +270,45 @@ func loadSnapshot(ctx *cli.Context) error {
 	return client.LoadSnapshot(snap)
 }
 
+func runScenario(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	}
+	sc, err := scenario.Load(args[0])
+	if err != nil {
+		return err
+	}
+	network := simulations.NewNetwork(adapters.NewSimAdapter(scenario.Services()), &simulations.NetworkConfig{
+		DefaultService: "simchain",
+	})
+	defer network.Shutdown()
+
+	runctx, cancel := context.WithTimeout(context.Background(), ctx.Duration("timeout"))
+	defer cancel()
+
+	result, err := scenario.Run(runctx, network, sc)
+	if result != nil {
+		w := tabwriter.NewWriter(ctx.App.Writer, 1, 2, 2, ' ', 0)
+		fmt.Fprintf(w, "STEP\tACTION\tNODE\tLATENCY\n")
+		for _, step := range result.Steps {
+			names := make([]string, 0, len(step.Latencies))
+			for name := range step.Latencies {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if len(names) == 0 {
+				fmt.Fprintf(w, "%d\t%s\t\t\n", step.Index, step.Action)
+			}
+			for _, name := range names {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%v\n", step.Index, step.Action, name, step.Latencies[name])
+			}
+		}
+		w.Flush()
+	}
+	return err
+}
+
 func listNodes(ctx *cli.Context) error {
 	if len(ctx.Args()) != 0 {
 		return cli.ShowCommandHelp(ctx, ctx.Command.Name)
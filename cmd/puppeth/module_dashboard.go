@@ -610,7 +610,7 @@ func deployDashboard(client *sshClient, network string, conf *config, config *da
 	}
 	template.Must(template.New("").Parse(dashboardContent)).Execute(indexfile, map[string]interface{}{
 		"Network":           network,
-		"NetworkID":         conf.Genesis.Config.GetNetworkID(),
+		"NetworkID":         conf.networkID(),
 		"NetworkTitle":      strings.Title(network),
 		"EthstatsPage":      config.ethstats,
 		"ExplorerPage":      config.explorer,
@@ -28,11 +28,13 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/types/goethereum"
 )
 
 // nodeDockerfile is the Dockerfile required to run an Ethereum node.
 var nodeDockerfile = `
-FROM ethereum/client-go:latest
+FROM {{.Image}}
 
 ADD genesis.json /genesis.json
 {{if .Unlock}}
@@ -98,6 +100,7 @@ func deployNode(client *sshClient, network string, bootnodes []string, config *n
 	}
 	dockerfile := new(bytes.Buffer)
 	template.Must(template.New("").Parse(nodeDockerfile)).Execute(dockerfile, map[string]interface{}{
+		"Image":     nodeImage(config.genesis),
 		"NetworkID": config.network,
 		"Port":      config.port,
 		"IP":        client.address,
@@ -149,6 +152,19 @@ func deployNode(client *sshClient, network string, bootnodes []string, config *n
 	return nil, client.Stream(fmt.Sprintf("cd %s && docker-compose -p %s up -d --build --force-recreate --timeout 60", workdir, network))
 }
 
+// nodeImage returns the docker image to run a node of the given genesis spec
+// with. Upstream go-ethereum can only run go-ethereum style chain configs, any
+// other format (e.g. Ethereum Classic networks) is deployed with core-geth.
+func nodeImage(genesis []byte) string {
+	spec := new(genesisT.Genesis)
+	if err := json.Unmarshal(genesis, spec); err == nil {
+		if _, ok := spec.Config.(*goethereum.ChainConfig); ok {
+			return "ethereum/client-go:latest"
+		}
+	}
+	return "etclabscore/core-geth:latest"
+}
+
 // nodeInfos is returned from a boot or seal node status check to allow reporting
 // various configuration parameters.
 type nodeInfos struct {
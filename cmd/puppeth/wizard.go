@@ -59,6 +59,15 @@ func (c config) servers() []string {
 	return servers
 }
 
+// networkID returns the network ID of the cached genesis, falling back to its
+// chain ID if the network ID is not set, as in configs without a networkId.
+func (c config) networkID() int64 {
+	if id := c.Genesis.Config.GetNetworkID(); id != nil && *id != 0 {
+		return int64(*id)
+	}
+	return c.Genesis.Config.GetChainID().Int64()
+}
+
 // flush dumps the contents of config to disk.
 func (c config) flush() {
 	os.MkdirAll(filepath.Dir(c.path), 0755)
@@ -248,6 +257,29 @@ func (w *wizard) readDefaultUint64P(def uint64) *uint64 {
 	}
 }
 
+// readOptionalUint64P reads a single line from stdin, trimming if from spaces,
+// enforcing it to parse into a uint64. If an empty line is entered, the default
+// value is returned, which may be nil to leave a setting unset.
+func (w *wizard) readOptionalUint64P(def *uint64) *uint64 {
+	for {
+		fmt.Printf("> ")
+		text, err := w.in.ReadString('\n')
+		if err != nil {
+			log.Crit("Failed to read user input", "err", err)
+		}
+		if text = strings.TrimSpace(text); text == "" {
+			return def
+		}
+		val, ok := new(big.Int).SetString(text, 0)
+		if !ok {
+			log.Error("Invalid input, expected big integer")
+			continue
+		}
+		v := val.Uint64()
+		return &v
+	}
+}
+
 /*
 // readFloat reads a single line from stdin, trimming if from spaces, enforcing it
 // to parse into a float.
@@ -49,7 +49,7 @@ func (w *wizard) deployFaucet() {
 	existed := err == nil
 
 	infos.node.genesis, _ = json.MarshalIndent(w.conf.Genesis, "", "  ")
-	infos.node.network = w.conf.Genesis.Config.GetChainID().Int64()
+	infos.node.network = w.conf.networkID()
 
 	// Figure out which port to listen on
 	fmt.Println()
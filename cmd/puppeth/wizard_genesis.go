@@ -31,10 +31,13 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params/confp"
 	"github.com/ethereum/go-ethereum/params/confp/tconvert"
+	"github.com/ethereum/go-ethereum/params/types/coregeth"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
 	"github.com/ethereum/go-ethereum/params/types/genesisT"
 	"github.com/ethereum/go-ethereum/params/types/goethereum"
+	"github.com/ethereum/go-ethereum/params/types/multigeth"
 )
 
 // makeGenesis creates a new genesis struct based on some user input.
@@ -56,6 +59,19 @@ func (w *wizard) makeGenesis() {
 			IstanbulBlock:       big.NewInt(0),
 		},
 	}
+	// Figure out which chain configuration format to use
+	fmt.Println()
+	fmt.Println("Which chain configuration format to use? (default = go-ethereum)")
+	fmt.Println(" 1. go-ethereum - Ethereum feature set")
+	fmt.Println(" 2. core-geth   - Ethereum Classic feature set (ECIP-1017, ECIP-1041, ECIP-1099, ECBP-1100)")
+
+	switch choice := w.read(); choice {
+	case "", "1":
+	case "2":
+		genesis.Config = newClassicChainConfig()
+	default:
+		log.Crit("Invalid chain configuration choice", "choice", choice)
+	}
 	// Figure out which consensus engine to choose
 	fmt.Println()
 	fmt.Println("Which consensus engine to use? (default = clique)")
@@ -69,6 +85,10 @@ func (w *wizard) makeGenesis() {
 		genesis.Config.MustSetConsensusEngineType(ctypes.ConsensusEngineT_Ethash)
 		genesis.ExtraData = make([]byte, 32)
 
+		if _, ok := genesis.Config.(*coregeth.CoreGethChainConfig); ok {
+			w.configureClassicEthash(genesis.Config)
+		}
+
 	case choice == "" || choice == "2":
 		// In the case of clique, configure the consensus parameters
 		genesis.Difficulty = big.NewInt(1)
@@ -136,7 +156,9 @@ func (w *wizard) makeGenesis() {
 	// Query the user for some custom extras
 	fmt.Println()
 	fmt.Println("Specify your chain/network ID if you want an explicit one (default = random)")
-	genesis.Config.SetChainID(new(big.Int).SetUint64(uint64(w.readDefaultInt(rand.Intn(65536)))))
+	id := uint64(w.readDefaultInt(rand.Intn(65536)))
+	genesis.Config.SetChainID(new(big.Int).SetUint64(id))
+	genesis.Config.SetNetworkID(&id)
 
 	// All done, store the genesis and flush to disk
 	log.Info("Configured new genesis block")
@@ -145,6 +167,95 @@ func (w *wizard) makeGenesis() {
 	w.conf.flush()
 }
 
+// newClassicChainConfig creates a core-geth chain configuration with all the
+// Ethereum Classic network upgrades up to and including Phoenix activated at
+// genesis.
+func newClassicChainConfig() *coregeth.CoreGethChainConfig {
+	return &coregeth.CoreGethChainConfig{
+		EIP2FBlock: big.NewInt(0),
+		EIP7FBlock: big.NewInt(0),
+
+		EIP150Block: big.NewInt(0),
+
+		EIP155Block: big.NewInt(0),
+
+		// EIP158 eq
+		EIP160FBlock: big.NewInt(0),
+		EIP161FBlock: big.NewInt(0),
+		EIP170FBlock: big.NewInt(0),
+
+		// Byzantium eq, aka Atlantis
+		EIP100FBlock: big.NewInt(0),
+		EIP140FBlock: big.NewInt(0),
+		EIP198FBlock: big.NewInt(0),
+		EIP211FBlock: big.NewInt(0),
+		EIP212FBlock: big.NewInt(0),
+		EIP213FBlock: big.NewInt(0),
+		EIP214FBlock: big.NewInt(0),
+		EIP658FBlock: big.NewInt(0),
+
+		// Constantinople eq, aka Agharta
+		EIP145FBlock:  big.NewInt(0),
+		EIP1014FBlock: big.NewInt(0),
+		EIP1052FBlock: big.NewInt(0),
+
+		// Istanbul eq, aka Phoenix
+		EIP152FBlock:  big.NewInt(0),
+		EIP1108FBlock: big.NewInt(0),
+		EIP1344FBlock: big.NewInt(0),
+		EIP1884FBlock: big.NewInt(0),
+		EIP2028FBlock: big.NewInt(0),
+		EIP2200FBlock: big.NewInt(0),
+	}
+}
+
+// configureClassicEthash queries the user for the Ethereum Classic specific
+// proof-of-work parameters: the monetary policy, the difficulty bomb and the
+// optional Etchash and MESS upgrades.
+func (w *wizard) configureClassicEthash(config ctypes.ChainConfigurator) {
+	zero := uint64(0)
+
+	fmt.Println()
+	fmt.Println("Should block rewards follow the ECIP-1017 monetary policy? (default = yes)")
+	if w.readDefaultYesNo(true) {
+		fmt.Println()
+		fmt.Println("How many blocks should an ECIP-1017 era last? (default = 5000000)")
+		config.SetEthashECIP1017Transition(&zero)
+		config.SetEthashECIP1017EraRounds(w.readDefaultUint64P(5000000))
+	}
+	fmt.Println()
+	fmt.Println("How should the difficulty bomb be handled? (default = 1)")
+	fmt.Println(" 1. Defuse it at genesis (ECIP-1041)")
+	fmt.Println(" 2. Pause it for a number of blocks (ECIP-1010)")
+	fmt.Println(" 3. Leave it armed")
+
+	switch choice := w.read(); choice {
+	case "", "1":
+		config.SetEthashECIP1041Transition(&zero)
+	case "2":
+		fmt.Println()
+		fmt.Println("Which block should the difficulty bomb be paused at? (default = 0)")
+		pause := w.readDefaultUint64P(0)
+
+		fmt.Println()
+		fmt.Println("For how many blocks should the difficulty bomb be paused? (default = 2000000)")
+		resume := *pause + *w.readDefaultUint64P(2000000)
+
+		config.SetEthashECIP1010PauseTransition(pause)
+		config.SetEthashECIP1010ContinueTransition(&resume)
+	case "3":
+	default:
+		log.Crit("Invalid difficulty bomb choice", "choice", choice)
+	}
+	fmt.Println()
+	fmt.Println("Which block should ECIP-1099 (Etchash) come into effect? (default = never)")
+	config.SetEthashECIP1099Transition(w.readOptionalUint64P(nil))
+
+	fmt.Println()
+	fmt.Println("Which block should ECBP-1100 (MESS) come into effect? (default = never)")
+	config.SetECBP1100Transition(w.readOptionalUint64P(nil))
+}
+
 // importGenesis imports a Geth genesis spec into puppeth.
 func (w *wizard) importGenesis() {
 	// Request the genesis JSON spec URL from the user
@@ -206,42 +317,52 @@ func (w *wizard) manageGenesis() {
 	case "1":
 		// Fork rule updating requested, iterate over each fork
 		fmt.Println()
-		fmt.Printf("Which block should Homestead come into effect? (default = %v)\n", w.conf.Genesis.Config.GetEthashHomesteadTransition())
-		w.conf.Genesis.Config.SetEthashHomesteadTransition(w.readDefaultUint64P(*w.conf.Genesis.Config.GetEthashHomesteadTransition()))
+		fmt.Printf("Which block should Homestead come into effect? (default = %s)\n", optionalBlock(w.conf.Genesis.Config.GetEthashHomesteadTransition()))
+		w.conf.Genesis.Config.SetEthashHomesteadTransition(w.readOptionalUint64P(w.conf.Genesis.Config.GetEthashHomesteadTransition()))
 
 		fmt.Println()
-		fmt.Printf("Which block should EIP150 (Tangerine Whistle) come into effect? (default = %v)\n", w.conf.Genesis.Config.GetEIP150Transition())
-		w.conf.Genesis.Config.SetEIP150Transition(w.readDefaultUint64P(*w.conf.Genesis.Config.GetEIP150Transition()))
+		fmt.Printf("Which block should EIP150 (Tangerine Whistle) come into effect? (default = %s)\n", optionalBlock(w.conf.Genesis.Config.GetEIP150Transition()))
+		w.conf.Genesis.Config.SetEIP150Transition(w.readOptionalUint64P(w.conf.Genesis.Config.GetEIP150Transition()))
 
 		fmt.Println()
-		fmt.Printf("Which block should EIP155 (Spurious Dragon) come into effect? (default = %v)\n", w.conf.Genesis.Config.GetEIP155Transition())
-		w.conf.Genesis.Config.SetEIP155Transition(w.readDefaultUint64P(*w.conf.Genesis.Config.GetEIP155Transition()))
+		fmt.Printf("Which block should EIP155 (Spurious Dragon) come into effect? (default = %s)\n", optionalBlock(w.conf.Genesis.Config.GetEIP155Transition()))
+		w.conf.Genesis.Config.SetEIP155Transition(w.readOptionalUint64P(w.conf.Genesis.Config.GetEIP155Transition()))
 
 		fmt.Println()
-		fmt.Printf("Which block should EIP158/161 (also Spurious Dragon) come into effect? (default = %v)\n", w.conf.Genesis.Config.GetEIP161dTransition())
-		w.conf.Genesis.Config.SetEIP161dTransition(w.readDefaultUint64P(*w.conf.Genesis.Config.GetEIP161dTransition()))
+		fmt.Printf("Which block should EIP158/161 (also Spurious Dragon) come into effect? (default = %s)\n", optionalBlock(w.conf.Genesis.Config.GetEIP161dTransition()))
+		w.conf.Genesis.Config.SetEIP161dTransition(w.readOptionalUint64P(w.conf.Genesis.Config.GetEIP161dTransition()))
 
 		fmt.Println()
-		fmt.Printf("Which block should Byzantium come into effect? (default = %v)\n", w.conf.Genesis.Config.GetEthashEIP649Transition())
-		w.conf.Genesis.Config.SetEthashEIP649Transition(w.readDefaultUint64P(*w.conf.Genesis.Config.GetEthashEIP649Transition()))
+		fmt.Printf("Which block should Byzantium come into effect? (default = %s)\n", optionalBlock(w.conf.Genesis.Config.GetEthashEIP649Transition()))
+		w.conf.Genesis.Config.SetEthashEIP649Transition(w.readOptionalUint64P(w.conf.Genesis.Config.GetEthashEIP649Transition()))
 
 		fmt.Println()
-		fmt.Printf("Which block should Constantinople come into effect? (default = %v)\n", w.conf.Genesis.Config.GetEthashEIP1234Transition())
-		w.conf.Genesis.Config.SetEthashEIP1234Transition(w.readDefaultUint64P(*w.conf.Genesis.Config.GetEthashEIP1234Transition()))
+		fmt.Printf("Which block should Constantinople come into effect? (default = %s)\n", optionalBlock(w.conf.Genesis.Config.GetEthashEIP1234Transition()))
+		w.conf.Genesis.Config.SetEthashEIP1234Transition(w.readOptionalUint64P(w.conf.Genesis.Config.GetEthashEIP1234Transition()))
 		if w.conf.Genesis.Config.GetEIP1283DisableTransition() == nil {
 			w.conf.Genesis.Config.SetEIP1283DisableTransition(w.conf.Genesis.Config.GetEthashEIP1234Transition())
 		}
 		fmt.Println()
-		fmt.Printf("Which block should Petersburg come into effect? (default = %v)\n", w.conf.Genesis.Config.GetEIP1283DisableTransition())
-		w.conf.Genesis.Config.SetEIP1283DisableTransition(w.readDefaultUint64P(*w.conf.Genesis.Config.GetEIP1283DisableTransition()))
+		fmt.Printf("Which block should Petersburg come into effect? (default = %s)\n", optionalBlock(w.conf.Genesis.Config.GetEIP1283DisableTransition()))
+		w.conf.Genesis.Config.SetEIP1283DisableTransition(w.readOptionalUint64P(w.conf.Genesis.Config.GetEIP1283DisableTransition()))
 
 		fmt.Println()
-		fmt.Printf("Which block should Istanbul come into effect? (default = %v)\n", w.conf.Genesis.Config.GetEIP145Transition())
-		w.conf.Genesis.Config.SetEIP145Transition(w.readDefaultUint64P(*w.conf.Genesis.Config.GetEIP145Transition()))
+		fmt.Printf("Which block should Istanbul come into effect? (default = %s)\n", optionalBlock(w.conf.Genesis.Config.GetEIP145Transition()))
+		w.conf.Genesis.Config.SetEIP145Transition(w.readOptionalUint64P(w.conf.Genesis.Config.GetEIP145Transition()))
 
 		fmt.Println()
-		fmt.Printf("Which block should YOLOv1 (EIP2537) come into effect? (default = %v)\n", w.conf.Genesis.Config.GetEIP2537Transition())
-		w.conf.Genesis.Config.SetEIP2537Transition(w.readDefaultUint64P(*w.conf.Genesis.Config.GetEIP2537Transition()))
+		fmt.Printf("Which block should YOLOv1 (EIP2537) come into effect? (default = %s)\n", optionalBlock(w.conf.Genesis.Config.GetEIP2537Transition()))
+		w.conf.Genesis.Config.SetEIP2537Transition(w.readOptionalUint64P(w.conf.Genesis.Config.GetEIP2537Transition()))
+
+		if _, ok := w.conf.Genesis.Config.(*coregeth.CoreGethChainConfig); ok && w.conf.Genesis.Config.GetConsensusEngineType().IsEthash() {
+			fmt.Println()
+			fmt.Printf("Which block should ECIP-1099 (Etchash) come into effect? (default = %s)\n", optionalBlock(w.conf.Genesis.Config.GetEthashECIP1099Transition()))
+			w.conf.Genesis.Config.SetEthashECIP1099Transition(w.readOptionalUint64P(w.conf.Genesis.Config.GetEthashECIP1099Transition()))
+
+			fmt.Println()
+			fmt.Printf("Which block should ECBP-1100 (MESS) come into effect? (default = %s)\n", optionalBlock(w.conf.Genesis.Config.GetECBP1100Transition()))
+			w.conf.Genesis.Config.SetECBP1100Transition(w.readOptionalUint64P(w.conf.Genesis.Config.GetECBP1100Transition()))
+		}
 
 		out, _ := json.MarshalIndent(w.conf.Genesis.Config, "", "  ")
 		fmt.Printf("Chain configuration updated:\n\n%s\n", out)
@@ -252,7 +373,7 @@ func (w *wizard) manageGenesis() {
 		// Save whatever genesis configuration we currently have
 		fmt.Println()
 		fmt.Printf("Which folder to save the genesis specs into? (default = current)\n")
-		fmt.Printf("  Will create %s.json, %s-aleth.json, %s-harmony.json, %s-parity.json\n", w.network, w.network, w.network, w.network)
+		fmt.Printf("  Will create %s.json, %s-aleth.json, %s-harmony.json, %s-parity.json, %s-multigeth.json\n", w.network, w.network, w.network, w.network, w.network)
 
 		folder := w.readDefaultString(".")
 		if err := os.MkdirAll(folder, 0755); err != nil {
@@ -284,6 +405,22 @@ func (w *wizard) manageGenesis() {
 		// Export the genesis spec used by Harmony (formerly EthereumJ)
 		saveGenesis(folder, w.network, "harmony", w.conf.Genesis)
 
+		// Export the genesis spec used by multi-geth
+		spec := *w.conf.Genesis
+		spec.Config = new(multigeth.ChainConfig)
+		err := confp.Convert(w.conf.Genesis.Config, spec.Config)
+		if rounds := w.conf.Genesis.Config.GetEthashECIP1017EraRounds(); err == nil && rounds != nil {
+			// Multi-geth keeps the ECIP-1017 transition and era rounds in a single
+			// field, in which only the era rounds are meaningful. Set them last so
+			// the converted transition doesn't clobber them.
+			err = spec.Config.SetEthashECIP1017EraRounds(rounds)
+		}
+		if err != nil {
+			log.Error("Failed to create multi-geth chain spec", "err", err)
+		} else {
+			saveGenesis(folder, w.network, "multigeth", &spec)
+		}
+
 	case "3":
 		// Make sure we don't have any services running
 		if len(w.conf.servers()) > 0 {
@@ -300,6 +437,14 @@ func (w *wizard) manageGenesis() {
 	}
 }
 
+// optionalBlock formats an optional fork block for displaying it to the user.
+func optionalBlock(n *uint64) string {
+	if n == nil {
+		return "never"
+	}
+	return fmt.Sprintf("%d", *n)
+}
+
 // saveGenesis JSON encodes an arbitrary genesis spec into a pre-defined file.
 func saveGenesis(folder, network, client string, spec interface{}) {
 	path := filepath.Join(folder, fmt.Sprintf("%s-%s.json", network, client))
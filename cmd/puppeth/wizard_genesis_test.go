@@ -0,0 +1,133 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params/types/coregeth"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/types/multigeth"
+)
+
+// newTestWizard creates a wizard reading its answers from the given script and
+// storing its configs in a temporary folder.
+func newTestWizard(t *testing.T, dir string, script ...string) *wizard {
+	t.Helper()
+
+	return &wizard{
+		network:  "testnet",
+		conf:     config{path: filepath.Join(dir, "testnet")},
+		servers:  make(map[string]*sshClient),
+		services: make(map[string][]string),
+		in:       bufio.NewReader(strings.NewReader(strings.Join(script, "\n") + "\n")),
+	}
+}
+
+func TestMakeGenesisClassicEthash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "puppeth-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	funded := common.HexToAddress("0x0000000000000000000000000000000000c0ffee")
+	w := newTestWizard(t, dir,
+		"2",       // core-geth chain configuration
+		"1",       // ethash
+		"yes",     // ECIP-1017 monetary policy
+		"2000000", // ECIP-1017 era rounds
+		"1",       // defuse the difficulty bomb
+		"",        // no Etchash, multi-geth doesn't support it
+		"",        // no MESS, multi-geth doesn't support it
+		strings.TrimPrefix(funded.Hex(), "0x"),
+		"",     // no more pre-funded accounts
+		"no",   // no precompile pre-funds
+		"1234", // chain/network ID
+	)
+	w.makeGenesis()
+
+	config, ok := w.conf.Genesis.Config.(*coregeth.CoreGethChainConfig)
+	if !ok {
+		t.Fatalf("chain config type mismatch: have %T, want %T", w.conf.Genesis.Config, config)
+	}
+	if rounds := config.GetEthashECIP1017EraRounds(); rounds == nil || *rounds != 2000000 {
+		t.Errorf("ECIP-1017 era rounds mismatch: have %v, want 2000000", rounds)
+	}
+	if n := config.GetEthashECIP1017Transition(); n == nil || *n != 0 {
+		t.Errorf("ECIP-1017 transition mismatch: have %v, want 0", n)
+	}
+	if n := config.GetEthashECIP1041Transition(); n == nil || *n != 0 {
+		t.Errorf("ECIP-1041 transition mismatch: have %v, want 0", n)
+	}
+	if n := config.GetEthashECIP1099Transition(); n != nil {
+		t.Errorf("ECIP-1099 transition mismatch: have %d, want never", *n)
+	}
+	if n := config.GetECBP1100Transition(); n != nil {
+		t.Errorf("ECBP-1100 transition mismatch: have %d, want never", *n)
+	}
+	if _, ok := w.conf.Genesis.Alloc[funded]; !ok || len(w.conf.Genesis.Alloc) != 1 {
+		t.Errorf("genesis alloc mismatch: have %d accounts, want only %x", len(w.conf.Genesis.Alloc), funded)
+	}
+	if id := w.conf.networkID(); id != 1234 {
+		t.Errorf("network ID mismatch: have %d, want 1234", id)
+	}
+	// Export the genesis and check the multi-geth spec retained the era rounds
+	w.in = bufio.NewReader(strings.NewReader("2\n" + dir + "\n"))
+	w.manageGenesis()
+
+	blob, err := ioutil.ReadFile(filepath.Join(dir, "testnet-multigeth.json"))
+	if err != nil {
+		t.Fatalf("failed to read multi-geth spec: %v", err)
+	}
+	var spec struct {
+		Config *multigeth.ChainConfig `json:"config"`
+	}
+	if err := json.Unmarshal(blob, &spec); err != nil {
+		t.Fatalf("failed to decode multi-geth spec: %v", err)
+	}
+	if spec.Config.ECIP1017EraBlock == nil || spec.Config.ECIP1017EraBlock.Uint64() != 2000000 {
+		t.Errorf("multi-geth ECIP-1017 era rounds mismatch: have %v, want 2000000", spec.Config.ECIP1017EraBlock)
+	}
+	if spec.Config.DisposalBlock == nil || spec.Config.DisposalBlock.Sign() != 0 {
+		t.Errorf("multi-geth bomb disposal mismatch: have %v, want 0", spec.Config.DisposalBlock)
+	}
+	if spec.Config.ChainID == nil || spec.Config.ChainID.Uint64() != 1234 {
+		t.Errorf("multi-geth chain ID mismatch: have %v, want 1234", spec.Config.ChainID)
+	}
+}
+
+func TestConfigNetworkID(t *testing.T) {
+	// An explicit network ID takes precedence over the chain ID
+	conf := config{Genesis: &genesisT.Genesis{Config: &coregeth.CoreGethChainConfig{ChainID: big.NewInt(1), NetworkID: 2}}}
+	if have := conf.networkID(); have != 2 {
+		t.Errorf("network ID mismatch: have %d, want 2", have)
+	}
+	// A missing network ID falls back to the chain ID
+	conf = config{Genesis: &genesisT.Genesis{Config: &coregeth.CoreGethChainConfig{ChainID: big.NewInt(61)}}}
+	if have := conf.networkID(); have != 61 {
+		t.Errorf("network ID fallback mismatch: have %d, want 61", have)
+	}
+}
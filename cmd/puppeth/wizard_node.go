@@ -56,7 +56,7 @@ func (w *wizard) deployNode(boot bool) {
 	existed := err == nil
 
 	infos.genesis, _ = json.MarshalIndent(w.conf.Genesis, "", "  ")
-	infos.network = w.conf.Genesis.Config.GetChainID().Int64()
+	infos.network = w.conf.networkID()
 
 	// Figure out where the user wants to store the persistent data
 	fmt.Println()
@@ -52,7 +52,7 @@ func (w *wizard) deployWallet() {
 	existed := err == nil
 
 	infos.genesis, _ = json.MarshalIndent(w.conf.Genesis, "", "  ")
-	infos.network = w.conf.Genesis.Config.GetChainID().Int64()
+	infos.network = w.conf.networkID()
 
 	// Figure out which port to listen on
 	fmt.Println()
@@ -68,6 +68,7 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
 	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/statediff"
 	pcsclite "github.com/gballet/go-libpcsclite"
 	cli "gopkg.in/urfave/cli.v1"
 )
@@ -243,6 +244,10 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index by-hash for (default = index all blocks)",
 		Value: 0,
 	}
+	AddressIndexFlag = cli.BoolFlag{
+		Name:  "addressindex",
+		Usage: "Index transactions by the addresses taking part in them (eth_getTransactionsByAddress)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -513,6 +518,30 @@ var (
 		Name:  "ethstats",
 		Usage: "Reporting URL of a ethstats service (nodename:secret@host:port)",
 	}
+	EthStatsTLSCertFlag = cli.StringFlag{
+		Name:  "ethstats.tls.cert",
+		Usage: "Client certificate file to authenticate to the ethstats service (requires a TLS websocket)",
+	}
+	EthStatsTLSKeyFlag = cli.StringFlag{
+		Name:  "ethstats.tls.key",
+		Usage: "Key file of the ethstats client certificate",
+	}
+	EthStatsTLSCAFlag = cli.StringFlag{
+		Name:  "ethstats.tls.ca",
+		Usage: "CA certificate file to verify the ethstats service with (default = system pool)",
+	}
+	StateDiffFlag = cli.BoolFlag{
+		Name:  "statediff",
+		Usage: "Enables the streaming of per-block state diffs (statediff RPC namespace)",
+	}
+	StateDiffFileFlag = cli.StringFlag{
+		Name:  "statediff.file",
+		Usage: "File to append the state diffs of all imported blocks to, as JSON lines",
+	}
+	StateDiffNodesFlag = cli.BoolFlag{
+		Name:  "statediff.nodes",
+		Usage: "Includes the created intermediate trie nodes in the state diffs",
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -573,6 +602,11 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 	}
+	GraphQLWSOriginsFlag = cli.StringFlag{
+		Name:  "graphql.wsorigins",
+		Usage: "Origins from which to accept GraphQL websocket requests",
+		Value: "",
+	}
 	WSEnabledFlag = cli.BoolFlag{
 		Name:  "ws",
 		Usage: "Enable the WS-RPC server",
@@ -652,6 +686,18 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	MaxPeersPerSubnetFlag = cli.IntFlag{
+		Name:  "maxpeers.subnet",
+		Usage: "Maximum number of network peers per /24 IPv4 or /48 IPv6 network (unlimited if set to 0)",
+	}
+	NetGroupsFlag = cli.StringFlag{
+		Name:  "netgroups",
+		Usage: "File assigning IP networks to groups such as autonomous systems (one CIDR mask and group name per line)",
+	}
+	MaxPeersPerNetGroupFlag = cli.IntFlag{
+		Name:  "maxpeers.netgroup",
+		Usage: "Maximum number of network peers per group of --netgroups (unlimited if set to 0)",
+	}
 	DNSDiscoveryFlag = cli.StringFlag{
 		Name:  "discovery.dns",
 		Usage: "Sets DNS discovery entry points (use \"\" to disable DNS)",
@@ -1010,6 +1056,9 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
 		cfg.GraphQLVirtualHosts = SplitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(GraphQLWSOriginsFlag.Name) {
+		cfg.GraphQLWSOrigins = SplitAndTrim(ctx.GlobalString(GraphQLWSOriginsFlag.Name))
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -1251,6 +1300,15 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	if ctx.GlobalIsSet(MaxPeersPerSubnetFlag.Name) {
+		cfg.MaxPeersPerSubnet = ctx.GlobalInt(MaxPeersPerSubnetFlag.Name)
+	}
+	if ctx.GlobalIsSet(NetGroupsFlag.Name) {
+		cfg.PrefixGroupsFile = ctx.GlobalString(NetGroupsFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaxPeersPerNetGroupFlag.Name) {
+		cfg.MaxPeersPerPrefixGroup = ctx.GlobalInt(MaxPeersPerNetGroupFlag.Name)
+	}
 
 	if ctx.GlobalBool(DeveloperFlag.Name) {
 		// --dev mode can't use p2p networking.
@@ -1660,6 +1718,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(AddressIndexFlag.Name) {
+		cfg.AddressIndex = ctx.GlobalBool(AddressIndexFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1892,19 +1953,29 @@ func RegisterEthService(stack *node.Node, cfg *eth.Config) ethapi.Backend {
 
 // RegisterEthStatsService configures the Ethereum Stats daemon and adds it to
 // the given node.
-func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url string) {
-	if err := ethstats.New(stack, backend, backend.Engine(), url); err != nil {
+func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, config ethstats.Config) {
+	if err := ethstats.NewWithConfig(stack, backend, backend.Engine(), config); err != nil {
 		Fatalf("Failed to register the Ethereum Stats service: %v", err)
 	}
 }
 
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
 func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, cfg node.Config) {
-	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
+	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts, cfg.GraphQLWSOrigins); err != nil {
 		Fatalf("Failed to register the GraphQL service: %v", err)
 	}
 }
 
+// RegisterStateDiffService configures the state diff service and adds it to the
+// given node.
+func RegisterStateDiffService(stack *node.Node, backend ethapi.Backend, cfg statediff.Config) {
+	fullBackend, ok := backend.(*eth.EthAPIBackend)
+	if !ok {
+		Fatalf("Failed to register the state diff service: light clients are not supported")
+	}
+	statediff.New(stack, fullBackend.BlockChain(), cfg)
+}
+
 func SetupMetrics(ctx *cli.Context) {
 	if metrics.Enabled {
 		log.Info("Enabling metrics collection")
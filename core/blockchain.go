@@ -90,6 +90,7 @@ const (
 	maxFutureBlocks     = 256
 	maxTimeFutureBlocks = 30
 	badBlockLimit       = 10
+	stateDiffQueueLimit = 64
 	TriesInMemory       = 128
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
@@ -180,7 +181,10 @@ type BlockChain struct {
 	chainHeadFeed event.Feed
 	logsFeed      event.Feed
 	blockProcFeed event.Feed
+	stateDiffFeed event.Feed
+	stateDiffs    chan StateDiffEvent // State diffs pending delivery, dropped if subscribers lag behind
 	scope         event.SubscriptionScope
+	diffScope     event.SubscriptionScope // State diff subscriptions, diffs are only captured if there are any
 	genesisBlock  *types.Block
 
 	chainmu sync.RWMutex // blockchain insertion lock
@@ -211,16 +215,28 @@ type BlockChain struct {
 	shouldPreserve  func(*types.Block) bool        // Function used to determine whether should preserve the given block.
 	terminateInsert func(common.Hash, uint64) bool // Testing hook used to terminate ancient receipt chain insertion.
 
-	artificialFinalityEnabled int32 // toggles artificial finality features
+	artificialFinalityEnabled int32           // toggles artificial finality features
+	rejectedReorgs            []RejectedReorg // recent reorgs rejected by artificial finality, oldest first
+	rejectedReorgsLock        sync.Mutex
 }
 
 // NewBlockChain returns a fully initialised block chain using information
 // available in the database. It initialises the default Ethereum Validator and
 // Processor.
 func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig ctypes.ChainConfigurator, engine consensus.Engine, vmConfig vm.Config, shouldPreserve func(block *types.Block) bool, txLookupLimit *uint64) (*BlockChain, error) {
+	return NewBlockChainWithStateDatabase(db, nil, cacheConfig, chainConfig, engine, vmConfig, shouldPreserve, txLookupLimit)
+}
+
+// NewBlockChainWithStateDatabase returns a fully initialised block chain like
+// NewBlockChain, but accessing the state through the given state database. If
+// stateDatabase is nil, a state database backed by db is used.
+func NewBlockChainWithStateDatabase(db ethdb.Database, stateDatabase state.Database, cacheConfig *CacheConfig, chainConfig ctypes.ChainConfigurator, engine consensus.Engine, vmConfig vm.Config, shouldPreserve func(block *types.Block) bool, txLookupLimit *uint64) (*BlockChain, error) {
 	if cacheConfig == nil {
 		cacheConfig = defaultCacheConfig
 	}
+	if stateDatabase == nil {
+		stateDatabase = state.NewDatabaseWithCache(db, cacheConfig.TrieCleanLimit, cacheConfig.TrieCleanJournal)
+	}
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	receiptsCache, _ := lru.New(receiptsCacheLimit)
@@ -234,8 +250,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig ctyp
 		cacheConfig:    cacheConfig,
 		db:             db,
 		triegc:         prque.New(nil),
-		stateCache:     state.NewDatabaseWithCache(db, cacheConfig.TrieCleanLimit, cacheConfig.TrieCleanJournal),
+		stateCache:     stateDatabase,
 		quit:           make(chan struct{}),
+		stateDiffs:     make(chan StateDiffEvent, stateDiffQueueLimit),
 		shouldPreserve: shouldPreserve,
 		bodyCache:      bodyCache,
 		bodyRLPCache:   bodyRLPCache,
@@ -349,6 +366,8 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig ctyp
 	}
 	// Take ownership of this particular state
 	go bc.update()
+	bc.wg.Add(1)
+	go bc.stateDiffLoop()
 	if txLookupLimit != nil {
 		bc.txLookupLimit = *txLookupLimit
 		go bc.maintainTxIndex(txIndexBlock)
@@ -939,6 +958,7 @@ func (bc *BlockChain) Stop() {
 	}
 	// Unsubscribe all subscriptions registered from blockchain
 	bc.scope.Close()
+	bc.diffScope.Close()
 	close(bc.quit)
 	bc.StopInsert()
 	bc.wg.Wait()
@@ -1467,6 +1487,18 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	if err := blockBatch.Write(); err != nil {
 		log.Crit("Failed to write block into disk", "err", err)
 	}
+	// Capture the state changes made by the block before they are committed, if
+	// anyone is interested in them.
+	var stateDiff *StateDiffEvent
+	if bc.diffScope.Count() > 0 {
+		state.IntermediateRoot(bc.chainConfig.IsEnabled(bc.chainConfig.GetEIP161dTransition, block.Number()))
+		accounts, err := state.StateDiff()
+		if err != nil {
+			log.Error("Failed to capture state diff", "number", block.Number(), "hash", block.Hash(), "err", err)
+		} else {
+			stateDiff = &StateDiffEvent{Block: block, Accounts: accounts}
+		}
+	}
 	// Commit all cached state changes into underlying memory database.
 	root, err := state.Commit(bc.chainConfig.IsEnabled(bc.chainConfig.GetEIP161dTransition, block.Number()))
 	if err != nil {
@@ -1526,6 +1558,15 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 			}
 		}
 	}
+	// Hand the state diff over for delivery, slow subscribers must not stall
+	// the import while the chain lock is held.
+	if stateDiff != nil {
+		select {
+		case bc.stateDiffs <- *stateDiff:
+		default:
+			log.Warn("Dropping state diff, subscribers are lagging", "number", block.Number(), "hash", block.Hash())
+		}
+	}
 	// If the total difficulty is higher than our known, add it to the canonical chain
 	// Second clause in the if statement reduces the vulnerability to selfish mining.
 	// Please refer to http://www.cs.cornell.edu/~ie53/publications/btcProcFC.pdf
@@ -1562,6 +1603,7 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 					if err := bc.ecbp1100(d.commonBlock.Header(), currentBlock.Header(), block.Header()); err != nil {
 
 						canonicalDisallowed = true
+						bc.addRejectedReorg(d.commonBlock.Header(), currentBlock.Header(), block.Header())
 						log.Warn("Reorg disallowed", "error", err)
 
 					} else if len(d.oldChain) > 2 {
@@ -1765,6 +1807,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 							if err := bc.ecbp1100(reorgData.commonBlock.Header(), current.Header(), block.Header()); err != nil {
 
 								canonicalDisallowed = true
+								bc.addRejectedReorg(reorgData.commonBlock.Header(), current.Header(), block.Header())
 								log.Trace("Reorg disallowed", "error", err)
 
 							}
@@ -2408,6 +2451,21 @@ func (bc *BlockChain) update() {
 	}
 }
 
+// stateDiffLoop delivers the queued state diffs to the subscribers, outside
+// of the chain lock.
+func (bc *BlockChain) stateDiffLoop() {
+	defer bc.wg.Done()
+
+	for {
+		select {
+		case diff := <-bc.stateDiffs:
+			bc.stateDiffFeed.Send(diff)
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
 // maintainTxIndex is responsible for the construction and deletion of the
 // transaction index.
 //
@@ -2665,6 +2723,12 @@ func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscript
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
 }
 
+// SubscribeStateDiffEvent registers a subscription of StateDiffEvent. State diffs
+// are only captured while there are subscribers.
+func (bc *BlockChain) SubscribeStateDiffEvent(ch chan<- StateDiffEvent) event.Subscription {
+	return bc.diffScope.Track(bc.stateDiffFeed.Subscribe(ch))
+}
+
 // SubscribeBlockProcessingEvent registers a subscription of bool where true means
 // block processing has started while false means it has stopped.
 func (bc *BlockChain) SubscribeBlockProcessingEvent(ch chan<- bool) event.Subscription {
@@ -52,6 +52,56 @@ func (bc *BlockChain) IsArtificialFinalityEnabled() bool {
 	return atomic.LoadInt32(&bc.artificialFinalityEnabled) == 1
 }
 
+// maxRejectedReorgs is the number of reorgs rejected by artificial finality
+// which are remembered for reporting.
+const maxRejectedReorgs = 16
+
+// RejectedReorg describes a chain reorganization rejected by artificial finality.
+type RejectedReorg struct {
+	Time     time.Time     // Time of the rejection
+	Common   *types.Header // Common ancestor of the chains
+	Current  *types.Header // Head of the local chain
+	Proposed *types.Header // Head of the rejected chain segment
+}
+
+// addRejectedReorg remembers a reorg rejected by artificial finality. The blocks
+// of a rejected side chain are rejected one by one, so a reorg from the same common
+// ancestor onto the same local head only advances the proposed head of the entry.
+func (bc *BlockChain) addRejectedReorg(commonAncestor, current, proposed *types.Header) {
+	bc.rejectedReorgsLock.Lock()
+	defer bc.rejectedReorgsLock.Unlock()
+
+	for i, reorg := range bc.rejectedReorgs {
+		if reorg.Common.Hash() != commonAncestor.Hash() || reorg.Current.Hash() != current.Hash() {
+			continue
+		}
+		reorg.Time = time.Now()
+		if proposed.Number.Cmp(reorg.Proposed.Number) > 0 {
+			reorg.Proposed = proposed
+		}
+		bc.rejectedReorgs = append(append(bc.rejectedReorgs[:i], bc.rejectedReorgs[i+1:]...), reorg)
+		return
+	}
+	if len(bc.rejectedReorgs) >= maxRejectedReorgs {
+		bc.rejectedReorgs = append(bc.rejectedReorgs[:0], bc.rejectedReorgs[1:]...)
+	}
+	bc.rejectedReorgs = append(bc.rejectedReorgs, RejectedReorg{
+		Time:     time.Now(),
+		Common:   commonAncestor,
+		Current:  current,
+		Proposed: proposed,
+	})
+}
+
+// RejectedReorgs returns the most recent reorgs rejected by artificial finality,
+// oldest first.
+func (bc *BlockChain) RejectedReorgs() []RejectedReorg {
+	bc.rejectedReorgsLock.Lock()
+	defer bc.rejectedReorgsLock.Unlock()
+
+	return append([]RejectedReorg(nil), bc.rejectedReorgs...)
+}
+
 // getTDRatio is a helper function returning the total difficulty ratio of
 // proposed over current chain segments.
 func (bc *BlockChain) getTDRatio(commonAncestor, current, proposed *types.Header) float64 {
@@ -74,22 +124,35 @@ func (bc *BlockChain) getTDRatio(commonAncestor, current, proposed *types.Header
 // "Modified Exponential Subjective Scoring" used to prefer known chain segments
 // over later-to-come counterparts, especially proposed segments stretching far into the past.
 func (bc *BlockChain) ecbp1100(commonAncestor, current, proposed *types.Header) error {
+	return ecbp1100(bc.GetTd, commonAncestor, current, proposed)
+}
+
+// ecbp1100 implements the ECBP1100-MESS arbitration of a reorganization for any
+// chain able to look up the total difficulties of the chain segments involved.
+// Since the arbitration cannot be done without them, a reorg is rejected if any
+// of the total difficulties is unknown.
+func ecbp1100(getTd func(common.Hash, uint64) *big.Int, commonAncestor, current, proposed *types.Header) error {
 
 	// Get the total difficulties of the proposed chain segment and the existing one.
-	commonAncestorTD := bc.GetTd(commonAncestor.Hash(), commonAncestor.Number.Uint64())
-	proposedParentTD := bc.GetTd(proposed.ParentHash, proposed.Number.Uint64()-1)
+	commonAncestorTD := getTd(commonAncestor.Hash(), commonAncestor.Number.Uint64())
+	proposedParentTD := getTd(proposed.ParentHash, proposed.Number.Uint64()-1)
+	localTD := getTd(current.Hash(), current.Number.Uint64())
+	if commonAncestorTD == nil || proposedParentTD == nil || localTD == nil {
+		return fmt.Errorf(`%w: ECBP1100-MESS 🔒 status=rejected reason="missing total difficulty" common.bno=%d common.hash=%s current.bno=%d current.hash=%s proposed.bno=%d proposed.hash=%s`,
+			errReorgFinality,
+			commonAncestor.Number.Uint64(), commonAncestor.Hash().Hex(),
+			current.Number.Uint64(), current.Hash().Hex(),
+			proposed.Number.Uint64(), proposed.Hash().Hex(),
+		)
+	}
 	proposedTD := new(big.Int).Add(proposed.Difficulty, proposedParentTD)
-	localTD := bc.GetTd(current.Hash(), current.Number.Uint64())
 
 	// if proposed_subchain_td * CURVE_FUNCTION_DENOMINATOR < get_curve_function_numerator(proposed.Time - commonAncestor.Time) * local_subchain_td.
 	proposedSubchainTD := new(big.Int).Sub(proposedTD, commonAncestorTD)
 	localSubchainTD := new(big.Int).Sub(localTD, commonAncestorTD)
 
 	xBig := big.NewInt(int64(current.Time - commonAncestor.Time))
-	eq := ecbp1100PolynomialV(xBig)
-	want := eq.Mul(eq, localSubchainTD)
-
-	got := new(big.Int).Mul(proposedSubchainTD, ecbp1100PolynomialVCurveFunctionDenominator)
+	got, want := ecbp1100Scores(xBig, localSubchainTD, proposedSubchainTD)
 
 	if got.Cmp(want) < 0 {
 		prettyRatio, _ := new(big.Float).Quo(
@@ -110,6 +173,26 @@ func (bc *BlockChain) ecbp1100(commonAncestor, current, proposed *types.Header)
 	return nil
 }
 
+// ecbp1100Scores returns the scaled total difficulty of a proposed chain segment
+// and the minimum it needs to reach for ECBP1100 to accept it, given the time
+// span of the local chain segment since the common ancestor.
+func ecbp1100Scores(span, localSubchainTD, proposedSubchainTD *big.Int) (got, want *big.Int) {
+	want = ecbp1100PolynomialV(span)
+	want.Mul(want, localSubchainTD)
+
+	got = new(big.Int).Mul(proposedSubchainTD, ecbp1100PolynomialVCurveFunctionDenominator)
+	return got, want
+}
+
+// ECBP1100Accepts reports whether ECBP1100-MESS accepts a reorganization onto a
+// proposed chain segment, given the time span (in seconds) of the local chain
+// segment since the common ancestor and the total difficulties the local and
+// proposed segments accumulated on top of it.
+func ECBP1100Accepts(span uint64, localSubchainTD, proposedSubchainTD *big.Int) bool {
+	got, want := ecbp1100Scores(new(big.Int).SetUint64(span), localSubchainTD, proposedSubchainTD)
+	return got.Cmp(want) >= 0
+}
+
 /*
 ecbp1100PolynomialV is a cubic function that looks a lot like Option 3's sin function,
 but adds the benefit that the calculation can be done with integers (instead of yucky floating points).
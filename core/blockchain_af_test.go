@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"image/color"
 	"log"
@@ -242,6 +243,131 @@ func TestAFKnownBlock(t *testing.T) {
 	}
 }
 
+// TestAFRejectedReorgs tests that reorgs rejected by artificial finality are
+// remembered, and that only the most recent ones are kept.
+func TestAFRejectedReorgs(t *testing.T) {
+	engine := ethash.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := params.DefaultMessNetGenesisBlock()
+	genesisB := MustCommitGenesis(db, genesis)
+
+	chain, err := NewBlockChain(db, nil, genesis.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	chain.EnableArtificialFinality(true)
+
+	easy, _ := GenerateChain(genesis.Config, genesisB, engine, db, 1000, func(i int, gen *BlockGen) {
+		gen.OffsetTime(0)
+	})
+	if _, err := chain.InsertChain(easy); err != nil {
+		t.Fatal(err)
+	}
+	if reorgs := chain.RejectedReorgs(); len(reorgs) != 0 {
+		t.Fatalf("rejected reorgs before any competing chain: %d", len(reorgs))
+	}
+	commonB := easy[len(easy)-300]
+	hard, _ := GenerateChain(genesis.Config, commonB, engine, db, 300, func(i int, gen *BlockGen) {
+		gen.OffsetTime(-7)
+	})
+	if _, err := chain.InsertChain(hard); err != nil {
+		t.Fatal(err)
+	}
+	if chain.CurrentBlock().Hash() != easy[len(easy)-1].Hash() {
+		t.Fatal("hard chain got head, reorg should be disallowed")
+	}
+	// The blocks of the side chain are all reported as a single rejection
+	reorgs := chain.RejectedReorgs()
+	if len(reorgs) != 1 {
+		t.Fatalf("rejected reorg count mismatch: have %d, want 1", len(reorgs))
+	}
+	reorg := reorgs[0]
+	if reorg.Common.Hash() != commonB.Hash() {
+		t.Errorf("common ancestor mismatch: have %d, want %d", reorg.Common.Number, commonB.Number())
+	}
+	if reorg.Current.Hash() != easy[len(easy)-1].Hash() {
+		t.Errorf("current head mismatch: have %d", reorg.Current.Number)
+	}
+	if h := chain.GetHeaderByHash(reorg.Proposed.Hash()); h == nil || reorg.Proposed.Number.Uint64() <= commonB.NumberU64() {
+		t.Errorf("invalid proposed head %d", reorg.Proposed.Number)
+	}
+	// Rejecting an earlier block of the same side chain keeps the highest head
+	chain.addRejectedReorg(commonB.Header(), easy[len(easy)-1].Header(), hard[0].Header())
+	if reorgs = chain.RejectedReorgs(); len(reorgs) != 1 || reorgs[0].Proposed.Hash() != reorg.Proposed.Hash() {
+		t.Errorf("proposed head not retained: %+v", reorgs)
+	}
+	// Only the most recent rejections are kept
+	for i := 0; i < 2*maxRejectedReorgs; i++ {
+		chain.addRejectedReorg(easy[i].Header(), easy[len(easy)-1].Header(), hard[i].Header())
+	}
+	reorgs = chain.RejectedReorgs()
+	if len(reorgs) != maxRejectedReorgs {
+		t.Fatalf("rejected reorg count mismatch: have %d, want %d", len(reorgs), maxRejectedReorgs)
+	}
+	if have, want := reorgs[0].Proposed.Hash(), hard[maxRejectedReorgs].Hash(); have != want {
+		t.Errorf("oldest rejected reorg mismatch: have %x, want %x", have, want)
+	}
+}
+
+// TestHeaderChainArtificialFinality tests that header-only chains, as used by
+// light clients, arbitrate reorgs with ECBP1100 when artificial finality is
+// enabled.
+func TestHeaderChainArtificialFinality(t *testing.T) {
+	for _, enable := range []bool{false, true} {
+		engine := ethash.NewFaker()
+
+		db := rawdb.NewMemoryDatabase()
+		genesis := params.DefaultMessNetGenesisBlock()
+		genesisB := MustCommitGenesis(db, genesis)
+
+		// Generate the blocks on a separate database, only the headers are inserted
+		gendb := rawdb.NewMemoryDatabase()
+		MustCommitGenesis(gendb, genesis)
+
+		hc, err := NewHeaderChain(db, genesis.Config, engine, func() bool { return false })
+		if err != nil {
+			t.Fatal(err)
+		}
+		hc.EnableArtificialFinality(enable)
+
+		easy, _ := GenerateChain(genesis.Config, genesisB, engine, gendb, 1000, func(i int, gen *BlockGen) {
+			gen.OffsetTime(0)
+		})
+		hard, _ := GenerateChain(genesis.Config, easy[len(easy)-300], engine, gendb, 300, func(i int, gen *BlockGen) {
+			gen.OffsetTime(-7)
+		})
+		insert := func(blocks []*types.Block) error {
+			headers := make([]*types.Header, len(blocks))
+			for i, block := range blocks {
+				headers[i] = block.Header()
+			}
+			writeHeader := func(header *types.Header) error {
+				_, err := hc.WriteHeader(header)
+				return err
+			}
+			_, err := hc.InsertHeaderChain(headers, writeHeader, time.Now())
+			return err
+		}
+		if err := insert(easy); err != nil {
+			t.Fatal(err)
+		}
+		// The hard chain is heavier and reorganizes the chain by TD alone, unless
+		// artificial finality rejects it.
+		want, wantErr := hard[len(hard)-1].Hash(), error(nil)
+		if enable {
+			want, wantErr = easy[len(easy)-1].Hash(), errReorgFinality
+		}
+		if err := insert(hard); !errors.Is(err, wantErr) {
+			t.Fatalf("af=%v: insert error mismatch: have %v, want %v", enable, err, wantErr)
+		}
+		if have := hc.CurrentHeader().Hash(); have != want {
+			t.Errorf("af=%v: head mismatch: have %d, want %x", enable, hc.CurrentHeader().Number, want)
+		}
+	}
+}
+
 // TestEcbp1100PolynomialV tests the general shape and return values of the ECBP1100 polynomial curve.
 // It makes sure domain values above the 'cap' do indeed get limited, as well
 // as sanity check some normal domain values.
@@ -266,6 +392,27 @@ func TestEcbp1100PolynomialV(t *testing.T) {
 	}
 }
 
+func TestECBP1100Accepts(t *testing.T) {
+	cases := []struct {
+		span            uint64
+		local, proposed int64
+		accept          bool
+	}{
+		{0, 0, 1, true},             // plain chain extension
+		{100 * 13, 100, 120, false}, // antigravity ~1.23
+		{100 * 13, 100, 130, true},
+		{1000 * 13, 1000, 2000, false},
+		{1000 * 13, 1000, 17000, true},
+		{1e9, 1000, 30000, false},
+		{1e9, 1000, 32000, true},
+	}
+	for i, c := range cases {
+		if have := ECBP1100Accepts(c.span, big.NewInt(c.local), big.NewInt(c.proposed)); have != c.accept {
+			t.Errorf("case %d: accept mismatch: have %v, want %v", i, have, c.accept)
+		}
+	}
+}
+
 func TestPlot_ecbp1100PolynomialV(t *testing.T) {
 	t.Skip("This test plots a graph of the ECBP1100 polynomial curve.")
 	p, err := plot.New()
@@ -189,6 +189,13 @@ func (b *BlockGen) OffsetTime(seconds int64) {
 // values. Inserting them into BlockChain requires use of FakePow or
 // a similar non-validating proof of work implementation.
 func GenerateChain(config ctypes.ChainConfigurator, parent *types.Block, engine consensus.Engine, db ethdb.Database, n int, gen func(int, *BlockGen)) ([]*types.Block, []types.Receipts) {
+	return GenerateChainWithStateDatabase(config, parent, engine, state.NewDatabase(db), n, gen)
+}
+
+// GenerateChainWithStateDatabase creates a chain of n blocks like GenerateChain,
+// but reading the parent's state from and writing the intermediate states to the
+// given state database.
+func GenerateChainWithStateDatabase(config ctypes.ChainConfigurator, parent *types.Block, engine consensus.Engine, stateDatabase state.Database, n int, gen func(int, *BlockGen)) ([]*types.Block, []types.Receipts) {
 	if config == nil {
 		config = params.TestChainConfig
 	}
@@ -231,7 +238,7 @@ func GenerateChain(config ctypes.ChainConfigurator, parent *types.Block, engine
 		return nil, nil
 	}
 	for i := 0; i < n; i++ {
-		statedb, err := state.New(parent.Root(), state.NewDatabase(db), nil)
+		statedb, err := state.New(parent.Root(), stateDatabase, nil)
 		if err != nil {
 			panic(err)
 		}
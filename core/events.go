@@ -18,6 +18,7 @@ package core
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
@@ -41,3 +42,10 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// StateDiffEvent is posted when the state of a block has been written, and holds
+// the changes the block made to the state of its parent.
+type StateDiffEvent struct {
+	Block    *types.Block
+	Accounts []state.AccountDiff
+}
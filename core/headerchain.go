@@ -70,6 +70,8 @@ type HeaderChain struct {
 
 	procInterrupt func() bool
 
+	artificialFinalityEnabled int32 // toggles artificial finality features for header-only chains
+
 	rand   *mrand.Rand
 	engine consensus.Engine
 }
@@ -174,6 +176,18 @@ func (hc *HeaderChain) WriteHeader(header *types.Header) (status WriteStatus, er
 			reorg = mrand.Float64() < 0.5
 		}
 	}
+	// If the header would reorganize the chain, check if artificial finality
+	// forbids the reorganization, overriding the simple TD comparison. Unlike
+	// full blocks, the rejected header is reported as an error, otherwise a
+	// header sync would wait forever for the local head to catch up.
+	if reorg && header.ParentHash != hc.currentHeaderHash && hc.IsArtificialFinalityEnabled() &&
+		hc.config.IsEnabled(hc.config.GetECBP1100Transition, hc.CurrentHeader().Number) {
+
+		if err := hc.ecbp1100(header); err != nil {
+			log.Warn("Reorg disallowed", "error", err)
+			return NonStatTy, err
+		}
+	}
 	if reorg {
 		// If the header can be added into canonical chain, adjust the
 		// header chain markers(canonical indexes and head header flag).
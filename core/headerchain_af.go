@@ -0,0 +1,78 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// EnableArtificialFinality enables and disables artificial finality features
+// for header-only chains (i.e. light clients), which insert headers without
+// going through BlockChain. The same caveats as for BlockChain apply: the
+// setting is only effective once ECBP1100 is activated by the chain
+// configuration. The method is idempotent.
+func (hc *HeaderChain) EnableArtificialFinality(enable bool, logValues ...interface{}) {
+	var statusLog string
+	if enable {
+		statusLog = "Enabled"
+		atomic.StoreInt32(&hc.artificialFinalityEnabled, 1)
+	} else {
+		statusLog = "Disabled"
+		atomic.StoreInt32(&hc.artificialFinalityEnabled, 0)
+	}
+	if !hc.config.IsEnabled(hc.config.GetECBP1100Transition, hc.CurrentHeader().Number) {
+		// Don't log anything if the config hasn't enabled it yet.
+		return
+	}
+	logFn := log.Warn // Deactivated and enabled
+	if enable {
+		logFn = log.Info // Activated and enabled
+	}
+	logFn(fmt.Sprintf("%s artificial finality features", statusLog), logValues...)
+}
+
+// IsArtificialFinalityEnabled returns the status of the header chain's
+// artificial finality feature setting.
+// This status is agnostic of feature activation by chain configuration.
+func (hc *HeaderChain) IsArtificialFinalityEnabled() bool {
+	return atomic.LoadInt32(&hc.artificialFinalityEnabled) == 1
+}
+
+// ecbp1100 arbitrates the reorganization of the header chain onto the given
+// header using ECBP1100-MESS. The proposed chain segment must already be
+// written to the database, up to and including the parent of the header.
+func (hc *HeaderChain) ecbp1100(proposed *types.Header) error {
+	current := hc.CurrentHeader()
+
+	// Find the most recent canonical ancestor of the proposed header
+	ancestor := hc.GetHeader(proposed.ParentHash, proposed.Number.Uint64()-1)
+	for ancestor != nil && hc.GetCanonicalHash(ancestor.Number.Uint64()) != ancestor.Hash() {
+		ancestor = hc.GetHeader(ancestor.ParentHash, ancestor.Number.Uint64()-1)
+	}
+	if ancestor == nil {
+		return fmt.Errorf("%w: ECBP1100-MESS 🔒 status=rejected reason=\"unknown common ancestor\" current.bno=%d current.hash=%s proposed.bno=%d proposed.hash=%s",
+			errReorgFinality,
+			current.Number.Uint64(), current.Hash().Hex(),
+			proposed.Number.Uint64(), proposed.Hash().Hex(),
+		)
+	}
+	return ecbp1100(hc.GetTd, ancestor, current, proposed)
+}
@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -175,3 +176,116 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// Address activity roles, flagging how an address took part in a transaction.
+const (
+	AddressActivitySender    uint8 = 1 << iota // Sender of the transaction
+	AddressActivityRecipient                   // Recipient of the transaction, or the contract it created
+	AddressActivityInternal                    // Sender or recipient of an internal value transfer
+)
+
+// AddressActivity is an entry of the address index, referencing a transaction
+// an address took part in.
+type AddressActivity struct {
+	BlockNumber uint64 `rlp:"-"`
+	TxIndex     uint32 `rlp:"-"`
+	BlockHash   common.Hash
+	TxHash      common.Hash
+	Roles       uint8
+}
+
+// IterateAddressActivity calls fn with the index entries of the given address
+// within the block range [from, to], ordered by block number and transaction
+// index, until fn returns false.
+func IterateAddressActivity(db ethdb.Iteratee, address common.Address, from uint64, to uint64, fn func(AddressActivity) bool) {
+	prefix := append(addressActivityPrefix, address.Bytes()...)
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+12 {
+			continue
+		}
+		var entry AddressActivity
+		if entry.BlockNumber = binary.BigEndian.Uint64(key[len(prefix):]); entry.BlockNumber > to {
+			break
+		}
+		entry.TxIndex = binary.BigEndian.Uint32(key[len(prefix)+8:])
+		if err := rlp.DecodeBytes(it.Value(), &entry); err != nil {
+			log.Error("Invalid address activity RLP", "address", address, "number", entry.BlockNumber, "err", err)
+			continue
+		}
+		if !fn(entry) {
+			return
+		}
+	}
+}
+
+// WriteAddressActivity stores an index entry of the given address.
+func WriteAddressActivity(db ethdb.KeyValueWriter, address common.Address, entry AddressActivity) {
+	data, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		log.Crit("Failed to encode address activity", "err", err)
+	}
+	if err := db.Put(addressActivityKey(address, entry.BlockNumber, entry.TxIndex), data); err != nil {
+		log.Crit("Failed to store address activity", "err", err)
+	}
+}
+
+// DeleteAddressActivity removes the index entries of the given address within
+// the block range [from, to).
+func DeleteAddressActivity(db ethdb.KeyValueStore, address common.Address, from uint64, to uint64) {
+	start, end := addressActivityKey(address, from, 0), addressActivityKey(address, to, 0)
+	it := db.NewIterator(nil, start)
+	defer it.Release()
+
+	for it.Next() {
+		if bytes.Compare(it.Key(), end) >= 0 {
+			break
+		}
+		if len(it.Key()) != len(end) {
+			continue
+		}
+		if err := db.Delete(it.Key()); err != nil {
+			log.Crit("Failed to delete address activity", "err", err)
+		}
+	}
+	if it.Error() != nil {
+		log.Crit("Failed to delete address activity", "err", it.Error())
+	}
+}
+
+// ReadAddressSection retrieves the addresses which have index entries within
+// the given address index section.
+func ReadAddressSection(db ethdb.KeyValueReader, section uint64) []common.Address {
+	data, _ := db.Get(addressSectionKey(section))
+	if len(data) == 0 {
+		return nil
+	}
+	var addresses []common.Address
+	if err := rlp.DecodeBytes(data, &addresses); err != nil {
+		log.Error("Invalid address section RLP", "section", section, "err", err)
+		return nil
+	}
+	return addresses
+}
+
+// WriteAddressSection stores the addresses which have index entries within the
+// given address index section.
+func WriteAddressSection(db ethdb.KeyValueWriter, section uint64, addresses []common.Address) {
+	data, err := rlp.EncodeToBytes(addresses)
+	if err != nil {
+		log.Crit("Failed to encode address section", "err", err)
+	}
+	if err := db.Put(addressSectionKey(section), data); err != nil {
+		log.Crit("Failed to store address section", "err", err)
+	}
+}
+
+// DeleteAddressSection removes the address list of the given address index section.
+func DeleteAddressSection(db ethdb.KeyValueWriter, section uint64) {
+	if err := db.Delete(addressSectionKey(section)); err != nil {
+		log.Crit("Failed to delete address section", "err", err)
+	}
+}
@@ -71,12 +71,15 @@ var (
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	codePrefix            = []byte("c") // codePrefix + code hash -> account code
+	addressActivityPrefix = []byte("x") // addressActivityPrefix + address + num (uint64 big endian) + tx index (uint32 big endian) -> address activity
+	addressSectionPrefix  = []byte("X") // addressSectionPrefix + section (uint64 big endian) -> addresses active in the section
 
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	ConfigPrefix   = []byte("ethereum-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	AddressIndexPrefix   = []byte("iA") // AddressIndexPrefix is the data table of the address activity indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -220,6 +223,21 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// addressActivityKey = addressActivityPrefix + address + num (uint64 big endian) + tx index (uint32 big endian)
+func addressActivityKey(address common.Address, number uint64, index uint32) []byte {
+	key := append(append(addressActivityPrefix, address.Bytes()...), make([]byte, 12)...)
+
+	binary.BigEndian.PutUint64(key[len(addressActivityPrefix)+common.AddressLength:], number)
+	binary.BigEndian.PutUint32(key[len(addressActivityPrefix)+common.AddressLength+8:], index)
+
+	return key
+}
+
+// addressSectionKey = addressSectionPrefix + section (uint64 big endian)
+func addressSectionKey(section uint64) []byte {
+	return append(addressSectionPrefix, encodeBlockNumber(section)...)
+}
+
 // preimageKey = preimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(preimagePrefix, hash.Bytes()...)
@@ -0,0 +1,179 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// DiffAccount is the state of an account before or after a state diff.
+type DiffAccount struct {
+	Balance  *hexutil.Big   `json:"balance"`
+	Nonce    hexutil.Uint64 `json:"nonce"`
+	CodeHash common.Hash    `json:"codeHash"`
+	Root     common.Hash    `json:"root"`
+}
+
+// StorageDiff is the change of a single storage slot.
+type StorageDiff struct {
+	Key    common.Hash `json:"key"`
+	Before common.Hash `json:"before"`
+	After  common.Hash `json:"after"`
+}
+
+// AccountDiff is the change of a single account. Before is nil if the account
+// was created, After is nil if the account was deleted. The storage of deleted
+// accounts is cleared as a whole, and is not listed slot by slot. The storage of
+// accounts deleted and created again is listed in full, including the slots
+// cleared by the deletion.
+type AccountDiff struct {
+	Address common.Address `json:"address"`
+	Before  *DiffAccount   `json:"before"`
+	After   *DiffAccount   `json:"after"`
+	Code    hexutil.Bytes  `json:"code,omitempty"` // Code of the account after the change, if it changed
+	Storage []StorageDiff  `json:"storage,omitempty"`
+}
+
+func newDiffAccount(data *Account) *DiffAccount {
+	return &DiffAccount{
+		Balance:  (*hexutil.Big)(data.Balance),
+		Nonce:    hexutil.Uint64(data.Nonce),
+		CodeHash: common.BytesToHash(data.CodeHash),
+		Root:     data.Root,
+	}
+}
+
+// StateDiff returns the changes made to the accounts and storage slots since the
+// state was opened, ordered by address and slot. It must be called after the
+// changes have been merged into the tries with IntermediateRoot, and before they
+// are committed.
+func (s *StateDB) StateDiff() ([]AccountDiff, error) {
+	original, err := s.db.OpenTrie(s.originalRoot)
+	if err != nil {
+		return nil, err
+	}
+	var diffs []AccountDiff
+	for addr := range s.stateObjectsDirty {
+		obj := s.stateObjects[addr]
+
+		var before *Account
+		enc, err := original.TryGet(addr.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		if len(enc) > 0 {
+			before = new(Account)
+			if err := rlp.DecodeBytes(enc, before); err != nil {
+				return nil, fmt.Errorf("invalid account %x: %v", addr, err)
+			}
+		}
+		diff := AccountDiff{Address: addr}
+		if before != nil {
+			diff.Before = newDiffAccount(before)
+		}
+		if !obj.deleted {
+			diff.After = newDiffAccount(&obj.data)
+			if diff.Before == nil || diff.Before.CodeHash != diff.After.CodeHash {
+				diff.Code = obj.Code(s.db)
+			}
+			if diff.Storage, err = s.storageDiff(obj, before); err != nil {
+				return nil, err
+			}
+		}
+		if before == nil && diff.After == nil {
+			continue // created and deleted again
+		}
+		if diff.Before != nil && diff.After != nil && len(diff.Storage) == 0 &&
+			diff.Before.Nonce == diff.After.Nonce && diff.Before.CodeHash == diff.After.CodeHash &&
+			diff.Before.Root == diff.After.Root && diff.Before.Balance.ToInt().Cmp(diff.After.Balance.ToInt()) == 0 {
+			continue // touched, but unchanged
+		}
+		diffs = append(diffs, diff)
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return bytes.Compare(diffs[i].Address[:], diffs[j].Address[:]) < 0
+	})
+	return diffs, nil
+}
+
+// storageDiff returns the changed storage slots of the object, compared to the
+// slots of the account before the changes.
+func (s *StateDB) storageDiff(obj *stateObject, before *Account) ([]StorageDiff, error) {
+	var original Trie
+	if before != nil && before.Root != emptyRoot {
+		tr, err := s.db.OpenStorageTrie(crypto.Keccak256Hash(obj.address[:]), before.Root)
+		if err != nil {
+			return nil, err
+		}
+		original = tr
+	}
+	var diffs []StorageDiff
+	for key, value := range obj.originStorage {
+		var prev common.Hash
+		if original != nil {
+			enc, err := original.TryGet(key.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			if len(enc) > 0 {
+				_, content, _, err := rlp.Split(enc)
+				if err != nil {
+					return nil, fmt.Errorf("invalid storage slot %x of %x: %v", key, obj.address, err)
+				}
+				prev.SetBytes(content)
+			}
+		}
+		if prev != value {
+			diffs = append(diffs, StorageDiff{Key: key, Before: prev, After: value})
+		}
+	}
+	// A recreated account starts out with empty storage, so all the slots of the
+	// previous account not set again are cleared
+	if obj.created && original != nil {
+		it := trie.NewIterator(original.NodeIterator(nil))
+		for it.Next() {
+			preimage := original.GetKey(it.Key)
+			if preimage == nil {
+				return nil, fmt.Errorf("missing preimage of storage slot %x of %x", it.Key, obj.address)
+			}
+			key := common.BytesToHash(preimage)
+			if _, ok := obj.originStorage[key]; ok {
+				continue
+			}
+			_, content, _, err := rlp.Split(it.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid storage slot %x of %x: %v", key, obj.address, err)
+			}
+			diffs = append(diffs, StorageDiff{Key: key, Before: common.BytesToHash(content)})
+		}
+		if it.Err != nil {
+			return nil, it.Err
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return bytes.Compare(diffs[i].Key[:], diffs[j].Key[:]) < 0
+	})
+	return diffs, nil
+}
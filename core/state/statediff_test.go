@@ -0,0 +1,123 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+// Tests that state diffs list exactly the changed accounts and storage slots.
+func TestStateDiff(t *testing.T) {
+	var (
+		db        = NewDatabase(rawdb.NewMemoryDatabase())
+		changed   = common.Address{1}
+		untouched = common.Address{2}
+		touched   = common.Address{3}
+		created   = common.Address{4}
+		deleted   = common.Address{5}
+		ephemeral = common.Address{6}
+		slot      = common.HexToHash("01")
+	)
+	state, _ := New(common.Hash{}, db, nil)
+	for _, addr := range []common.Address{changed, untouched, touched, deleted} {
+		state.SetBalance(addr, big.NewInt(1))
+		state.SetState(addr, slot, common.HexToHash("01"))
+	}
+	root, _ := state.Commit(false)
+
+	state, _ = New(root, db, nil)
+	state.SetNonce(changed, 1)
+	state.SetState(changed, slot, common.HexToHash("02"))
+	state.SetState(changed, common.HexToHash("02"), common.HexToHash("03"))
+	state.AddBalance(touched, new(big.Int))
+	state.SetCode(created, []byte{0x60})
+	state.Suicide(deleted)
+	state.SetBalance(ephemeral, big.NewInt(1))
+	state.Suicide(ephemeral)
+	state.IntermediateRoot(false)
+
+	diffs, err := state.StateDiff()
+	if err != nil {
+		t.Fatalf("failed to compute state diff: %v", err)
+	}
+	if len(diffs) != 3 {
+		t.Fatalf("diff count mismatch: have %d, want 3: %+v", len(diffs), diffs)
+	}
+	if diffs[0].Address != changed || diffs[1].Address != created || diffs[2].Address != deleted {
+		t.Fatalf("diff accounts mismatch: have %x, %x, %x", diffs[0].Address, diffs[1].Address, diffs[2].Address)
+	}
+	if diffs[0].Before.Nonce != 0 || diffs[0].After.Nonce != 1 {
+		t.Errorf("nonce diff mismatch: have %d -> %d, want 0 -> 1", diffs[0].Before.Nonce, diffs[0].After.Nonce)
+	}
+	want := []StorageDiff{
+		{Key: slot, Before: common.HexToHash("01"), After: common.HexToHash("02")},
+		{Key: common.HexToHash("02"), Before: common.Hash{}, After: common.HexToHash("03")},
+	}
+	if !reflect.DeepEqual(diffs[0].Storage, want) {
+		t.Errorf("storage diff mismatch: have %+v, want %+v", diffs[0].Storage, want)
+	}
+	if diffs[1].Before != nil || diffs[1].After == nil || !reflect.DeepEqual([]byte(diffs[1].Code), []byte{0x60}) {
+		t.Errorf("created account diff mismatch: %+v", diffs[1])
+	}
+	if diffs[2].Before == nil || diffs[2].After != nil {
+		t.Errorf("deleted account diff mismatch: %+v", diffs[2])
+	}
+}
+
+// Tests that the storage diff of an account deleted and created again within
+// the same state transition includes the slots cleared by the deletion.
+func TestStateDiffRecreated(t *testing.T) {
+	var (
+		db   = NewDatabase(rawdb.NewMemoryDatabase())
+		addr = common.Address{1}
+	)
+	state, _ := New(common.Hash{}, db, nil)
+	state.SetBalance(addr, big.NewInt(1))
+	state.SetState(addr, common.HexToHash("01"), common.HexToHash("01"))
+	state.SetState(addr, common.HexToHash("02"), common.HexToHash("02"))
+	root, _ := state.Commit(false)
+
+	state, _ = New(root, db, nil)
+	state.Suicide(addr)
+	state.Finalise(true)
+	state.CreateAccount(addr)
+	state.SetBalance(addr, big.NewInt(1))
+	state.SetState(addr, common.HexToHash("02"), common.HexToHash("05"))
+	state.SetState(addr, common.HexToHash("03"), common.HexToHash("06"))
+	state.IntermediateRoot(false)
+
+	diffs, err := state.StateDiff()
+	if err != nil {
+		t.Fatalf("failed to compute state diff: %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].Before == nil || diffs[0].After == nil {
+		t.Fatalf("diff mismatch: %+v", diffs)
+	}
+	want := []StorageDiff{
+		{Key: common.HexToHash("01"), Before: common.HexToHash("01"), After: common.Hash{}},
+		{Key: common.HexToHash("02"), Before: common.HexToHash("02"), After: common.HexToHash("05")},
+		{Key: common.HexToHash("03"), Before: common.Hash{}, After: common.HexToHash("06")},
+	}
+	if !reflect.DeepEqual(diffs[0].Storage, want) {
+		t.Errorf("storage diff mismatch: have %+v, want %+v", diffs[0].Storage, want)
+	}
+}
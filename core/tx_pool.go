@@ -18,6 +18,7 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"sort"
@@ -32,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/vars"
 )
 
 const (
@@ -247,6 +249,7 @@ type TxPool struct {
 
 	eip2f    bool
 	eip2028f bool
+	eip3860f bool
 }
 
 type txpoolResetRequest struct {
@@ -556,8 +559,12 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
 		return ErrInsufficientFunds
 	}
+	// Check whether the init code size has been exceeded.
+	if pool.eip3860f && tx.To() == nil && len(tx.Data()) > int(vars.MaxInitCodeSize) {
+		return fmt.Errorf("%w: code size %v limit %v", ErrMaxInitCodeSizeExceeded, len(tx.Data()), vars.MaxInitCodeSize)
+	}
 	// Ensure the transaction has more gas than the basic tx fee.
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.eip2f, pool.eip2028f)
+	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.eip2f, pool.eip2028f, pool.eip3860f)
 	if err != nil {
 		return err
 	}
@@ -1196,6 +1203,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// Update all fork indicator by next pending block number.
 	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
 	pool.eip2028f = pool.chainconfig.IsEnabled(pool.chainconfig.GetEIP2028Transition, next)
+	pool.eip3860f = pool.chainconfig.IsEnabled(pool.chainconfig.GetEIP3860Transition, next)
 }
 
 // promoteExecutables moves transactions that have become processable from the
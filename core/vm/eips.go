@@ -29,6 +29,11 @@ var activators = map[int]func(*JumpTable){
 	1884: enable1884,
 	1344: enable1344,
 	2315: enable2315,
+	3529: enable3529,
+	3541: enable3541,
+	3651: enable3651,
+	3855: enable3855,
+	3860: enable3860,
 }
 
 // EnableEIP enables the given EIP on the config.
@@ -138,3 +143,49 @@ func enable2315(jt *JumpTable) {
 		jumps:       true,
 	}
 }
+
+// enable3529 applies EIP-3529 (Reduction in refunds)
+// - Removes refunds for selfdestructs
+// The reduction of the SSTORE refunds is applied by the EIP-2200 SSTORE gas
+// function, and the reduction of the maximum refund quotient by the state
+// transition.
+func enable3529(jt *JumpTable) {
+	jt[SELFDESTRUCT].dynamicGas = gasSelfdestructEIP3529
+}
+
+// enable3541 applies EIP-3541 (Reject new contracts starting with the 0xEF byte)
+// The instruction set is unchanged, the rule is enforced when storing the code
+// of created contracts.
+func enable3541(jt *JumpTable) {}
+
+// enable3651 applies EIP-3651 (Warm COINBASE)
+// The instruction set is unchanged.
+func enable3651(jt *JumpTable) {}
+
+// enable3855 applies EIP-3855 (PUSH0 instruction)
+// - Adds an opcode that pushes the constant value 0 onto the stack
+func enable3855(jt *JumpTable) {
+	// New opcode
+	jt[PUSH0] = &operation{
+		execute:     opPush0,
+		constantGas: GasQuickStep,
+		minStack:    minStack(0, 1),
+		maxStack:    maxStack(0, 1),
+	}
+}
+
+// opPush0 implements the PUSH0 opcode
+func opPush0(pc *uint64, interpreter *EVMInterpreter, callContext *callCtx) ([]byte, error) {
+	callContext.stack.push(new(uint256.Int))
+	return nil, nil
+}
+
+// enable3860 applies EIP-3860 (Limit and meter initcode)
+// - Charges CREATE and CREATE2 for the words of their init code
+// - Limits the size of the init code to MaxInitCodeSize
+func enable3860(jt *JumpTable) {
+	jt[CREATE].dynamicGas = gasCreateEIP3860
+	if jt[CREATE2] != nil {
+		jt[CREATE2].dynamicGas = gasCreate2EIP3860
+	}
+}
@@ -175,6 +175,20 @@ func (evm *EVM) Interpreter() Interpreter {
 	return evm.interpreter
 }
 
+// IsEIPEnabled tells if the given EIP applies to the current block, either because
+// its transition has been reached or because it was enabled with the ExtraEips option.
+func (evm *EVM) IsEIPEnabled(eip int, fn func() *uint64) bool {
+	if evm.chainConfig.IsEnabled(fn, evm.BlockNumber) {
+		return true
+	}
+	for _, extra := range evm.vmConfig.ExtraEips {
+		if extra == eip {
+			return true
+		}
+	}
+	return false
+}
+
 // Call executes the contract associated with the addr with the given input as
 // parameters. It also handles any necessary value transfer required and takes
 // the necessary steps to create accounts and reverses the state in case of an
@@ -435,6 +449,11 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 
 	// check whether the max code size has been exceeded
 	maxCodeSizeExceeded := evm.ChainConfig().IsEnabled(evm.chainConfig.GetEIP170Transition, evm.BlockNumber) && uint64(len(ret)) > vars.MaxCodeSize
+	// Reject code starting with 0xEF if EIP-3541 is enabled.
+	if err == nil && len(ret) >= 1 && ret[0] == 0xEF && evm.IsEIPEnabled(3541, evm.chainConfig.GetEIP3541Transition) {
+		err = ErrInvalidCode
+	}
+
 	// if the contract creation ran successfully and no errors were returned
 	// calculate the gas required to store the code. If the code could not
 	// be stored due to not enough gas set an error and let it be handled
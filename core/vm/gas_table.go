@@ -167,6 +167,24 @@ func gasSStore(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySi
 	return vars.NetSstoreDirtyGas, nil
 }
 
+var (
+	gasSStoreEIP2200Refunds = makeGasSStoreEIP2200Func(vars.SstoreClearsScheduleRefundEIP2200)
+	gasSStoreEIP3529Refunds = makeGasSStoreEIP2200Func(vars.SstoreClearsScheduleRefundEIP3529)
+)
+
+// gasSStoreEIP2200 implements the EIP-2200 SSTORE gas metering, with the refund
+// for clearing a slot reduced once EIP-3529 is enabled. EIP-3529 is only defined
+// on top of EIP-2200, the other SSTORE gas functions are not affected by it.
+func gasSStoreEIP2200(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	if evm.IsEIPEnabled(3529, evm.chainConfig.GetEIP3529Transition) {
+		return gasSStoreEIP3529Refunds(evm, contract, stack, mem, memorySize)
+	}
+	return gasSStoreEIP2200Refunds(evm, contract, stack, mem, memorySize)
+}
+
+// makeGasSStoreEIP2200Func creates an EIP-2200 SSTORE gas function, refunding
+// clearingRefund as the SSTORE_CLEARS_SCHEDULE:
+//
 // 0. If *gasleft* is less than or equal to 2300, fail the current call.
 // 1. If current value equals new value (this is a no-op), SLOAD_GAS is deducted.
 // 2. If current value does not equal new value:
@@ -180,46 +198,48 @@ func gasSStore(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySi
 //     2.2.2. If original value equals new value (this storage slot is reset):
 //       2.2.2.1. If original value is 0, add SSTORE_SET_GAS - SLOAD_GAS to refund counter.
 //       2.2.2.2. Otherwise, add SSTORE_RESET_GAS - SLOAD_GAS gas to refund counter.
-func gasSStoreEIP2200(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
-	// If we fail the minimum gas availability invariant, fail (0)
-	if contract.Gas <= vars.SstoreSentryGasEIP2200 {
-		return 0, errors.New("not enough gas for reentrancy sentry")
-	}
-	// Gas sentry honoured, do the actual gas calculation based on the stored value
-	var (
-		y, x    = stack.Back(1), stack.Back(0)
-		current = evm.StateDB.GetState(contract.Address(), common.Hash(x.Bytes32()))
-	)
-	value := common.Hash(y.Bytes32())
-
-	if current == value { // noop (1)
-		return vars.SloadGasEIP2200, nil
-	}
-	original := evm.StateDB.GetCommittedState(contract.Address(), common.Hash(x.Bytes32()))
-	if original == current {
-		if original == (common.Hash{}) { // create slot (2.1.1)
-			return vars.SstoreSetGasEIP2200, nil
+func makeGasSStoreEIP2200Func(clearingRefund uint64) gasFunc {
+	return func(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		// If we fail the minimum gas availability invariant, fail (0)
+		if contract.Gas <= vars.SstoreSentryGasEIP2200 {
+			return 0, errors.New("not enough gas for reentrancy sentry")
 		}
-		if value == (common.Hash{}) { // delete slot (2.1.2b)
-			evm.StateDB.AddRefund(vars.SstoreClearsScheduleRefundEIP2200)
+		// Gas sentry honoured, do the actual gas calculation based on the stored value
+		var (
+			y, x    = stack.Back(1), stack.Back(0)
+			current = evm.StateDB.GetState(contract.Address(), common.Hash(x.Bytes32()))
+		)
+		value := common.Hash(y.Bytes32())
+
+		if current == value { // noop (1)
+			return vars.SloadGasEIP2200, nil
 		}
-		return vars.SstoreResetGasEIP2200, nil // write existing slot (2.1.2)
-	}
-	if original != (common.Hash{}) {
-		if current == (common.Hash{}) { // recreate slot (2.2.1.1)
-			evm.StateDB.SubRefund(vars.SstoreClearsScheduleRefundEIP2200)
-		} else if value == (common.Hash{}) { // delete slot (2.2.1.2)
-			evm.StateDB.AddRefund(vars.SstoreClearsScheduleRefundEIP2200)
+		original := evm.StateDB.GetCommittedState(contract.Address(), common.Hash(x.Bytes32()))
+		if original == current {
+			if original == (common.Hash{}) { // create slot (2.1.1)
+				return vars.SstoreSetGasEIP2200, nil
+			}
+			if value == (common.Hash{}) { // delete slot (2.1.2b)
+				evm.StateDB.AddRefund(clearingRefund)
+			}
+			return vars.SstoreResetGasEIP2200, nil // write existing slot (2.1.2)
 		}
-	}
-	if original == value {
-		if original == (common.Hash{}) { // reset to original inexistent slot (2.2.2.1)
-			evm.StateDB.AddRefund(vars.SstoreSetGasEIP2200 - vars.SloadGasEIP2200)
-		} else { // reset to original existing slot (2.2.2.2)
-			evm.StateDB.AddRefund(vars.SstoreResetGasEIP2200 - vars.SloadGasEIP2200)
+		if original != (common.Hash{}) {
+			if current == (common.Hash{}) { // recreate slot (2.2.1.1)
+				evm.StateDB.SubRefund(clearingRefund)
+			} else if value == (common.Hash{}) { // delete slot (2.2.1.2)
+				evm.StateDB.AddRefund(clearingRefund)
+			}
+		}
+		if original == value {
+			if original == (common.Hash{}) { // reset to original inexistent slot (2.2.2.1)
+				evm.StateDB.AddRefund(vars.SstoreSetGasEIP2200 - vars.SloadGasEIP2200)
+			} else { // reset to original existing slot (2.2.2.2)
+				evm.StateDB.AddRefund(vars.SstoreResetGasEIP2200 - vars.SloadGasEIP2200)
+			}
 		}
+		return vars.SloadGasEIP2200, nil // dirty update (2.2)
 	}
-	return vars.SloadGasEIP2200, nil // dirty update (2.2)
 }
 
 func makeGasLog(n uint64) gasFunc {
@@ -286,40 +306,53 @@ var (
 )
 
 func gasCreate(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
-	var overflow bool
-	gas, err := memoryGasCost(mem, memorySize)
-	if err != nil {
-		return 0, err
-	}
-	remainingGasTemp, overflow := math.SafeSub(contract.Gas, gas)
+	return createGas(evm, contract, mem, memorySize, 0)
+}
+
+func gasCreate2(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	wordGas, overflow := stack.Back(2).Uint64WithOverflow()
 	if overflow {
 		return 0, ErrGasUintOverflow
 	}
-	evm.CallGasTemp, err = createGasEip150(evm.ChainConfig().IsEnabled(evm.chainConfig.GetEIP150Transition, evm.BlockNumber), remainingGasTemp)
-	if err != nil {
-		return 0, err
+	if wordGas, overflow = math.SafeMul(toWordSize(wordGas), vars.Sha3WordGas); overflow {
+		return 0, ErrGasUintOverflow
 	}
-	if gas, overflow = math.SafeAdd(gas, evm.CallGasTemp); overflow {
+	return createGas(evm, contract, mem, memorySize, wordGas)
+}
+
+// gasCreateEIP3860 charges CREATE for the words of its init code, which may not
+// exceed MaxInitCodeSize.
+func gasCreateEIP3860(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	size, overflow := stack.Back(2).Uint64WithOverflow()
+	if overflow || size > vars.MaxInitCodeSize {
 		return 0, ErrGasUintOverflow
 	}
-	return gas, nil
+	// Since size <= MaxInitCodeSize, this multiplication cannot overflow
+	return createGas(evm, contract, mem, memorySize, toWordSize(size)*vars.InitCodeWordGas)
 }
 
-func gasCreate2(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+// gasCreate2EIP3860 charges CREATE2 for hashing and metering the words of its
+// init code, which may not exceed MaxInitCodeSize.
+func gasCreate2EIP3860(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	size, overflow := stack.Back(2).Uint64WithOverflow()
+	if overflow || size > vars.MaxInitCodeSize {
+		return 0, ErrGasUintOverflow
+	}
+	// Since size <= MaxInitCodeSize, this multiplication cannot overflow
+	return createGas(evm, contract, mem, memorySize, toWordSize(size)*(vars.InitCodeWordGas+vars.Sha3WordGas))
+}
+
+// createGas adds the memory expansion and word costs of a create operation, and
+// reserves the gas passed on to the created contract.
+func createGas(evm *EVM, contract *Contract, mem *Memory, memorySize uint64, wordGas uint64) (uint64, error) {
 	gas, err := memoryGasCost(mem, memorySize)
 	if err != nil {
 		return 0, err
 	}
-	wordGas, overflow := stack.Back(2).Uint64WithOverflow()
+	gas, overflow := math.SafeAdd(gas, wordGas)
 	if overflow {
 		return 0, ErrGasUintOverflow
 	}
-	if wordGas, overflow = math.SafeMul(toWordSize(wordGas), vars.Sha3WordGas); overflow {
-		return 0, ErrGasUintOverflow
-	}
-	if gas, overflow = math.SafeAdd(gas, wordGas); overflow {
-		return 0, ErrGasUintOverflow
-	}
 	remainingGasTemp, overflow := math.SafeSub(contract.Gas, gas)
 	if overflow {
 		return 0, ErrGasUintOverflow
@@ -453,25 +486,34 @@ func gasStaticCall(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memo
 	return gas, nil
 }
 
-func gasSelfdestruct(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
-	var gas uint64
-	// EIP150 homestead gas reprice fork:
-	if evm.ChainConfig().IsEnabled(evm.chainConfig.GetEIP150Transition, evm.BlockNumber) {
-		gas = vars.SelfdestructGasEIP150
-		var address = common.Address(stack.Back(0).Bytes20())
+var (
+	gasSelfdestruct        = makeSelfdestructGasFn(true)
+	gasSelfdestructEIP3529 = makeSelfdestructGasFn(false)
+)
 
-		if evm.ChainConfig().IsEnabled(evm.chainConfig.GetEIP161abcTransition, evm.BlockNumber) {
-			// if empty and transfers value
-			if evm.StateDB.Empty(address) && evm.StateDB.GetBalance(contract.Address()).Sign() != 0 {
+// makeSelfdestructGasFn creates a SELFDESTRUCT gas function, which credits the
+// selfdestruct refund if refundsEnabled is set.
+func makeSelfdestructGasFn(refundsEnabled bool) gasFunc {
+	return func(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		var gas uint64
+		// EIP150 homestead gas reprice fork:
+		if evm.ChainConfig().IsEnabled(evm.chainConfig.GetEIP150Transition, evm.BlockNumber) {
+			gas = vars.SelfdestructGasEIP150
+			var address = common.Address(stack.Back(0).Bytes20())
+
+			if evm.ChainConfig().IsEnabled(evm.chainConfig.GetEIP161abcTransition, evm.BlockNumber) {
+				// if empty and transfers value
+				if evm.StateDB.Empty(address) && evm.StateDB.GetBalance(contract.Address()).Sign() != 0 {
+					gas += vars.CreateBySelfdestructGas
+				}
+			} else if !evm.StateDB.Exist(address) {
 				gas += vars.CreateBySelfdestructGas
 			}
-		} else if !evm.StateDB.Exist(address) {
-			gas += vars.CreateBySelfdestructGas
 		}
-	}
 
-	if !evm.StateDB.HasSuicided(contract.Address()) {
-		evm.StateDB.AddRefund(vars.SelfdestructRefundGas)
+		if refundsEnabled && !evm.StateDB.HasSuicided(contract.Address()) {
+			evm.StateDB.AddRefund(vars.SelfdestructRefundGas)
+		}
+		return gas, nil
 	}
-	return gas, nil
 }
@@ -106,3 +106,144 @@ func TestEIP2200(t *testing.T) {
 		}
 	}
 }
+
+func TestEIP3529(t *testing.T) {
+	for i, tt := range []struct {
+		eips         []int
+		used, refund uint64
+	}{
+		// EIP-2200 metering with the reduced SSTORE_CLEARS_SCHEDULE
+		{[]int{2200, 3529}, 5812, 4800},
+		// Without EIP-2200, the SSTORE metering is left untouched
+		{[]int{3529}, 10012, 15000},
+	} {
+		address := common.BytesToAddress([]byte("contract"))
+
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, hexutil.MustDecode("0x60006000556000600055")) // 1 -> 0 -> 0
+		statedb.SetState(address, common.Hash{}, common.BytesToHash([]byte{1}))
+		statedb.Finalise(true)
+
+		vmctx := Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		}
+		vmenv := NewEVM(vmctx, statedb, params.AllEthashProtocolChanges, Config{ExtraEips: tt.eips})
+
+		_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, math.MaxUint64, new(big.Int))
+		if err != nil {
+			t.Fatalf("test %d: call failed: %v", i, err)
+		}
+		if used := math.MaxUint64 - gas; used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %v, want %v", i, used, tt.used)
+		}
+		if refund := vmenv.StateDB.GetRefund(); refund != tt.refund {
+			t.Errorf("test %d: gas refund mismatch: have %v, want %v", i, refund, tt.refund)
+		}
+	}
+}
+
+func TestEIP3860(t *testing.T) {
+	for i, tt := range []struct {
+		code    string
+		eips    []int
+		used    uint64
+		failure error
+	}{
+		// CREATE of 64 bytes of zero init code
+		{"0x604060006000f000", nil, 32015, nil},
+		{"0x604060006000f000", []int{3860}, 32019, nil},
+		// CREATE of init code one byte larger than the limit
+		{"0x61c00160006000f000", nil, 0, nil},
+		{"0x61c00160006000f000", []int{3860}, 0, ErrOutOfGas},
+	} {
+		address := common.BytesToAddress([]byte("contract"))
+
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, hexutil.MustDecode(tt.code))
+
+		vmctx := Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		}
+		vmenv := NewEVM(vmctx, statedb, params.AllEthashProtocolChanges, Config{ExtraEips: tt.eips})
+
+		_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, 10000000, new(big.Int))
+		if err != tt.failure {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, err, tt.failure)
+		}
+		if used := 10000000 - gas; tt.used != 0 && used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %v, want %v", i, used, tt.used)
+		}
+	}
+}
+
+func TestEIP3541(t *testing.T) {
+	// Init code returning a single 0xEF byte as the contract code
+	code := hexutil.MustDecode("0x60ef60005360016000f3")
+
+	for i, tt := range []struct {
+		eips    []int
+		failure error
+	}{
+		{nil, nil},
+		{[]int{3541}, ErrInvalidCode},
+	} {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		vmctx := Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		}
+		vmenv := NewEVM(vmctx, statedb, params.AllEthashProtocolChanges, Config{ExtraEips: tt.eips})
+
+		_, addr, _, err := vmenv.Create(AccountRef(common.Address{}), code, 100000, new(big.Int))
+		if err != tt.failure {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, err, tt.failure)
+		}
+		if stored := statedb.GetCode(addr); (err == nil) != (len(stored) == 1) {
+			t.Errorf("test %d: unexpected code stored: %x", i, stored)
+		}
+	}
+}
+
+func TestEIP3855(t *testing.T) {
+	// PUSH0 PUSH0 SSTORE PUSH1 1 PUSH0 SSTORE: stores 0 and then 1 at slot 0
+	code := hexutil.MustDecode("0x5f5f5560015f55")
+
+	for i, tt := range []struct {
+		eips    []int
+		used    uint64
+		invalid bool
+	}{
+		{nil, 0, true},
+		{[]int{3855}, 2 + 2 + 5000 + 3 + 2 + 20000, false},
+	} {
+		address := common.BytesToAddress([]byte("contract"))
+
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, code)
+
+		vmctx := Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		}
+		vmenv := NewEVM(vmctx, statedb, params.AllEthashProtocolChanges, Config{ExtraEips: tt.eips})
+
+		_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, 100000, new(big.Int))
+		if _, ok := err.(*ErrInvalidOpCode); ok != tt.invalid {
+			t.Errorf("test %d: invalid opcode mismatch: have %v, want %v", i, err, tt.invalid)
+		}
+		if tt.invalid {
+			continue
+		}
+		if used := 100000 - gas; used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %v, want %v", i, used, tt.used)
+		}
+		if have := statedb.GetState(address, common.Hash{}); have != common.BytesToHash([]byte{1}) {
+			t.Errorf("test %d: storage mismatch: have %x", i, have)
+		}
+	}
+}
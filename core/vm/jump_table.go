@@ -178,6 +178,15 @@ func instructionSetForConfig(config ctypes.ChainConfigurator, bn *big.Int) JumpT
 	if config.IsEnabled(config.GetEIP2200Transition, bn) && !config.IsEnabled(config.GetEIP2200DisableTransition, bn) {
 		enable2200(&instructionSet) // Net metered SSTORE - https://eips.ethereum.org/EIPS/eip-2200
 	}
+	if config.IsEnabled(config.GetEIP3529Transition, bn) {
+		enable3529(&instructionSet) // Reduction in refunds - https://eips.ethereum.org/EIPS/eip-3529
+	}
+	if config.IsEnabled(config.GetEIP3855Transition, bn) {
+		enable3855(&instructionSet) // PUSH0 instruction - https://eips.ethereum.org/EIPS/eip-3855
+	}
+	if config.IsEnabled(config.GetEIP3860Transition, bn) {
+		enable3860(&instructionSet) // Limit and meter initcode - https://eips.ethereum.org/EIPS/eip-3860
+	}
 
 	return instructionSet
 }
@@ -122,6 +122,7 @@ const (
 	BEGINSUB  OpCode = 0x5c
 	RETURNSUB OpCode = 0x5d
 	JUMPSUB   OpCode = 0x5e
+	PUSH0     OpCode = 0x5f
 )
 
 // 0x60 range.
@@ -303,6 +304,7 @@ var opCodeToString = map[OpCode]string{
 	BEGINSUB:  "BEGINSUB",
 	JUMPSUB:   "JUMPSUB",
 	RETURNSUB: "RETURNSUB",
+	PUSH0:     "PUSH0",
 
 	// 0x60 range - push.
 	PUSH1:  "PUSH1",
@@ -471,6 +473,7 @@ var stringToOp = map[string]OpCode{
 	"BEGINSUB":       BEGINSUB,
 	"RETURNSUB":      RETURNSUB,
 	"JUMPSUB":        JUMPSUB,
+	"PUSH0":          PUSH0,
 	"PUSH1":          PUSH1,
 	"PUSH2":          PUSH2,
 	"PUSH3":          PUSH3,
@@ -0,0 +1,333 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// AddressIndexSectionSize is the number of blocks in an address index section.
+	// It is kept small, so that the state needed to trace the internal value
+	// transfers of a section is usually still available when it is processed.
+	AddressIndexSectionSize = 64
+
+	// AddressIndexConfirms is the number of confirmations before a section is
+	// considered final and indexed.
+	AddressIndexConfirms = 16
+
+	// addressIndexThrottling is the time to wait between processing two
+	// consecutive address index sections.
+	addressIndexThrottling = 10 * time.Millisecond
+)
+
+// AddressIndexer implements a core.ChainIndexer, indexing the transactions each
+// address took part in: as sender, as recipient, or as sender or recipient of an
+// internal value transfer. Internal transfers are found by re-executing blocks,
+// so they are only indexed for blocks whose parent state is available.
+type AddressIndexer struct {
+	db      ethdb.Database              // database instance to write index data into
+	chain   *core.BlockChain            // chain to re-execute blocks on for internal transfers
+	size    uint64                      // section size to index addresses for
+	section uint64                      // Section is the section number being processed currently
+	batch   ethdb.Batch                 // Batch collecting the index entries of the section
+	active  map[common.Address]struct{} // Addresses with entries in the section
+}
+
+// NewAddressIndexer returns a chain indexer that indexes the transactions of the
+// canonical chain by the addresses taking part in them.
+func NewAddressIndexer(db ethdb.Database, chain *core.BlockChain, size, confirms uint64, throttling time.Duration) *core.ChainIndexer {
+	backend := &AddressIndexer{
+		db:    db,
+		chain: chain,
+		size:  size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.AddressIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, size, confirms, throttling, "addressindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new address index
+// section and rolling back any entries of a reorged previous run over it.
+func (b *AddressIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	for _, address := range rawdb.ReadAddressSection(b.db, section) {
+		rawdb.DeleteAddressActivity(b.db, address, section*b.size, (section+1)*b.size)
+	}
+	rawdb.DeleteAddressSection(b.db, section)
+
+	b.section, b.batch, b.active = section, b.db.NewBatch(), make(map[common.Address]struct{})
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the transactions of a new
+// header's block into the index.
+func (b *AddressIndexer) Process(ctx context.Context, header *types.Header) error {
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+		config = b.chain.Config()
+	)
+	block := rawdb.ReadBlock(b.db, hash, number)
+	if block == nil {
+		return fmt.Errorf("block #%d [%x…] not found", number, hash[:4])
+	}
+	txs := block.Transactions()
+	if len(txs) == 0 {
+		return nil
+	}
+	receipts := rawdb.ReadReceipts(b.db, hash, number, config)
+	if len(receipts) != len(txs) {
+		return fmt.Errorf("receipts of block #%d [%x…] not found", number, hash[:4])
+	}
+	var (
+		signer   = types.MakeSigner(config, header.Number)
+		roles    = make([]map[common.Address]uint8, len(txs))
+		internal = b.internalTransfers(block)
+	)
+	for i, tx := range txs {
+		roles[i] = make(map[common.Address]uint8)
+
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		roles[i][from] |= rawdb.AddressActivitySender
+		if to := tx.To(); to != nil {
+			roles[i][*to] |= rawdb.AddressActivityRecipient
+		} else {
+			roles[i][receipts[i].ContractAddress] |= rawdb.AddressActivityRecipient
+		}
+		if internal != nil {
+			for _, transfer := range internal[i] {
+				roles[i][transfer.from] |= rawdb.AddressActivityInternal
+				roles[i][transfer.to] |= rawdb.AddressActivityInternal
+			}
+		}
+	}
+	for i, tx := range txs {
+		for address, role := range roles[i] {
+			rawdb.WriteAddressActivity(b.batch, address, rawdb.AddressActivity{
+				BlockNumber: number,
+				TxIndex:     uint32(i),
+				BlockHash:   hash,
+				TxHash:      tx.Hash(),
+				Roles:       role,
+			})
+			b.active[address] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the address index
+// section and writing it out into the database.
+func (b *AddressIndexer) Commit() error {
+	addresses := make([]common.Address, 0, len(b.active))
+	for address := range b.active {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i][:], addresses[j][:]) < 0
+	})
+	rawdb.WriteAddressSection(b.batch, b.section, addresses)
+	return b.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (b *AddressIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// internalTransfers re-executes the block on top of the state of its parent,
+// returning the internal value transfers made by each transaction. It returns
+// nil if the parent state is not available.
+func (b *AddressIndexer) internalTransfers(block *types.Block) [][]valueTransfer {
+	parent := b.chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil
+	}
+	statedb, err := b.chain.StateAt(parent.Root())
+	if err != nil {
+		log.Trace("Skipping internal transfers of unavailable state", "number", block.Number(), "hash", block.Hash())
+		return nil
+	}
+	var (
+		config    = b.chain.Config()
+		header    = block.Header()
+		gp        = new(core.GasPool).AddGas(block.GasLimit())
+		usedGas   uint64
+		tracer    = new(transferTracer)
+		transfers = make([][]valueTransfer, len(block.Transactions()))
+	)
+	if config.IsEnabled(config.GetEthashEIP779Transition, block.Number()) {
+		if daoNumber := config.GetEthashEIP779Transition(); daoNumber != nil && *daoNumber == block.NumberU64() {
+			misc.ApplyDAOHardFork(statedb)
+		}
+	}
+	for i, tx := range block.Transactions() {
+		tracer.frames, tracer.transfers = nil, nil
+
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		receipt, err := core.ApplyTransaction(config, b.chain, nil, gp, statedb, header, tx, &usedGas, vm.Config{Debug: true, Tracer: tracer})
+		if err != nil {
+			log.Warn("Failed to trace internal transfers", "number", block.Number(), "hash", block.Hash(), "tx", tx.Hash(), "err", err)
+			return nil
+		}
+		if receipt.Status == types.ReceiptStatusSuccessful {
+			transfers[i] = tracer.transfers
+		}
+	}
+	return transfers
+}
+
+// valueTransfer is an internal transfer of value between two accounts.
+type valueTransfer struct {
+	from, to common.Address
+	value    *big.Int
+}
+
+// transferFrame is the state of a call frame traced by a transferTracer.
+type transferFrame struct {
+	transfers []valueTransfer // Transfers made by the frame and its successful subcalls
+	calling   bool            // Whether the frame made a call which did not return yet
+	call      *valueTransfer  // Value transferred by the pending call, if any
+	returned  []valueTransfer // Transfers made by the pending call's frame
+}
+
+// settle resolves the pending call of the frame, keeping its transfers only if
+// the call succeeded.
+func (f *transferFrame) settle(success bool) {
+	if f.calling && success {
+		if f.call != nil {
+			f.transfers = append(f.transfers, *f.call)
+		}
+		f.transfers = append(f.transfers, f.returned...)
+	}
+	f.calling, f.call, f.returned = false, nil, nil
+}
+
+// transferTracer is a vm.Tracer collecting the internal value transfers of a
+// transaction, dropping those made by call frames which were reverted.
+type transferTracer struct {
+	frames    []*transferFrame
+	transfers []valueTransfer
+}
+
+// CaptureStart implements vm.Tracer, starting the tracing of a transaction.
+func (t *transferTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.frames, t.transfers = nil, nil
+	return nil
+}
+
+// CaptureState implements vm.Tracer, tracking the call frames and the value
+// transfers made by calls, creations and self-destructs.
+func (t *transferTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	if err != nil {
+		return nil
+	}
+	for len(t.frames) < depth {
+		t.frames = append(t.frames, new(transferFrame))
+	}
+	for len(t.frames) > depth {
+		t.pop()
+	}
+	frame := t.frames[depth-1]
+
+	// The first operation after a call returned has the call's outcome on the stack
+	if frame.calling {
+		frame.settle(stack.Back(0).Sign() != 0)
+	}
+	switch op {
+	case vm.CALL:
+		frame.calling = true
+		if value := stack.Back(2); value.Sign() > 0 {
+			frame.call = &valueTransfer{
+				from:  contract.Address(),
+				to:    common.Address(stack.Back(1).Bytes20()),
+				value: value.ToBig(),
+			}
+		}
+	case vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		frame.calling = true
+	case vm.CREATE, vm.CREATE2:
+		frame.calling = true
+		if value := stack.Back(0); value.Sign() > 0 {
+			caller := contract.Address()
+			var created common.Address
+			if op == vm.CREATE {
+				created = crypto.CreateAddress(caller, env.StateDB.GetNonce(caller))
+			} else {
+				code := memory.GetCopy(int64(stack.Back(1).Uint64()), int64(stack.Back(2).Uint64()))
+				created = crypto.CreateAddress2(caller, stack.Back(3).Bytes32(), crypto.Keccak256(code))
+			}
+			frame.call = &valueTransfer{from: caller, to: created, value: value.ToBig()}
+		}
+	case vm.SELFDESTRUCT:
+		if balance := env.StateDB.GetBalance(contract.Address()); balance.Sign() > 0 {
+			frame.transfers = append(frame.transfers, valueTransfer{
+				from:  contract.Address(),
+				to:    common.Address(stack.Back(0).Bytes20()),
+				value: balance,
+			})
+		}
+	}
+	return nil
+}
+
+// pop removes the innermost call frame, which has returned, handing its transfers
+// to the pending call of its parent. A call still pending in the returned frame
+// was the frame's last operation, and is assumed to have succeeded.
+func (t *transferTracer) pop() {
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+
+	frame.settle(true)
+	if len(t.frames) > 0 {
+		parent := t.frames[len(t.frames)-1]
+		parent.returned = append(parent.returned, frame.transfers...)
+	} else {
+		t.transfers = frame.transfers
+	}
+}
+
+// CaptureFault implements vm.Tracer.
+func (t *transferTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer, finishing the tracing of a transaction.
+func (t *transferTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	for len(t.frames) > 0 {
+		t.pop()
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/vars"
+)
+
+// forwardCode returns contract code forwarding the call value to the given
+// address, reverting afterwards if requested.
+func forwardCode(to common.Address, revert bool) []byte {
+	code := []byte{0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, byte(vm.CALLVALUE), byte(vm.PUSH20)}
+	code = append(code, to.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL))
+	if revert {
+		return append(code, 0x60, 0x00, 0x60, 0x00, byte(vm.REVERT))
+	}
+	return append(code, byte(vm.STOP))
+}
+
+// Tests that the address indexer records senders, recipients and internal value
+// transfers, ignoring those of reverted calls, and rolls back reset sections.
+func TestAddressIndexer(t *testing.T) {
+	var (
+		key, _    = crypto.GenerateKey()
+		sender    = crypto.PubkeyToAddress(key.PublicKey)
+		recipient = common.Address{0x01}
+		forwarder = common.Address{0x02} // Forwards value to beneficiary
+		wrapper   = common.Address{0x03} // Forwards value to reverter
+		reverter  = common.Address{0x04} // Forwards value to lost, then reverts
+		lost      = common.Address{0x05}
+		benef     = common.Address{0x06}
+		config    = params.TestChainConfig
+		gspec     = &genesisT.Genesis{
+			Config: config,
+			Alloc: genesisT.GenesisAlloc{
+				sender:    {Balance: big.NewInt(vars.Ether)},
+				forwarder: {Balance: new(big.Int), Code: forwardCode(benef, false)},
+				wrapper:   {Balance: new(big.Int), Code: forwardCode(reverter, false)},
+				reverter:  {Balance: new(big.Int), Code: forwardCode(lost, true)},
+			},
+		}
+		db      = rawdb.NewMemoryDatabase()
+		genesis = core.MustCommitGenesis(db, gspec)
+		signer  = types.HomesteadSigner{}
+	)
+	send := func(gen *core.BlockGen, to *common.Address) *types.Transaction {
+		var tx *types.Transaction
+		if to == nil {
+			tx = types.NewContractCreation(gen.TxNonce(sender), big.NewInt(1), 100000, big.NewInt(1), nil)
+		} else {
+			tx = types.NewTransaction(gen.TxNonce(sender), *to, big.NewInt(1000), 100000, big.NewInt(1), nil)
+		}
+		tx, _ = types.SignTx(tx, signer, key)
+		gen.AddTx(tx)
+		return tx
+	}
+	var txs []*types.Transaction
+	blocks, _ := core.GenerateChain(config, genesis, ethash.NewFaker(), db, 3, func(i int, gen *core.BlockGen) {
+		switch i {
+		case 0:
+			txs = append(txs, send(gen, &recipient))
+		case 1:
+			txs = append(txs, send(gen, &forwarder))
+		case 2:
+			txs = append(txs, send(gen, &wrapper), send(gen, nil))
+		}
+	})
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Index the first section by hand, to not depend on the indexer's timing
+	indexer := &AddressIndexer{db: db, chain: chain, size: 4}
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset section: %v", err)
+	}
+	if err := indexer.Process(context.Background(), genesis.Header()); err != nil {
+		t.Fatalf("failed to process genesis: %v", err)
+	}
+	for _, block := range blocks {
+		if err := indexer.Process(context.Background(), block.Header()); err != nil {
+			t.Fatalf("failed to process block %d: %v", block.NumberU64(), err)
+		}
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit section: %v", err)
+	}
+	created := crypto.CreateAddress(sender, txs[3].Nonce())
+
+	type activity struct {
+		tx    common.Hash
+		roles uint8
+	}
+	collect := func(address common.Address) []activity {
+		var result []activity
+		rawdb.IterateAddressActivity(db, address, 0, 3, func(entry rawdb.AddressActivity) bool {
+			result = append(result, activity{entry.TxHash, entry.Roles})
+			return true
+		})
+		return result
+	}
+	tests := []struct {
+		address common.Address
+		want    []activity
+	}{
+		{sender, []activity{
+			{txs[0].Hash(), rawdb.AddressActivitySender},
+			{txs[1].Hash(), rawdb.AddressActivitySender},
+			{txs[2].Hash(), rawdb.AddressActivitySender},
+			{txs[3].Hash(), rawdb.AddressActivitySender},
+		}},
+		{recipient, []activity{{txs[0].Hash(), rawdb.AddressActivityRecipient}}},
+		{forwarder, []activity{{txs[1].Hash(), rawdb.AddressActivityRecipient | rawdb.AddressActivityInternal}}},
+		{benef, []activity{{txs[1].Hash(), rawdb.AddressActivityInternal}}},
+		{wrapper, []activity{{txs[2].Hash(), rawdb.AddressActivityRecipient}}},
+		{reverter, nil},
+		{lost, nil},
+		{created, []activity{{txs[3].Hash(), rawdb.AddressActivityRecipient}}},
+	}
+	for _, tt := range tests {
+		have := collect(tt.address)
+		if len(have) != len(tt.want) {
+			t.Errorf("%x: activity count mismatch: have %d, want %d", tt.address, len(have), len(tt.want))
+			continue
+		}
+		for i := range have {
+			if have[i] != tt.want[i] {
+				t.Errorf("%x: activity %d mismatch: have %x/%d, want %x/%d", tt.address, i, have[i].tx, have[i].roles, tt.want[i].tx, tt.want[i].roles)
+			}
+		}
+	}
+	// Resetting the section should roll back all its entries
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset section: %v", err)
+	}
+	for _, tt := range tests {
+		if have := collect(tt.address); len(have) != 0 {
+			t.Errorf("%x: activity left after reset: %v", tt.address, have)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// addressIndexPageSize is the number of transactions returned per page by
+// eth_getTransactionsByAddress.
+const addressIndexPageSize = 100
+
+// AddressTransaction references a transaction an address took part in.
+type AddressTransaction struct {
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	BlockHash        common.Hash    `json:"blockHash"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	TransactionHash  common.Hash    `json:"transactionHash"`
+	Sent             bool           `json:"sent"`     // The address sent the transaction
+	Received         bool           `json:"received"` // The address received the transaction, or was created by it
+	Internal         bool           `json:"internal"` // The address sent or received an internal value transfer
+}
+
+// PublicAddressIndexAPI provides access to the address activity index.
+type PublicAddressIndexAPI struct {
+	e *Ethereum
+}
+
+// NewPublicAddressIndexAPI creates a new address index API.
+func NewPublicAddressIndexAPI(e *Ethereum) *PublicAddressIndexAPI {
+	return &PublicAddressIndexAPI{e}
+}
+
+// GetTransactionsByAddress returns a page of the transactions the address took
+// part in within the given block range, ordered by block number and transaction
+// index. Only the range covered by the index is searched, which trails the head
+// of the chain by up to a section of blocks plus confirmations.
+func (api *PublicAddressIndexAPI) GetTransactionsByAddress(address common.Address, fromBlock, toBlock rpc.BlockNumber, page hexutil.Uint64) ([]*AddressTransaction, error) {
+	if fromBlock >= 0 && toBlock >= 0 && fromBlock > toBlock {
+		return nil, errors.New("invalid block range")
+	}
+	sections, _, _ := api.e.addressIndexer.Sections()
+	if sections == 0 {
+		return []*AddressTransaction{}, nil
+	}
+	// Clamp the range to the indexed blocks, resolving latest and pending to the last one
+	indexed := sections*AddressIndexSectionSize - 1
+
+	from, to := uint64(fromBlock), uint64(toBlock)
+	if fromBlock < 0 {
+		from = indexed
+	}
+	if toBlock < 0 || to > indexed {
+		to = indexed
+	}
+	if from > to {
+		return []*AddressTransaction{}, nil
+	}
+	var (
+		db   = api.e.ChainDb()
+		skip = uint64(page) * addressIndexPageSize
+		txs  = []*AddressTransaction{}
+	)
+	rawdb.IterateAddressActivity(db, address, from, to, func(entry rawdb.AddressActivity) bool {
+		// Skip any entries of reorged blocks which are not rolled back yet
+		if rawdb.ReadCanonicalHash(db, entry.BlockNumber) != entry.BlockHash {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		txs = append(txs, &AddressTransaction{
+			BlockNumber:      hexutil.Uint64(entry.BlockNumber),
+			BlockHash:        entry.BlockHash,
+			TransactionIndex: hexutil.Uint64(entry.TxIndex),
+			TransactionHash:  entry.TxHash,
+			Sent:             entry.Roles&rawdb.AddressActivitySender != 0,
+			Received:         entry.Roles&rawdb.AddressActivityRecipient != 0,
+			Internal:         entry.Roles&rawdb.AddressActivityInternal != 0,
+		})
+		return len(txs) < addressIndexPageSize
+	})
+	return txs, nil
+}
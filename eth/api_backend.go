@@ -264,6 +264,10 @@ func (b *EthAPIBackend) TxPool() *core.TxPool {
 	return b.eth.TxPool()
 }
 
+func (b *EthAPIBackend) BlockChain() *core.BlockChain {
+	return b.eth.BlockChain()
+}
+
 func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.TxPool().SubscribeNewTxsEvent(ch)
 }
@@ -34,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params/vars"
 	"github.com/ethereum/go-ethereum/trie"
 )
@@ -359,6 +360,23 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 	return err
 }
 
+// PeerOffence classifies an error returned by Synchronise by the misbehaviour of
+// the synced peer it was caused by, if any.
+func PeerOffence(err error) (p2p.Offence, bool) {
+	switch {
+	case errors.Is(err, errTimeout), errors.Is(err, errStallingPeer):
+		return p2p.OffenceTimeout, true
+	case errors.Is(err, errInvalidChain), errors.Is(err, errInvalidAncestor):
+		return p2p.OffenceInvalidBlock, true
+	case errors.Is(err, errBadPeer):
+		return p2p.OffenceProtocolViolation, true
+	case errors.Is(err, errUnsyncedPeer), errors.Is(err, errEmptyHeaderSet):
+		return p2p.OffenceUselessResponse, true
+	default:
+		return 0, false
+	}
+}
+
 // synchronise will select the peer and use it for synchronising. If an empty string is given
 // it will use the best peer possible and synchronize if its TD is higher than our own. If any of the
 // checks fail an error will be returned. This method is synchronous
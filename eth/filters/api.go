@@ -135,7 +135,8 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
 // enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// If fullTx is set, the complete transaction is sent instead of only its hash.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -143,6 +144,29 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 
 	rpcSub := notifier.CreateSubscription()
 
+	if fullTx != nil && *fullTx {
+		txs := make(chan []*types.Transaction, 128)
+		pendingTxSub := api.events.SubscribeFullPendingTxs(txs)
+
+		go func() {
+			for {
+				select {
+				case batch := <-txs:
+					for _, tx := range batch {
+						notifier.Notify(rpcSub.ID, tx)
+					}
+				case <-rpcSub.Err():
+					pendingTxSub.Unsubscribe()
+					return
+				case <-notifier.Closed():
+					pendingTxSub.Unsubscribe()
+					return
+				}
+			}
+		}()
+		return rpcSub, nil
+	}
+
 	go func() {
 		txHashes := make(chan []common.Hash, 128)
 		pendingTxSub := api.events.SubscribePendingTxs(txHashes)
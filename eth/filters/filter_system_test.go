@@ -210,6 +210,34 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestEventSystemStop tests that subscriptions can be ended and created after
+// the event system has been stopped, without blocking.
+func TestEventSystemStop(t *testing.T) {
+	t.Parallel()
+
+	var (
+		backend = &testBackend{db: rawdb.NewMemoryDatabase()}
+		events  = NewEventSystem(backend, false)
+	)
+	sub := events.SubscribeNewHeads(make(chan *types.Header))
+	events.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sub.Unsubscribe()
+
+		late := events.SubscribeNewHeads(make(chan *types.Header))
+		<-late.Err()
+		late.Unsubscribe()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriptions blocked after stopping the event system")
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
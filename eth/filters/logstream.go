@@ -0,0 +1,304 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// replayBatchSize is the number of blocks whose logs are replayed at once.
+	replayBatchSize = 2048
+
+	// replayOverlap is the number of blocks below the head at subscription time
+	// whose replayed logs are reconciled with the live ones received during the
+	// replay. Reorgs deeper than this while replaying may cause duplicate logs.
+	replayOverlap = 128
+)
+
+// LogCursor is a position in a log stream. All logs before the cursor, which are
+// the logs of the blocks preceding the cursor block and the logs of the cursor
+// block with an index below LogIndex, have been delivered.
+type LogCursor struct {
+	BlockHash common.Hash  `json:"blockHash"`
+	LogIndex  hexutil.Uint `json:"logIndex"`
+}
+
+// CursorLog is a log delivered by a log stream, along with the cursor to resume
+// the stream from after the log. If the stream fails, a final notification without
+// a log carries the error, after which no more logs are delivered and the stream
+// should be resumed from the last received cursor.
+type CursorLog struct {
+	Log    *types.Log `json:"log,omitempty"`
+	Cursor LogCursor  `json:"cursor"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// cursorAfter returns the cursor of the stream after delivering the log. Removed
+// logs are delivered in reverse order, so removing a log moves the cursor before it.
+func cursorAfter(log *types.Log) LogCursor {
+	if log.Removed {
+		return LogCursor{BlockHash: log.BlockHash, LogIndex: hexutil.Uint(log.Index)}
+	}
+	return LogCursor{BlockHash: log.BlockHash, LogIndex: hexutil.Uint(log.Index + 1)}
+}
+
+// replayResult is a batch of replayed logs, or the error which aborted the replay.
+type replayResult struct {
+	logs []*types.Log
+	err  error
+}
+
+// LogStream creates a subscription that replays the logs matching the given
+// criteria from crit.FromBlock, or from after the cursor if one is given, up to
+// the current head, and then continues with the logs of newly imported blocks
+// without gaps or duplicates. Logs removed by chain reorganisations are delivered
+// again with the removed property set. Every log comes with the cursor from which
+// a new stream can resume after it.
+func (api *PublicFilterAPI) LogStream(ctx context.Context, crit FilterCriteria, cursor *LogCursor) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if crit.ToBlock != nil {
+		return nil, errors.New("log streams do not support toBlock")
+	}
+	// Resolve the first block to replay, removing the delivered logs of any
+	// blocks of the cursor that were reorged out in the meantime
+	var (
+		begin   uint64
+		removed []*types.Log
+		err     error
+	)
+	if cursor != nil {
+		if begin, removed, err = api.resolveCursor(ctx, crit, *cursor); err != nil {
+			return nil, err
+		}
+	} else if crit.FromBlock != nil && crit.FromBlock.Sign() >= 0 {
+		begin = crit.FromBlock.Uint64()
+	} else {
+		head, err := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if head != nil {
+			begin = head.Number.Uint64() + 1
+		}
+	}
+	// Subscribe to the live logs before looking up the head, so that no logs
+	// are missed between the end of the replay and the live ones
+	matchedLogs := make(chan []*types.Log)
+	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery{Addresses: crit.Addresses, Topics: crit.Topics}, matchedLogs)
+	if err != nil {
+		return nil, err
+	}
+	var end uint64
+	head, err := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		logsSub.Unsubscribe()
+		return nil, err
+	}
+	if head != nil {
+		end = head.Number.Uint64()
+	}
+	var (
+		rpcSub            = notifier.CreateSubscription()
+		replayCtx, cancel = context.WithCancel(context.Background())
+		replayed          = make(chan replayResult)
+	)
+	go api.replayLogs(replayCtx, crit, cursor, begin, end, replayed)
+
+	go func() {
+		defer logsSub.Unsubscribe()
+		defer cancel()
+
+		var (
+			pending [][]*types.Log               // Live logs received during the replay
+			seen    = make(map[common.Hash]bool) // Replayed blocks near the head
+		)
+		deliver := func(logs []*types.Log) {
+			for _, log := range logs {
+				notifier.Notify(rpcSub.ID, &CursorLog{Log: log, Cursor: cursorAfter(log)})
+			}
+		}
+		deliver(removed)
+
+		for {
+			select {
+			case res, ok := <-replayed:
+				if !ok {
+					// Replay finished, reconcile the live logs received meanwhile
+					for _, logs := range pending {
+						deliver(reconcileLogs(orderLogs(logs), end, seen))
+					}
+					pending, replayed = nil, nil
+					continue
+				}
+				if res.err != nil {
+					log.Warn("Log stream replay failed", "id", rpcSub.ID, "err", res.err)
+					notifier.Notify(rpcSub.ID, &CursorLog{Error: res.err.Error()})
+					return
+				}
+				for _, log := range res.logs {
+					if log.BlockNumber+replayOverlap > end {
+						seen[log.BlockHash] = true
+					}
+				}
+				deliver(res.logs)
+			case logs := <-matchedLogs:
+				if replayed != nil {
+					pending = append(pending, logs)
+				} else {
+					deliver(orderLogs(logs))
+				}
+			case <-rpcSub.Err(): // client send an unsubscribe request
+				return
+			case <-notifier.Closed(): // connection dropped
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// resolveCursor returns the first block to replay for resuming after the cursor,
+// along with the delivered logs to remove if the cursor block is no longer part
+// of the canonical chain.
+func (api *PublicFilterAPI) resolveCursor(ctx context.Context, crit FilterCriteria, cursor LogCursor) (uint64, []*types.Log, error) {
+	header, err := api.backend.HeaderByHash(ctx, cursor.BlockHash)
+	if err != nil {
+		return 0, nil, err
+	}
+	if header == nil {
+		return 0, nil, errors.New("unknown cursor block")
+	}
+	var removed []*types.Log
+	for {
+		canonical, err := api.backend.HeaderByNumber(ctx, rpc.BlockNumber(header.Number.Int64()))
+		if err != nil {
+			return 0, nil, err
+		}
+		if canonical != nil && canonical.Hash() == header.Hash() {
+			break
+		}
+		logs, err := NewBlockFilter(api.backend, header.Hash(), crit.Addresses, crit.Topics).Logs(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		for i := len(logs) - 1; i >= 0; i-- {
+			if header.Hash() == cursor.BlockHash && logs[i].Index >= uint(cursor.LogIndex) {
+				continue // Never delivered
+			}
+			log := *logs[i]
+			log.Removed = true
+			removed = append(removed, &log)
+		}
+		if header, err = api.backend.HeaderByHash(ctx, header.ParentHash); err != nil {
+			return 0, nil, err
+		}
+		if header == nil {
+			return 0, nil, errors.New("missing ancestor of cursor block")
+		}
+	}
+	if header.Hash() == cursor.BlockHash {
+		return header.Number.Uint64(), removed, nil
+	}
+	return header.Number.Uint64() + 1, removed, nil
+}
+
+// replayLogs retrieves the logs of the blocks [begin, end] in batches, skipping
+// the logs of the cursor block which were already delivered. The results channel
+// is closed once the replay is done.
+func (api *PublicFilterAPI) replayLogs(ctx context.Context, crit FilterCriteria, cursor *LogCursor, begin, end uint64, results chan<- replayResult) {
+	defer close(results)
+
+	for from := begin; from <= end; from += replayBatchSize {
+		to := from + replayBatchSize - 1
+		if to > end {
+			to = end
+		}
+		logs, err := NewRangeFilter(api.backend, int64(from), int64(to), crit.Addresses, crit.Topics).Logs(ctx)
+		if err == nil && cursor != nil && from == begin {
+			var rest []*types.Log
+			for _, log := range logs {
+				if log.BlockHash != cursor.BlockHash || log.Index >= uint(cursor.LogIndex) {
+					rest = append(rest, log)
+				}
+			}
+			logs = rest
+		}
+		select {
+		case results <- replayResult{logs: logs, err: err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// reconcileLogs filters the live logs received during a replay which ended at
+// the given block, dropping the logs which were replayed already, as well as the
+// removals of logs which were never delivered.
+func reconcileLogs(logs []*types.Log, end uint64, seen map[common.Hash]bool) []*types.Log {
+	var (
+		result    []*types.Log
+		delivered []common.Hash
+	)
+	for _, log := range logs {
+		if log.BlockNumber > end {
+			result = append(result, log)
+			continue
+		}
+		replayed := seen[log.BlockHash] || log.BlockNumber+replayOverlap <= end
+		if log.Removed == replayed {
+			result = append(result, log)
+			if !log.Removed {
+				delivered = append(delivered, log.BlockHash)
+			}
+		}
+	}
+	for _, hash := range delivered {
+		seen[hash] = true
+	}
+	return result
+}
+
+// orderLogs sorts a batch of removed logs into reverse order, matching the way
+// cursors move when logs are removed.
+func orderLogs(logs []*types.Log) []*types.Log {
+	if len(logs) > 0 && logs[0].Removed {
+		sort.SliceStable(logs, func(i, j int) bool {
+			if logs[i].BlockNumber != logs[j].BlockNumber {
+				return logs[i].BlockNumber > logs[j].BlockNumber
+			}
+			return logs[i].Index > logs[j].Index
+		})
+	}
+	return logs
+}
@@ -0,0 +1,235 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type streamedLog struct {
+	hash    common.Hash
+	index   uint
+	removed bool
+}
+
+func expectLogs(t *testing.T, ch chan CursorLog, want []streamedLog) {
+	t.Helper()
+	for i, w := range want {
+		select {
+		case have := <-ch:
+			if have.Log.BlockHash != w.hash || have.Log.Index != w.index || have.Log.Removed != w.removed {
+				t.Fatalf("log %d mismatch: have %x/%d (removed %v), want %x/%d (removed %v)",
+					i, have.Log.BlockHash, have.Log.Index, have.Log.Removed, w.hash, w.index, w.removed)
+			}
+			if want := cursorAfter(have.Log); have.Cursor != want {
+				t.Fatalf("log %d cursor mismatch: have %+v, want %+v", i, have.Cursor, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for log %d", i)
+		}
+	}
+	select {
+	case have := <-ch:
+		t.Fatalf("unexpected log %x/%d", have.Log.BlockHash, have.Log.Index)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestLogStream tests that log streams replay historical logs, continue with
+// live ones and resume from cursors, also after reorgs.
+func TestLogStream(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false)
+		addr    = common.BytesToAddress([]byte("logstream"))
+		topic   = common.BytesToHash([]byte("topic"))
+	)
+	addLogs := func(gen *core.BlockGen, n int) {
+		receipt := types.NewReceipt(nil, false, 0)
+		for i := 0; i < n; i++ {
+			receipt.Logs = append(receipt.Logs, &types.Log{Address: addr, Topics: []common.Hash{topic}})
+		}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(gen.Number().Int64()), common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil))
+	}
+	genesis := core.GenesisBlockForTesting(db, common.Address{}, big.NewInt(1))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 3, func(i int, gen *core.BlockGen) {
+		addLogs(gen, i+1)
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Create a side block competing with the last canonical one
+	fork, forkReceipts := core.GenerateChain(params.TestChainConfig, chain[1], ethash.NewFaker(), db, 1, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{1})
+		addLogs(gen, 2)
+	})
+	rawdb.WriteBlock(db, fork[0])
+	rawdb.WriteReceipts(db, fork[0].Hash(), fork[0].NumberU64(), forkReceipts[0])
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	crit := map[string]interface{}{"fromBlock": "0x0", "address": addr}
+	subscribe := func(cursor *LogCursor) chan CursorLog {
+		ch := make(chan CursorLog, 16)
+		if _, err := client.EthSubscribe(context.Background(), ch, "logStream", crit, cursor); err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		return ch
+	}
+	var (
+		b1, b2, b3 = chain[0].Hash(), chain[1].Hash(), chain[2].Hash()
+		history    = []streamedLog{{b1, 0, false}, {b2, 0, false}, {b2, 1, false}, {b3, 0, false}, {b3, 1, false}, {b3, 2, false}}
+	)
+	// Replay all logs, then continue with live and removed ones
+	ch := subscribe(nil)
+	expectLogs(t, ch, history)
+
+	live := common.HexToHash("0x1234")
+	backend.logsFeed.Send([]*types.Log{
+		{Address: addr, Topics: []common.Hash{topic}, BlockNumber: 4, BlockHash: live, Index: 0},
+		{Address: addr, Topics: []common.Hash{topic}, BlockNumber: 4, BlockHash: live, Index: 1},
+	})
+	expectLogs(t, ch, []streamedLog{{live, 0, false}, {live, 1, false}})
+
+	backend.rmLogsFeed.Send(core.RemovedLogsEvent{Logs: []*types.Log{
+		{Address: addr, Topics: []common.Hash{topic}, BlockNumber: 4, BlockHash: live, Index: 0, Removed: true},
+		{Address: addr, Topics: []common.Hash{topic}, BlockNumber: 4, BlockHash: live, Index: 1, Removed: true},
+	}})
+	expectLogs(t, ch, []streamedLog{{live, 1, true}, {live, 0, true}})
+
+	// Resume from the middle of a canonical block
+	ch = subscribe(&LogCursor{BlockHash: b2, LogIndex: 1})
+	expectLogs(t, ch, history[2:])
+
+	// Resume from a reorged block, removing its delivered logs first
+	ch = subscribe(&LogCursor{BlockHash: fork[0].Hash(), LogIndex: 2})
+	expectLogs(t, ch, append([]streamedLog{{fork[0].Hash(), 1, true}, {fork[0].Hash(), 0, true}}, history[3:]...))
+
+	// Unknown cursors should be rejected
+	if _, err := client.EthSubscribe(context.Background(), make(chan CursorLog), "logStream", crit, &LogCursor{BlockHash: common.HexToHash("0xdead")}); err == nil {
+		t.Fatal("expected error for unknown cursor block")
+	}
+}
+
+// failingLogsBackend is a test backend failing to retrieve the logs of blocks.
+type failingLogsBackend struct {
+	*testBackend
+}
+
+func (b failingLogsBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
+	return nil, errors.New("logs unavailable")
+}
+
+// TestLogStreamReplayFailure tests that a failing replay is reported on the
+// stream instead of silently stalling it.
+func TestLogStreamReplayFailure(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = failingLogsBackend{&testBackend{db: db}}
+		api     = NewPublicFilterAPI(backend, false)
+		addr    = common.BytesToAddress([]byte("logstream"))
+	)
+	genesis := core.GenesisBlockForTesting(db, common.Address{}, big.NewInt(1))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 1, func(i int, gen *core.BlockGen) {
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{{Address: addr}}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil))
+	})
+	rawdb.WriteBlock(db, chain[0])
+	rawdb.WriteCanonicalHash(db, chain[0].Hash(), chain[0].NumberU64())
+	rawdb.WriteHeadBlockHash(db, chain[0].Hash())
+	rawdb.WriteReceipts(db, chain[0].Hash(), chain[0].NumberU64(), receipts[0])
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	ch := make(chan CursorLog, 16)
+	crit := map[string]interface{}{"fromBlock": "0x0", "address": addr}
+	if _, err := client.EthSubscribe(context.Background(), ch, "logStream", crit, nil); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	select {
+	case have := <-ch:
+		if have.Log != nil || have.Error == "" {
+			t.Fatalf("expected replay error, got %+v", have)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for replay error")
+	}
+}
+
+// TestReconcileLogs tests the reconciliation of live logs received while
+// replaying historical ones.
+func TestReconcileLogs(t *testing.T) {
+	var (
+		end      = uint64(1000)
+		replayed = common.HexToHash("0x01")
+		reorged  = common.HexToHash("0x02")
+		seen     = map[common.Hash]bool{replayed: true}
+	)
+	logs := []*types.Log{
+		{BlockNumber: 1000, BlockHash: replayed, Index: 0},                // Replayed already
+		{BlockNumber: 1000, BlockHash: reorged, Index: 0, Removed: true},  // Never delivered
+		{BlockNumber: 1000, BlockHash: replayed, Index: 0, Removed: true}, // Replayed, now removed
+		{BlockNumber: 1000, BlockHash: reorged, Index: 0},                 // Not replayed
+		{BlockNumber: 1000, BlockHash: reorged, Index: 1},                 // Not replayed
+		{BlockNumber: 1001, BlockHash: common.HexToHash("0x03")},          // Beyond the replay
+		{BlockNumber: 10, BlockHash: common.HexToHash("0x04")},            // Deep, assumed replayed
+	}
+	result := reconcileLogs(logs, end, seen)
+	want := []*types.Log{logs[2], logs[3], logs[4], logs[5]}
+	if len(result) != len(want) {
+		t.Fatalf("result length mismatch: have %d, want %d", len(result), len(want))
+	}
+	for i := range want {
+		if result[i] != want[i] {
+			t.Errorf("log %d mismatch: have %+v, want %+v", i, result[i], want[i])
+		}
+	}
+	if !seen[reorged] {
+		t.Errorf("delivered block not marked as seen")
+	}
+}
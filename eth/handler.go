@@ -58,7 +58,17 @@ var (
 )
 
 func errResp(code errCode, format string, v ...interface{}) error {
-	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+	return &protocolError{code: code, msg: fmt.Sprintf(format, v...)}
+}
+
+// protocolError is an error caused by a peer violating the protocol.
+type protocolError struct {
+	code errCode
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("%v - %v", e.code, e.msg)
 }
 
 type ProtocolManager struct {
@@ -189,7 +199,7 @@ func NewProtocolManager(config ctypes.ChainConfigurator, checkpoint *ctypes.Trus
 		}
 		return n, err
 	}
-	manager.blockFetcher = fetcher.NewBlockFetcher(false, nil, blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, nil, inserter, manager.removePeer)
+	manager.blockFetcher = fetcher.NewBlockFetcher(false, nil, blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, nil, inserter, manager.dropInvalidBlockPeer)
 
 	fetchTx := func(peer string, hashes []common.Hash) error {
 		p := manager.peers.Peer(peer)
@@ -249,6 +259,15 @@ func (pm *ProtocolManager) removePeer(id string) {
 	peer.Peer.Disconnect(p2p.DiscUselessPeer)
 }
 
+// dropInvalidBlockPeer penalizes and removes a peer which propagated an invalid
+// block or header.
+func (pm *ProtocolManager) dropInvalidBlockPeer(id string) {
+	if peer := pm.peers.Peer(id); peer != nil {
+		peer.Penalize(p2p.OffenceInvalidBlock)
+	}
+	pm.removePeer(id)
+}
+
 func (pm *ProtocolManager) Start(maxPeers int) {
 	pm.maxPeers = maxPeers
 
@@ -354,6 +373,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		// Start a timer to disconnect if the peer doesn't reply in time
 		p.syncDrop = time.AfterFunc(syncChallengeTimeout, func() {
 			p.Log().Warn("Checkpoint challenge timed out, dropping", "addr", p.RemoteAddr(), "type", p.Name())
+			p.Penalize(p2p.OffenceTimeout)
 			pm.removePeer(p.id)
 		})
 		// Make sure it's cleaned up if the peer dies off
@@ -374,6 +394,9 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	for {
 		if err := pm.handleMsg(p); err != nil {
 			p.Log().Debug("Ethereum message handling failed", "err", err)
+			if _, ok := err.(*protocolError); ok {
+				p.Penalize(p2p.OffenceProtocolViolation)
+			}
 			return err
 		}
 	}
@@ -503,6 +526,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			// joining the network
 			if atomic.LoadUint32(&pm.fastSync) == 1 {
 				p.Log().Warn("Dropping unsynced node during fast sync", "addr", p.RemoteAddr(), "type", p.Name())
+				p.Penalize(p2p.OffenceUselessResponse)
 				return errors.New("unsynced node cannot serve fast sync")
 			}
 		}
@@ -517,6 +541,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 				// Validate the header and either drop the peer or continue
 				if headers[0].Hash() != pm.checkpointHash {
+					p.Penalize(p2p.OffenceInvalidBlock)
 					return errors.New("checkpoint hash mismatch")
 				}
 				return nil
@@ -525,6 +550,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			if want, ok := pm.whitelist[headers[0].Number.Uint64()]; ok {
 				if hash := headers[0].Hash(); want != hash {
 					p.Log().Info("Whitelist mismatch, dropping peer", "number", headers[0].Number.Uint64(), "hash", hash, "want", want)
+					p.Penalize(p2p.OffenceInvalidBlock)
 					return errors.New("whitelist block mismatch")
 				}
 				p.Log().Debug("Whitelist block verified", "number", headers[0].Number.Uint64(), "hash", want)
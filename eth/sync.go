@@ -374,6 +374,9 @@ func (pm *ProtocolManager) doSync(op *chainSyncOp) error {
 	// Run the sync cycle, and disable fast sync if we're past the pivot block
 	err := pm.downloader.Synchronise(op.peer.id, op.head, op.td, op.mode)
 	if err != nil {
+		if offence, ok := downloader.PeerOffence(err); ok {
+			op.peer.Penalize(offence)
+		}
 		return err
 	}
 	if atomic.LoadUint32(&pm.fastSync) == 1 {
@@ -17,6 +17,7 @@
 package ethclient
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -369,3 +370,88 @@ func TestBlockNumber(t *testing.T) {
 		t.Fatalf("BlockNumber returned wrong number: %d", blockNumber)
 	}
 }
+
+func TestGetProof(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+	ec := NewClient(client)
+
+	result, err := ec.GetProof(context.Background(), testAddr, []common.Hash{{}}, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Address != testAddr {
+		t.Fatalf("GetProof returned wrong address: %x", result.Address)
+	}
+	if result.Balance.Cmp(testBalance) != 0 {
+		t.Fatalf("GetProof returned wrong balance: %v, want %v", result.Balance, testBalance)
+	}
+	if result.Nonce != 0 || result.StorageHash != types.EmptyRootHash || result.CodeHash != crypto.Keccak256Hash(nil) {
+		t.Fatalf("GetProof returned wrong account: nonce %d, storage %x, code %x", result.Nonce, result.StorageHash, result.CodeHash)
+	}
+	if len(result.AccountProof) == 0 {
+		t.Fatal("GetProof returned no account proof")
+	}
+	if len(result.StorageProof) != 1 || result.StorageProof[0].Value.Sign() != 0 {
+		t.Fatalf("GetProof returned wrong storage proof: %+v", result.StorageProof)
+	}
+}
+
+func TestBlockReceipts(t *testing.T) {
+	backend, chain := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+	ec := NewClient(client)
+
+	receipts, err := ec.BlockReceipts(context.Background(), chain[1].Hash())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(receipts) != 0 {
+		t.Fatalf("BlockReceipts returned receipts for empty block: %v", receipts)
+	}
+	receipts, err = ec.BlockReceiptsByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(receipts) != 0 {
+		t.Fatalf("BlockReceiptsByNumber returned receipts for empty block: %v", receipts)
+	}
+	if _, err := ec.BlockReceipts(context.Background(), common.Hash{1}); err == nil {
+		t.Fatal("BlockReceipts for unknown block returned no error")
+	}
+}
+
+func TestSubscribePendingTransactions(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+	ec := NewClient(client)
+
+	ch := make(chan *types.Transaction)
+	sub, err := ec.SubscribePendingTransactions(context.Background(), ch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	signer := types.NewEIP155Signer(params.AllEthashProtocolChanges.GetChainID())
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), 25000, big.NewInt(1), []byte("data")), signer, testKey)
+	if err := ec.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("can't send transaction: %v", err)
+	}
+	select {
+	case have := <-ch:
+		if have.Hash() != tx.Hash() || !bytes.Equal(have.Data(), tx.Data()) {
+			t.Fatalf("received wrong transaction: %x", have.Hash())
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for pending transaction")
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package gethclient provides an RPC client for the node specific namespaces
+// which are not covered by the standard eth API: debug, txpool, admin, ethash
+// and clique.
+package gethclient
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Client is a wrapper around rpc.Client that implements the node specific
+// namespaces.
+type Client struct {
+	c *rpc.Client
+}
+
+// New creates a client that uses the given RPC client.
+func New(c *rpc.Client) *Client {
+	return &Client{c}
+}
+
+// OverrideAccount specifies the state of an account to be overridden while
+// executing a call.
+type OverrideAccount struct {
+	Nonce     uint64
+	Code      []byte
+	Balance   *big.Int
+	State     map[common.Hash]common.Hash
+	StateDiff map[common.Hash]common.Hash
+}
+
+// MarshalJSON implements json.Marshaler, omitting the fields which should
+// not be overridden.
+func (a OverrideAccount) MarshalJSON() ([]byte, error) {
+	type acc struct {
+		Nonce     hexutil.Uint64              `json:"nonce,omitempty"`
+		Code      string                      `json:"code,omitempty"`
+		Balance   *hexutil.Big                `json:"balance,omitempty"`
+		State     interface{}                 `json:"state,omitempty"`
+		StateDiff map[common.Hash]common.Hash `json:"stateDiff,omitempty"`
+	}
+	output := acc{
+		Nonce:     hexutil.Uint64(a.Nonce),
+		Balance:   (*hexutil.Big)(a.Balance),
+		StateDiff: a.StateDiff,
+	}
+	if a.Code != nil {
+		output.Code = hexutil.Encode(a.Code)
+	}
+	if a.State != nil {
+		output.State = a.State
+	}
+	return json.Marshal(output)
+}
+
+// CallContractWithOverrides executes a message call transaction, which is directly
+// executed in the VM of the node, but never mined into the blockchain. Before the
+// call, the state of the accounts in overrides is replaced, allowing to execute
+// against modified balances, code or storage.
+//
+// blockNumber selects the block height at which the call runs. It can be nil, in
+// which case the code is taken from the latest known block.
+func (gc *Client) CallContractWithOverrides(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int, overrides map[common.Address]OverrideAccount) ([]byte, error) {
+	var hex hexutil.Bytes
+	err := gc.c.CallContext(ctx, &hex, "eth_call", toCallArg(msg), toBlockNumArg(blockNumber), overrides)
+	return hex, err
+}
+
+// TraceConfig holds the options of the debug tracing methods.
+type TraceConfig struct {
+	DisableMemory     bool    `json:"disableMemory,omitempty"`
+	DisableStack      bool    `json:"disableStack,omitempty"`
+	DisableStorage    bool    `json:"disableStorage,omitempty"`
+	DisableReturnData bool    `json:"disableReturnData,omitempty"`
+	Limit             int     `json:"limit,omitempty"`
+	Tracer            string  `json:"tracer,omitempty"`
+	Timeout           string  `json:"timeout,omitempty"`
+	Reexec            *uint64 `json:"reexec,omitempty"`
+}
+
+// ExecutionResult is the output of the default struct logger.
+type ExecutionResult struct {
+	Gas         uint64      `json:"gas"`
+	Failed      bool        `json:"failed"`
+	ReturnValue string      `json:"returnValue"`
+	StructLogs  []StructLog `json:"structLogs"`
+}
+
+// StructLog is a single opcode step recorded by the struct logger. The stack,
+// memory and storage are nil if disabled in the trace config.
+type StructLog struct {
+	Pc      uint64             `json:"pc"`
+	Op      string             `json:"op"`
+	Gas     uint64             `json:"gas"`
+	GasCost uint64             `json:"gasCost"`
+	Depth   int                `json:"depth"`
+	Error   string             `json:"error,omitempty"`
+	Stack   *[]string          `json:"stack,omitempty"`
+	Memory  *[]string          `json:"memory,omitempty"`
+	Storage *map[string]string `json:"storage,omitempty"`
+}
+
+// CallFrame is a message call as reported by the built-in callTracer.
+type CallFrame struct {
+	Type    string         `json:"type"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"`
+	Value   *hexutil.Big   `json:"value,omitempty"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Input   hexutil.Bytes  `json:"input"`
+	Output  hexutil.Bytes  `json:"output,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Time    string         `json:"time,omitempty"`
+	Calls   []*CallFrame   `json:"calls,omitempty"`
+}
+
+// TraceTransaction replays the transaction with the given hash and returns the
+// opcode level trace of the struct logger. The Tracer field of config must be
+// empty, custom tracers are served by TraceTransactionWithTracer.
+func (gc *Client) TraceTransaction(ctx context.Context, hash common.Hash, config *TraceConfig) (*ExecutionResult, error) {
+	var result ExecutionResult
+	if err := gc.c.CallContext(ctx, &result, "debug_traceTransaction", hash, config); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// TraceTransactionWithTracer replays the transaction with the given hash using
+// the tracer named in config, decoding the tracer's output into result.
+func (gc *Client) TraceTransactionWithTracer(ctx context.Context, hash common.Hash, config *TraceConfig, result interface{}) error {
+	return gc.c.CallContext(ctx, result, "debug_traceTransaction", hash, config)
+}
+
+// TraceCall executes a message call on top of the given block and returns the
+// opcode level trace of the struct logger.
+func (gc *Client) TraceCall(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int, config *TraceConfig) (*ExecutionResult, error) {
+	var result ExecutionResult
+	if err := gc.c.CallContext(ctx, &result, "debug_traceCall", toCallArg(msg), toBlockNumArg(blockNumber), config); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CallTrace replays the transaction with the given hash using the callTracer
+// and returns the resulting tree of message calls.
+func (gc *Client) CallTrace(ctx context.Context, hash common.Hash) (*CallFrame, error) {
+	var result CallFrame
+	config := &TraceConfig{Tracer: "callTracer"}
+	if err := gc.TraceTransactionWithTracer(ctx, hash, config, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// TxPoolContent lists the transactions in the transaction pool, grouped by
+// sender and nonce.
+type TxPoolContent struct {
+	Pending map[common.Address]map[uint64]*types.Transaction `json:"pending"`
+	Queued  map[common.Address]map[uint64]*types.Transaction `json:"queued"`
+}
+
+// TxPoolInspect is a textual summary of the transactions in the transaction
+// pool, grouped by sender and nonce.
+type TxPoolInspect struct {
+	Pending map[common.Address]map[uint64]string `json:"pending"`
+	Queued  map[common.Address]map[uint64]string `json:"queued"`
+}
+
+// TxPoolContent returns the pending and queued transactions of the pool.
+func (gc *Client) TxPoolContent(ctx context.Context) (*TxPoolContent, error) {
+	var result TxPoolContent
+	if err := gc.c.CallContext(ctx, &result, "txpool_content"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// TxPoolInspect returns a summary of the pending and queued transactions of
+// the pool.
+func (gc *Client) TxPoolInspect(ctx context.Context) (*TxPoolInspect, error) {
+	var result TxPoolInspect
+	if err := gc.c.CallContext(ctx, &result, "txpool_inspect"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// TxPoolStatus returns the number of pending and queued transactions.
+func (gc *Client) TxPoolStatus(ctx context.Context) (pending, queued uint, err error) {
+	var result struct {
+		Pending hexutil.Uint `json:"pending"`
+		Queued  hexutil.Uint `json:"queued"`
+	}
+	if err := gc.c.CallContext(ctx, &result, "txpool_status"); err != nil {
+		return 0, 0, err
+	}
+	return uint(result.Pending), uint(result.Queued), nil
+}
+
+// NodeInfo returns information about the running node.
+func (gc *Client) NodeInfo(ctx context.Context) (*p2p.NodeInfo, error) {
+	var result p2p.NodeInfo
+	if err := gc.c.CallContext(ctx, &result, "admin_nodeInfo"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Peers returns information about the connected remote peers.
+func (gc *Client) Peers(ctx context.Context) ([]*p2p.PeerInfo, error) {
+	var result []*p2p.PeerInfo
+	err := gc.c.CallContext(ctx, &result, "admin_peers")
+	return result, err
+}
+
+// AddPeer requests connecting to the remote node at the given enode URL.
+func (gc *Client) AddPeer(ctx context.Context, url string) error {
+	return gc.c.CallContext(ctx, nil, "admin_addPeer", url)
+}
+
+// RemovePeer disconnects from the remote node at the given enode URL.
+func (gc *Client) RemovePeer(ctx context.Context, url string) error {
+	return gc.c.CallContext(ctx, nil, "admin_removePeer", url)
+}
+
+// Work is a mining work package handed out to remote miners.
+type Work struct {
+	HeaderHash common.Hash // Proof-of-work hash of the block header
+	SeedHash   common.Hash // Seed hash selecting the DAG
+	Target     common.Hash // Boundary condition, 2^256/difficulty
+	Number     uint64      // Number of the block being mined
+}
+
+// GetWork returns the work package for remote miners.
+func (gc *Client) GetWork(ctx context.Context) (*Work, error) {
+	var result [4]string
+	if err := gc.c.CallContext(ctx, &result, "ethash_getWork"); err != nil {
+		return nil, err
+	}
+	number, err := hexutil.DecodeUint64(result[3])
+	if err != nil {
+		return nil, err
+	}
+	return &Work{
+		HeaderHash: common.HexToHash(result[0]),
+		SeedHash:   common.HexToHash(result[1]),
+		Target:     common.HexToHash(result[2]),
+		Number:     number,
+	}, nil
+}
+
+// SubmitWork submits a proof-of-work solution. It reports whether the solution
+// was accepted.
+func (gc *Client) SubmitWork(ctx context.Context, nonce types.BlockNonce, hash, digest common.Hash) (bool, error) {
+	var accepted bool
+	err := gc.c.CallContext(ctx, &accepted, "ethash_submitWork", nonce, hash, digest)
+	return accepted, err
+}
+
+// SubmitHashrate reports the hash rate of the remote miner identified by id.
+func (gc *Client) SubmitHashrate(ctx context.Context, rate uint64, id common.Hash) (bool, error) {
+	var accepted bool
+	err := gc.c.CallContext(ctx, &accepted, "ethash_submitHashRate", hexutil.Uint64(rate), id)
+	return accepted, err
+}
+
+// Hashrate returns the total hash rate of the local and remote miners.
+func (gc *Client) Hashrate(ctx context.Context) (uint64, error) {
+	var rate uint64
+	err := gc.c.CallContext(ctx, &rate, "ethash_getHashrate")
+	return rate, err
+}
+
+// CliqueSigners returns the authorized signers at the given block. Use nil for
+// the latest block.
+func (gc *Client) CliqueSigners(ctx context.Context, number *big.Int) ([]common.Address, error) {
+	var signers []common.Address
+	err := gc.c.CallContext(ctx, &signers, "clique_getSigners", toBlockNumArg(number))
+	return signers, err
+}
+
+// CliqueSignersAtHash returns the authorized signers at the given block.
+func (gc *Client) CliqueSignersAtHash(ctx context.Context, hash common.Hash) ([]common.Address, error) {
+	var signers []common.Address
+	err := gc.c.CallContext(ctx, &signers, "clique_getSignersAtHash", hash)
+	return signers, err
+}
+
+// CliqueProposals returns the signer votes the node is currently casting.
+func (gc *Client) CliqueProposals(ctx context.Context) (map[common.Address]bool, error) {
+	var proposals map[common.Address]bool
+	err := gc.c.CallContext(ctx, &proposals, "clique_proposals")
+	return proposals, err
+}
+
+// CliquePropose casts a vote to authorize or deauthorize the given signer.
+func (gc *Client) CliquePropose(ctx context.Context, address common.Address, auth bool) error {
+	return gc.c.CallContext(ctx, nil, "clique_propose", address, auth)
+}
+
+// CliqueDiscard drops a currently running vote about the given signer.
+func (gc *Client) CliqueDiscard(ctx context.Context, address common.Address) error {
+	return gc.c.CallContext(ctx, nil, "clique_discard", address)
+}
+
+// CliqueStatus returns the signing activity over the last blocks.
+func (gc *Client) CliqueStatus(ctx context.Context) (*clique.Status, error) {
+	var status clique.Status
+	if err := gc.c.CallContext(ctx, &status, "clique_status"); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	pending := big.NewInt(-1)
+	if number.Cmp(pending) == 0 {
+		return "pending"
+	}
+	return hexutil.EncodeBig(number)
+}
+
+func toCallArg(msg ethereum.CallMsg) interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["data"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	return arg
+}
@@ -0,0 +1,276 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package gethclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+)
+
+var (
+	testKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr     = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance  = big.NewInt(2e18)
+	testContract = common.HexToAddress("0x00000000000000000000000000000000000c0de")
+
+	// testCode returns the value of storage slot zero.
+	testCode = common.FromHex("0x60005460005260206000f3")
+)
+
+func newTestBackend(t *testing.T) (*node.Node, *eth.Ethereum, []*types.Block) {
+	genesis, blocks := generateTestChain()
+	n, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("can't create new node: %v", err)
+	}
+	config := &eth.Config{Genesis: genesis}
+	config.Ethash.PowMode = ethash.ModeFake
+	ethservice, err := eth.New(n, config)
+	if err != nil {
+		t.Fatalf("can't create new ethereum service: %v", err)
+	}
+	if err := n.Start(); err != nil {
+		t.Fatalf("can't start test node: %v", err)
+	}
+	if _, err := ethservice.BlockChain().InsertChain(blocks[1:]); err != nil {
+		t.Fatalf("can't import test blocks: %v", err)
+	}
+	return n, ethservice, blocks
+}
+
+func generateTestChain() (*genesisT.Genesis, []*types.Block) {
+	db := rawdb.NewMemoryDatabase()
+	config := params.AllEthashProtocolChanges
+	genesis := &genesisT.Genesis{
+		Config: config,
+		Alloc: genesisT.GenesisAlloc{
+			testAddr:     {Balance: testBalance},
+			testContract: {Balance: big.NewInt(0), Code: testCode, Storage: map[common.Hash]common.Hash{{}: common.HexToHash("0x07")}},
+		},
+		ExtraData: []byte("test genesis"),
+		Timestamp: 9000,
+	}
+	signer := types.NewEIP155Signer(config.GetChainID())
+	generate := func(i int, g *core.BlockGen) {
+		g.OffsetTime(5)
+		g.SetExtra([]byte("test"))
+		tx, _ := types.SignTx(types.NewTransaction(0, testContract, big.NewInt(0), 100000, big.NewInt(1), nil), signer, testKey)
+		g.AddTx(tx)
+	}
+	gblock := core.GenesisToBlock(genesis, db)
+	engine := ethash.NewFaker()
+	blocks, _ := core.GenerateChain(config, gblock, engine, db, 1, generate)
+	blocks = append([]*types.Block{gblock}, blocks...)
+	return genesis, blocks
+}
+
+func TestCallContractWithOverrides(t *testing.T) {
+	backend, _, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	gc := New(client)
+	msg := ethereum.CallMsg{From: testAddr, To: &testContract}
+
+	out, err := gc.CallContractWithOverrides(context.Background(), msg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0x07").Bytes(); !bytes.Equal(out, want) {
+		t.Fatalf("call result mismatch: have %x, want %x", out, want)
+	}
+	overrides := map[common.Address]OverrideAccount{
+		testContract: {StateDiff: map[common.Hash]common.Hash{{}: common.HexToHash("0x2a")}},
+	}
+	out, err = gc.CallContractWithOverrides(context.Background(), msg, nil, overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0x2a").Bytes(); !bytes.Equal(out, want) {
+		t.Fatalf("overridden call result mismatch: have %x, want %x", out, want)
+	}
+}
+
+func TestTraceTransaction(t *testing.T) {
+	backend, _, blocks := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	gc := New(client)
+	tx := blocks[1].Transactions()[0]
+
+	result, err := gc.TraceTransaction(context.Background(), tx.Hash(), &TraceConfig{DisableStack: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed {
+		t.Fatal("transaction reported as failed")
+	}
+	if len(result.StructLogs) != 7 {
+		t.Fatalf("wrong number of struct logs: have %d, want 7", len(result.StructLogs))
+	}
+	if op := result.StructLogs[1].Op; op != "SLOAD" {
+		t.Fatalf("wrong second opcode: have %s, want SLOAD", op)
+	}
+	frame, err := gc.CallTrace(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != "CALL" || frame.From != testAddr || frame.To != testContract {
+		t.Fatalf("unexpected call frame: %+v", frame)
+	}
+	if want := common.HexToHash("0x07").Bytes(); !bytes.Equal(frame.Output, want) {
+		t.Fatalf("call frame output mismatch: have %x, want %x", frame.Output, want)
+	}
+}
+
+func TestTxPool(t *testing.T) {
+	backend, ethservice, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	gc := New(client)
+	signer := types.NewEIP155Signer(params.AllEthashProtocolChanges.GetChainID())
+	tx, _ := types.SignTx(types.NewTransaction(1, testAddr, big.NewInt(1), 21000, big.NewInt(1), nil), signer, testKey)
+	if err := ethservice.TxPool().AddLocal(tx); err != nil {
+		t.Fatal(err)
+	}
+	pending, queued, err := gc.TxPoolStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != 1 || queued != 0 {
+		t.Fatalf("wrong pool status: have %d/%d, want 1/0", pending, queued)
+	}
+	content, err := gc.TxPoolContent(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := content.Pending[testAddr][1]; have == nil || have.Hash() != tx.Hash() {
+		t.Fatalf("pending transaction missing from pool content: %v", content.Pending)
+	}
+	inspect, err := gc.TxPoolInspect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspect.Pending[testAddr][1] == "" {
+		t.Fatalf("pending transaction missing from pool summary: %v", inspect.Pending)
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	backend, _, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	gc := New(client)
+	info, err := gc.NodeInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ID != backend.Server().Self().ID().String() {
+		t.Fatalf("node id mismatch: have %s, want %s", info.ID, backend.Server().Self().ID())
+	}
+	peers, err := gc.Peers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 0 {
+		t.Fatalf("unexpected peers: %v", peers)
+	}
+}
+
+// Tests that the traces served by the debug namespace decode into the client
+// types without losing any field.
+func TestTraceTypesRoundTrip(t *testing.T) {
+	backend, _, blocks := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	roundTrip := func(name string, blob []byte, result interface{}) {
+		t.Helper()
+		if err := json.Unmarshal(blob, result); err != nil {
+			t.Fatalf("%s: failed to decode: %v", name, err)
+		}
+		have, _ := json.Marshal(result)
+
+		var want, got interface{}
+		json.Unmarshal(blob, &want)
+		json.Unmarshal(have, &got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: round trip mismatch:\nhave %s\nwant %s", name, have, blob)
+		}
+	}
+	tx := blocks[1].Transactions()[0]
+
+	var logs, frame json.RawMessage
+	if err := client.CallContext(context.Background(), &logs, "debug_traceTransaction", tx.Hash(), nil); err != nil {
+		t.Fatal(err)
+	}
+	roundTrip("struct logs", logs, new(ExecutionResult))
+
+	if err := client.CallContext(context.Background(), &frame, "debug_traceTransaction", tx.Hash(), &TraceConfig{Tracer: "callTracer"}); err != nil {
+		t.Fatal(err)
+	}
+	roundTrip("call frame", frame, new(CallFrame))
+
+	// Check the server type with all fields populated
+	full, err := json.Marshal(&ethapi.ExecutionResult{
+		Gas:         21000,
+		Failed:      true,
+		ReturnValue: "2a",
+		StructLogs: ethapi.FormatLogs([]vm.StructLog{{
+			Pc:      1,
+			Op:      vm.SSTORE,
+			Gas:     5000,
+			GasCost: 20000,
+			Memory:  make([]byte, 32),
+			Stack:   []*big.Int{big.NewInt(1), big.NewInt(2)},
+			Storage: map[common.Hash]common.Hash{{0x01}: {0x02}},
+			Depth:   1,
+			Err:     errors.New("out of gas"),
+		}}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip("full struct logs", full, new(ExecutionResult))
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package traceclient provides an RPC client for the Parity/OpenEthereum
+// compatible trace namespace.
+package traceclient
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Client is a wrapper around rpc.Client that implements the trace namespace.
+type Client struct {
+	c *rpc.Client
+}
+
+// New creates a client that uses the given RPC client.
+func New(c *rpc.Client) *Client {
+	return &Client{c}
+}
+
+// Block returns the traces of all transactions in the given block, followed by
+// the block and uncle reward traces. Use nil for the latest block.
+func (tc *Client) Block(ctx context.Context, number *big.Int) ([]*Trace, error) {
+	var result []*Trace
+	if err := tc.c.CallContext(ctx, &result, "trace_block", toBlockNumArg(number)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Transaction returns the traces of the transaction with the given hash.
+func (tc *Client) Transaction(ctx context.Context, hash common.Hash) ([]*Trace, error) {
+	var result []*Trace
+	if err := tc.c.CallContext(ctx, &result, "trace_transaction", hash); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ethereum.NotFound
+	}
+	return result, nil
+}
+
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	pending := big.NewInt(-1)
+	if number.Cmp(pending) == 0 {
+		return "pending"
+	}
+	return hexutil.EncodeBig(number)
+}
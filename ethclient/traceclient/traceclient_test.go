@@ -0,0 +1,186 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package traceclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethclient/traceclient"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = big.NewInt(2e18)
+	testTo      = common.HexToAddress("0x00000000000000000000000000000000000b0b")
+)
+
+func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
+	db := rawdb.NewMemoryDatabase()
+	config := params.AllEthashProtocolChanges
+	genesis := &genesisT.Genesis{
+		Config:    config,
+		Alloc:     genesisT.GenesisAlloc{testAddr: {Balance: testBalance}},
+		ExtraData: []byte("test genesis"),
+		Timestamp: 9000,
+	}
+	signer := types.NewEIP155Signer(config.GetChainID())
+	generate := func(i int, g *core.BlockGen) {
+		g.OffsetTime(5)
+		tx, _ := types.SignTx(types.NewTransaction(0, testTo, big.NewInt(1000), 21000, big.NewInt(1), nil), signer, testKey)
+		g.AddTx(tx)
+	}
+	gblock := core.GenesisToBlock(genesis, db)
+	blocks, _ := core.GenerateChain(config, gblock, ethash.NewFaker(), db, 1, generate)
+
+	n, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("can't create new node: %v", err)
+	}
+	ethConfig := &eth.Config{Genesis: genesis}
+	ethConfig.Ethash.PowMode = ethash.ModeFake
+	ethservice, err := eth.New(n, ethConfig)
+	if err != nil {
+		t.Fatalf("can't create new ethereum service: %v", err)
+	}
+	if err := n.Start(); err != nil {
+		t.Fatalf("can't start test node: %v", err)
+	}
+	if _, err := ethservice.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("can't import test blocks: %v", err)
+	}
+	return n, blocks
+}
+
+func TestTraceBlock(t *testing.T) {
+	backend, blocks := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	traces, err := traceclient.New(client).Block(context.Background(), big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 2 {
+		t.Fatalf("wrong number of traces: have %d, want 2", len(traces))
+	}
+	call, reward := traces[0], traces[1]
+	if call.Type != "call" || call.Action.CallType != "call" {
+		t.Fatalf("wrong call trace type: %s/%s", call.Type, call.Action.CallType)
+	}
+	if *call.Action.From != testAddr || *call.Action.To != testTo {
+		t.Fatalf("wrong call participants: %x -> %x", *call.Action.From, *call.Action.To)
+	}
+	if call.Action.Value.ToInt().Int64() != 1000 {
+		t.Fatalf("wrong call value: %v", call.Action.Value)
+	}
+	if *call.TransactionHash != blocks[0].Transactions()[0].Hash() {
+		t.Fatalf("wrong transaction hash: %x", *call.TransactionHash)
+	}
+	if reward.Type != "reward" || reward.Action.RewardType != "block" || *reward.Action.Author != blocks[0].Coinbase() {
+		t.Fatalf("unexpected reward trace: %+v", reward.Action)
+	}
+	if reward.BlockHash != blocks[0].Hash() || reward.Result != nil {
+		t.Fatalf("unexpected reward trace: %+v", reward)
+	}
+}
+
+func TestTraceTransaction(t *testing.T) {
+	backend, blocks := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	tx := blocks[0].Transactions()[0]
+	traces, err := traceclient.New(client).Transaction(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 1 {
+		t.Fatalf("wrong number of traces: have %d, want 1", len(traces))
+	}
+	if traces[0].BlockNumber != 1 || *traces[0].TransactionPosition != 0 {
+		t.Fatalf("wrong trace position: block %d, index %d", traces[0].BlockNumber, *traces[0].TransactionPosition)
+	}
+	if traces[0].Result == nil || traces[0].Result.GasUsed == nil {
+		t.Fatalf("missing trace result: %+v", traces[0])
+	}
+}
+
+// Tests that the traces served by the trace namespace decode into the client
+// types without losing any field.
+func TestTraceTypesRoundTrip(t *testing.T) {
+	backend, blocks := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	var served []json.RawMessage
+	if err := client.CallContext(context.Background(), &served, "trace_block", "0x1"); err != nil {
+		t.Fatal(err)
+	}
+	// Add a reward trace with all fields of the server type populated
+	var (
+		hash     = blocks[0].Transactions()[0].Hash()
+		position = uint64(0)
+		author   = blocks[0].Coinbase()
+	)
+	full, err := json.Marshal(&eth.ParityTrace{
+		Action:              eth.TraceRewardAction{Value: (*hexutil.Big)(big.NewInt(2)), Author: &author, RewardType: "uncle"},
+		BlockHash:           blocks[0].Hash(),
+		BlockNumber:         1,
+		Error:               "failure",
+		Subtraces:           1,
+		TraceAddress:        []int{0, 1},
+		TransactionHash:     &hash,
+		TransactionPosition: &position,
+		Type:                "reward",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, blob := range append(served, full) {
+		var trace traceclient.Trace
+		if err := json.Unmarshal(blob, &trace); err != nil {
+			t.Fatalf("trace %d: failed to decode: %v", i, err)
+		}
+		have, _ := json.Marshal(&trace)
+
+		var want, got interface{}
+		json.Unmarshal(blob, &want)
+		json.Unmarshal(have, &got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("trace %d: round trip mismatch:\nhave %s\nwant %s", i, have, blob)
+		}
+	}
+}
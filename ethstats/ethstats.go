@@ -19,9 +19,12 @@ package ethstats
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"net/http"
 	"runtime"
@@ -34,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -43,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/gorilla/websocket"
 )
@@ -57,6 +62,12 @@ const (
 	txChanSize = 4096
 	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
 	chainHeadChanSize = 10
+
+	// minReconnectDelay and maxReconnectDelay bound the time waited before trying
+	// to reconnect after failing to reach or log in to the stats server. The delay
+	// doubles with every failed attempt.
+	minReconnectDelay = 5 * time.Second
+	maxReconnectDelay = 5 * time.Minute
 )
 
 // backend encompasses the bare-minimum functionality needed for ethstats reporting
@@ -69,6 +80,8 @@ type backend interface {
 	GetTd(ctx context.Context, hash common.Hash) *big.Int
 	Stats() (pending int, queued int)
 	Downloader() *downloader.Downloader
+	ChainConfig() ctypes.ChainConfigurator
+	SuggestPrice(ctx context.Context) (*big.Int, error)
 }
 
 // fullNodeBackend encompasses the functionality necessary for a full node
@@ -78,7 +91,7 @@ type fullNodeBackend interface {
 	Miner() *miner.Miner
 	BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error)
 	CurrentBlock() *types.Block
-	SuggestPrice(ctx context.Context) (*big.Int, error)
+	BlockChain() *core.BlockChain
 }
 
 // Service implements an Ethereum netstats reporting daemon that pushes local
@@ -92,6 +105,8 @@ type Service struct {
 	pass string // Password to authorize access to the monitoring page
 	host string // Remote address of the monitoring service
 
+	tlsConfig *tls.Config // TLS configuration with the client certificate, nil if none is used
+
 	pongCh chan struct{} // Pong notifications are fed into this channel
 	histCh chan []uint64 // History request block numbers are fed into this channel
 
@@ -166,27 +181,111 @@ func parseEthstatsURL(url string) (parts []string, err error) {
 	return []string{nodename, pass, host}, nil
 }
 
+// Config contains the settings of the stats reporting service.
+type Config struct {
+	URL string // Reporting URL of the form nodename:secret@host:port
+
+	// TLSCert and TLSKey are the files holding the client certificate and its key
+	// used to authenticate to the stats server. If set, the connection is only
+	// established over a TLS websocket.
+	TLSCert string
+	TLSKey  string
+
+	// TLSCA is the file holding the certificates used to verify the stats server,
+	// defaulting to the system pool if empty.
+	TLSCA string
+}
+
+// loadTLSConfig assembles the TLS configuration of the websocket connection, or
+// returns nil if no client certificate is configured.
+func (c *Config) loadTLSConfig() (*tls.Config, error) {
+	if c.TLSCert == "" && c.TLSKey == "" {
+		if c.TLSCA != "" {
+			return nil, errors.New("ethstats CA certificate requires a client certificate")
+		}
+		return nil, nil
+	}
+	if c.TLSCert == "" || c.TLSKey == "" {
+		return nil, errors.New("ethstats client certificate and key must be set together")
+	}
+	cert, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ethstats client certificate: %v", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if c.TLSCA != "" {
+		pem, err := ioutil.ReadFile(c.TLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ethstats CA certificate: %v", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.TLSCA)
+		}
+	}
+	return config, nil
+}
+
 // New returns a monitoring service ready for stats reporting.
 func New(node *node.Node, backend backend, engine consensus.Engine, url string) error {
-	parts, err := parseEthstatsURL(url)
+	return NewWithConfig(node, backend, engine, Config{URL: url})
+}
+
+// NewWithConfig returns a monitoring service ready for stats reporting, using
+// the given connection settings.
+func NewWithConfig(node *node.Node, backend backend, engine consensus.Engine, config Config) error {
+	parts, err := parseEthstatsURL(config.URL)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := config.loadTLSConfig()
 	if err != nil {
 		return err
 	}
 	ethstats := &Service{
-		backend: backend,
-		engine:  engine,
-		server:  node.Server(),
-		node:    parts[0],
-		pass:    parts[1],
-		host:    parts[2],
-		pongCh:  make(chan struct{}),
-		histCh:  make(chan []uint64, 1),
+		backend:   backend,
+		engine:    engine,
+		server:    node.Server(),
+		node:      parts[0],
+		pass:      parts[1],
+		host:      parts[2],
+		tlsConfig: tlsConfig,
+		pongCh:    make(chan struct{}),
+		histCh:    make(chan []uint64, 1),
 	}
 
 	node.RegisterLifecycle(ethstats)
 	return nil
 }
 
+// dialURLs returns the websocket URLs to try connecting to the stats server on.
+// Unless the scheme is given explicitly, TLS is attempted first, falling back to
+// a plain connection if no client certificate is configured.
+func (s *Service) dialURLs() []string {
+	path := fmt.Sprintf("%s/api", s.host)
+
+	// url.Parse and url.IsAbs is unsuitable (https://github.com/golang/go/issues/19779)
+	if strings.Contains(path, "://") {
+		return []string{path}
+	}
+	if s.tlsConfig != nil {
+		return []string{"wss://" + path}
+	}
+	return []string{"wss://" + path, "ws://" + path}
+}
+
+// nextReconnectDelay returns the time to wait before the next connection attempt,
+// given the delay used for the previous one.
+func nextReconnectDelay(delay time.Duration) time.Duration {
+	if delay < minReconnectDelay {
+		return minReconnectDelay
+	}
+	if delay *= 2; delay > maxReconnectDelay {
+		return maxReconnectDelay
+	}
+	return delay
+}
+
 // Start implements node.Lifecycle, starting up the monitoring and reporting daemon.
 func (s *Service) Start() error {
 	go s.loop()
@@ -255,14 +354,9 @@ func (s *Service) loop() {
 	}()
 
 	// Resolve the URL, defaulting to TLS, but falling back to none too
-	path := fmt.Sprintf("%s/api", s.host)
-	urls := []string{path}
-
-	// url.Parse and url.IsAbs is unsuitable (https://github.com/golang/go/issues/19779)
-	if !strings.Contains(path, "://") {
-		urls = []string{"wss://" + path, "ws://" + path}
-	}
+	urls := s.dialURLs()
 
+	var retryDelay time.Duration
 	errTimer := time.NewTimer(0)
 	defer errTimer.Stop()
 	// Loop reporting until termination
@@ -276,7 +370,7 @@ func (s *Service) loop() {
 				conn *connWrapper
 				err  error
 			)
-			dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+			dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second, TLSClientConfig: s.tlsConfig}
 			header := make(http.Header)
 			header.Set("origin", "http://localhost")
 			for _, url := range urls {
@@ -288,17 +382,20 @@ func (s *Service) loop() {
 				}
 			}
 			if err != nil {
-				log.Warn("Stats server unreachable", "err", err)
-				errTimer.Reset(10 * time.Second)
+				retryDelay = nextReconnectDelay(retryDelay)
+				log.Warn("Stats server unreachable", "err", err, "retry", retryDelay)
+				errTimer.Reset(retryDelay)
 				continue
 			}
 			// Authenticate the client with the server
 			if err = s.login(conn); err != nil {
-				log.Warn("Stats login failed", "err", err)
+				retryDelay = nextReconnectDelay(retryDelay)
+				log.Warn("Stats login failed", "err", err, "retry", retryDelay)
 				conn.Close()
-				errTimer.Reset(10 * time.Second)
+				errTimer.Reset(retryDelay)
 				continue
 			}
+			retryDelay = 0
 			go s.readLoop(conn)
 
 			// Send the initial stats so our node looks decent from the get go
@@ -727,20 +824,22 @@ func (s *Service) reportHistory(conn *connWrapper, list []uint64) error {
 // pendStats is the information to report about pending transactions.
 type pendStats struct {
 	Pending int `json:"pending"`
+	Queued  int `json:"queued"`
 }
 
 // reportPending retrieves the current number of pending transactions and reports
 // it to the stats server.
 func (s *Service) reportPending(conn *connWrapper) error {
-	// Retrieve the pending count from the local blockchain
-	pending, _ := s.backend.Stats()
+	// Retrieve the pending and queued counts from the local transaction pool
+	pending, queued := s.backend.Stats()
 	// Assemble the transaction stats and send it to the server
-	log.Trace("Sending pending transactions to ethstats", "count", pending)
+	log.Trace("Sending pending transactions to ethstats", "count", pending, "queued", queued)
 
 	stats := map[string]interface{}{
 		"id": s.node,
 		"stats": &pendStats{
 			Pending: pending,
+			Queued:  queued,
 		},
 	}
 	report := map[string][]interface{}{
@@ -758,6 +857,93 @@ type nodeStats struct {
 	Peers    int  `json:"peers"`
 	GasPrice int  `json:"gasPrice"`
 	Uptime   int  `json:"uptime"`
+
+	SyncProgress       *syncStats     `json:"syncProgress,omitempty"`
+	PeerProtocols      map[string]int `json:"peerProtocols,omitempty"`
+	ForkID             *forkIDStats   `json:"forkId,omitempty"`
+	ArtificialFinality *afStats       `json:"artificialFinality,omitempty"`
+}
+
+// syncStats is the downloader progress of the local node.
+type syncStats struct {
+	StartingBlock uint64 `json:"startingBlock"`
+	CurrentBlock  uint64 `json:"currentBlock"`
+	HighestBlock  uint64 `json:"highestBlock"`
+	PulledStates  uint64 `json:"pulledStates"`
+	KnownStates   uint64 `json:"knownStates"`
+}
+
+// forkIDStats is the EIP-2124 fork identifier of the local chain.
+type forkIDStats struct {
+	Hash string `json:"hash"`
+	Next uint64 `json:"next"`
+}
+
+// afStats is the state of the ECBP-1100 artificial finality protection.
+type afStats struct {
+	Enabled  bool            `json:"enabled"`
+	Rejected []rejectedStats `json:"rejectedReorgs"`
+}
+
+// rejectedStats is the information to report about a reorg rejected by
+// artificial finality.
+type rejectedStats struct {
+	Time     int64       `json:"time"`
+	Common   uint64      `json:"commonNumber"`
+	Current  common.Hash `json:"currentHash"`
+	Proposed common.Hash `json:"proposedHash"`
+	Length   uint64      `json:"proposedLength"`
+}
+
+// assembleAFStats collects the artificial finality state of a block chain.
+func assembleAFStats(chain *core.BlockChain) *afStats {
+	config := chain.Config()
+	stats := &afStats{
+		Enabled:  chain.IsArtificialFinalityEnabled() && config.IsEnabled(config.GetECBP1100Transition, chain.CurrentHeader().Number),
+		Rejected: []rejectedStats{},
+	}
+	for _, reorg := range chain.RejectedReorgs() {
+		stats.Rejected = append(stats.Rejected, rejectedStats{
+			Time:     reorg.Time.Unix(),
+			Common:   reorg.Common.Number.Uint64(),
+			Current:  reorg.Current.Hash(),
+			Proposed: reorg.Proposed.Hash(),
+			Length:   reorg.Proposed.Number.Uint64() - reorg.Common.Number.Uint64(),
+		})
+	}
+	return stats
+}
+
+// peerProtocols counts the connected peers by the highest version of each
+// protocol negotiated with them, keyed like "eth/65".
+func (s *Service) peerProtocols() map[string]int {
+	local := make(map[p2p.Cap]bool)
+	for _, proto := range s.server.Protocols {
+		local[p2p.Cap{Name: proto.Name, Version: proto.Version}] = true
+	}
+	counts := make(map[string]int)
+	for _, peer := range s.server.Peers() {
+		versions := make(map[string]uint)
+		for _, cap := range peer.Caps() {
+			if local[cap] && cap.Version > versions[cap.Name] {
+				versions[cap.Name] = cap.Version
+			}
+		}
+		for name, version := range versions {
+			counts[fmt.Sprintf("%s/%d", name, version)]++
+		}
+	}
+	return counts
+}
+
+// forkID computes the fork identifier of the local chain at its current head.
+func (s *Service) forkID() *forkIDStats {
+	genesis, err := s.backend.HeaderByNumber(context.Background(), 0)
+	if err != nil || genesis == nil {
+		return nil
+	}
+	id := forkid.NewID(s.backend.ChainConfig(), genesis.Hash(), s.backend.CurrentHeader().Number.Uint64())
+	return &forkIDStats{Hash: fmt.Sprintf("%#x", id.Hash), Next: id.Next}
 }
 
 // reportStats retrieves various stats about the node at the networking and
@@ -767,23 +953,21 @@ func (s *Service) reportStats(conn *connWrapper) error {
 	var (
 		mining   bool
 		hashrate int
-		syncing  bool
 		gasprice int
+		af       *afStats
 	)
 	// check if backend is a full node
 	fullBackend, ok := s.backend.(fullNodeBackend)
 	if ok {
 		mining = fullBackend.Miner().Mining()
 		hashrate = int(fullBackend.Miner().HashRate())
+		af = assembleAFStats(fullBackend.BlockChain())
+	}
+	sync := s.backend.Downloader().Progress()
+	syncing := s.backend.CurrentHeader().Number.Uint64() >= sync.HighestBlock
 
-		sync := fullBackend.Downloader().Progress()
-		syncing = fullBackend.CurrentHeader().Number.Uint64() >= sync.HighestBlock
-
-		price, _ := fullBackend.SuggestPrice(context.Background())
+	if price, err := s.backend.SuggestPrice(context.Background()); err == nil && price != nil {
 		gasprice = int(price.Uint64())
-	} else {
-		sync := s.backend.Downloader().Progress()
-		syncing = s.backend.CurrentHeader().Number.Uint64() >= sync.HighestBlock
 	}
 	// Assemble the node stats and send it to the server
 	log.Trace("Sending node details to ethstats")
@@ -798,6 +982,17 @@ func (s *Service) reportStats(conn *connWrapper) error {
 			GasPrice: gasprice,
 			Syncing:  syncing,
 			Uptime:   100,
+
+			SyncProgress: &syncStats{
+				StartingBlock: sync.StartingBlock,
+				CurrentBlock:  sync.CurrentBlock,
+				HighestBlock:  sync.HighestBlock,
+				PulledStates:  sync.PulledStates,
+				KnownStates:   sync.KnownStates,
+			},
+			PeerProtocols:      s.peerProtocols(),
+			ForkID:             s.forkID(),
+			ArtificialFinality: af,
 		},
 	}
 	report := map[string][]interface{}{
@@ -1,8 +1,21 @@
 package ethstats
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func TestParseEthstatsURL(t *testing.T) {
@@ -65,3 +78,113 @@ func TestParseEthstatsURL(t *testing.T) {
 	}
 
 }
+
+func TestNextReconnectDelay(t *testing.T) {
+	var (
+		delay time.Duration
+		want  = []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second, 160 * time.Second, 5 * time.Minute, 5 * time.Minute}
+	)
+	for i, w := range want {
+		if delay = nextReconnectDelay(delay); delay != w {
+			t.Errorf("attempt %d: delay mismatch: have %v, want %v", i, delay, w)
+		}
+	}
+}
+
+func TestDialURLs(t *testing.T) {
+	cases := []struct {
+		host    string
+		withTLS bool
+		want    []string
+	}{
+		{"stats.example.org:3000", false, []string{"wss://stats.example.org:3000/api", "ws://stats.example.org:3000/api"}},
+		{"stats.example.org:3000", true, []string{"wss://stats.example.org:3000/api"}},
+		{"ws://stats.example.org:3000", false, []string{"ws://stats.example.org:3000/api"}},
+		{"ws://stats.example.org:3000", true, []string{"ws://stats.example.org:3000/api"}},
+	}
+	for i, c := range cases {
+		s := &Service{host: c.host}
+		if c.withTLS {
+			s.tlsConfig = new(tls.Config)
+		}
+		if have := s.dialURLs(); !reflect.DeepEqual(have, c.want) {
+			t.Errorf("case=%d mismatch urls, got: %v, want: %v", i, have, c.want)
+		}
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate and its key to the given
+// directory, returning the paths of the files.
+func writeTestCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ethstats test"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethstats-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCertificate(t, dir)
+
+	// No client certificate means no TLS configuration
+	if config, err := (&Config{}).loadTLSConfig(); config != nil || err != nil {
+		t.Fatalf("unexpected config without certificate: %v, %v", config, err)
+	}
+	// Partial or invalid settings are rejected
+	for i, c := range []Config{
+		{TLSCert: certFile},
+		{TLSKey: keyFile},
+		{TLSCA: certFile},
+		{TLSCert: certFile, TLSKey: certFile},
+		{TLSCert: certFile, TLSKey: keyFile, TLSCA: keyFile},
+		{TLSCert: certFile, TLSKey: keyFile, TLSCA: filepath.Join(dir, "missing.pem")},
+	} {
+		if _, err := c.loadTLSConfig(); err == nil {
+			t.Errorf("case=%d: expected error for invalid settings", i)
+		}
+	}
+	// A valid certificate, optionally with a CA, is loaded
+	config, err := (&Config{TLSCert: certFile, TLSKey: keyFile}).loadTLSConfig()
+	if err != nil {
+		t.Fatalf("failed to load certificate: %v", err)
+	}
+	if len(config.Certificates) != 1 || config.RootCAs != nil {
+		t.Errorf("unexpected config: %d certificates, root CAs %v", len(config.Certificates), config.RootCAs)
+	}
+	config, err = (&Config{TLSCert: certFile, TLSKey: keyFile, TLSCA: certFile}).loadTLSConfig()
+	if err != nil {
+		t.Fatalf("failed to load certificate with CA: %v", err)
+	}
+	if config.RootCAs == nil {
+		t.Error("CA certificate not loaded")
+	}
+}
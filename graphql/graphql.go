@@ -18,8 +18,10 @@
 package graphql
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"sort"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -29,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -88,6 +91,114 @@ func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash })
 	return state.GetState(a.address, args.Slot), nil
 }
 
+func (a *Account) Proof(ctx context.Context, args struct{ Slots *[]common.Hash }) (*AccountProof, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	accountProof, err := state.GetProof(a.address)
+	if err != nil {
+		return nil, err
+	}
+	proof := &AccountProof{
+		address:      a.address,
+		accountProof: toBytesList(accountProof),
+		balance:      hexutil.Big(*state.GetBalance(a.address)),
+		codeHash:     state.GetCodeHash(a.address),
+		nonce:        hexutil.Uint64(state.GetNonce(a.address)),
+		storageHash:  types.EmptyRootHash,
+		storageProof: []*StorageProof{},
+	}
+	storageTrie := state.StorageTrie(a.address)
+	if storageTrie != nil {
+		proof.storageHash = storageTrie.Hash()
+	} else {
+		// The account does not exist, so the code hash is the hash of an empty byte array.
+		proof.codeHash = crypto.Keccak256Hash(nil)
+	}
+	if args.Slots != nil {
+		for _, slot := range *args.Slots {
+			sp := &StorageProof{key: slot, proof: []hexutil.Bytes{}}
+			if storageTrie != nil {
+				p, err := state.GetStorageProof(a.address, slot)
+				if err != nil {
+					return nil, err
+				}
+				sp.value = hexutil.Big(*state.GetState(a.address, slot).Big())
+				sp.proof = toBytesList(p)
+			}
+			proof.storageProof = append(proof.storageProof, sp)
+		}
+	}
+	return proof, state.Error()
+}
+
+func toBytesList(list [][]byte) []hexutil.Bytes {
+	ret := make([]hexutil.Bytes, len(list))
+	for i, b := range list {
+		ret[i] = b
+	}
+	return ret
+}
+
+// AccountProof is a Merkle proof of an account and a set of its storage slots.
+type AccountProof struct {
+	address      common.Address
+	accountProof []hexutil.Bytes
+	balance      hexutil.Big
+	codeHash     common.Hash
+	nonce        hexutil.Uint64
+	storageHash  common.Hash
+	storageProof []*StorageProof
+}
+
+func (p *AccountProof) Address() common.Address {
+	return p.address
+}
+
+func (p *AccountProof) AccountProof() []hexutil.Bytes {
+	return p.accountProof
+}
+
+func (p *AccountProof) Balance() hexutil.Big {
+	return p.balance
+}
+
+func (p *AccountProof) CodeHash() common.Hash {
+	return p.codeHash
+}
+
+func (p *AccountProof) Nonce() hexutil.Uint64 {
+	return p.nonce
+}
+
+func (p *AccountProof) StorageHash() common.Hash {
+	return p.storageHash
+}
+
+func (p *AccountProof) StorageProof() []*StorageProof {
+	return p.storageProof
+}
+
+// StorageProof is a Merkle proof of a single storage slot.
+type StorageProof struct {
+	key   common.Hash
+	value hexutil.Big
+	proof []hexutil.Bytes
+}
+
+func (p *StorageProof) Key() common.Hash {
+	return p.key
+}
+
+func (p *StorageProof) Value() hexutil.Big {
+	return p.value
+}
+
+func (p *StorageProof) Proof() []hexutil.Bytes {
+	return p.proof
+}
+
 // Log represents an individual log message. All arguments are mandatory.
 type Log struct {
 	backend     ethapi.Backend
@@ -341,6 +452,22 @@ func (t *Transaction) V(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*v), nil
 }
 
+func (t *Transaction) Trace(ctx context.Context) (*CallTrace, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil || t.block == nil {
+		return nil, err
+	}
+	block, err := t.block.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	frame, err := traceTransaction(ctx, t.backend, block, int(t.index))
+	if err != nil {
+		return nil, err
+	}
+	return &CallTrace{frame}, nil
+}
+
 type BlockType int
 
 // Block represents an Ethereum block.
@@ -896,9 +1023,60 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 	return ethapi.DoEstimateGas(ctx, p.backend, args.Data, pendingBlockNr, p.backend.RPCGasCap())
 }
 
+// TxPool represents the content of the transaction pool.
+type TxPool struct {
+	backend ethapi.Backend
+}
+
+func (p *TxPool) PendingCount(ctx context.Context) int32 {
+	pending, _ := p.backend.Stats()
+	return int32(pending)
+}
+
+func (p *TxPool) QueuedCount(ctx context.Context) int32 {
+	_, queued := p.backend.Stats()
+	return int32(queued)
+}
+
+func (p *TxPool) Pending(ctx context.Context, args struct{ From *common.Address }) []*Transaction {
+	pending, _ := p.backend.TxPoolContent()
+	return p.transactions(pending, args.From)
+}
+
+func (p *TxPool) Queued(ctx context.Context, args struct{ From *common.Address }) []*Transaction {
+	_, queued := p.backend.TxPoolContent()
+	return p.transactions(queued, args.From)
+}
+
+// transactions flattens the per-account content of the pool, ordered by sender
+// and nonce, optionally retaining only the transactions of a single sender.
+func (p *TxPool) transactions(content map[common.Address]types.Transactions, from *common.Address) []*Transaction {
+	senders := make([]common.Address, 0, len(content))
+	for sender := range content {
+		if from == nil || *from == sender {
+			senders = append(senders, sender)
+		}
+	}
+	sort.Slice(senders, func(i, j int) bool {
+		return bytes.Compare(senders[i][:], senders[j][:]) < 0
+	})
+	ret := []*Transaction{}
+	for _, sender := range senders {
+		for _, tx := range content[sender] {
+			ret = append(ret, &Transaction{
+				backend: p.backend,
+				hash:    tx.Hash(),
+				tx:      tx,
+			})
+		}
+	}
+	return ret
+}
+
 // Resolver is the top-level object in the GraphQL hierarchy.
 type Resolver struct {
 	backend ethapi.Backend
+	events  *filters.EventSystem // nil if the backend is unavailable
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
@@ -968,6 +1146,10 @@ func (r *Resolver) Pending(ctx context.Context) *Pending {
 	return &Pending{r.backend}
 }
 
+func (r *Resolver) TxPool(ctx context.Context) *TxPool {
+	return &TxPool{r.backend}
+}
+
 func (r *Resolver) Transaction(ctx context.Context, args struct{ Hash common.Hash }) (*Transaction, error) {
 	tx := &Transaction{
 		backend: r.backend,
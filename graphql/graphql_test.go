@@ -17,14 +17,29 @@
 package graphql
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/vars"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,7 +49,7 @@ func TestBuildSchema(t *testing.T) {
 		t.Fatalf("could not create new node: %v", err)
 	}
 	// Make sure the schema can be parsed and matched up to the object model.
-	if err := newHandler(stack, nil, []string{}, []string{}); err != nil {
+	if err := newHandler(stack, nil, []string{}, []string{}, []string{}); err != nil {
 		t.Errorf("Could not construct GraphQL handler: %v", err)
 	}
 }
@@ -90,6 +105,208 @@ func TestGraphQLHTTPOnSamePort_GQLRequest_Unsuccessful(t *testing.T) {
 	assert.Equal(t, "404 page not found\n", string(bodyBytes))
 }
 
+// Tests that the transaction pool can be inspected.
+func TestGraphQLTxPool(t *testing.T) {
+	stack := createNode(t, true)
+	defer stack.Close()
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	body := strings.NewReader("{\"query\": \"{txPool{pendingCount queuedCount pending{hash} queued{hash}}}\",\"variables\": null}")
+	gqlReq, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/graphql", "127.0.0.1:9393"), body)
+	if err != nil {
+		t.Error("could not issue new http request ", err)
+	}
+	gqlReq.Header.Set("Content-Type", "application/json")
+	resp := doHTTPRequest(t, gqlReq)
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("could not read from response body: %v", err)
+	}
+	expected := "{\"data\":{\"txPool\":{\"pendingCount\":0,\"queuedCount\":0,\"pending\":[],\"queued\":[]}}}"
+	assert.Equal(t, expected, string(bodyBytes))
+}
+
+// Tests that queries and subscriptions are served to websocket clients using the graphql-ws protocol.
+func TestGraphQLWebsocket(t *testing.T) {
+	stack := createNode(t, true)
+	defer stack.Close()
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	conn, _, err := dialer.Dial("ws://127.0.0.1:9393/graphql", nil)
+	if err != nil {
+		t.Fatalf("could not dial websocket: %v", err)
+	}
+	defer conn.Close()
+
+	send := func(msg string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("could not write message: %v", err)
+		}
+	}
+	expect := func(want string) {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("could not read message: %v", err)
+		}
+		assert.Equal(t, want, strings.TrimSpace(string(msg)))
+	}
+	send(`{"type":"connection_init","payload":{}}`)
+	expect(`{"type":"connection_ack"}`)
+	expect(`{"type":"ka"}`)
+
+	// Queries are answered with a single result.
+	send(`{"id":"1","type":"start","payload":{"query":"{block{number}}"}}`)
+	expect(`{"id":"1","type":"data","payload":{"data":{"block":{"number":"0x0"}}}}`)
+	expect(`{"id":"1","type":"complete"}`)
+
+	// Subscriptions run until stopped.
+	send(`{"id":"2","type":"start","payload":{"query":"subscription {newBlocks{number}}"}}`)
+	send(`{"id":"2","type":"stop"}`)
+	expect(`{"id":"2","type":"complete"}`)
+}
+
+// Tests that websocket connections are subject to the virtual host and origin checks.
+func TestGraphQLWebsocketRejected(t *testing.T) {
+	stack := createNode(t, true)
+	defer stack.Close()
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	for _, header := range []http.Header{
+		{"Host": []string{"evil.example"}},
+		{"Origin": []string{"http://evil.example"}},
+	} {
+		conn, _, err := dialer.Dial("ws://127.0.0.1:9393/graphql", header)
+		if err == nil {
+			conn.Close()
+			t.Errorf("websocket connection with header %v accepted", header)
+		}
+	}
+}
+
+func TestGraphQLWebsocketOrigins(t *testing.T) {
+	tests := []struct {
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{nil, "", true},
+		{nil, "http://localhost", true},
+		{nil, "http://localhost:3000", true},
+		{nil, "https://localhost:3000", false},
+		{nil, "http://localhost.evil.example", false},
+		{nil, "http://evil.example", false},
+		{[]string{"*"}, "http://evil.example", true},
+		{[]string{"http://example.org"}, "http://EXAMPLE.org:8080", true},
+		{[]string{"http://example.org:8080"}, "http://example.org:8080", true},
+		{[]string{"http://example.org:8080"}, "http://example.org:9090", false},
+		{[]string{"http://example.org:8080"}, "http://example.org", false},
+		{[]string{"http://[::1]"}, "http://[::1]:3000", true},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/graphql", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if have := wsOriginValidator(tt.allowed)(req); have != tt.want {
+			t.Errorf("test %d: origin %q with allowed %v: have %v, want %v", i, tt.origin, tt.allowed, have, tt.want)
+		}
+	}
+}
+
+// Tests that the call traces of mined transactions are available.
+func TestGraphQLTransactionTrace(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		callee  = common.HexToAddress("0x00000000000000000000000000000000000000ca")
+		genesis = &genesisT.Genesis{
+			Config:   params.AllEthashProtocolChanges,
+			GasLimit: 10000000,
+			Alloc: genesisT.GenesisAlloc{
+				addr: {Balance: big.NewInt(vars.Ether)},
+				// CALL(gas, 0xff, 0, 0, 0, 0, 0)
+				callee: {Balance: new(big.Int), Code: common.FromHex("600060006000600060007300000000000000000000000000000000000000ff5af100")},
+			},
+		}
+	)
+	stack, err := node.New(&node.Config{
+		HTTPHost: "127.0.0.1",
+		HTTPPort: 9393,
+	})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	defer stack.Close()
+	ethConf := eth.DefaultConfig
+	ethConf.Genesis = genesis
+	ethConf.Ethash.PowMode = ethash.ModeFake
+	ethBackend, err := eth.New(stack, &ethConf)
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	if err := New(stack, ethBackend.APIBackend, []string{}, []string{}, []string{}); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(genesis.Config, core.MustCommitGenesis(db, genesis), ethash.NewFaker(), db, 1, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), callee, big.NewInt(1), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+		gen.AddTx(tx)
+	})
+	if _, err := ethBackend.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("could not insert chain: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	body := strings.NewReader("{\"query\": \"{block(number: 1){transactions{trace{type to value calls{type from to calls{type}}}}}}\",\"variables\": null}")
+	gqlReq, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/graphql", "127.0.0.1:9393"), body)
+	if err != nil {
+		t.Error("could not issue new http request ", err)
+	}
+	gqlReq.Header.Set("Content-Type", "application/json")
+	resp := doHTTPRequest(t, gqlReq)
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("could not read from response body: %v", err)
+	}
+	expected := `{"data":{"block":{"transactions":[{"trace":{"type":"CALL","to":"0x00000000000000000000000000000000000000ca","value":"0x1",` +
+		`"calls":[{"type":"CALL","from":"0x00000000000000000000000000000000000000ca","to":"0x00000000000000000000000000000000000000ff","calls":[]}]}}]}}}`
+	assert.Equal(t, expected, string(bodyBytes))
+
+	// Account proofs are available for the state of the block.
+	body = strings.NewReader(fmt.Sprintf("{\"query\": \"{block(number: 1){account(address: \\\"%s\\\"){proof{nonce accountProof}}}}\",\"variables\": null}", addr.Hex()))
+	gqlReq, err = http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/graphql", "127.0.0.1:9393"), body)
+	if err != nil {
+		t.Error("could not issue new http request ", err)
+	}
+	gqlReq.Header.Set("Content-Type", "application/json")
+	resp = doHTTPRequest(t, gqlReq)
+	var result struct {
+		Data struct {
+			Block struct {
+				Account struct {
+					Proof struct {
+						Nonce        hexutil.Uint64
+						AccountProof []hexutil.Bytes
+					}
+				}
+			}
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("could not decode response body: %v", err)
+	}
+	proof := result.Data.Block.Account.Proof
+	assert.Equal(t, hexutil.Uint64(1), proof.Nonce)
+	assert.NotEmpty(t, proof.AccountProof)
+}
+
 func createNode(t *testing.T, gqlEnabled bool) *node.Node {
 	stack, err := node.New(&node.Config{
 		HTTPHost: "127.0.0.1",
@@ -117,7 +334,7 @@ func createGQLService(t *testing.T, stack *node.Node, endpoint string) {
 	}
 
 	// create gql service
-	err = New(stack, ethBackend.APIBackend, []string{}, []string{})
+	err = New(stack, ethBackend.APIBackend, []string{}, []string{}, []string{})
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
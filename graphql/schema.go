@@ -34,6 +34,7 @@ const schema string = `
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Ethereum account at a particular block.
@@ -52,6 +53,39 @@ const schema string = `
         # Storage provides access to the storage of a contract account, indexed
         # by its 32 byte slot identifier.
         storage(slot: Bytes32!): Bytes32!
+        # Proof returns the Merkle proof of this account, and of the given storage
+        # slots, against the state root of the account's block (see EIP-1186).
+        proof(slots: [Bytes32!]): AccountProof!
+    }
+
+    # AccountProof is a Merkle proof of an account and some of its storage slots.
+    type AccountProof {
+        # Address is the address owning the account.
+        address: Address!
+        # AccountProof is the list of RLP-encoded state trie nodes, from the
+        # state root to the account.
+        accountProof: [Bytes!]!
+        # Balance is the balance of the account, in wei.
+        balance: BigInt!
+        # CodeHash is the keccak256 hash of the code of the account.
+        codeHash: Bytes32!
+        # Nonce is the nonce of the account.
+        nonce: Long!
+        # StorageHash is the root hash of the storage trie of the account.
+        storageHash: Bytes32!
+        # StorageProof is the list of proofs for the requested storage slots.
+        storageProof: [StorageProof!]!
+    }
+
+    # StorageProof is a Merkle proof of a single storage slot.
+    type StorageProof {
+        # Key is the requested storage slot.
+        key: Bytes32!
+        # Value is the value of the storage slot.
+        value: BigInt!
+        # Proof is the list of RLP-encoded storage trie nodes, from the storage
+        # root to the slot.
+        proof: [Bytes!]!
     }
 
     # Log is an Ethereum event log.
@@ -118,6 +152,35 @@ const schema string = `
         r: BigInt!
         s: BigInt!
         v: BigInt!
+        # Trace is the call trace of this transaction, obtained by re-executing it
+        # on top of the state of its parent block. If the transaction has not yet
+        # been mined, this field will be null.
+        trace: CallTrace
+    }
+
+    # CallTrace is a (possibly nested) call made during the execution of a transaction.
+    type CallTrace {
+        # Type is the type of the call, eg. CALL, STATICCALL, CREATE or SELFDESTRUCT.
+        type: String!
+        # From is the address making the call.
+        from: Address!
+        # To is the address the call is sent to, or the created contract.
+        to: Address
+        # Value is the value, in wei, sent along with the call.
+        value: BigInt
+        # Gas is the amount of gas available to the call.
+        gas: Long!
+        # GasUsed is the amount of gas used by the call.
+        gasUsed: Long!
+        # Input is the data sent to the callee.
+        input: Bytes!
+        # Output is the data returned by the callee. This will be null if the call failed
+        # without reverting.
+        output: Bytes
+        # Error is the error encountered by the call, if any.
+        error: String
+        # Calls is the list of calls made by this call, in execution order.
+        calls: [CallTrace!]!
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -294,6 +357,22 @@ const schema string = `
       estimateGas(data: CallData!): Long!
     }
 
+    # TxPool is the content of the node's transaction pool.
+    type TxPool {
+        # PendingCount is the number of transactions which are executable
+        # on top of the current state.
+        pendingCount: Int!
+        # QueuedCount is the number of transactions which are not yet executable,
+        # eg. due to a nonce gap.
+        queuedCount: Int!
+        # Pending is the list of executable transactions, optionally restricted to
+        # those sent from the given address. Transactions are ordered by sender and nonce.
+        pending(from: Address): [Transaction!]!
+        # Queued is the list of non-executable transactions, optionally restricted to
+        # those sent from the given address. Transactions are ordered by sender and nonce.
+        queued(from: Address): [Transaction!]!
+    }
+
     type Query {
         # Block fetches an Ethereum block by number or by hash. If neither is
         # supplied, the most recent known block is returned.
@@ -316,10 +395,24 @@ const schema string = `
         syncing: SyncState
         # ChainID returns the current chain ID for transaction replay protection.
         chainID: BigInt!
+        # TxPool returns the content of the transaction pool.
+        txPool: TxPool!
     }
 
     type Mutation {
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # Subscriptions are available on the GraphQL endpoint to websocket clients
+    # using the graphql-ws protocol.
+    type Subscription {
+        # NewBlocks emits each block added to the head of the canonical chain.
+        newBlocks: Block!
+        # NewLogs emits the log entries of new blocks matching the provided filter.
+        # If the chain is reorganised, logs of removed blocks are not re-emitted.
+        newLogs(filter: BlockFilterCriteria!): Log!
+        # NewPendingTransactions emits each transaction added to the transaction pool.
+        newPendingTransactions: Transaction!
+    }
 `
@@ -17,32 +17,52 @@
 package graphql
 
 import (
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/gorilla/websocket"
 	"github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
 )
 
 // New constructs a new GraphQL service instance.
-func New(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
+func New(stack *node.Node, backend ethapi.Backend, cors, vhosts, wsOrigins []string) error {
 	if backend == nil {
 		panic("missing backend")
 	}
 	// check if http server with given endpoint exists and enable graphQL on it
-	return newHandler(stack, backend, cors, vhosts)
+	return newHandler(stack, backend, cors, vhosts, wsOrigins)
 }
 
-// newHandler returns a new `http.Handler` that will answer GraphQL queries.
+// newHandler returns a new `http.Handler` that will answer GraphQL queries,
+// and serve subscriptions to websocket clients.
 // It additionally exports an interactive query browser on the / endpoint.
-func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
-	q := Resolver{backend}
+func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts, wsOrigins []string) error {
+	q := Resolver{backend: backend}
+	if backend != nil {
+		q.events = filters.NewEventSystem(backend, false)
+		stack.RegisterLifecycle(&eventSystemLifecycle{q.events})
+	}
 
 	s, err := graphql.ParseSchema(schema, &q)
 	if err != nil {
 		return err
 	}
 	h := &relay.Handler{Schema: s}
-	handler := node.NewHTTPHandlerStack(h, cors, vhosts)
+	httpHandler := node.NewHTTPHandlerStack(h, cors, vhosts)
+
+	// Websocket connections are served subscriptions. They bypass the HTTP handler
+	// stack, as its response compression does not support connection upgrades.
+	wsHandler := node.NewWSHandlerStack(newWSHandler(s, wsOrigins), vhosts)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			wsHandler.ServeHTTP(w, r)
+			return
+		}
+		httpHandler.ServeHTTP(w, r)
+	})
 
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
 	stack.RegisterHandler("GraphQL", "/graphql", handler)
@@ -50,3 +70,16 @@ func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string)
 
 	return nil
 }
+
+// eventSystemLifecycle stops the event system serving subscriptions along with
+// the node.
+type eventSystemLifecycle struct {
+	events *filters.EventSystem
+}
+
+func (l *eventSystemLifecycle) Start() error { return nil }
+
+func (l *eventSystemLifecycle) Stop() error {
+	l.events.Stop()
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// traceTimeout is the amount of time a single transaction can execute
+// for before the trace is interrupted.
+const traceTimeout = 5 * time.Second
+
+// callFrame is a single call of a transaction, in the format produced by the
+// built-in callTracer.
+type callFrame struct {
+	Type    string          `json:"type"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to"`
+	Value   *hexutil.Big    `json:"value"`
+	Gas     hexutil.Uint64  `json:"gas"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Input   hexutil.Bytes   `json:"input"`
+	Output  *hexutil.Bytes  `json:"output"`
+	Error   *string         `json:"error"`
+	Calls   []*callFrame    `json:"calls"`
+}
+
+// CallTrace represents a call made during the execution of a transaction.
+type CallTrace struct {
+	frame *callFrame
+}
+
+func (c *CallTrace) Type() string {
+	return c.frame.Type
+}
+
+func (c *CallTrace) From() common.Address {
+	return c.frame.From
+}
+
+func (c *CallTrace) To() *common.Address {
+	return c.frame.To
+}
+
+func (c *CallTrace) Value() *hexutil.Big {
+	return c.frame.Value
+}
+
+func (c *CallTrace) Gas() hexutil.Uint64 {
+	return c.frame.Gas
+}
+
+func (c *CallTrace) GasUsed() hexutil.Uint64 {
+	return c.frame.GasUsed
+}
+
+func (c *CallTrace) Input() hexutil.Bytes {
+	return c.frame.Input
+}
+
+func (c *CallTrace) Output() *hexutil.Bytes {
+	return c.frame.Output
+}
+
+func (c *CallTrace) Error() *string {
+	return c.frame.Error
+}
+
+func (c *CallTrace) Calls() []*CallTrace {
+	ret := make([]*CallTrace, len(c.frame.Calls))
+	for i, frame := range c.frame.Calls {
+		ret[i] = &CallTrace{frame}
+	}
+	return ret
+}
+
+// traceTransaction re-executes the transactions of a block up to the given index
+// on top of the parent block's state, and returns the call trace of the transaction
+// at the index.
+func traceTransaction(ctx context.Context, backend ethapi.Backend, block *types.Block, index int) (*callFrame, error) {
+	statedb, _, err := backend.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(block.ParentHash(), false))
+	if err != nil {
+		return nil, err
+	}
+	if statedb == nil {
+		return nil, fmt.Errorf("state of parent %#x not available", block.ParentHash())
+	}
+	signer := types.MakeSigner(backend.ChainConfig(), block.Number())
+
+	for idx, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return nil, err
+		}
+		vmenv, _, err := backend.GetEVM(ctx, msg, statedb, block.Header())
+		if err != nil {
+			return nil, err
+		}
+		if idx == index {
+			return traceMessage(ctx, vmenv, msg, statedb)
+		}
+		// Not yet the searched for transaction, execute on top of the current state
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+		statedb.Finalise(vmenv.ChainConfig().IsEnabled(vmenv.ChainConfig().GetEIP161dTransition, block.Number()))
+	}
+	return nil, fmt.Errorf("transaction index %d out of range for block %#x", index, block.Hash())
+}
+
+// traceMessage executes the message in the environment of the given EVM with
+// the callTracer enabled.
+func traceMessage(ctx context.Context, vmenv *vm.EVM, msg core.Message, statedb *state.StateDB) (*callFrame, error) {
+	tracer, err := tracers.New("callTracer")
+	if err != nil {
+		return nil, err
+	}
+	// Handle timeouts and request cancellations
+	deadlineCtx, cancel := context.WithTimeout(ctx, traceTimeout)
+	go func() {
+		<-deadlineCtx.Done()
+		tracer.Stop(errors.New("execution timeout"))
+	}()
+	defer cancel()
+
+	tracingEnv := vm.NewEVM(vmenv.Context, statedb, vmenv.ChainConfig(), vm.Config{Debug: true, Tracer: tracer})
+	if _, err := core.ApplyMessage(tracingEnv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
+		return nil, fmt.Errorf("tracing failed: %v", err)
+	}
+	result, err := tracer.GetResult()
+	if err != nil {
+		return nil, err
+	}
+	frame := new(callFrame)
+	if err := json.Unmarshal(result, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
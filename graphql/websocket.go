@@ -0,0 +1,271 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// The websocket transport implements the graphql-ws protocol, as defined by
+// https://github.com/apollographql/subscriptions-transport-ws/blob/master/PROTOCOL.md
+const wsProtocol = "graphql-ws"
+
+// Message types of the graphql-ws protocol.
+const (
+	wsConnectionInit      = "connection_init"      // client -> server
+	wsConnectionTerminate = "connection_terminate" // client -> server
+	wsStart               = "start"                // client -> server
+	wsStop                = "stop"                 // client -> server
+	wsConnectionAck       = "connection_ack"       // server -> client
+	wsConnectionError     = "connection_error"     // server -> client
+	wsKeepAlive           = "ka"                   // server -> client
+	wsData                = "data"                 // server -> client
+	wsError               = "error"                // server -> client
+	wsComplete            = "complete"             // server -> client
+)
+
+const (
+	wsReadLimit         = 1024 * 1024
+	wsWriteTimeout      = 10 * time.Second
+	wsKeepAliveInterval = 30 * time.Second
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type wsOperation struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type wsErrorPayload struct {
+	Message string `json:"message"`
+}
+
+// wsHandler serves GraphQL subscriptions, queries and mutations to websocket clients.
+type wsHandler struct {
+	schema   *graphql.Schema
+	upgrader websocket.Upgrader
+}
+
+func newWSHandler(schema *graphql.Schema, allowedOrigins []string) *wsHandler {
+	return &wsHandler{
+		schema: schema,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{wsProtocol},
+			CheckOrigin:  wsOriginValidator(allowedOrigins),
+		},
+	}
+}
+
+// wsOriginValidator verifies the origin of browser websocket connections.
+// When '*' is specified as an allowed origin, all connections are accepted. If no origins
+// are specified, only localhost connections are accepted. Allowed origins without a port
+// match the same host on any port.
+func wsOriginValidator(allowedOrigins []string) func(*http.Request) bool {
+	origins := make(map[string]bool)
+	for _, origin := range allowedOrigins {
+		if origin != "" {
+			origins[strings.ToLower(origin)] = true
+		}
+	}
+	if len(origins) == 0 {
+		origins["http://localhost"] = true
+		if hostname, err := os.Hostname(); err == nil {
+			origins["http://"+strings.ToLower(hostname)] = true
+		}
+	}
+	return func(r *http.Request) bool {
+		// Non-browser clients need not send an origin, and can't be protected against by checking it.
+		if _, ok := r.Header["Origin"]; !ok {
+			return true
+		}
+		origin := strings.ToLower(r.Header.Get("Origin"))
+		if origins["*"] || origins[origin] {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && u.Port() != "" && origins[strings.TrimSuffix(origin, ":"+u.Port())] {
+			return true
+		}
+		log.Warn("Rejected GraphQL websocket connection", "origin", origin)
+		return false
+	}
+}
+
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL websocket upgrade failed", "err", err)
+		return
+	}
+	c := &wsConn{
+		conn:   conn,
+		schema: h.schema,
+		ops:    make(map[string]context.CancelFunc),
+	}
+	c.serve()
+}
+
+// wsConn is a single graphql-ws client connection.
+type wsConn struct {
+	conn   *websocket.Conn
+	schema *graphql.Schema
+
+	writeMu sync.Mutex // serialises writes to conn
+	opsMu   sync.Mutex
+	ops     map[string]context.CancelFunc // running operations, by id
+	wg      sync.WaitGroup
+}
+
+// serve reads and handles client messages until the connection is closed or terminated.
+func (c *wsConn) serve() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		c.wg.Wait()
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(wsReadLimit)
+
+	initialised := false
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			log.Trace("GraphQL websocket read failed", "err", err)
+			return
+		}
+		switch msg.Type {
+		case wsConnectionInit:
+			if initialised {
+				c.writeError(msg.ID, wsConnectionError, "connection already initialised")
+				continue
+			}
+			initialised = true
+			c.write(wsMessage{Type: wsConnectionAck})
+			c.write(wsMessage{Type: wsKeepAlive})
+			c.wg.Add(1)
+			go c.keepAlive(ctx)
+
+		case wsConnectionTerminate:
+			return
+
+		case wsStart:
+			var op wsOperation
+			if err := json.Unmarshal(msg.Payload, &op); err != nil {
+				c.writeError(msg.ID, wsError, err.Error())
+				continue
+			}
+			c.start(ctx, msg.ID, &op)
+
+		case wsStop:
+			c.opsMu.Lock()
+			if cancel, ok := c.ops[msg.ID]; ok {
+				cancel()
+			}
+			c.opsMu.Unlock()
+
+		default:
+			c.writeError(msg.ID, wsConnectionError, "unknown message type "+msg.Type)
+		}
+	}
+}
+
+// start executes an operation, writing all of its results to the client until
+// it is stopped or completes.
+func (c *wsConn) start(ctx context.Context, id string, op *wsOperation) {
+	c.opsMu.Lock()
+	if _, ok := c.ops[id]; ok {
+		c.opsMu.Unlock()
+		c.writeError(id, wsError, "duplicate operation id "+id)
+		return
+	}
+	opCtx, cancel := context.WithCancel(ctx)
+	c.ops[id] = cancel
+	c.opsMu.Unlock()
+
+	responses, err := c.schema.Subscribe(opCtx, op.Query, op.OperationName, op.Variables)
+	if err != nil {
+		c.finish(id, cancel)
+		c.writeError(id, wsError, err.Error())
+		return
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for response := range responses {
+			payload, err := json.Marshal(response)
+			if err != nil {
+				log.Debug("GraphQL websocket response encoding failed", "err", err)
+				continue
+			}
+			c.write(wsMessage{ID: id, Type: wsData, Payload: payload})
+		}
+		c.finish(id, cancel)
+		c.write(wsMessage{ID: id, Type: wsComplete})
+	}()
+}
+
+// finish releases a completed or stopped operation.
+func (c *wsConn) finish(id string, cancel context.CancelFunc) {
+	cancel()
+	c.opsMu.Lock()
+	delete(c.ops, id)
+	c.opsMu.Unlock()
+}
+
+func (c *wsConn) keepAlive(ctx context.Context) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(wsKeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.write(wsMessage{Type: wsKeepAlive})
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *wsConn) writeError(id string, typ string, message string) {
+	payload, _ := json.Marshal(wsErrorPayload{Message: message})
+	c.write(wsMessage{ID: id, Type: typ, Payload: payload})
+}
+
+func (c *wsConn) write(msg wsMessage) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		log.Trace("GraphQL websocket write failed", "err", err)
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package openrpc_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/internal/openrpc"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+	"github.com/ethereum/go-ethereum/params/vars"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// schemaValidator validates JSON values against the subset of JSON Schema used by
+// generated OpenRPC documents.
+type schemaValidator struct {
+	schemas map[string]interface{} // component schemas
+}
+
+func (v *schemaValidator) validate(schema interface{}, value interface{}, path string) error {
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: invalid schema %v", path, schema)
+	}
+	if ref, ok := s["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		target, ok := v.schemas[name]
+		if !ok {
+			return fmt.Errorf("%s: unresolved reference %s", path, ref)
+		}
+		return v.validate(target, value, path)
+	}
+	if oneOf, ok := s["oneOf"].([]interface{}); ok {
+		var matches int
+		for _, sub := range oneOf {
+			if v.validate(sub, value, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("%s: value %v matches %d of oneOf schemas %v", path, value, matches, oneOf)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		var matched bool
+		for _, sub := range anyOf {
+			if v.validate(sub, value, path) == nil {
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: value %v matches none of anyOf schemas %v", path, value, anyOf)
+		}
+	}
+	if typ, ok := s["type"]; ok {
+		var types []interface{}
+		switch typ := typ.(type) {
+		case string:
+			types = []interface{}{typ}
+		case []interface{}:
+			types = typ
+		}
+		var matched bool
+		for _, t := range types {
+			if jsonType(value, t.(string)) {
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: value %v is not of type %v", path, value, typ)
+		}
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		var matched bool
+		for _, e := range enum {
+			if reflect.DeepEqual(e, value) {
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: value %v not in enum %v", path, value, enum)
+		}
+	}
+	switch value := value.(type) {
+	case string:
+		if pattern, ok := s["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(value) {
+			return fmt.Errorf("%s: value %q does not match pattern %s", path, value, pattern)
+		}
+	case []interface{}:
+		if items, ok := s["items"]; ok {
+			for i, item := range value {
+				if err := v.validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+		if min, ok := s["minItems"].(float64); ok && len(value) < int(min) {
+			return fmt.Errorf("%s: too few items", path)
+		}
+		if max, ok := s["maxItems"].(float64); ok && len(value) > int(max) {
+			return fmt.Errorf("%s: too many items", path)
+		}
+	case map[string]interface{}:
+		if required, ok := s["required"].([]interface{}); ok {
+			for _, r := range required {
+				if _, ok := value[r.(string)]; !ok {
+					return fmt.Errorf("%s: missing required property %s", path, r)
+				}
+			}
+		}
+		properties, _ := s["properties"].(map[string]interface{})
+		for k, pv := range value {
+			if ps, ok := properties[k]; ok {
+				if err := v.validate(ps, pv, path+"."+k); err != nil {
+					return err
+				}
+			} else if as, ok := s["additionalProperties"]; ok {
+				if err := v.validate(as, pv, path+"."+k); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func jsonType(value interface{}, typ string) bool {
+	switch typ {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return false
+}
+
+// TestDiscoverConformance calls methods of a running node, validating their parameters
+// and results against the schemas of the node's OpenRPC document.
+func TestDiscoverConformance(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = &genesisT.Genesis{
+			Config:   params.AllEthashProtocolChanges,
+			GasLimit: 10000000,
+			Alloc:    genesisT.GenesisAlloc{addr: {Balance: big.NewInt(vars.Ether)}},
+		}
+	)
+	if err := rpc.SetDefaultOpenRPCSchemaRaw(openrpc.OpenRPCSchema); err != nil {
+		t.Fatal(err)
+	}
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	defer stack.Close()
+	ethConf := eth.DefaultConfig
+	ethConf.Genesis = genesis
+	ethConf.Ethash.PowMode = ethash.ModeFake
+	ethBackend, err := eth.New(stack, &ethConf)
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(genesis.Config, core.MustCommitGenesis(db, genesis), ethash.NewFaker(), db, 2, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{0xaa}, big.NewInt(1), 21000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+		gen.AddTx(tx)
+	})
+	if _, err := ethBackend.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("could not insert chain: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	client, err := stack.Attach()
+	if err != nil {
+		t.Fatalf("could not attach to node: %v", err)
+	}
+	defer client.Close()
+
+	var doc rpc.OpenRPCDiscoverSchemaT
+	if err := client.Call(&doc, "rpc_discover"); err != nil {
+		t.Fatalf("could not discover: %v", err)
+	}
+	methods := make(map[string]map[string]interface{})
+	for _, m := range doc.Methods {
+		methods[m["name"].(string)] = m
+	}
+	schemas, _ := doc.Components["schemas"].(map[string]interface{})
+	validator := &schemaValidator{schemas: schemas}
+
+	var (
+		txHash    = blocks[0].Transactions()[0].Hash()
+		blockHash = blocks[1].Hash()
+		call      = map[string]interface{}{"from": addr, "to": common.Address{0xaa}, "value": "0x1"}
+	)
+	tests := []struct {
+		method string
+		params []interface{}
+	}{
+		{"web3_clientVersion", nil},
+		{"web3_sha3", []interface{}{"0x01"}},
+		{"net_version", nil},
+		{"rpc_modules", nil},
+		{"eth_blockNumber", nil},
+		{"eth_chainId", nil},
+		{"eth_gasPrice", nil},
+		{"eth_syncing", nil},
+		{"eth_mining", nil},
+		{"eth_getBalance", []interface{}{addr, "latest"}},
+		{"eth_getBalance", []interface{}{addr, blockHash}},
+		{"eth_getTransactionCount", []interface{}{addr, "0x1"}},
+		{"eth_getCode", []interface{}{addr, "latest"}},
+		{"eth_getStorageAt", []interface{}{addr, "0x0", "latest"}},
+		{"eth_getBlockByNumber", []interface{}{"0x1", true}},
+		{"eth_getBlockByNumber", []interface{}{"0x2", false}},
+		{"eth_getBlockByNumber", []interface{}{"0x10", false}},
+		{"eth_getBlockByHash", []interface{}{blockHash, true}},
+		{"eth_getBlockTransactionCountByNumber", []interface{}{"0x1"}},
+		{"eth_getUncleCountByBlockHash", []interface{}{blockHash}},
+		{"eth_getTransactionByHash", []interface{}{txHash}},
+		{"eth_getTransactionByBlockNumberAndIndex", []interface{}{"0x1", "0x0"}},
+		{"eth_getRawTransactionByHash", []interface{}{txHash}},
+		{"eth_getTransactionReceipt", []interface{}{txHash}},
+		{"eth_getProof", []interface{}{addr, []string{"0x0"}, "latest"}},
+		{"eth_getLogs", []interface{}{map[string]interface{}{"fromBlock": "0x0"}}},
+		{"eth_call", []interface{}{call, "latest"}},
+		{"eth_estimateGas", []interface{}{call}},
+		{"eth_pendingTransactions", nil},
+		{"eth_newBlockFilter", nil},
+		{"ethash_submitHashRate", []interface{}{"0x1", common.Hash{0x01}}},
+		{"debug_traceTransaction", []interface{}{txHash}},
+		{"debug_getBadBlocks", nil},
+		{"debug_getModifiedAccountsByNumber", []interface{}{1}},
+		{"trace_block", []interface{}{"0x1"}},
+		{"trace_transaction", []interface{}{txHash}},
+		{"admin_nodeInfo", nil},
+		{"admin_peers", nil},
+		{"txpool_status", nil},
+		{"txpool_content", nil},
+	}
+	for _, test := range tests {
+		method, ok := methods[test.method]
+		if !ok {
+			t.Errorf("%s: method not described", test.method)
+			continue
+		}
+		// Validate the parameters
+		params := method["params"].([]interface{})
+		if len(test.params) > len(params) {
+			t.Errorf("%s: too many parameters", test.method)
+			continue
+		}
+		for i, param := range params {
+			param := param.(map[string]interface{})
+			if i >= len(test.params) {
+				if param["required"] == true {
+					t.Errorf("%s: missing required parameter %v", test.method, param["name"])
+				}
+				continue
+			}
+			var value interface{}
+			enc, _ := json.Marshal(test.params[i])
+			json.Unmarshal(enc, &value)
+			if err := validator.validate(param["schema"], value, fmt.Sprintf("%s.params[%d]", test.method, i)); err != nil {
+				t.Error(err)
+			}
+		}
+		// Validate the result
+		var raw json.RawMessage
+		if err := client.Call(&raw, test.method, test.params...); err != nil {
+			t.Errorf("%s: call failed: %v", test.method, err)
+			continue
+		}
+		var value interface{}
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		result := method["result"].(map[string]interface{})
+		if err := validator.validate(result["schema"], value, test.method+".result"); err != nil {
+			t.Error(err)
+		}
+	}
+}
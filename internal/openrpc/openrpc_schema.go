@@ -18,8 +18,9 @@ package openrpc
 
 // This file contains a string constant containing the JSON schema data for OpenRPC.
 
-// OpenRPCSchema defines the default full suite of possibly available go-ethereum RPC
-// methods.
+// OpenRPCSchema documents the default full suite of possibly available go-ethereum RPC
+// methods. The rpc.discover documents served by the node are generated from the available
+// methods, using this document for their summaries and descriptions.
 const OpenRPCSchema = `
 {
     "openrpc": "1.0.0",
@@ -17,8 +17,11 @@
 package utesting
 
 import (
+	"bytes"
+	"encoding/xml"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestTest(t *testing.T) {
@@ -53,3 +56,45 @@ func TestTest(t *testing.T) {
 		t.Fatalf("wrong result for panicking test: %#v", results[2])
 	}
 }
+
+func TestJUnitReport(t *testing.T) {
+	results := []Result{
+		{Name: "passing", Output: "log output\n", Duration: 1500 * time.Millisecond},
+		{Name: "failing", Failed: true, Output: "bad <value>\n", Duration: 20 * time.Millisecond},
+	}
+	var buf bytes.Buffer
+	if err := WriteJUnitReport(&buf, "suite", results); err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Suites []struct {
+			Name     string `xml:"name,attr"`
+			Tests    int    `xml:"tests,attr"`
+			Failures int    `xml:"failures,attr"`
+			Cases    []struct {
+				Name    string `xml:"name,attr"`
+				Time    string `xml:"time,attr"`
+				Failure *struct {
+					Output string `xml:",chardata"`
+				} `xml:"failure"`
+				SystemOut string `xml:"system-out"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid report: %v\n%s", err, buf.String())
+	}
+	if len(report.Suites) != 1 {
+		t.Fatalf("wrong number of suites: %d", len(report.Suites))
+	}
+	suite := report.Suites[0]
+	if suite.Name != "suite" || suite.Tests != 2 || suite.Failures != 1 || len(suite.Cases) != 2 {
+		t.Fatalf("wrong suite summary: %+v", suite)
+	}
+	if c := suite.Cases[0]; c.Name != "passing" || c.Time != "1.500" || c.Failure != nil || c.SystemOut != "log output\n" {
+		t.Errorf("wrong passing test case: %+v", c)
+	}
+	if c := suite.Cases[1]; c.Name != "failing" || c.Failure == nil || c.Failure.Output != "bad <value>\n" {
+		t.Errorf("wrong failing test case: %+v", c)
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+)
+
+// classicGenesis returns a genesis for a simulated chain running the Classic
+// chain configuration, with ECBP1100 artificial finality activated from the
+// start.
+func classicGenesis() *genesisT.Genesis {
+	config := *params.ClassicChainConfig
+	config.ECBP1100FBlock = big.NewInt(0)
+
+	return &genesisT.Genesis{
+		Config:   &config,
+		Alloc:    genesisT.GenesisAlloc{bankAddr: {Balance: bankFunds}},
+		GasLimit: 100000000,
+	}
+}
+
+// classicServer is a les server serving a simulated Classic chain.
+type classicServer struct {
+	db      ethdb.Database
+	chain   *core.BlockChain
+	handler *serverHandler
+
+	chtIndexer       *core.ChainIndexer
+	bloomIndexer     *core.ChainIndexer
+	bloomTrieIndexer *core.ChainIndexer
+}
+
+// newClassicServer creates a les server for the given chain segment built on
+// top of the genesis, waiting for its indexers to finish if sections is non-zero.
+func newClassicServer(t *testing.T, gspec *genesisT.Genesis, blocks []*types.Block, sections uint64) *classicServer {
+	db := rawdb.NewMemoryDatabase()
+	core.MustCommitGenesis(db, gspec)
+
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	indexers := testIndexers(db, nil, light.TestServerIndexerConfig, true)
+	indexers[0].Start(chain)
+	indexers[1].Start(chain)
+	for {
+		cs, _, _ := indexers[0].Sections()
+		bts, _, _ := indexers[2].Sections()
+		if cs >= sections && bts >= sections {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	handler := newTestLesServer(chain, db, nil, newClientPeerSet(), &mclock.System{})
+	handler.start()
+
+	return &classicServer{
+		db:               db,
+		chain:            chain,
+		handler:          handler,
+		chtIndexer:       indexers[0],
+		bloomIndexer:     indexers[1],
+		bloomTrieIndexer: indexers[2],
+	}
+}
+
+func (s *classicServer) close() {
+	s.handler.stop()
+	s.chtIndexer.Close()
+	s.bloomIndexer.Close()
+	s.chain.Stop()
+}
+
+// checkpoint assembles a trusted checkpoint from the latest local section.
+func (s *classicServer) checkpoint() *ctypes.TrustedCheckpoint {
+	sections, _, head := s.chtIndexer.Sections()
+	return &ctypes.TrustedCheckpoint{
+		SectionIndex: sections - 1,
+		SectionHead:  head,
+		CHTRoot:      light.GetChtRoot(s.db, sections-1, head),
+		BloomRoot:    light.GetBloomTrieRoot(s.db, sections-1, head),
+	}
+}
+
+// classicClient is a les client following a simulated Classic chain.
+type classicClient struct {
+	odr     *LesOdr
+	chain   *light.LightChain
+	handler *clientHandler
+	synced  chan *types.Header
+
+	chtIndexer   *core.ChainIndexer
+	bloomIndexer *core.ChainIndexer
+}
+
+// newClassicClient creates a les client for the chain of the genesis, syncing
+// from the given hardcoded checkpoint if it's non-nil.
+func newClassicClient(t *testing.T, gspec *genesisT.Genesis, checkpoint *ctypes.TrustedCheckpoint) *classicClient {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		peers  = newServerPeerSet()
+		dist   = newRequestDistributor(peers, &mclock.System{})
+		rm     = newRetrieveManager(peers, dist, func() time.Duration { return time.Millisecond * 500 })
+		odr    = NewLesOdr(db, light.TestClientIndexerConfig, rm)
+		engine = ethash.NewFaker()
+	)
+	indexers := testIndexers(db, odr, light.TestClientIndexerConfig, true)
+	odr.SetIndexers(indexers[0], indexers[1], indexers[2])
+
+	core.MustCommitGenesis(db, gspec)
+	chain, err := light.NewLightChain(odr, gspec.Config, engine, checkpoint)
+	if err != nil {
+		t.Fatalf("failed to create light chain: %v", err)
+	}
+	handler := newTestLesClient(chain, engine, odr, db, nil, peers, nil, 0)
+	handler.checkpoint = checkpoint
+
+	synced := make(chan *types.Header, 1)
+	handler.syncDone = func() { synced <- chain.CurrentHeader() }
+	handler.start()
+
+	indexers[0].Start(chain)
+	indexers[1].Start(chain)
+
+	return &classicClient{
+		odr:          odr,
+		chain:        chain,
+		handler:      handler,
+		synced:       synced,
+		chtIndexer:   indexers[0],
+		bloomIndexer: indexers[1],
+	}
+}
+
+func (c *classicClient) close() {
+	c.handler.stop()
+	c.chtIndexer.Close()
+	c.bloomIndexer.Close()
+}
+
+// connect connects the client to the server and waits for the triggered sync
+// to finish, returning the head of the client after syncing.
+func (c *classicClient) connect(t *testing.T, name string, s *classicServer) *types.Header {
+	speer, cpeer, err := newTestPeerPair(name, lpv3, s.handler, c.handler)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		speer.close()
+		cpeer.close()
+	})
+	select {
+	case head := <-c.synced:
+		return head
+	case <-time.After(10 * time.Second):
+		t.Fatalf("sync with %s timed out", name)
+	}
+	return nil
+}
+
+// Tests that light clients can sync a Classic chain from a hardcoded checkpoint,
+// retrieving headers and total difficulties below it through the CHT.
+func TestClassicCheckpointSyncing(t *testing.T) {
+	var (
+		gspec   = classicGenesis()
+		config  = light.TestServerIndexerConfig
+		gendb   = rawdb.NewMemoryDatabase()
+		genesis = core.MustCommitGenesis(gendb, gspec)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, int(config.ChtSize+config.ChtConfirms), nil)
+
+	server := newClassicServer(t, gspec, blocks, 1)
+	defer server.close()
+
+	client := newClassicClient(t, gspec, server.checkpoint())
+	defer client.close()
+
+	if head := client.connect(t, "server", server); head.Hash() != server.chain.CurrentHeader().Hash() {
+		t.Fatalf("head mismatch: have %d, want %d", head.Number, server.chain.CurrentHeader().Number)
+	}
+	// The headers below the checkpoint were not downloaded, retrieve one
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	want := blocks[9].Header()
+	if client.chain.GetHeaderByNumber(want.Number.Uint64()) != nil {
+		t.Fatalf("header %d below checkpoint downloaded", want.Number)
+	}
+	header, err := light.GetHeaderByNumber(ctx, client.odr, want.Number.Uint64())
+	if err != nil {
+		t.Fatalf("failed to retrieve header: %v", err)
+	}
+	if header.Hash() != want.Hash() {
+		t.Fatalf("header mismatch: have %x, want %x", header.Hash(), want.Hash())
+	}
+	if have, want := client.chain.GetTdOdr(ctx, header.Hash(), header.Number.Uint64()), server.chain.GetTd(want.Hash(), want.Number.Uint64()); have == nil || have.Cmp(want) != 0 {
+		t.Fatalf("total difficulty mismatch: have %v, want %v", have, want)
+	}
+}
+
+func TestClassicArtificialFinality(t *testing.T)         { testClassicArtificialFinality(t, true) }
+func TestClassicArtificialFinalityDisabled(t *testing.T) { testClassicArtificialFinality(t, false) }
+
+// testClassicArtificialFinality tests that light clients synced to a Classic
+// chain refuse to reorg onto a heavier competing chain if artificial finality
+// is enabled, and follow it otherwise.
+func testClassicArtificialFinality(t *testing.T, enable bool) {
+	defer func(min int) { minArtificialFinalityPeers = min }(minArtificialFinalityPeers)
+	minArtificialFinalityPeers = 1
+	if !enable {
+		minArtificialFinalityPeers = 100
+	}
+	var (
+		gspec   = classicGenesis()
+		gendb   = rawdb.NewMemoryDatabase()
+		genesis = core.MustCommitGenesis(gendb, gspec)
+	)
+	// Generate a chain and a competing one forking off 200 blocks in the past,
+	// heavier, but not enough to overcome ECBP1100.
+	honest, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 300, nil)
+	attack, _ := core.GenerateChain(gspec.Config, honest[99], ethash.NewFaker(), gendb, 260, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{0xa})
+	})
+	attack = append(honest[:100:100], attack...)
+
+	honestServer := newClassicServer(t, gspec, honest, 0)
+	defer honestServer.close()
+	attackServer := newClassicServer(t, gspec, attack, 0)
+	defer attackServer.close()
+
+	if honestServer.chain.GetTd(honest[299].Hash(), 300).Cmp(attackServer.chain.GetTd(attack[359].Hash(), 360)) >= 0 {
+		t.Fatal("competing chain not heavier")
+	}
+	client := newClassicClient(t, gspec, nil)
+	defer client.close()
+
+	if head := client.connect(t, "honest", honestServer); head.Hash() != honest[299].Hash() {
+		t.Fatalf("head mismatch after initial sync: have %d", head.Number)
+	}
+	if have := client.chain.IsArtificialFinalityEnabled(); have != enable {
+		t.Fatalf("artificial finality mismatch after sync: have %v, want %v", have, enable)
+	}
+	want := attack[359].Hash()
+	if enable {
+		want = honest[299].Hash()
+	}
+	if head := client.connect(t, "attack", attackServer); head.Hash() != want {
+		t.Fatalf("head mismatch after competing sync: have %d %x, want %x", head.Number, head.Hash(), want)
+	}
+}
@@ -43,8 +43,6 @@ import (
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/params/confp"
-	"github.com/ethereum/go-ethereum/params/types/coregeth"
-	"github.com/ethereum/go-ethereum/params/types/goethereum"
 	"github.com/ethereum/go-ethereum/params/vars"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -133,11 +131,7 @@ func New(stack *node.Node, config *eth.Config) (*LightEthereum, error) {
 
 	checkpoint := config.Checkpoint
 	if checkpoint == nil {
-		if p, ok := chainConfig.(*coregeth.CoreGethChainConfig); ok {
-			checkpoint = p.TrustedCheckpoint
-		} else if p, ok := chainConfig.(*goethereum.ChainConfig); ok {
-			checkpoint = p.TrustedCheckpoint
-		}
+		checkpoint = chainConfig.GetTrustedCheckpoint()
 	}
 	// Note: NewLightChain adds the trusted checkpoint so it needs an ODR with
 	// indexers already set but not started yet
@@ -148,7 +142,10 @@ func New(stack *node.Node, config *eth.Config) (*LightEthereum, error) {
 	leth.txPool = light.NewTxPool(leth.chainConfig, leth.blockchain, leth.relay)
 
 	// Set up checkpoint oracle.
-	leth.oracle = leth.setupOracle(stack, genesisHash, config)
+	leth.oracle = leth.setupOracle(stack, chainConfig, config)
+	if checkpoint == nil && leth.oracle == nil {
+		log.Warn("No trusted checkpoint available, light sync starts from genesis", "chain", chainConfig.GetChainID())
+	}
 
 	// Note: AddChildIndexer starts the update process for the child
 	leth.bloomIndexer.AddChildIndexer(leth.bloomTrieIndexer)
@@ -73,6 +73,9 @@ func newClientHandler(ulcServers []string, ulcFraction int, checkpoint *ctypes.T
 
 func (h *clientHandler) start() {
 	h.fetcher.start()
+
+	h.wg.Add(1)
+	go h.artificialFinalitySafetyLoop()
 }
 
 func (h *clientHandler) stop() {
@@ -125,6 +128,11 @@ func (h *clientHandler) handle(p *serverPeer) error {
 		h.backend.peers.unregister(p.id)
 		connectionTimer.Update(time.Duration(mclock.Now() - connectedAt))
 		serverConnectionGauge.Update(int64(h.backend.peers.len()))
+
+		// Disable artificial finality if we're at risk of being eclipsed.
+		if peers := h.backend.peers.len(); peers < minArtificialFinalityPeers && h.backend.blockchain.IsArtificialFinalityEnabled() {
+			h.backend.blockchain.EnableArtificialFinality(false, "reason", "low peers", "peers", peers)
+		}
 	}()
 	h.fetcher.announce(p, &announceData{Hash: p.headInfo.Hash, Number: p.headInfo.Number, Td: p.headInfo.Td})
 
@@ -35,7 +35,6 @@ import (
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/discv5"
 	"github.com/ethereum/go-ethereum/p2p/enode"
-	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
 )
 
@@ -151,11 +150,13 @@ func (c *lesCommons) localCheckpoint(index uint64) ctypes.TrustedCheckpoint {
 }
 
 // setupOracle sets up the checkpoint oracle contract client.
-func (c *lesCommons) setupOracle(node *node.Node, genesis common.Hash, ethconfig *eth.Config) *checkpointoracle.CheckpointOracle {
+func (c *lesCommons) setupOracle(node *node.Node, chainConfig ctypes.ChainConfigurator, ethconfig *eth.Config) *checkpointoracle.CheckpointOracle {
 	config := ethconfig.CheckpointOracle
 	if config == nil {
-		// Try loading default config.
-		config = params.CheckpointOracles[genesis]
+		// Try loading the oracle configured for the chain. The oracles can't be
+		// looked up by genesis hash alone, as e.g. Ethereum and Ethereum Classic
+		// share their genesis block.
+		config = chainConfig.GetTrustedCheckpointOracle()
 	}
 	if config == nil {
 		log.Info("Checkpoint registrar is not enabled")
@@ -26,10 +26,23 @@ import (
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params/vars"
 )
 
 var errInvalidCheckpoint = errors.New("invalid advertised checkpoint")
 
+var (
+	// minArtificialFinalityPeers defines the minimum number of servers the light client must be
+	// connected to in order to enable artificial finality features. It's lower than the full node
+	// equivalent, since light clients are usually connected to a handful of servers only.
+	minArtificialFinalityPeers = 3
+
+	// artificialFinalitySafetyInterval defines the interval at which the local head is checked for staleness.
+	// If the head is found to be stale across this interval, artificial finality features are disabled.
+	// This prevents an abandoned victim of an eclipse attack from being forever destitute.
+	artificialFinalitySafetyInterval = time.Second * time.Duration(30*vars.DurationLimit.Uint64())
+)
+
 const (
 	// lightSync starts syncing from the current highest block.
 	// If the chain is empty, syncing the entire header chain.
@@ -183,4 +196,32 @@ func (h *clientHandler) synchronise(peer *serverPeer) {
 		return
 	}
 	log.Debug("Synchronise finished", "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Enable artificial finality once synced with enough servers.
+	if peers := h.backend.peers.len(); peers >= minArtificialFinalityPeers && !h.backend.blockchain.IsArtificialFinalityEnabled() {
+		h.backend.blockchain.EnableArtificialFinality(true, "reason", "synced", "peers", peers)
+	}
+}
+
+// artificialFinalitySafetyLoop compares the local head across timer intervals.
+// If it doesn't change, we've stalled syncing for some reason, and artificial
+// finality is disabled in case that's keeping us on a dead chain.
+func (h *clientHandler) artificialFinalitySafetyLoop() {
+	defer h.wg.Done()
+
+	t := time.NewTicker(artificialFinalitySafetyInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if h.backend.blockchain.IsArtificialFinalityEnabled() {
+				if time.Since(time.Unix(int64(h.backend.blockchain.CurrentHeader().Time), 0)) > artificialFinalitySafetyInterval {
+					h.backend.blockchain.EnableArtificialFinality(false, "reason", "stale safety interval", "interval", artificialFinalitySafetyInterval)
+				}
+			}
+		case <-h.closeCh:
+			return
+		}
+	}
 }
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/contracts/checkpointoracle/contract"
 	"github.com/ethereum/go-ethereum/core"
@@ -173,7 +174,6 @@ func testIndexers(db ethdb.Database, odr light.OdrBackend, config *light.Indexer
 
 func newTestClientHandler(backend *backends.SimulatedBackend, odr *LesOdr, indexers []*core.ChainIndexer, db ethdb.Database, peers *serverPeerSet, ulcServers []string, ulcFraction int) *clientHandler {
 	var (
-		evmux  = new(event.TypeMux)
 		engine = ethash.NewFaker()
 		gspec  = genesisT.Genesis{
 			Config:   params.AllEthashProtocolChanges,
@@ -182,7 +182,7 @@ func newTestClientHandler(backend *backends.SimulatedBackend, odr *LesOdr, index
 		}
 		oracle *checkpointoracle.CheckpointOracle
 	)
-	genesis := core.MustCommitGenesis(db, &gspec)
+	core.MustCommitGenesis(db, &gspec)
 	chain, _ := light.NewLightChain(odr, gspec.Config, engine, nil)
 	if indexers != nil {
 		checkpointConfig := &ctypes.CheckpointOracleConfig{
@@ -202,11 +202,22 @@ func newTestClientHandler(backend *backends.SimulatedBackend, odr *LesOdr, index
 		}
 		oracle = checkpointoracle.New(checkpointConfig, getLocal)
 	}
+	client := newTestLesClient(chain, engine, odr, db, oracle, peers, ulcServers, ulcFraction)
+	if oracle != nil {
+		oracle.Start(backend)
+	}
+	client.start()
+	return client
+}
+
+// newTestLesClient assembles a les client handler on top of the given light
+// chain. The handler is not started.
+func newTestLesClient(chain *light.LightChain, engine consensus.Engine, odr *LesOdr, db ethdb.Database, oracle *checkpointoracle.CheckpointOracle, peers *serverPeerSet, ulcServers []string, ulcFraction int) *clientHandler {
 	client := &LightEthereum{
 		lesCommons: lesCommons{
-			genesis:     genesis.Hash(),
+			genesis:     chain.Genesis().Hash(),
 			config:      &eth.Config{LightPeers: 100, NetworkId: NetworkId},
-			chainConfig: params.AllEthashProtocolChanges,
+			chainConfig: chain.Config(),
 			iConfig:     light.TestClientIndexerConfig,
 			chainDb:     db,
 			oracle:      oracle,
@@ -219,14 +230,9 @@ func newTestClientHandler(backend *backends.SimulatedBackend, odr *LesOdr, index
 		odr:        odr,
 		engine:     engine,
 		blockchain: chain,
-		eventMux:   evmux,
+		eventMux:   new(event.TypeMux),
 	}
 	client.handler = newClientHandler(ulcServers, ulcFraction, nil, client)
-
-	if client.oracle != nil {
-		client.oracle.Start(backend)
-	}
-	client.handler.start()
 	return client.handler
 }
 
@@ -239,15 +245,12 @@ func newTestServerHandler(blocks int, indexers []*core.ChainIndexer, db ethdb.Da
 		}
 		oracle *checkpointoracle.CheckpointOracle
 	)
-	genesis := core.MustCommitGenesis(db, &gspec)
+	core.MustCommitGenesis(db, &gspec)
 
 	// create a simulation backend and pre-commit several customized block to the database.
 	simulation := backends.NewSimulatedBackendWithDatabase(db, gspec.Alloc, 100000000)
 	prepare(blocks, simulation)
 
-	txpoolConfig := core.DefaultTxPoolConfig
-	txpoolConfig.Journal = ""
-	txpool := core.NewTxPool(txpoolConfig, gspec.Config, simulation.Blockchain())
 	if indexers != nil {
 		checkpointConfig := &ctypes.CheckpointOracleConfig{
 			Address:   crypto.CreateAddress(bankAddr, 0),
@@ -266,14 +269,29 @@ func newTestServerHandler(blocks int, indexers []*core.ChainIndexer, db ethdb.Da
 		}
 		oracle = checkpointoracle.New(checkpointConfig, getLocal)
 	}
+	server := newTestLesServer(simulation.Blockchain(), db, oracle, peers, clock)
+	if oracle != nil {
+		oracle.Start(simulation)
+	}
+	server.start()
+	return server, simulation
+}
+
+// newTestLesServer assembles a les server handler serving the given chain. The
+// handler is not started.
+func newTestLesServer(chain *core.BlockChain, db ethdb.Database, oracle *checkpointoracle.CheckpointOracle, peers *clientPeerSet, clock mclock.Clock) *serverHandler {
+	txpoolConfig := core.DefaultTxPoolConfig
+	txpoolConfig.Journal = ""
+	txpool := core.NewTxPool(txpoolConfig, chain.Config(), chain)
+
 	server := &LesServer{
 		lesCommons: lesCommons{
-			genesis:     genesis.Hash(),
+			genesis:     chain.Genesis().Hash(),
 			config:      &eth.Config{LightPeers: 100, NetworkId: NetworkId},
-			chainConfig: params.AllEthashProtocolChanges,
+			chainConfig: chain.Config(),
 			iConfig:     light.TestServerIndexerConfig,
 			chainDb:     db,
-			chainReader: simulation.Blockchain(),
+			chainReader: chain,
 			oracle:      oracle,
 			closeCh:     make(chan struct{}),
 		},
@@ -289,13 +307,9 @@ func newTestServerHandler(blocks int, indexers []*core.ChainIndexer, db ethdb.Da
 	server.costTracker.testCostList = testCostList(0) // Disable flow control mechanism.
 	server.clientPool = newClientPool(db, testBufRecharge, defaultConnectedBias, clock, func(id enode.ID) {})
 	server.clientPool.setLimits(10000, 10000) // Assign enough capacity for clientpool
-	server.handler = newServerHandler(server, simulation.Blockchain(), db, txpool, func() bool { return true })
-	if server.oracle != nil {
-		server.oracle.Start(simulation)
-	}
+	server.handler = newServerHandler(server, chain, db, txpool, func() bool { return true })
 	server.servingQueue.setThreads(4)
-	server.handler.start()
-	return server.handler, simulation
+	return server.handler
 }
 
 // testPeer is a simulated peer to allow testing direct network calls.
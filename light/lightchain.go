@@ -580,3 +580,17 @@ func (lc *LightChain) DisableCheckFreq() {
 func (lc *LightChain) EnableCheckFreq() {
 	atomic.StoreInt32(&lc.disableCheckFreq, 0)
 }
+
+// EnableArtificialFinality enables and disables artificial finality features
+// (ECBP1100-MESS) for the light chain, if activated by the chain configuration.
+// Since the arbitration of reorgs relies on the total difficulties of the chain
+// segments, reorgs whose segments have no known total difficulty are rejected.
+func (lc *LightChain) EnableArtificialFinality(enable bool, logValues ...interface{}) {
+	lc.hc.EnableArtificialFinality(enable, logValues...)
+}
+
+// IsArtificialFinalityEnabled returns the status of the light chain's artificial
+// finality feature setting.
+func (lc *LightChain) IsArtificialFinalityEnabled() bool {
+	return lc.hc.IsArtificialFinalityEnabled()
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/vars"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -70,6 +71,7 @@ type TxPool struct {
 
 	eip2f    bool
 	eip2028f bool
+	eip3860f bool
 }
 
 // TxRelayBackend provides an interface to the mechanism that forwards transacions
@@ -333,6 +335,7 @@ func (pool *TxPool) setNewHead(head *types.Header) {
 	// Update fork indicator by next pending block number
 	next := new(big.Int).Add(head.Number, big.NewInt(1))
 	pool.eip2028f = pool.config.IsEnabled(pool.config.GetEIP2028Transition, next)
+	pool.eip3860f = pool.config.IsEnabled(pool.config.GetEIP3860Transition, next)
 }
 
 // Stop stops the light transaction pool
@@ -399,8 +402,13 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 		return core.ErrInsufficientFunds
 	}
 
+	// Should not exceed the init code size limit
+	if pool.eip3860f && tx.To() == nil && len(tx.Data()) > int(vars.MaxInitCodeSize) {
+		return fmt.Errorf("%w: code size %v limit %v", core.ErrMaxInitCodeSizeExceeded, len(tx.Data()), vars.MaxInitCodeSize)
+	}
+
 	// Should supply enough intrinsic gas
-	gas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, pool.eip2f, pool.eip2028f)
+	gas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, pool.eip2f, pool.eip2028f, pool.eip3860f)
 	if err != nil {
 		return err
 	}
@@ -19,7 +19,9 @@ package node
 import (
 	"context"
 	"fmt"
+	"net"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -124,6 +126,76 @@ func (api *privateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	return true, nil
 }
 
+// BanPeer bans a remote node, given by its enode URL or node ID, or all nodes of
+// an IP address for the given number of seconds, or permanently if omitted. It
+// also disconnects the banned peers.
+func (api *privateAdminAPI) BanPeer(target string, seconds *uint64) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	id, ip, err := parseBanTarget(target)
+	if err != nil {
+		return false, err
+	}
+	var duration time.Duration
+	if seconds != nil {
+		duration = time.Duration(*seconds) * time.Second
+	}
+	if ip != nil {
+		err = server.BanIP(ip, duration)
+	} else {
+		err = server.BanNode(id, duration)
+	}
+	return err == nil, err
+}
+
+// UnbanPeer lifts the ban of a remote node, given by its enode URL or node ID,
+// or of an IP address.
+func (api *privateAdminAPI) UnbanPeer(target string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	id, ip, err := parseBanTarget(target)
+	if err != nil {
+		return false, err
+	}
+	if ip != nil {
+		err = server.UnbanIP(ip)
+	} else {
+		err = server.UnbanNode(id)
+	}
+	return err == nil, err
+}
+
+// ListBans retrieves the banned node IDs and IP addresses.
+func (api *privateAdminAPI) ListBans() ([]*p2p.BanInfo, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.Bans(), nil
+}
+
+// parseBanTarget parses an IP address, enode URL or node ID into the node ID or
+// IP address to ban.
+func parseBanTarget(target string) (enode.ID, net.IP, error) {
+	if ip := net.ParseIP(target); ip != nil {
+		return enode.ID{}, ip, nil
+	}
+	if node, err := enode.Parse(enode.ValidSchemes, target); err == nil {
+		return node.ID(), nil, nil
+	}
+	id, err := enode.ParseID(target)
+	if err != nil {
+		return enode.ID{}, nil, fmt.Errorf("invalid enode, node ID or IP: %v", target)
+	}
+	return id, nil, nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *privateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -168,6 +168,12 @@ type Config struct {
 	// useless for custom HTTP clients.
 	GraphQLCors []string `toml:",omitempty"`
 
+	// GraphQLWSOrigins is the list of domains to accept GraphQL websocket requests
+	// from. If empty, only requests from localhost are accepted. Please be aware
+	// that the server can only act upon the HTTP request the client sends and
+	// cannot verify the validity of the request header.
+	GraphQLWSOrigins []string `toml:",omitempty"`
+
 	// GraphQLVirtualHosts is the list of virtual hostnames which are allowed on incoming requests.
 	// This is by default {'localhost'}. Using this prevents attacks like
 	// DNS rebinding, which bypasses SOP by simply masquerading as being within the same
@@ -0,0 +1,172 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+)
+
+const (
+	// Prefix lengths of the subnets peers are limited per.
+	subnetBitsIPv4 = 24
+	subnetBitsIPv6 = 48
+
+	groupGaugePrefix = "p2p/peers/group/"
+)
+
+var (
+	peerSubnetsGauge       = metrics.NewRegisteredGauge("p2p/peers/subnets", nil)
+	peerLargestSubnetGauge = metrics.NewRegisteredGauge("p2p/peers/subnets/largest", nil)
+	peerGroupsGauge        = metrics.NewRegisteredGauge("p2p/peers/groups", nil)
+)
+
+// subnet returns the /24 IPv4 or /48 IPv6 network of an IP address.
+func subnet(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(subnetBitsIPv4, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(subnetBitsIPv6, 128)).String()
+}
+
+// remoteIP returns the IP address of the remote end of a connection, or nil if
+// the peer diversity limits don't apply to it.
+func remoteIP(c *conn) net.IP {
+	if c.fd == nil {
+		return nil
+	}
+	addr, ok := c.fd.RemoteAddr().(*net.TCPAddr)
+	if !ok || netutil.IsLAN(addr.IP) {
+		return nil
+	}
+	return addr.IP
+}
+
+// peerDiversity limits the number of peers connected from the same subnet or
+// prefix group, to make it harder for an attacker controlling a few networks to
+// eclipse the node. Trusted and static peers, as well as peers from the local
+// network, are not limited.
+type peerDiversity struct {
+	subnetLimit int                   // maximum number of peers per subnet, unlimited if zero
+	groupLimit  int                   // maximum number of peers per prefix group, unlimited if zero
+	groups      *netutil.PrefixGroups // assigns IP networks to prefix groups
+	reported    map[string]bool       // prefix groups with a peer count gauge
+}
+
+// counts returns the number of limited peers per subnet and prefix group, not
+// counting the peers being evicted.
+func (d *peerDiversity) counts(peers map[enode.ID]*Peer) (subnets, groups map[string]int) {
+	subnets, groups = make(map[string]int), make(map[string]int)
+	for _, p := range peers {
+		ip := remoteIP(p.rw)
+		if ip == nil || p.evicted || p.rw.is(trustedConn|staticDialedConn) {
+			continue
+		}
+		subnets[subnet(ip)]++
+		if group := d.groups.Group(ip); group != "" {
+			groups[group]++
+		}
+	}
+	return subnets, groups
+}
+
+// allowed reports whether adding the connection keeps the peer counts within
+// the limits of its subnet and prefix group.
+func (d *peerDiversity) allowed(peers map[enode.ID]*Peer, c *conn) bool {
+	ip := remoteIP(c)
+	if ip == nil || c.is(trustedConn|staticDialedConn) || (d.subnetLimit == 0 && d.groupLimit == 0) {
+		return true
+	}
+	subnets, groups := d.counts(peers)
+	if d.subnetLimit > 0 && subnets[subnet(ip)] >= d.subnetLimit {
+		return false
+	}
+	if group := d.groups.Group(ip); d.groupLimit > 0 && group != "" && groups[group] >= d.groupLimit {
+		return false
+	}
+	return true
+}
+
+// evictionCandidate returns the peer to disconnect for making room for an inbound
+// connection from a less represented subnet, or nil if there is none. Only inbound
+// peers of the subnet with the most peers are evicted, preferring to keep the ones
+// which are connected the longest.
+func (d *peerDiversity) evictionCandidate(peers map[enode.ID]*Peer, c *conn) *Peer {
+	ip := remoteIP(c)
+	if ip == nil || !c.is(inboundConn) || d.subnetLimit == 0 {
+		return nil
+	}
+	subnets, _ := d.counts(peers)
+
+	var (
+		largest string
+		most    = subnets[subnet(ip)] + 1
+	)
+	for sn, count := range subnets {
+		if count > most {
+			largest, most = sn, count
+		}
+	}
+	if largest == "" {
+		return nil
+	}
+	var candidate *Peer
+	for _, p := range peers {
+		ip := remoteIP(p.rw)
+		if ip == nil || p.evicted || !p.rw.is(inboundConn) || p.rw.is(trustedConn) || subnet(ip) != largest {
+			continue
+		}
+		if candidate == nil || p.created > candidate.created {
+			candidate = p
+		}
+	}
+	return candidate
+}
+
+// updateMetrics reports the distribution of the peers over subnets and prefix groups.
+func (d *peerDiversity) updateMetrics(peers map[enode.ID]*Peer) {
+	if !metrics.Enabled {
+		return
+	}
+	subnets, groups := d.counts(peers)
+
+	var largest int
+	for _, count := range subnets {
+		if count > largest {
+			largest = count
+		}
+	}
+	peerSubnetsGauge.Update(int64(len(subnets)))
+	peerLargestSubnetGauge.Update(int64(largest))
+	peerGroupsGauge.Update(int64(len(groups)))
+
+	if d.reported == nil {
+		d.reported = make(map[string]bool)
+	}
+	for group := range d.reported {
+		if groups[group] == 0 {
+			metrics.GetOrRegisterGauge(groupGaugePrefix+group, nil).Update(0)
+		}
+	}
+	for group, count := range groups {
+		metrics.GetOrRegisterGauge(groupGaugePrefix+group, nil).Update(int64(count))
+		d.reported[group] = true
+	}
+}
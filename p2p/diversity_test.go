@@ -0,0 +1,117 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+)
+
+// diversityConn creates a connection from the given IP address.
+func diversityConn(ip string, flags connFlag) *conn {
+	fd := &fakeAddrConn{remoteAddr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 30303}}
+	return &conn{fd: fd, flags: flags, node: enode.SignNull(new(enr.Record), randomID())}
+}
+
+func TestPeerDiversityLimits(t *testing.T) {
+	groups, err := netutil.ParsePrefixGroups(strings.NewReader("1.2.0.0/16 AS1\n5.6.0.0/16 AS1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &peerDiversity{subnetLimit: 2, groupLimit: 3, groups: groups}
+
+	peers := make(map[enode.ID]*Peer)
+	add := func(c *conn, created mclock.AbsTime) *Peer {
+		p := &Peer{rw: c, created: created}
+		peers[c.node.ID()] = p
+		return p
+	}
+	add(diversityConn("1.2.3.1", inboundConn), 1)
+	add(diversityConn("1.2.3.2", inboundConn), 2)
+
+	tests := []struct {
+		ip    string
+		flags connFlag
+		want  bool
+	}{
+		{"1.2.3.3", inboundConn, false},              // subnet full
+		{"1.2.3.3", inboundConn | trustedConn, true}, // trusted peers are exempt
+		{"1.2.3.3", staticDialedConn, true},          // static peers are exempt
+		{"192.168.0.1", inboundConn, true},           // LAN peers are exempt
+		{"1.2.4.1", inboundConn, true},               // other subnet of the group
+		{"2001:db8:1:1::1", inboundConn, true},       // IPv6 subnet
+		{"9.9.9.9", dynDialedConn, true},             // no group
+	}
+	for i, tt := range tests {
+		if have := d.allowed(peers, diversityConn(tt.ip, tt.flags)); have != tt.want {
+			t.Errorf("test %d (%s): allowed mismatch: have %v, want %v", i, tt.ip, have, tt.want)
+		}
+	}
+	// Fill up the prefix group across subnets
+	add(diversityConn("5.6.7.8", inboundConn), 3)
+	if d.allowed(peers, diversityConn("1.2.4.1", inboundConn)) {
+		t.Error("connection allowed into full prefix group")
+	}
+	// IPv6 peers are limited per /48
+	add(diversityConn("2001:db8:1:1::1", inboundConn), 4)
+	add(diversityConn("2001:db8:1:2::1", inboundConn), 5)
+	if d.allowed(peers, diversityConn("2001:db8:1:ffff::1", inboundConn)) {
+		t.Error("connection allowed into full IPv6 subnet")
+	}
+	if !d.allowed(peers, diversityConn("2001:db8:2::1", inboundConn)) {
+		t.Error("connection rejected from other IPv6 subnet")
+	}
+}
+
+func TestPeerDiversityEviction(t *testing.T) {
+	d := &peerDiversity{subnetLimit: 4}
+
+	peers := make(map[enode.ID]*Peer)
+	add := func(c *conn, created mclock.AbsTime) *Peer {
+		p := &Peer{rw: c, created: created}
+		peers[c.node.ID()] = p
+		return p
+	}
+	add(diversityConn("1.2.3.1", inboundConn), 1)
+	young := add(diversityConn("1.2.3.2", inboundConn), 5)
+	add(diversityConn("1.2.3.3", inboundConn|trustedConn), 6)
+	add(diversityConn("1.2.3.4", dynDialedConn), 7)
+	add(diversityConn("1.2.3.5", inboundConn), 3)
+	add(diversityConn("4.4.4.4", inboundConn), 8)
+
+	// Dialed connections and ones from well represented subnets evict nobody
+	if p := d.evictionCandidate(peers, diversityConn("9.9.9.9", dynDialedConn)); p != nil {
+		t.Errorf("peer evicted for dialed connection: %v", p.rw.fd.RemoteAddr())
+	}
+	if p := d.evictionCandidate(peers, diversityConn("1.2.3.9", inboundConn)); p != nil {
+		t.Errorf("peer evicted for connection from the largest subnet: %v", p.rw.fd.RemoteAddr())
+	}
+	// Inbound connections from new subnets evict the youngest evictable peer
+	if p := d.evictionCandidate(peers, diversityConn("9.9.9.9", inboundConn)); p != young {
+		t.Fatalf("wrong eviction candidate: have %v, want %v", p, young)
+	}
+	young.evicted = true
+	if p := d.evictionCandidate(peers, diversityConn("9.9.9.9", inboundConn)); p == young || p == nil {
+		t.Fatalf("wrong eviction candidate after eviction: %v", p)
+	}
+}
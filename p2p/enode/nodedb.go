@@ -26,6 +26,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
@@ -53,6 +54,12 @@ const (
 	// Local information is keyed by ID only, the full key is "local:<ID>:seq".
 	// Use localItemKey to create those keys.
 	dbLocalSeq = "seq"
+
+	// Bans are keyed by node ID or IP, the full key is "ban:id:<ID>" or "ban:ip:<IP>".
+	// Use banKey to create those keys.
+	dbBanPrefix = "ban:"
+	dbBanID     = "id:"
+	dbBanIP     = "ip:"
 )
 
 const (
@@ -191,6 +198,30 @@ func localItemKey(id ID, field string) []byte {
 	return key
 }
 
+// banKey returns the key of a ban list entry.
+func banKey(ban Ban) []byte {
+	if ban.IP != nil {
+		return append([]byte(dbBanPrefix+dbBanIP), ban.IP.To16()...)
+	}
+	return append([]byte(dbBanPrefix+dbBanID), ban.ID[:]...)
+}
+
+// splitBanKey returns the ban list entry of a key created by banKey.
+func splitBanKey(key []byte) (ban Ban) {
+	key = key[len(dbBanPrefix):]
+	switch {
+	case bytes.HasPrefix(key, []byte(dbBanIP)):
+		// Iterator keys are only valid until the next step, so copy the IP
+		ban.IP = net.IP(common.CopyBytes(key[len(dbBanIP):]))
+		if ip4 := ban.IP.To4(); ip4 != nil {
+			ban.IP = ip4
+		}
+	case bytes.HasPrefix(key, []byte(dbBanID)):
+		copy(ban.ID[:], key[len(dbBanID):])
+	}
+	return ban
+}
+
 // fetchInt64 retrieves an integer associated with a particular key.
 func (db *DB) fetchInt64(key []byte) int64 {
 	blob, err := db.lvl.Get(key, nil)
@@ -311,6 +342,7 @@ func (db *DB) expirer() {
 		select {
 		case <-tick.C:
 			db.expireNodes()
+			db.expireBans(time.Now())
 		case <-db.quit:
 			return
 		}
@@ -399,6 +431,90 @@ func (db *DB) UpdateFindFailsV5(id ID, ip net.IP, fails int) error {
 	return db.storeInt64(v5Key(id, ip, dbNodeFindFails), int64(fails))
 }
 
+// Ban is an entry of the ban list, banning either a node ID or an IP address.
+type Ban struct {
+	ID     ID        // Banned node ID, if IP is nil
+	IP     net.IP    // Banned IP address
+	Expiry time.Time // Time the ban expires at, zero for permanent bans
+}
+
+// expired reports whether the ban has expired at the given time.
+func (b Ban) expired(now time.Time) bool {
+	return !b.Expiry.IsZero() && !now.Before(b.Expiry)
+}
+
+// AddBan inserts - potentially overwriting - an entry into the ban list.
+func (db *DB) AddBan(ban Ban) error {
+	var expiry int64
+	if !ban.Expiry.IsZero() {
+		expiry = ban.Expiry.Unix()
+	}
+	return db.storeInt64(banKey(ban), expiry)
+}
+
+// RemoveBan deletes the entry banning the node ID or IP address of ban from the
+// ban list.
+func (db *DB) RemoveBan(ban Ban) error {
+	return db.lvl.Delete(banKey(ban), nil)
+}
+
+// Banned reports whether the given node ID or IP address is banned at the given
+// time. A nil IP is not checked.
+func (db *DB) Banned(id ID, ip net.IP, now time.Time) bool {
+	if db.banned(Ban{ID: id}, now) {
+		return true
+	}
+	return ip != nil && db.IPBanned(ip, now)
+}
+
+// IPBanned reports whether the given IP address is banned at the given time.
+func (db *DB) IPBanned(ip net.IP, now time.Time) bool {
+	return db.banned(Ban{IP: ip}, now)
+}
+
+// banned reports whether the ban list holds an unexpired entry for the node ID or
+// IP address of ban.
+func (db *DB) banned(ban Ban, now time.Time) bool {
+	blob, err := db.lvl.Get(banKey(ban), nil)
+	if err != nil {
+		return false
+	}
+	if expiry, _ := binary.Varint(blob); expiry != 0 {
+		ban.Expiry = time.Unix(expiry, 0)
+	}
+	return !ban.expired(now)
+}
+
+// Bans returns all entries of the ban list which have not expired at the given time.
+func (db *DB) Bans(now time.Time) []Ban {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbBanPrefix)), nil)
+	defer it.Release()
+
+	var bans []Ban
+	for it.Next() {
+		ban := splitBanKey(it.Key())
+		if expiry, _ := binary.Varint(it.Value()); expiry != 0 {
+			ban.Expiry = time.Unix(expiry, 0)
+		}
+		if !ban.expired(now) {
+			bans = append(bans, ban)
+		}
+	}
+	return bans
+}
+
+// expireBans deletes all entries of the ban list which have expired at the given time.
+func (db *DB) expireBans(now time.Time) {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbBanPrefix)), nil)
+	defer it.Release()
+
+	for it.Next() {
+		if expiry, _ := binary.Varint(it.Value()); expiry != 0 && !now.Before(time.Unix(expiry, 0)) {
+			db.lvl.Delete(it.Key(), nil)
+		}
+	}
+}
+
 // LocalSeq retrieves the local record sequence counter.
 func (db *DB) localSeq(id ID) uint64 {
 	return db.fetchUint64(localItemKey(id, dbLocalSeq))
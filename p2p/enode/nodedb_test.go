@@ -473,3 +473,88 @@ func TestDBExpireV5(t *testing.T) {
 	db.UpdateFindFailsV5(ID{}, ip, 4)
 	db.expireNodes()
 }
+
+func TestDBBans(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	var (
+		now     = time.Now()
+		node    = ID{0x01}
+		other   = ID{0x02}
+		ip      = net.IP{10, 0, 0, 1}
+		expired = ID{0x03}
+	)
+	db.AddBan(Ban{ID: node})
+	db.AddBan(Ban{IP: ip, Expiry: now.Add(time.Hour)})
+	db.AddBan(Ban{ID: expired, Expiry: now.Add(-time.Second)})
+
+	if !db.Banned(node, nil, now) {
+		t.Error("permanently banned node not banned")
+	}
+	if !db.Banned(other, ip, now) || !db.IPBanned(ip, now) {
+		t.Error("node of banned IP not banned")
+	}
+	if db.Banned(other, net.IP{10, 0, 0, 2}, now) {
+		t.Error("unbanned node banned")
+	}
+	if db.Banned(expired, nil, now) {
+		t.Error("node with expired ban banned")
+	}
+	if db.IPBanned(ip, now.Add(2*time.Hour)) {
+		t.Error("IP banned after ban expiry")
+	}
+	if bans := db.Bans(now); len(bans) != 2 {
+		t.Errorf("ban count mismatch: have %d, want 2: %v", len(bans), bans)
+	} else if bans[0].ID != node || !bans[1].IP.Equal(ip) || !bans[0].Expiry.IsZero() || bans[1].Expiry.Unix() != now.Add(time.Hour).Unix() {
+		t.Errorf("bans mismatch: %+v", bans)
+	}
+	// Expired bans should be deleted, lifted ones gone
+	db.expireBans(now)
+	if _, err := db.lvl.Get(banKey(Ban{ID: expired}), nil); err == nil {
+		t.Error("expired ban not deleted")
+	}
+	db.RemoveBan(Ban{ID: node})
+	if db.Banned(node, nil, now) {
+		t.Error("node banned after ban removal")
+	}
+}
+
+// Tests that the ban list of a persistent database can be listed with several
+// IP bans, whose keys are stored in reused iterator buffers.
+func TestDBBansPersistent(t *testing.T) {
+	root, err := ioutil.TempDir("", "nodedb-")
+	if err != nil {
+		t.Fatalf("failed to create temporary data folder: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	db, err := OpenDB(filepath.Join(root, "database"))
+	if err != nil {
+		t.Fatalf("failed to create persistent database: %v", err)
+	}
+	defer db.Close()
+
+	ips := []net.IP{
+		{10, 0, 0, 1},
+		{10, 0, 0, 2},
+		{192, 168, 1, 1},
+		net.ParseIP("2001:db8::1"),
+	}
+	for _, ip := range ips {
+		db.AddBan(Ban{IP: ip})
+	}
+	bans := db.Bans(time.Now())
+	if len(bans) != len(ips) {
+		t.Fatalf("ban count mismatch: have %d, want %d: %v", len(bans), len(ips), bans)
+	}
+	for _, ip := range ips {
+		found := false
+		for _, ban := range bans {
+			found = found || ban.IP.Equal(ip)
+		}
+		if !found {
+			t.Errorf("IP %v missing from bans: %v", ip, bans)
+		}
+	}
+}
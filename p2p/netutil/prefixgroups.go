@@ -0,0 +1,107 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package netutil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// PrefixGroups assigns IP networks to named groups, such as the autonomous
+// systems announcing them.
+type PrefixGroups struct {
+	prefixes []prefixGroup // sorted by descending prefix length
+}
+
+type prefixGroup struct {
+	net   net.IPNet
+	group string
+}
+
+// ParsePrefixGroups parses a list of IP networks and their groups. Every line
+// holds a CIDR mask followed by the group name, separated by whitespace. Empty
+// lines and lines starting with '#' are ignored.
+func ParsePrefixGroups(r io.Reader) (*PrefixGroups, error) {
+	var (
+		groups  = new(PrefixGroups)
+		scanner = bufio.NewScanner(r)
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want CIDR mask and group, have %q", line, text)
+		}
+		_, n, err := net.ParseCIDR(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		groups.prefixes = append(groups.prefixes, prefixGroup{net: *n, group: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(groups.prefixes, func(i, j int) bool {
+		bi, _ := groups.prefixes[i].net.Mask.Size()
+		bj, _ := groups.prefixes[j].net.Mask.Size()
+		return bi > bj
+	})
+	return groups, nil
+}
+
+// LoadPrefixGroups reads a list of IP networks and their groups from a file in
+// the format accepted by ParsePrefixGroups.
+func LoadPrefixGroups(file string) (*PrefixGroups, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParsePrefixGroups(f)
+}
+
+// Group returns the group of the most specific network containing the given IP,
+// or the empty string if no network contains it.
+func (g *PrefixGroups) Group(ip net.IP) string {
+	if g == nil {
+		return ""
+	}
+	for _, prefix := range g.prefixes {
+		if prefix.net.Contains(ip) {
+			return prefix.group
+		}
+	}
+	return ""
+}
+
+// Len returns the number of networks in the list.
+func (g *PrefixGroups) Len() int {
+	if g == nil {
+		return 0
+	}
+	return len(g.prefixes)
+}
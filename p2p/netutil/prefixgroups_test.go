@@ -0,0 +1,57 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package netutil
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParsePrefixGroups(t *testing.T) {
+	list := `
+# Comments and empty lines are ignored
+10.0.0.0/8      AS100
+10.1.0.0/16     AS200
+2001:db8::/32   AS300
+`
+	groups, err := ParsePrefixGroups(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("failed to parse prefix groups: %v", err)
+	}
+	if groups.Len() != 3 {
+		t.Fatalf("prefix count mismatch: have %d, want 3", groups.Len())
+	}
+	tests := []struct {
+		ip, group string
+	}{
+		{"10.2.3.4", "AS100"},
+		{"10.1.3.4", "AS200"}, // Most specific prefix wins
+		{"2001:db8:1::1", "AS300"},
+		{"192.0.2.1", ""},
+	}
+	for _, tt := range tests {
+		if have := groups.Group(net.ParseIP(tt.ip)); have != tt.group {
+			t.Errorf("%s: group mismatch: have %q, want %q", tt.ip, have, tt.group)
+		}
+	}
+	for _, invalid := range []string{"10.0.0.0/8", "10.0.0.0/33 AS1", "10.0.0.0/8 AS1 extra"} {
+		if _, err := ParsePrefixGroups(strings.NewReader(invalid)); err == nil {
+			t.Errorf("no error for invalid list %q", invalid)
+		}
+	}
+}
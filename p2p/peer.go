@@ -116,6 +116,12 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	// penalize lowers the reputation of the peer if set
+	penalize func(*Peer, Offence)
+
+	// evicted is set by the server's run loop when evicting the peer
+	evicted bool
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -202,6 +208,14 @@ func newPeer(log log.Logger, conn *conn, protocols []Protocol) *Peer {
 	return p
 }
 
+// Penalize lowers the reputation of the peer for an offence. Peers accumulating
+// too many offences are disconnected and banned.
+func (p *Peer) Penalize(offence Offence) {
+	if p.penalize != nil {
+		p.penalize(p, offence)
+	}
+}
+
 func (p *Peer) Log() log.Logger {
 	return p.log
 }
@@ -0,0 +1,143 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// banThreshold is the misbehaviour score at which a peer gets banned.
+	banThreshold = 100
+
+	// scoreHalfLife is the time it takes for a misbehaviour score to halve.
+	scoreHalfLife = 10 * time.Minute
+
+	// maxTrackedScores is the number of misbehaviour scores above which decayed
+	// scores are dropped.
+	maxTrackedScores = 1024
+
+	// defaultBanDuration is the time peers are banned for by default when their
+	// misbehaviour score reaches the ban threshold.
+	defaultBanDuration = time.Hour
+)
+
+// Offence is a kind of peer misbehaviour, which lowers the reputation of the peer.
+// Peers are banned when too many offences accumulate in a short time.
+type Offence int
+
+const (
+	OffenceProtocolViolation Offence = iota // Peer sent an invalid or unexpected message
+	OffenceInvalidBlock                     // Peer sent a block or header failing validation
+	OffenceUselessResponse                  // Peer sent an empty or unusable response
+	OffenceTimeout                          // Peer did not respond to a request in time
+)
+
+var offencePenalties = [...]float64{
+	OffenceProtocolViolation: 50,
+	OffenceInvalidBlock:      50,
+	OffenceUselessResponse:   10,
+	OffenceTimeout:           20,
+}
+
+var offenceToString = [...]string{
+	OffenceProtocolViolation: "protocol violation",
+	OffenceInvalidBlock:      "invalid block",
+	OffenceUselessResponse:   "useless response",
+	OffenceTimeout:           "timeout",
+}
+
+func (o Offence) String() string {
+	if int(o) < len(offenceToString) {
+		return offenceToString[o]
+	}
+	return fmt.Sprintf("unknown offence %d", o)
+}
+
+// peerScore is the misbehaviour score of a node, decaying over time.
+type peerScore struct {
+	value   float64
+	updated mclock.AbsTime
+}
+
+// decayed returns the value of the score at the given time.
+func (s *peerScore) decayed(now mclock.AbsTime) float64 {
+	elapsed := time.Duration(now - s.updated)
+	return s.value * math.Pow(0.5, float64(elapsed)/float64(scoreHalfLife))
+}
+
+// reputation tracks the misbehaviour scores of nodes.
+type reputation struct {
+	clock  mclock.Clock
+	lock   sync.Mutex
+	scores map[enode.ID]*peerScore
+}
+
+func newReputation(clock mclock.Clock) *reputation {
+	return &reputation{clock: clock, scores: make(map[enode.ID]*peerScore)}
+}
+
+// penalize adds the penalty of an offence to the misbehaviour score of a node,
+// returning the new score.
+func (r *reputation) penalize(id enode.ID, offence Offence) float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.clock.Now()
+	if len(r.scores) >= maxTrackedScores {
+		for id, score := range r.scores {
+			if score.decayed(now) < 1 {
+				delete(r.scores, id)
+			}
+		}
+	}
+	score := r.scores[id]
+	if score == nil {
+		score = new(peerScore)
+		r.scores[id] = score
+	}
+	if int(offence) < len(offencePenalties) {
+		score.value = score.decayed(now) + offencePenalties[offence]
+		score.updated = now
+	}
+	return score.value
+}
+
+// score returns the current misbehaviour score of a node.
+func (r *reputation) score(id enode.ID) float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if score := r.scores[id]; score != nil {
+		return score.decayed(r.clock.Now())
+	}
+	return 0
+}
+
+// forget drops the misbehaviour score of a node.
+func (r *reputation) forget(id enode.ID) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.scores, id)
+}
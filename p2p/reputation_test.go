@@ -0,0 +1,47 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func TestReputationDecay(t *testing.T) {
+	var (
+		clock = new(mclock.Simulated)
+		rep   = newReputation(clock)
+		id    = enode.ID{1}
+	)
+	if score := rep.penalize(id, OffenceTimeout); score != 20 {
+		t.Fatalf("score mismatch: have %v, want 20", score)
+	}
+	clock.Run(scoreHalfLife)
+	if score := rep.score(id); math.Abs(score-10) > 1e-9 {
+		t.Fatalf("decayed score mismatch: have %v, want 10", score)
+	}
+	if score := rep.penalize(id, OffenceProtocolViolation); math.Abs(score-60) > 1e-9 {
+		t.Fatalf("score mismatch: have %v, want 60", score)
+	}
+	rep.forget(id)
+	if score := rep.score(id); score != 0 {
+		t.Fatalf("forgotten score mismatch: have %v, want 0", score)
+	}
+}
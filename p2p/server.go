@@ -120,10 +120,28 @@ type Config struct {
 	// IP networks contained in the list are considered.
 	NetRestrict *netutil.Netlist `toml:",omitempty"`
 
+	// MaxPeersPerSubnet limits the number of peers connected from the same /24
+	// IPv4 or /48 IPv6 network. When the peer slots are full, inbound peers of
+	// the best represented network are evicted in favour of peers from other
+	// networks. Trusted and static peers are exempt. Zero disables the limit.
+	MaxPeersPerSubnet int `toml:",omitempty"`
+
+	// PrefixGroupsFile is the path to a file assigning IP networks to groups,
+	// such as autonomous systems. Every line holds a CIDR mask and a group name.
+	PrefixGroupsFile string `toml:",omitempty"`
+
+	// MaxPeersPerPrefixGroup limits the number of peers connected from the
+	// networks of the same group of PrefixGroupsFile. Zero disables the limit.
+	MaxPeersPerPrefixGroup int `toml:",omitempty"`
+
 	// NodeDatabase is the path to the database containing the previously seen
-	// live nodes in the network.
+	// live nodes in the network. It also holds the ban list.
 	NodeDatabase string `toml:",omitempty"`
 
+	// BanDuration is the time peers are banned for when their misbehaviour score
+	// reaches the ban threshold. Zero defaults to one hour.
+	BanDuration time.Duration `toml:",omitempty"`
+
 	// Protocols should contain the protocols supported
 	// by the server. Matching protocols are launched for
 	// each peer.
@@ -179,12 +197,14 @@ type Server struct {
 	peerFeed     event.Feed
 	log          log.Logger
 
-	nodedb    *enode.DB
-	localnode *enode.LocalNode
-	ntab      *discover.UDPv4
-	DiscV5    *discv5.Network
-	discmix   *enode.FairMix
-	dialsched *dialScheduler
+	nodedb     *enode.DB
+	reputation *reputation
+	diversity  *peerDiversity
+	localnode  *enode.LocalNode
+	ntab       *discover.UDPv4
+	DiscV5     *discv5.Network
+	discmix    *enode.FairMix
+	dialsched  *dialScheduler
 
 	// Channels into the run loop.
 	quit                    chan struct{}
@@ -227,6 +247,8 @@ type conn struct {
 	cont  chan error // The run loop uses cont to signal errors to SetupConn.
 	caps  []Cap      // valid after the protocol handshake
 	name  string     // valid after the protocol handshake
+
+	victim *Peer // peer evicted to make room for the connection, until it has left
 }
 
 type transport interface {
@@ -371,6 +393,105 @@ func (srv *Server) RemoveTrustedPeer(node *enode.Node) {
 	}
 }
 
+// BanNode bans the node with the given ID for the given duration, or permanently
+// if the duration is zero. It also disconnects from the node if it is currently
+// connected as a peer.
+func (srv *Server) BanNode(id enode.ID, d time.Duration) error {
+	if err := srv.addBan(enode.Ban{ID: id}, d); err != nil {
+		return err
+	}
+	srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+		if peer := peers[id]; peer != nil {
+			peer.Disconnect(DiscRequested)
+		}
+	})
+	return nil
+}
+
+// BanIP bans the given IP address for the given duration, or permanently if the
+// duration is zero. It also disconnects from all peers connected from the IP.
+func (srv *Server) BanIP(ip net.IP, d time.Duration) error {
+	if err := srv.addBan(enode.Ban{IP: ip}, d); err != nil {
+		return err
+	}
+	srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+		for _, peer := range peers {
+			if addr, ok := peer.RemoteAddr().(*net.TCPAddr); ok && addr.IP.Equal(ip) {
+				peer.Disconnect(DiscRequested)
+			}
+		}
+	})
+	return nil
+}
+
+// addBan inserts an entry expiring after the given duration into the ban list.
+func (srv *Server) addBan(ban enode.Ban, d time.Duration) error {
+	if d > 0 {
+		ban.Expiry = time.Now().Add(d)
+	}
+	if ban.IP == nil {
+		srv.reputation.forget(ban.ID)
+	}
+	return srv.nodedb.AddBan(ban)
+}
+
+// UnbanNode lifts the ban of the node with the given ID.
+func (srv *Server) UnbanNode(id enode.ID) error {
+	return srv.nodedb.RemoveBan(enode.Ban{ID: id})
+}
+
+// UnbanIP lifts the ban of the given IP address.
+func (srv *Server) UnbanIP(ip net.IP) error {
+	return srv.nodedb.RemoveBan(enode.Ban{IP: ip})
+}
+
+// Bans returns the entries of the ban list.
+func (srv *Server) Bans() []*BanInfo {
+	bans := srv.nodedb.Bans(time.Now())
+
+	infos := make([]*BanInfo, 0, len(bans))
+	for _, ban := range bans {
+		info := new(BanInfo)
+		if ban.IP != nil {
+			info.IP = ban.IP.String()
+		} else {
+			info.ID = ban.ID.String()
+		}
+		if !ban.Expiry.IsZero() {
+			expiry := ban.Expiry
+			info.Expiry = &expiry
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// banned reports whether the node ID or IP address of n is banned.
+func (srv *Server) banned(n *enode.Node) bool {
+	return srv.nodedb.Banned(n.ID(), n.IP(), time.Now())
+}
+
+// penalize lowers the reputation of a peer for an offence, banning it if its
+// misbehaviour score reaches the ban threshold. Trusted peers are never banned.
+func (srv *Server) penalize(p *Peer, offence Offence) {
+	score := srv.reputation.penalize(p.ID(), offence)
+	p.log.Debug("Peer misbehaved", "offence", offence, "score", score)
+
+	if score < banThreshold || p.rw.is(trustedConn) {
+		return
+	}
+	duration := srv.BanDuration
+	if duration == 0 {
+		duration = defaultBanDuration
+	}
+	if err := srv.addBan(enode.Ban{ID: p.ID()}, duration); err != nil {
+		p.log.Warn("Failed to ban misbehaving peer", "err", err)
+	} else {
+		p.log.Debug("Banned misbehaving peer", "duration", duration)
+	}
+	p.Disconnect(DiscUselessPeer)
+}
+
 // SubscribePeers subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -470,6 +591,13 @@ func (srv *Server) Start() (err error) {
 	srv.removetrusted = make(chan *enode.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
+	srv.reputation = newReputation(srv.clock)
+	srv.diversity = &peerDiversity{subnetLimit: srv.MaxPeersPerSubnet, groupLimit: srv.MaxPeersPerPrefixGroup}
+	if srv.PrefixGroupsFile != "" {
+		if srv.diversity.groups, err = netutil.LoadPrefixGroups(srv.PrefixGroupsFile); err != nil {
+			return fmt.Errorf("invalid prefix groups: %v", err)
+		}
+	}
 
 	if err := srv.setupLocalNode(); err != nil {
 		return err
@@ -621,6 +749,7 @@ func (srv *Server) setupDialScheduler() {
 		maxActiveDials: srv.MaxPendingPeers,
 		log:            srv.Logger,
 		netRestrict:    srv.NetRestrict,
+		banned:         srv.banned,
 		dialer:         srv.Dialer,
 		clock:          srv.clock,
 	}
@@ -702,12 +831,35 @@ func (srv *Server) run() {
 		peers        = make(map[enode.ID]*Peer)
 		inboundCount = 0
 		trusted      = make(map[enode.ID]bool, len(srv.TrustedNodes))
+		awaiting     = make(map[enode.ID]*conn) // connections waiting for an evicted peer to leave
 	)
 	// Put trusted nodes into a map to speed up checks.
 	// Trusted peers are loaded on startup or added via AddTrustedPeer RPC.
 	for _, n := range srv.TrustedNodes {
 		trusted[n.ID()] = true
 	}
+	// addPeer launches the connection as a peer if it passes the checks. If a
+	// peer had to be evicted to make room for it, the connection waits until
+	// the evicted peer has left, so the peer limits are never exceeded.
+	addPeer := func(c *conn) {
+		err := srv.addPeerChecks(peers, inboundCount, c)
+		if err == nil && c.victim != nil {
+			awaiting[c.victim.ID()] = c
+			return
+		}
+		if err == nil {
+			// The handshakes are done and it passed all checks.
+			p := srv.launchPeer(c)
+			peers[c.node.ID()] = p
+			srv.log.Debug("Adding p2p peer", "peercount", len(peers), "id", p.ID(), "conn", c.flags, "addr", p.RemoteAddr(), "name", p.Name())
+			srv.dialsched.peerAdded(c)
+			if p.Inbound() {
+				inboundCount++
+			}
+			srv.diversity.updateMetrics(peers)
+		}
+		c.cont <- err
+	}
 
 running:
 	for {
@@ -752,18 +904,7 @@ running:
 		case c := <-srv.checkpointAddPeer:
 			// At this point the connection is past the protocol handshake.
 			// Its capabilities are known and the remote identity is verified.
-			err := srv.addPeerChecks(peers, inboundCount, c)
-			if err == nil {
-				// The handshakes are done and it passed all checks.
-				p := srv.launchPeer(c)
-				peers[c.node.ID()] = p
-				srv.log.Debug("Adding p2p peer", "peercount", len(peers), "id", p.ID(), "conn", c.flags, "addr", p.RemoteAddr(), "name", p.Name())
-				srv.dialsched.peerAdded(c)
-				if p.Inbound() {
-					inboundCount++
-				}
-			}
-			c.cont <- err
+			addPeer(c)
 
 		case pd := <-srv.delpeer:
 			// A peer disconnected.
@@ -774,11 +915,24 @@ running:
 			if pd.Inbound() {
 				inboundCount--
 			}
+			srv.diversity.updateMetrics(peers)
+
+			// Recheck the connection waiting for the room made by the peer
+			if c := awaiting[pd.ID()]; c != nil {
+				delete(awaiting, pd.ID())
+				c.victim = nil
+				addPeer(c)
+			}
 		}
 	}
 
 	srv.log.Trace("P2P networking is spinning down")
 
+	// Reject the connections still waiting for evicted peers.
+	for _, c := range awaiting {
+		c.cont <- errServerStopped
+	}
+
 	// Terminate discovery. If there is a running lookup it will terminate soon.
 	if srv.ntab != nil {
 		srv.ntab.Close()
@@ -802,17 +956,30 @@ running:
 
 func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
 	switch {
-	case !c.is(trustedConn) && len(peers) >= srv.MaxPeers:
-		return DiscTooManyPeers
-	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
-		return DiscTooManyPeers
 	case peers[c.node.ID()] != nil:
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
-	default:
-		return nil
+	case srv.banned(c.node):
+		return errBanned
+	case !c.is(trustedConn) && !srv.diversity.allowed(peers, c):
+		return DiscTooManyPeers
+	}
+	if room, _ := srv.hasRoom(peers, inboundCount, c); !room {
+		return DiscTooManyPeers
 	}
+	return nil
+}
+
+// hasRoom reports whether the connection fits within the peer limits, along with
+// the peer to evict from the best represented subnet if it only fits after that.
+func (srv *Server) hasRoom(peers map[enode.ID]*Peer, inboundCount int, c *conn) (bool, *Peer) {
+	full := len(peers) >= srv.MaxPeers || (c.is(inboundConn) && inboundCount >= srv.maxInboundConns())
+	if c.is(trustedConn) || !full {
+		return true, nil
+	}
+	victim := srv.diversity.evictionCandidate(peers, c)
+	return victim != nil, victim
 }
 
 func (srv *Server) addPeerChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
@@ -822,7 +989,16 @@ func (srv *Server) addPeerChecks(peers map[enode.ID]*Peer, inboundCount int, c *
 	}
 	// Repeat the post-handshake checks because the
 	// peer set might have changed since those checks were performed.
-	return srv.postHandshakeChecks(peers, inboundCount, c)
+	if err := srv.postHandshakeChecks(peers, inboundCount, c); err != nil {
+		return err
+	}
+	// Make room for the connection only once nothing can reject it anymore.
+	if _, victim := srv.hasRoom(peers, inboundCount, c); victim != nil {
+		victim.log.Debug("Evicting peer for subnet diversity", "addr", victim.RemoteAddr())
+		victim.Disconnect(DiscTooManyPeers)
+		victim.evicted, c.victim = true, victim
+	}
+	return nil
 }
 
 // listenLoop runs in its own goroutine and accepts
@@ -900,6 +1076,10 @@ func (srv *Server) checkInboundConn(fd net.Conn, remoteIP net.IP) error {
 	if srv.NetRestrict != nil && !srv.NetRestrict.Contains(remoteIP) {
 		return fmt.Errorf("not whitelisted in NetRestrict")
 	}
+	// Reject banned IPs.
+	if srv.nodedb.IPBanned(remoteIP, time.Now()) {
+		return errBanned
+	}
 	// Reject Internet peers that try too often.
 	now := srv.clock.Now()
 	srv.inboundHistory.expire(now, nil)
@@ -1009,6 +1189,7 @@ func (srv *Server) checkpoint(c *conn, stage chan<- *conn) error {
 
 func (srv *Server) launchPeer(c *conn) *Peer {
 	p := newPeer(srv.log, c, srv.Protocols)
+	p.penalize = srv.penalize
 	if srv.EnableMsgEvents {
 		// If message events are enabled, pass the peerFeed
 		// to the peer.
@@ -1051,6 +1232,13 @@ func (srv *Server) runPeer(p *Peer) {
 	})
 }
 
+// BanInfo represents an entry of the ban list.
+type BanInfo struct {
+	ID     string     `json:"id,omitempty"`     // Banned node ID
+	IP     string     `json:"ip,omitempty"`     // Banned IP address
+	Expiry *time.Time `json:"expiry,omitempty"` // Time the ban expires at, absent for permanent bans
+}
+
 // NodeInfo represents a short summary of the information known about the host.
 type NodeInfo struct {
 	ID    string `json:"id"`    // Unique node identifier (also the encryption key)
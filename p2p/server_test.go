@@ -300,6 +300,67 @@ func TestServerAtCap(t *testing.T) {
 	}
 }
 
+// TestServerEviction checks that peers are only evicted for connections passing
+// all other checks, and that the connection is added once the evicted peer left.
+func TestServerEviction(t *testing.T) {
+	srv := &Server{
+		Config: Config{
+			PrivateKey:        newkey(),
+			MaxPeers:          2,
+			MaxPeersPerSubnet: 2,
+			NoDial:            true,
+			NoDiscovery:       true,
+			Protocols:         []Protocol{discard},
+			Logger:            testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(ip string, id enode.ID, caps []Cap) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&newkey().PublicKey, fd, nil)
+		node := enode.SignNull(new(enr.Record), id)
+		addr := &net.TCPAddr{IP: net.ParseIP(ip), Port: 30303}
+		return &conn{fd: &fakeAddrConn{fd, addr}, transport: tx, flags: inboundConn, node: node, caps: caps, cont: make(chan error)}
+	}
+	caps := []Cap{discard.cap()}
+	oldID, youngID := randomID(), randomID()
+	if err := srv.checkpoint(newconn("1.2.3.1", oldID, caps), srv.checkpointAddPeer); err != nil {
+		t.Fatalf("could not add conn: %v", err)
+	}
+	if err := srv.checkpoint(newconn("1.2.3.2", youngID, caps), srv.checkpointAddPeer); err != nil {
+		t.Fatalf("could not add conn: %v", err)
+	}
+	// Connections failing the other checks must not evict anyone
+	if err := srv.checkpoint(newconn("9.9.9.9", randomID(), nil), srv.checkpointAddPeer); err != DiscUselessPeer {
+		t.Errorf("wrong error for useless conn: %v", err)
+	}
+	if err := srv.checkpoint(newconn("9.9.9.9", oldID, caps), srv.checkpointAddPeer); err != DiscAlreadyConnected {
+		t.Errorf("wrong error for duplicate conn: %v", err)
+	}
+	for _, p := range srv.Peers() {
+		if p.evicted {
+			t.Errorf("peer %v evicted for rejected connection", p.ID())
+		}
+	}
+	// A connection from another subnet evicts the youngest peer, and is only
+	// added after it left
+	newID := randomID()
+	if err := srv.checkpoint(newconn("9.9.9.9", newID, caps), srv.checkpointAddPeer); err != nil {
+		t.Fatalf("could not add conn: %v", err)
+	}
+	peers := make(map[enode.ID]bool)
+	for _, p := range srv.Peers() {
+		peers[p.ID()] = true
+	}
+	if len(peers) != 2 || !peers[oldID] || !peers[newID] {
+		t.Errorf("wrong peers after eviction: %v", peers)
+	}
+}
+
 func TestServerPeerLimits(t *testing.T) {
 	srvkey := newkey()
 	clientkey := newkey()
@@ -619,3 +680,84 @@ func syncAddPeer(srv *Server, node *enode.Node) bool {
 		}
 	}
 }
+
+// Tests that peers accumulating offences get disconnected and banned, and that
+// banned nodes cannot reconnect.
+func TestServerBanMisbehavingPeer(t *testing.T) {
+	connected := make(chan *Peer, 1)
+	remid := &newkey().PublicKey
+	srv := startTestServer(t, remid, func(p *Peer) {
+		connected <- p
+	})
+	defer srv.Stop()
+
+	events := make(chan *PeerEvent, 10)
+	sub := srv.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+
+	conn, err := net.DialTimeout("tcp", srv.ListenAddr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+
+	var peer *Peer
+	select {
+	case peer = <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("server did not accept within one second")
+	}
+	peer.Penalize(OffenceUselessResponse)
+	if srv.banned(peer.Node()) {
+		t.Fatal("peer banned below the ban threshold")
+	}
+	peer.Penalize(OffenceProtocolViolation)
+	peer.Penalize(OffenceProtocolViolation)
+	if !srv.banned(peer.Node()) {
+		t.Fatal("peer not banned above the ban threshold")
+	}
+	for ev := range events {
+		if ev.Type == PeerEventTypeDrop && ev.Peer == peer.ID() {
+			break
+		}
+	}
+	// Reconnecting should fail, until the ban is lifted
+	conn, err = net.DialTimeout("tcp", srv.ListenAddr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+	select {
+	case <-connected:
+		t.Fatal("banned peer reconnected")
+	case <-time.After(200 * time.Millisecond):
+	}
+	if bans := srv.Bans(); len(bans) != 1 || bans[0].ID != peer.ID().String() || bans[0].Expiry == nil {
+		t.Fatalf("ban list mismatch: %+v", bans)
+	}
+	if err := srv.UnbanNode(peer.ID()); err != nil {
+		t.Fatalf("failed to unban node: %v", err)
+	}
+	if srv.banned(peer.Node()) {
+		t.Fatal("peer banned after unban")
+	}
+}
+
+func TestServerBanIP(t *testing.T) {
+	srv := startTestServer(t, &newkey().PublicKey, nil)
+	defer srv.Stop()
+
+	ip := net.IP{95, 33, 21, 2}
+	if err := srv.checkInboundConn(nil, ip); err != nil {
+		t.Fatalf("unbanned IP rejected: %v", err)
+	}
+	if err := srv.BanIP(ip, 0); err != nil {
+		t.Fatalf("failed to ban IP: %v", err)
+	}
+	if err := srv.checkInboundConn(nil, ip); err != errBanned {
+		t.Fatalf("wrong error for banned IP: have %v, want %v", err, errBanned)
+	}
+	if bans := srv.Bans(); len(bans) != 1 || bans[0].IP != ip.String() || bans[0].Expiry != nil {
+		t.Fatalf("ban list mismatch: %+v", bans)
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package scenario
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// simBlockTime is the simulated time between two blocks in seconds, matching
+// the target block time of Ethereum Classic.
+const simBlockTime = 13
+
+// Services returns the service constructors built into the scenario runner.
+// The only one is "simchain", a lightweight simulated blockchain propagating
+// blocks between peers, optionally enforcing ECBP-1100 (MESS) artificial
+// finality on reorganizations.
+func Services() adapters.LifecycleConstructors {
+	return adapters.LifecycleConstructors{
+		"simchain": func(ctx *adapters.ServiceContext, stack *node.Node) (node.Lifecycle, error) {
+			chain := newSimChain(ctx.Config.ID)
+			stack.RegisterProtocols(chain.Protocols())
+			stack.RegisterAPIs(chain.APIs())
+			return chain, nil
+		},
+	}
+}
+
+// simBlock is a block of the simulated chain, reduced to the fields relevant
+// for fork choice.
+type simBlock struct {
+	Parent     common.Hash
+	Number     uint64
+	Difficulty uint64
+	Time       uint64
+	Miner      enode.ID
+
+	hash common.Hash
+	td   *big.Int
+}
+
+// Hash returns the identifier of the block.
+func (b *simBlock) Hash() common.Hash {
+	if b.hash == (common.Hash{}) {
+		blob, _ := rlp.EncodeToBytes(b)
+		b.hash = crypto.Keccak256Hash(blob)
+	}
+	return b.hash
+}
+
+// simGenesis is the genesis block shared by all simulated chains.
+var simGenesis = &simBlock{Difficulty: 1}
+
+// simChain is a simulated blockchain service. Blocks are produced on request
+// through the RPC API and propagated to all peers, which adopt the chain with
+// the highest total difficulty unless artificial finality rejects the reorg.
+type simChain struct {
+	self enode.ID
+
+	blocks   map[common.Hash]*simBlock
+	head     *simBlock
+	finality bool // Whether ECBP-1100 is enforced on reorgs
+	rejected int  // Number of reorgs rejected by ECBP-1100
+
+	peers map[enode.ID]chan []*simBlock // Outbound block queues of the peers
+	lock  sync.Mutex
+}
+
+func newSimChain(self enode.ID) *simChain {
+	genesis := &simBlock{Difficulty: simGenesis.Difficulty, td: new(big.Int).SetUint64(simGenesis.Difficulty)}
+	return &simChain{
+		self:   self,
+		blocks: map[common.Hash]*simBlock{genesis.Hash(): genesis},
+		head:   genesis,
+		peers:  make(map[enode.ID]chan []*simBlock),
+	}
+}
+
+// Protocols implements the protocols of the simulated chain.
+func (c *simChain) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    "simchain",
+		Version: 1,
+		Length:  1,
+		Run:     c.run,
+	}}
+}
+
+// APIs returns the RPC API of the simulated chain.
+func (c *simChain) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: "simchain",
+		Version:   "1.0",
+		Service:   &SimChainAPI{chain: c},
+		Public:    true,
+	}}
+}
+
+// Start implements node.Lifecycle.
+func (c *simChain) Start() error { return nil }
+
+// Stop implements node.Lifecycle.
+func (c *simChain) Stop() error { return nil }
+
+// run exchanges chains with a peer: the local chain is sent on connect and
+// whenever the head changes, the peer's chains are imported.
+func (c *simChain) run(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+	queue := make(chan []*simBlock, 16)
+
+	c.lock.Lock()
+	c.peers[peer.ID()] = queue
+	queue <- c.chain(c.head)
+	c.lock.Unlock()
+
+	defer func() {
+		c.lock.Lock()
+		delete(c.peers, peer.ID())
+		c.lock.Unlock()
+	}()
+
+	errc := make(chan error, 1)
+	quit := make(chan struct{})
+	defer close(quit)
+
+	go func() {
+		for {
+			select {
+			case blocks := <-queue:
+				if err := p2p.Send(rw, 0, blocks); err != nil {
+					errc <- err
+					return
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+	go func() {
+		for {
+			msg, err := rw.ReadMsg()
+			if err != nil {
+				errc <- err
+				return
+			}
+			var blocks []*simBlock
+			err = msg.Decode(&blocks)
+			msg.Discard()
+			if err != nil {
+				errc <- err
+				return
+			}
+			c.insert(blocks)
+		}
+	}()
+	return <-errc
+}
+
+// chain returns the blocks from genesis up to the given head. The caller must
+// hold the lock.
+func (c *simChain) chain(head *simBlock) []*simBlock {
+	blocks := make([]*simBlock, head.Number+1)
+	for block := head; ; block = c.blocks[block.Parent] {
+		blocks[block.Number] = block
+		if block.Number == 0 {
+			return blocks
+		}
+	}
+}
+
+// insert imports a chain of blocks and switches the head to it if it's heavier
+// and artificial finality permits the reorg.
+func (c *simChain) insert(blocks []*simBlock) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var best *simBlock
+	for _, block := range blocks {
+		if known, ok := c.blocks[block.Hash()]; ok {
+			best = known
+			continue
+		}
+		parent, ok := c.blocks[block.Parent]
+		if !ok || parent.Number+1 != block.Number {
+			return
+		}
+		block.td = new(big.Int).Add(parent.td, new(big.Int).SetUint64(block.Difficulty))
+		c.blocks[block.Hash()] = block
+		best = block
+	}
+	if best != nil {
+		c.setHead(best)
+	}
+}
+
+// setHead switches the head to the given block if it's heavier than the current
+// one and artificial finality permits the reorg. The caller must hold the lock.
+func (c *simChain) setHead(block *simBlock) {
+	if block.td.Cmp(c.head.td) <= 0 {
+		return
+	}
+	if c.finality {
+		ancestor := c.ancestor(c.head, block)
+		var (
+			local    = new(big.Int).Sub(c.head.td, ancestor.td)
+			proposed = new(big.Int).Sub(block.td, ancestor.td)
+		)
+		if !core.ECBP1100Accepts(c.head.Time-ancestor.Time, local, proposed) {
+			log.Debug("Simulated reorg rejected by artificial finality", "node", c.self, "common", ancestor.Number, "current", c.head.Number, "proposed", block.Number)
+			c.rejected++
+			return
+		}
+	}
+	c.head = block
+
+	chain := c.chain(block)
+	for _, queue := range c.peers {
+		select {
+		case queue <- chain:
+		default:
+		}
+	}
+}
+
+// ancestor returns the most recent common ancestor of two blocks. The caller
+// must hold the lock.
+func (c *simChain) ancestor(a, b *simBlock) *simBlock {
+	for a.Number > b.Number {
+		a = c.blocks[a.Parent]
+	}
+	for b.Number > a.Number {
+		b = c.blocks[b.Parent]
+	}
+	for a.Hash() != b.Hash() {
+		a, b = c.blocks[a.Parent], c.blocks[b.Parent]
+	}
+	return a
+}
+
+// SimChainAPI is the RPC API of the simulated chain, in the simchain namespace.
+type SimChainAPI struct {
+	chain *simChain
+}
+
+// Mine produces count blocks of the given difficulty on top of the current head
+// and returns the hash of the new head.
+func (api *SimChainAPI) Mine(count uint64, difficulty uint64) common.Hash {
+	c := api.chain
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if difficulty == 0 {
+		difficulty = 1
+	}
+	head := c.head
+	for i := uint64(0); i < count; i++ {
+		block := &simBlock{
+			Parent:     head.Hash(),
+			Number:     head.Number + 1,
+			Difficulty: difficulty,
+			Time:       head.Time + simBlockTime,
+			Miner:      c.self,
+		}
+		block.td = new(big.Int).Add(head.td, new(big.Int).SetUint64(difficulty))
+		c.blocks[block.Hash()] = block
+		head = block
+	}
+	c.setHead(head)
+	return c.head.Hash()
+}
+
+// Head returns the hash of the current head block.
+func (api *SimChainAPI) Head() common.Hash {
+	api.chain.lock.Lock()
+	defer api.chain.lock.Unlock()
+
+	return api.chain.head.Hash()
+}
+
+// Number returns the number of the current head block.
+func (api *SimChainAPI) Number() uint64 {
+	api.chain.lock.Lock()
+	defer api.chain.lock.Unlock()
+
+	return api.chain.head.Number
+}
+
+// Rejected returns the number of reorgs rejected by artificial finality.
+func (api *SimChainAPI) Rejected() int {
+	api.chain.lock.Lock()
+	defer api.chain.lock.Unlock()
+
+	return api.chain.rejected
+}
+
+// SetFinality enables or disables ECBP-1100 artificial finality.
+func (api *SimChainAPI) SetFinality(enable bool) {
+	api.chain.lock.Lock()
+	defer api.chain.lock.Unlock()
+
+	api.chain.finality = enable
+}
@@ -0,0 +1,374 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+)
+
+// pollInterval is the interval at which expectations are rechecked.
+const pollInterval = 50 * time.Millisecond
+
+// Result is the outcome of a scenario run.
+type Result struct {
+	Steps []*StepResult
+}
+
+// StepResult is the outcome of a single step of a scenario.
+type StepResult struct {
+	Index     int                      // Index of the step in the scenario
+	Action    string                   // Action performed
+	StartedAt time.Time                // Time the step started
+	Latencies map[string]time.Duration // Time it took each node to meet the expectation
+}
+
+// runner holds the state of a scenario run.
+type runner struct {
+	net   *simulations.Network
+	ids   map[string]enode.ID        // Node IDs by name
+	names []string                   // Node names in creation order
+	vars  map[string]json.RawMessage // Saved RPC results
+	cuts  [][2]enode.ID              // Connections severed by partitions
+}
+
+// Run executes the scenario on the given network, which should be empty. The
+// nodes are created and started, connected according to the topology and the
+// steps executed at their time offsets. The run is aborted with an error at the
+// first failing step.
+func Run(ctx context.Context, net *simulations.Network, sc *Scenario) (*Result, error) {
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	r := &runner{
+		net:  net,
+		ids:  make(map[string]enode.ID),
+		vars: make(map[string]json.RawMessage),
+	}
+	if err := r.setup(sc); err != nil {
+		return nil, err
+	}
+	// Execute the steps in the order of their time offsets
+	steps := make([]int, len(sc.Steps))
+	for i := range steps {
+		steps[i] = i
+	}
+	sort.SliceStable(steps, func(a, b int) bool { return sc.Steps[steps[a]].At < sc.Steps[steps[b]].At })
+
+	var (
+		result = new(Result)
+		start  = time.Now()
+	)
+	for _, i := range steps {
+		step := &sc.Steps[i]
+		select {
+		case <-time.After(time.Until(start.Add(time.Duration(step.At)))):
+		case <-ctx.Done():
+			return result, ctx.Err()
+		}
+		log.Debug("Executing scenario step", "index", i, "at", time.Duration(step.At), "action", step.Action)
+
+		res := &StepResult{Index: i, Action: step.Action, StartedAt: time.Now()}
+		result.Steps = append(result.Steps, res)
+		if err := r.execute(ctx, step, res); err != nil {
+			return result, fmt.Errorf("step %d (%s): %v", i, step.Action, err)
+		}
+	}
+	return result, nil
+}
+
+// setup creates and starts the nodes of the scenario and connects them.
+func (r *runner) setup(sc *Scenario) error {
+	for _, spec := range sc.Nodes {
+		names := (&Scenario{Nodes: []NodeSpec{spec}}).NodeNames()
+		for _, name := range names {
+			conf := adapters.RandomNodeConfig()
+			conf.Name = name
+			conf.Lifecycles = spec.Services
+			conf.Properties = spec.Properties
+
+			node, err := r.net.NewNodeWithConfig(conf)
+			if err != nil {
+				return fmt.Errorf("failed to create node %s: %v", name, err)
+			}
+			r.ids[name] = node.ID()
+			r.names = append(r.names, name)
+		}
+	}
+	if err := r.net.StartAll(); err != nil {
+		return err
+	}
+	ids := r.lookup(nil)
+
+	var err error
+	switch sc.Topology.Type {
+	case "chain":
+		err = r.net.ConnectNodesChain(ids)
+	case "ring":
+		err = r.net.ConnectNodesRing(ids)
+	case "star":
+		err = r.net.ConnectNodesStar(ids, r.ids[sc.Topology.Center])
+	case "full":
+		err = r.net.ConnectNodesFull(ids)
+	}
+	if err != nil {
+		return err
+	}
+	for _, link := range sc.Topology.Links {
+		if err := r.net.Connect(r.ids[link[0]], r.ids[link[1]]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup resolves node names into IDs, returning all nodes if none are given.
+func (r *runner) lookup(names []string) []enode.ID {
+	if len(names) == 0 {
+		names = r.names
+	}
+	ids := make([]enode.ID, len(names))
+	for i, name := range names {
+		ids[i] = r.ids[name]
+	}
+	return ids
+}
+
+// execute performs a single step of the scenario.
+func (r *runner) execute(ctx context.Context, step *Step, res *StepResult) error {
+	switch step.Action {
+	case ActionConnect:
+		return r.net.Connect(r.ids[step.Nodes[0]], r.ids[step.Nodes[1]])
+
+	case ActionDisconnect:
+		return r.net.Disconnect(r.ids[step.Nodes[0]], r.ids[step.Nodes[1]])
+
+	case ActionPartition:
+		return r.partition(step.Groups)
+
+	case ActionHeal:
+		return r.heal(ctx)
+
+	case ActionStart:
+		for _, id := range r.lookup(step.Nodes) {
+			if err := r.net.Start(id); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case ActionStop:
+		for _, id := range r.lookup(step.Nodes) {
+			if err := r.net.Stop(id); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case ActionRPC:
+		params, err := r.resolveParams(step.Params)
+		if err != nil {
+			return err
+		}
+		for i, id := range r.lookup(step.Nodes) {
+			var result json.RawMessage
+			if err := r.call(ctx, id, &result, step.Method, params); err != nil {
+				return err
+			}
+			if i == 0 && step.Save != "" {
+				r.vars[step.Save] = result
+			}
+		}
+		return nil
+
+	case ActionExpect:
+		return r.expect(ctx, step, res)
+	}
+	return fmt.Errorf("unknown action %q", step.Action)
+}
+
+// partition disconnects all live connections between nodes in different groups,
+// remembering them to be restored by a later heal.
+func (r *runner) partition(groups [][]string) error {
+	for i := range groups {
+		for j := i + 1; j < len(groups); j++ {
+			for _, one := range r.lookup(groups[i]) {
+				for _, other := range r.lookup(groups[j]) {
+					if conn := r.net.GetConn(one, other); conn == nil || !conn.Up {
+						continue
+					}
+					if err := r.net.Disconnect(one, other); err != nil {
+						return err
+					}
+					r.cuts = append(r.cuts, [2]enode.ID{one, other})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// heal restores all connections severed by partitions. The p2p dialer refuses
+// to redial recently dialed nodes, so both ends are asked to dial each other,
+// with the simulation network's connection model tracking the side succeeding.
+func (r *runner) heal(ctx context.Context) error {
+	for _, cut := range r.cuts {
+		if err := r.net.Connect(cut[0], cut[1]); err != nil {
+			return err
+		}
+		node := r.net.GetNode(cut[0])
+		if node == nil {
+			return fmt.Errorf("unknown node %s", cut[0])
+		}
+		if err := r.call(ctx, cut[1], nil, "admin_addPeer", []interface{}{string(node.Addr())}); err != nil {
+			return err
+		}
+	}
+	r.cuts = nil
+	return nil
+}
+
+// expect waits until all nodes of the step meet its expectation, recording the
+// time each node took.
+func (r *runner) expect(ctx context.Context, step *Step, res *StepResult) error {
+	exp := step.Expect
+
+	var want interface{}
+	if exp.Method != "" {
+		blob, err := r.resolve(exp.Result)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(blob, &want); err != nil {
+			return fmt.Errorf("invalid expected result: %v", err)
+		}
+	}
+	params, err := r.resolveParams(exp.Params)
+	if err != nil {
+		return err
+	}
+	names := step.Nodes
+	if len(names) == 0 {
+		names = r.names
+	}
+	res.Latencies = make(map[string]time.Duration)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(exp.Within))
+	defer cancel()
+
+	for {
+		var failures []string
+		for _, name := range names {
+			if _, ok := res.Latencies[name]; ok {
+				continue
+			}
+			ok, err := r.check(ctx, r.ids[name], exp, params, want)
+			if err != nil && ctx.Err() == nil {
+				return fmt.Errorf("node %s: %v", name, err)
+			}
+			if ok {
+				res.Latencies[name] = time.Since(res.StartedAt)
+				continue
+			}
+			failures = append(failures, name)
+		}
+		if len(failures) == 0 {
+			return nil
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("expectation not met within %v by %s", time.Duration(exp.Within), strings.Join(failures, ", "))
+		}
+	}
+}
+
+// check evaluates an expectation on a single node.
+func (r *runner) check(ctx context.Context, id enode.ID, exp *Expectation, params []interface{}, want interface{}) (bool, error) {
+	if exp.MinPeers != nil || exp.MaxPeers != nil {
+		var peers []*p2p.PeerInfo
+		if err := r.call(ctx, id, &peers, "admin_peers", nil); err != nil {
+			return false, err
+		}
+		if exp.MinPeers != nil && len(peers) < *exp.MinPeers {
+			return false, nil
+		}
+		if exp.MaxPeers != nil && len(peers) > *exp.MaxPeers {
+			return false, nil
+		}
+	}
+	if exp.Method != "" {
+		var have interface{}
+		if err := r.call(ctx, id, &have, exp.Method, params); err != nil {
+			return false, err
+		}
+		if !reflect.DeepEqual(have, want) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// call invokes an RPC method on the given node.
+func (r *runner) call(ctx context.Context, id enode.ID, result interface{}, method string, params []interface{}) error {
+	node := r.net.GetNode(id)
+	if node == nil {
+		return fmt.Errorf("unknown node %s", id)
+	}
+	client, err := node.Client()
+	if err != nil {
+		return err
+	}
+	return client.CallContext(ctx, result, method, params...)
+}
+
+// resolve substitutes a "$name" reference with the saved variable.
+func (r *runner) resolve(value json.RawMessage) (json.RawMessage, error) {
+	var name string
+	if err := json.Unmarshal(value, &name); err != nil || !strings.HasPrefix(name, "$") {
+		return value, nil
+	}
+	saved, ok := r.vars[name[1:]]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %s", name)
+	}
+	return saved, nil
+}
+
+// resolveParams substitutes saved variables into RPC parameters.
+func (r *runner) resolveParams(params []json.RawMessage) ([]interface{}, error) {
+	resolved := make([]interface{}, len(params))
+	for i, param := range params {
+		blob, err := r.resolve(param)
+		if err != nil {
+			return nil, err
+		}
+		resolved[i] = blob
+	}
+	return resolved, nil
+}
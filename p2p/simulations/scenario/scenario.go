@@ -0,0 +1,221 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package scenario runs reproducible experiments on simulated p2p networks.
+//
+// A scenario describes the nodes of a network, their initial topology and a
+// timeline of steps: network events like partitions, heals and peer drops, RPC
+// calls into the node services (e.g. to produce blocks) and expectations on
+// peer counts and on the propagation of state across the network.
+//
+// The built-in "simchain" service only models block propagation and fork choice.
+// It reuses the ECBP-1100 (MESS) acceptance polynomial to reject reorgs, but does
+// not run the reorg path of core.BlockChain, so its scenarios are not end-to-end
+// coverage of artificial finality.
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Duration is a time.Duration encoded in JSON as a string (e.g. "1m30s").
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(input []byte) error {
+	var s string
+	if err := json.Unmarshal(input, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Scenario is a reproducible network simulation experiment.
+type Scenario struct {
+	Name     string     `json:"name"`
+	Nodes    []NodeSpec `json:"nodes"`
+	Topology Topology   `json:"topology"`
+	Steps    []Step     `json:"steps"`
+}
+
+// NodeSpec describes a group of identical nodes in the network.
+type NodeSpec struct {
+	Name       string   `json:"name"`       // Node name, suffixed with an index if Count > 1
+	Count      int      `json:"count"`      // Number of nodes to create, defaults to 1
+	Services   []string `json:"services"`   // Services to run on the nodes
+	Properties []string `json:"properties"` // Properties to attach to the nodes
+}
+
+// Topology describes the initial connections between the nodes.
+type Topology struct {
+	Type   string      `json:"type"`   // One of chain, ring, star, full or empty for explicit links only
+	Center string      `json:"center"` // Center node of a star topology
+	Links  [][2]string `json:"links"`  // Additional connections between pairs of nodes
+}
+
+// Step actions.
+const (
+	ActionConnect    = "connect"    // Connect the two nodes in Nodes
+	ActionDisconnect = "disconnect" // Disconnect the two nodes in Nodes, i.e. drop a peer
+	ActionPartition  = "partition"  // Disconnect all nodes in different Groups
+	ActionHeal       = "heal"       // Reconnect all connections severed by partitions
+	ActionStart      = "start"      // Start the nodes in Nodes
+	ActionStop       = "stop"       // Stop the nodes in Nodes
+	ActionRPC        = "rpc"        // Call Method with Params on the nodes in Nodes
+	ActionExpect     = "expect"     // Wait for Expect to be met by the nodes in Nodes
+)
+
+// Step is a single event on the timeline of a scenario.
+type Step struct {
+	At     Duration          `json:"at"`     // Time offset from the start of the scenario
+	Action string            `json:"action"` // Action to perform
+	Nodes  []string          `json:"nodes"`  // Nodes to perform the action on, all if empty
+	Groups [][]string        `json:"groups"` // Node groups to partition the network into
+	Method string            `json:"method"` // RPC method to call
+	Params []json.RawMessage `json:"params"` // RPC parameters to call the method with
+	Save   string            `json:"save"`   // Variable to save the RPC result of the first node into
+	Expect *Expectation      `json:"expect"` // Expectation to wait for
+}
+
+// Expectation is a condition which all nodes of an expect step must meet
+// within a given time. Either the peer counts or an RPC result are checked.
+type Expectation struct {
+	MinPeers *int              `json:"minPeers"` // Minimum number of peers of each node
+	MaxPeers *int              `json:"maxPeers"` // Maximum number of peers of each node
+	Method   string            `json:"method"`   // RPC method to query
+	Params   []json.RawMessage `json:"params"`   // RPC parameters to query the method with
+	Result   json.RawMessage   `json:"result"`   // Expected result, or "$name" for a saved variable
+	Within   Duration          `json:"within"`   // Maximum time for all nodes to meet the expectation
+}
+
+// Load reads a scenario from a JSON file.
+func Load(path string) (*Scenario, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sc := new(Scenario)
+	if err := json.Unmarshal(blob, sc); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %v", path, err)
+	}
+	return sc, sc.Validate()
+}
+
+// NodeNames returns the names of all the nodes in the scenario, in creation order.
+func (sc *Scenario) NodeNames() []string {
+	var names []string
+	for _, spec := range sc.Nodes {
+		if spec.Count <= 1 {
+			names = append(names, spec.Name)
+			continue
+		}
+		for i := 1; i <= spec.Count; i++ {
+			names = append(names, fmt.Sprintf("%s%d", spec.Name, i))
+		}
+	}
+	return names
+}
+
+// Validate checks the scenario for consistency.
+func (sc *Scenario) Validate() error {
+	names := make(map[string]bool)
+	for _, name := range sc.NodeNames() {
+		if name == "" {
+			return errors.New("unnamed node")
+		}
+		if names[name] {
+			return fmt.Errorf("duplicate node %q", name)
+		}
+		names[name] = true
+	}
+	known := func(nodes ...string) error {
+		for _, name := range nodes {
+			if !names[name] {
+				return fmt.Errorf("unknown node %q", name)
+			}
+		}
+		return nil
+	}
+	switch sc.Topology.Type {
+	case "", "chain", "ring", "full":
+	case "star":
+		if err := known(sc.Topology.Center); err != nil {
+			return fmt.Errorf("topology: %v", err)
+		}
+	default:
+		return fmt.Errorf("unknown topology %q", sc.Topology.Type)
+	}
+	for _, link := range sc.Topology.Links {
+		if err := known(link[:]...); err != nil {
+			return fmt.Errorf("topology: %v", err)
+		}
+	}
+	for i, step := range sc.Steps {
+		if err := known(step.Nodes...); err != nil {
+			return fmt.Errorf("step %d: %v", i, err)
+		}
+		switch step.Action {
+		case ActionConnect, ActionDisconnect:
+			if len(step.Nodes) != 2 {
+				return fmt.Errorf("step %d: %s needs exactly two nodes", i, step.Action)
+			}
+		case ActionPartition:
+			if len(step.Groups) < 2 {
+				return fmt.Errorf("step %d: partition needs at least two groups", i)
+			}
+			for _, group := range step.Groups {
+				if len(group) == 0 {
+					return fmt.Errorf("step %d: empty partition group", i)
+				}
+				if err := known(group...); err != nil {
+					return fmt.Errorf("step %d: %v", i, err)
+				}
+			}
+		case ActionHeal, ActionStart, ActionStop:
+		case ActionRPC:
+			if step.Method == "" {
+				return fmt.Errorf("step %d: rpc needs a method", i)
+			}
+		case ActionExpect:
+			if step.Expect == nil {
+				return fmt.Errorf("step %d: expect needs an expectation", i)
+			}
+			if step.Expect.Method == "" && step.Expect.MinPeers == nil && step.Expect.MaxPeers == nil {
+				return fmt.Errorf("step %d: empty expectation", i)
+			}
+			if step.Expect.Method != "" && len(step.Expect.Result) == 0 {
+				return fmt.Errorf("step %d: expected result of %s missing", i, step.Expect.Method)
+			}
+		default:
+			return fmt.Errorf("step %d: unknown action %q", i, step.Action)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+)
+
+// partitionScenario splits a network of three nodes in two, lets each side
+// mine its own chain, heals the partition and finally checks the heads of all
+// nodes against the expectations substituted for the placeholders.
+const partitionScenario = `{
+	"name": "partition",
+	"nodes": [
+		{"name": "a", "count": 2, "services": ["simchain"]},
+		{"name": "b", "services": ["simchain"]}
+	],
+	"topology": {"type": "full"},
+	"steps": [
+		{"at": "0s", "action": "expect", "expect": {"minPeers": 2, "within": "10s"}},
+		{"at": "0s", "action": "rpc", "method": "simchain_setFinality", "params": [FINALITY]},
+		{"at": "0s", "action": "partition", "groups": [["a1", "a2"], ["b"]]},
+		{"at": "0s", "action": "expect", "expect": {"maxPeers": 1, "within": "10s"}},
+		{"at": "0s", "action": "rpc", "nodes": ["a1"], "method": "simchain_mine", "params": [1000, 1], "save": "headA"},
+		{"at": "0s", "action": "rpc", "nodes": ["b"], "method": "simchain_mine", "params": [1000, 2], "save": "headB"},
+		{"at": "0s", "action": "expect", "nodes": ["a1", "a2"], "expect": {"method": "simchain_head", "result": "$headA", "within": "10s"}},
+		{"at": "0s", "action": "heal"},
+		{"at": "0s", "action": "expect", "expect": {"minPeers": 2, "within": "10s"}},
+		{"at": "0s", "action": "expect", "nodes": ["a1", "a2"], "expect": {"method": "simchain_head", "result": "HEAD_A", "within": "10s"}},
+		{"at": "0s", "action": "expect", "nodes": ["b"], "expect": {"method": "simchain_head", "result": "$headB", "within": "10s"}}
+	]
+}`
+
+// runScenario runs the partition scenario and returns the number of reorgs
+// rejected by artificial finality on node a1.
+func runScenario(t *testing.T, finality bool) int {
+	spec := strings.Replace(partitionScenario, "FINALITY", "false", 1)
+	spec = strings.Replace(spec, "HEAD_A", "$headB", 1)
+	if finality {
+		spec = strings.Replace(partitionScenario, "FINALITY", "true", 1)
+		spec = strings.Replace(spec, "HEAD_A", "$headA", 1)
+	}
+	sc := new(Scenario)
+	if err := json.Unmarshal([]byte(spec), sc); err != nil {
+		t.Fatalf("failed to parse scenario: %v", err)
+	}
+	net := simulations.NewNetwork(adapters.NewSimAdapter(Services()), &simulations.NetworkConfig{DefaultService: "simchain"})
+	t.Cleanup(net.Shutdown)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	res, err := Run(ctx, net, sc)
+	if err != nil {
+		t.Fatalf("scenario failed: %v", err)
+	}
+	if len(res.Steps) != len(sc.Steps) {
+		t.Fatalf("step count mismatch: have %d, want %d", len(res.Steps), len(sc.Steps))
+	}
+	client, err := net.GetNodeByName("a1").Client()
+	if err != nil {
+		t.Fatalf("failed to attach to node: %v", err)
+	}
+	var rejected int
+	if err := client.Call(&rejected, "simchain_rejected"); err != nil {
+		t.Fatalf("failed to query rejections: %v", err)
+	}
+	return rejected
+}
+
+// Tests that without artificial finality the heavier chain wins after healing
+// a partition.
+func TestPartitionReorg(t *testing.T) {
+	rejected := runScenario(t, false)
+	if rejected != 0 {
+		t.Errorf("rejected reorgs mismatch: have %d, want 0", rejected)
+	}
+}
+
+// Tests that with ECBP-1100 enabled the nodes refuse to reorg onto a competing
+// chain of insufficient difficulty after healing a partition.
+func TestPartitionFinality(t *testing.T) {
+	rejected := runScenario(t, true)
+	if rejected == 0 {
+		t.Errorf("no reorgs rejected by artificial finality")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	nodes := []NodeSpec{{Name: "a", Count: 2}, {Name: "b"}}
+	tests := []struct {
+		sc   Scenario
+		fail string
+	}{
+		{sc: Scenario{Nodes: nodes, Topology: Topology{Type: "full"}}},
+		{sc: Scenario{Nodes: []NodeSpec{{Name: "a"}, {Name: "a"}}}, fail: "duplicate node"},
+		{sc: Scenario{Nodes: []NodeSpec{{}}}, fail: "unnamed node"},
+		{sc: Scenario{Nodes: nodes, Topology: Topology{Type: "mesh"}}, fail: "unknown topology"},
+		{sc: Scenario{Nodes: nodes, Topology: Topology{Type: "star", Center: "a"}}, fail: "unknown node"},
+		{sc: Scenario{Nodes: nodes, Topology: Topology{Links: [][2]string{{"a1", "c"}}}}, fail: "unknown node"},
+		{sc: Scenario{Nodes: nodes, Steps: []Step{{Action: ActionConnect, Nodes: []string{"a1"}}}}, fail: "exactly two nodes"},
+		{sc: Scenario{Nodes: nodes, Steps: []Step{{Action: ActionPartition, Groups: [][]string{{"a1"}}}}}, fail: "at least two groups"},
+		{sc: Scenario{Nodes: nodes, Steps: []Step{{Action: ActionPartition, Groups: [][]string{{"a1"}, {}}}}}, fail: "empty partition group"},
+		{sc: Scenario{Nodes: nodes, Steps: []Step{{Action: ActionRPC}}}, fail: "needs a method"},
+		{sc: Scenario{Nodes: nodes, Steps: []Step{{Action: ActionExpect}}}, fail: "needs an expectation"},
+		{sc: Scenario{Nodes: nodes, Steps: []Step{{Action: ActionExpect, Expect: &Expectation{Method: "simchain_head"}}}}, fail: "expected result of simchain_head missing"},
+		{sc: Scenario{Nodes: nodes, Steps: []Step{{Action: ActionExpect, Expect: &Expectation{Method: "simchain_head", Result: json.RawMessage(`"$head"`)}}}}},
+		{sc: Scenario{Nodes: nodes, Steps: []Step{{Action: "explode"}}}, fail: "unknown action"},
+	}
+	for i, tt := range tests {
+		err := tt.sc.Validate()
+		switch {
+		case tt.fail == "" && err != nil:
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		case tt.fail != "" && (err == nil || !strings.Contains(err.Error(), tt.fail)):
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.fail)
+		}
+	}
+}
@@ -345,3 +345,42 @@ func TestFoundationIsEIP779(t *testing.T) {
 		}
 	}
 }
+
+func TestConvertCheckpointOracle(t *testing.T) {
+	c := &coregeth.CoreGethChainConfig{}
+	if err := confp.Convert(MainnetChainConfig, c); err != nil {
+		t.Fatal(err)
+	}
+	if c.GetTrustedCheckpointOracle() != MainnetCheckpointOracle {
+		t.Fatalf("checkpoint oracle not converted: %v", c.GetTrustedCheckpointOracle())
+	}
+	if c.GetTrustedCheckpoint() != MainnetTrustedCheckpoint {
+		t.Fatalf("trusted checkpoint not converted: %v", c.GetTrustedCheckpoint())
+	}
+	// Ethereum Classic shares the genesis with Ethereum, but must never use
+	// its checkpoint oracle.
+	if ClassicChainConfig.GetTrustedCheckpointOracle() == MainnetCheckpointOracle {
+		t.Fatal("classic config uses the mainnet checkpoint oracle")
+	}
+}
+
+func TestCheckpointConfigs(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		config     ctypes.ChainConfigurator
+		checkpoint *ctypes.TrustedCheckpoint
+		oracle     *ctypes.CheckpointOracleConfig
+	}{
+		{"mainnet", MainnetChainConfig, MainnetTrustedCheckpoint, MainnetCheckpointOracle},
+		{"ropsten", RopstenChainConfig, RopstenTrustedCheckpoint, RopstenCheckpointOracle},
+		{"rinkeby", RinkebyChainConfig, RinkebyTrustedCheckpoint, RinkebyCheckpointOracle},
+		{"goerli", GoerliChainConfig, GoerliTrustedCheckpoint, GoerliCheckpointOracle},
+	} {
+		if have := tt.config.GetTrustedCheckpoint(); have != tt.checkpoint {
+			t.Errorf("%s: trusted checkpoint mismatch: have %v", tt.name, have)
+		}
+		if have := tt.config.GetTrustedCheckpointOracle(); have != tt.oracle {
+			t.Errorf("%s: checkpoint oracle mismatch: have %v", tt.name, have)
+		}
+	}
+}
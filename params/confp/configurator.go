@@ -37,7 +37,9 @@ var (
 	}
 )
 
-func nameSignalsCompatibility(name string) bool {
+// NameSignalsCompatibility tells if a transition name belongs to a protocol naming scheme
+// which is cross-compatible with configurations either having or lacking it.
+func NameSignalsCompatibility(name string) bool {
 	for _, s := range compatibleProtocolNameSchemes {
 		if regexp.MustCompile(s).MatchString(name) {
 			return true
@@ -154,7 +156,7 @@ func compatible(head *uint64, a, b ctypes.ChainConfigurator) *ConfigCompatError
 	for i, afn := range aFns {
 		// Skip cross-compatible namespaced transition names, assuming
 		// these will not be enforced as hardforks.
-		if nameSignalsCompatibility(aNames[i]) {
+		if NameSignalsCompatibility(aNames[i]) {
 			continue
 		}
 		if err := func(c1, c2, head *uint64) *ConfigCompatError {
@@ -280,7 +282,7 @@ func Forks(conf ctypes.ChainConfigurator) []uint64 {
 	for i, tr := range transitions {
 		// Skip cross-compatible namespaced transition names, assuming
 		// these will not be enforced as hardforks.
-		if nameSignalsCompatibility(names[i]) {
+		if NameSignalsCompatibility(names[i]) {
 			continue
 		}
 		// Extract the fork rule block number and aggregate it
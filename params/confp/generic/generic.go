@@ -139,6 +139,15 @@ func UnmarshalChainConfigurator(input []byte) (ctypes.ChainConfigurator, error)
 	return nil, errors.New("invalid configurator schema")
 }
 
+// UnmarshalChainConfiguratorOrGenesis is like UnmarshalChainConfigurator, but also accepts
+// genesis-formatted input, in which case the configurator is read from the 'config' field.
+func UnmarshalChainConfiguratorOrGenesis(input []byte) (ctypes.ChainConfigurator, error) {
+	if c := gjson.GetBytes(input, "config"); c.IsObject() {
+		input = []byte(c.Raw)
+	}
+	return UnmarshalChainConfigurator(input)
+}
+
 func asMapHasAnyKey(input []byte, keys []string) (bool, error) {
 	results := gjson.GetManyBytes(input, keys...)
 	for _, g := range results {
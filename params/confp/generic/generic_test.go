@@ -296,3 +296,30 @@ func TestUnmarshalChainConfigurator2(t *testing.T) {
 		}
 	}
 }
+
+func TestUnmarshalChainConfiguratorOrGenesis(t *testing.T) {
+	cases := []struct {
+		file  string
+		wantT interface{}
+	}{
+		{filepath.Join("..", "testdata", "stureby_parity.json"), &parity.ParityChainSpec{}},
+		{filepath.Join("..", "testdata", "stureby_geth.json"), &goethereum.ChainConfig{}},
+		{filepath.Join("..", "testdata", "stureby_multigeth.json"), &coregeth.CoreGethChainConfig{}},
+	}
+	for i, c := range cases {
+		b, err := ioutil.ReadFile(c.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := UnmarshalChainConfiguratorOrGenesis(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.TypeOf(got) != reflect.TypeOf(c.wantT) {
+			t.Errorf("%d / wrong type: want %T, got %T", i, c.wantT, got)
+		}
+		if got.GetChainID().Cmp(big.NewInt(314158)) != 0 {
+			t.Errorf("%d / wrong chain id: want %d, got %v", i, 314158, got.GetChainID())
+		}
+	}
+}
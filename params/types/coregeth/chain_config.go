@@ -165,6 +165,26 @@ type CoreGethChainConfig struct {
 	// https://github.com/ethereum/EIPs/pull/2537: BLS12-381 curve operations
 	EIP2537FBlock *big.Int `json:"eip2315FBlock,omitempty"`
 
+	// EIP-3529: Reduction in refunds
+	// https://eips.ethereum.org/EIPS/eip-3529
+	EIP3529FBlock *big.Int `json:"eip3529FBlock,omitempty"`
+
+	// EIP-3541: Reject new contracts starting with the 0xEF byte
+	// https://eips.ethereum.org/EIPS/eip-3541
+	EIP3541FBlock *big.Int `json:"eip3541FBlock,omitempty"`
+
+	// EIP-3651: Warm COINBASE
+	// https://eips.ethereum.org/EIPS/eip-3651
+	EIP3651FBlock *big.Int `json:"eip3651FBlock,omitempty"`
+
+	// EIP-3855: PUSH0 instruction
+	// https://eips.ethereum.org/EIPS/eip-3855
+	EIP3855FBlock *big.Int `json:"eip3855FBlock,omitempty"`
+
+	// EIP-3860: Limit and meter initcode
+	// https://eips.ethereum.org/EIPS/eip-3860
+	EIP3860FBlock *big.Int `json:"eip3860FBlock,omitempty"`
+
 	//EWASMBlock *big.Int `json:"ewasmBlock,omitempty"` // EWASM switch block (nil = no fork, 0 = already activated)
 
 	ECIP1010PauseBlock *big.Int `json:"ecip1010PauseBlock,omitempty"` // ECIP1010 pause HF block
@@ -390,6 +390,51 @@ func (c *CoreGethChainConfig) SetECBP1100Transition(n *uint64) error {
 	return nil
 }
 
+func (c *CoreGethChainConfig) GetEIP3529Transition() *uint64 {
+	return bigNewU64(c.EIP3529FBlock)
+}
+
+func (c *CoreGethChainConfig) SetEIP3529Transition(n *uint64) error {
+	c.EIP3529FBlock = setBig(c.EIP3529FBlock, n)
+	return nil
+}
+
+func (c *CoreGethChainConfig) GetEIP3541Transition() *uint64 {
+	return bigNewU64(c.EIP3541FBlock)
+}
+
+func (c *CoreGethChainConfig) SetEIP3541Transition(n *uint64) error {
+	c.EIP3541FBlock = setBig(c.EIP3541FBlock, n)
+	return nil
+}
+
+func (c *CoreGethChainConfig) GetEIP3651Transition() *uint64 {
+	return bigNewU64(c.EIP3651FBlock)
+}
+
+func (c *CoreGethChainConfig) SetEIP3651Transition(n *uint64) error {
+	c.EIP3651FBlock = setBig(c.EIP3651FBlock, n)
+	return nil
+}
+
+func (c *CoreGethChainConfig) GetEIP3855Transition() *uint64 {
+	return bigNewU64(c.EIP3855FBlock)
+}
+
+func (c *CoreGethChainConfig) SetEIP3855Transition(n *uint64) error {
+	c.EIP3855FBlock = setBig(c.EIP3855FBlock, n)
+	return nil
+}
+
+func (c *CoreGethChainConfig) GetEIP3860Transition() *uint64 {
+	return bigNewU64(c.EIP3860FBlock)
+}
+
+func (c *CoreGethChainConfig) SetEIP3860Transition(n *uint64) error {
+	c.EIP3860FBlock = setBig(c.EIP3860FBlock, n)
+	return nil
+}
+
 func (c *CoreGethChainConfig) IsEnabled(fn func() *uint64, n *big.Int) bool {
 	f := fn()
 	if f == nil || n == nil {
@@ -422,6 +467,24 @@ func (c *CoreGethChainConfig) GetForkCanonHashes() map[uint64]common.Hash {
 	return c.RequireBlockHashes
 }
 
+func (c *CoreGethChainConfig) GetTrustedCheckpoint() *ctypes.TrustedCheckpoint {
+	return c.TrustedCheckpoint
+}
+
+func (c *CoreGethChainConfig) SetTrustedCheckpoint(cp *ctypes.TrustedCheckpoint) error {
+	c.TrustedCheckpoint = cp
+	return nil
+}
+
+func (c *CoreGethChainConfig) GetTrustedCheckpointOracle() *ctypes.CheckpointOracleConfig {
+	return c.TrustedCheckpointOracle
+}
+
+func (c *CoreGethChainConfig) SetTrustedCheckpointOracle(o *ctypes.CheckpointOracleConfig) error {
+	c.TrustedCheckpointOracle = o
+	return nil
+}
+
 func (c *CoreGethChainConfig) GetConsensusEngineType() ctypes.ConsensusEngineT {
 	if c.Ethash != nil {
 		return ctypes.ConsensusEngineT_Ethash
@@ -45,7 +45,7 @@ type ChainConfigurator interface {
 	ProtocolSpecifier
 	Forker
 	ConsensusEnginator // Consensus Engine
-	// CHTer
+	CHTer
 }
 
 // ProtocolSpecifier defines protocol interfaces that are agnostic of consensus engine.
@@ -132,6 +132,16 @@ type ProtocolSpecifier interface {
 	SetEIP2537Transition(n *uint64) error
 	GetECBP1100Transition() *uint64
 	SetECBP1100Transition(n *uint64) error
+	GetEIP3529Transition() *uint64
+	SetEIP3529Transition(n *uint64) error
+	GetEIP3541Transition() *uint64
+	SetEIP3541Transition(n *uint64) error
+	GetEIP3651Transition() *uint64
+	SetEIP3651Transition(n *uint64) error
+	GetEIP3855Transition() *uint64
+	SetEIP3855Transition(n *uint64) error
+	GetEIP3860Transition() *uint64
+	SetEIP3860Transition(n *uint64) error
 }
 
 type Forker interface {
@@ -146,6 +156,15 @@ type Forker interface {
 	GetForkCanonHashes() map[uint64]common.Hash
 }
 
+// CHTer defines the light client checkpointing parameters of a chain: the
+// hardcoded trusted checkpoint and the on-chain checkpoint oracle.
+type CHTer interface {
+	GetTrustedCheckpoint() *TrustedCheckpoint
+	SetTrustedCheckpoint(c *TrustedCheckpoint) error
+	GetTrustedCheckpointOracle() *CheckpointOracleConfig
+	SetTrustedCheckpointOracle(c *CheckpointOracleConfig) error
+}
+
 type ConsensusEnginator interface {
 	GetConsensusEngineType() ConsensusEngineT
 	MustSetConsensusEngineType(t ConsensusEngineT) error
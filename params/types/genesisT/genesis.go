@@ -552,6 +552,46 @@ func (g *Genesis) SetECBP1100Transition(n *uint64) error {
 	return g.Config.SetECBP1100Transition(n)
 }
 
+func (g *Genesis) GetEIP3529Transition() *uint64 {
+	return g.Config.GetEIP3529Transition()
+}
+
+func (g *Genesis) SetEIP3529Transition(n *uint64) error {
+	return g.Config.SetEIP3529Transition(n)
+}
+
+func (g *Genesis) GetEIP3541Transition() *uint64 {
+	return g.Config.GetEIP3541Transition()
+}
+
+func (g *Genesis) SetEIP3541Transition(n *uint64) error {
+	return g.Config.SetEIP3541Transition(n)
+}
+
+func (g *Genesis) GetEIP3651Transition() *uint64 {
+	return g.Config.GetEIP3651Transition()
+}
+
+func (g *Genesis) SetEIP3651Transition(n *uint64) error {
+	return g.Config.SetEIP3651Transition(n)
+}
+
+func (g *Genesis) GetEIP3855Transition() *uint64 {
+	return g.Config.GetEIP3855Transition()
+}
+
+func (g *Genesis) SetEIP3855Transition(n *uint64) error {
+	return g.Config.SetEIP3855Transition(n)
+}
+
+func (g *Genesis) GetEIP3860Transition() *uint64 {
+	return g.Config.GetEIP3860Transition()
+}
+
+func (g *Genesis) SetEIP3860Transition(n *uint64) error {
+	return g.Config.SetEIP3860Transition(n)
+}
+
 func (g *Genesis) IsEnabled(fn func() *uint64, n *big.Int) bool {
 	return g.Config.IsEnabled(fn, n)
 }
@@ -568,6 +608,22 @@ func (g *Genesis) GetForkCanonHashes() map[uint64]common.Hash {
 	return g.Config.GetForkCanonHashes()
 }
 
+func (g *Genesis) GetTrustedCheckpoint() *ctypes.TrustedCheckpoint {
+	return g.Config.GetTrustedCheckpoint()
+}
+
+func (g *Genesis) SetTrustedCheckpoint(cp *ctypes.TrustedCheckpoint) error {
+	return g.Config.SetTrustedCheckpoint(cp)
+}
+
+func (g *Genesis) GetTrustedCheckpointOracle() *ctypes.CheckpointOracleConfig {
+	return g.Config.GetTrustedCheckpointOracle()
+}
+
+func (g *Genesis) SetTrustedCheckpointOracle(o *ctypes.CheckpointOracleConfig) error {
+	return g.Config.SetTrustedCheckpointOracle(o)
+}
+
 func (g *Genesis) GetConsensusEngineType() ctypes.ConsensusEngineT {
 	return g.Config.GetConsensusEngineType()
 }
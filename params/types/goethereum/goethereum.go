@@ -70,6 +70,11 @@ type ChainConfig struct {
 
 	EIP1706Transition  *big.Int `json:"-"`
 	ECIP1080Transition *big.Int `json:"-"`
+	EIP3529Transition  *big.Int `json:"-"`
+	EIP3541Transition  *big.Int `json:"-"`
+	EIP3651Transition  *big.Int `json:"-"`
+	EIP3855Transition  *big.Int `json:"-"`
+	EIP3860Transition  *big.Int `json:"-"`
 
 	// Cache types for use with testing, but will not show up in config API.
 	ecbp1100Transition *big.Int
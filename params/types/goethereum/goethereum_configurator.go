@@ -400,6 +400,51 @@ func (c *ChainConfig) SetECBP1100Transition(n *uint64) error {
 	return nil
 }
 
+func (c *ChainConfig) GetEIP3529Transition() *uint64 {
+	return bigNewU64(c.EIP3529Transition)
+}
+
+func (c *ChainConfig) SetEIP3529Transition(n *uint64) error {
+	c.EIP3529Transition = setBig(c.EIP3529Transition, n)
+	return nil
+}
+
+func (c *ChainConfig) GetEIP3541Transition() *uint64 {
+	return bigNewU64(c.EIP3541Transition)
+}
+
+func (c *ChainConfig) SetEIP3541Transition(n *uint64) error {
+	c.EIP3541Transition = setBig(c.EIP3541Transition, n)
+	return nil
+}
+
+func (c *ChainConfig) GetEIP3651Transition() *uint64 {
+	return bigNewU64(c.EIP3651Transition)
+}
+
+func (c *ChainConfig) SetEIP3651Transition(n *uint64) error {
+	c.EIP3651Transition = setBig(c.EIP3651Transition, n)
+	return nil
+}
+
+func (c *ChainConfig) GetEIP3855Transition() *uint64 {
+	return bigNewU64(c.EIP3855Transition)
+}
+
+func (c *ChainConfig) SetEIP3855Transition(n *uint64) error {
+	c.EIP3855Transition = setBig(c.EIP3855Transition, n)
+	return nil
+}
+
+func (c *ChainConfig) GetEIP3860Transition() *uint64 {
+	return bigNewU64(c.EIP3860Transition)
+}
+
+func (c *ChainConfig) SetEIP3860Transition(n *uint64) error {
+	c.EIP3860Transition = setBig(c.EIP3860Transition, n)
+	return nil
+}
+
 func (c *ChainConfig) IsEnabled(fn func() *uint64, n *big.Int) bool {
 	f := fn()
 	if f == nil || n == nil {
@@ -432,6 +477,24 @@ func (c *ChainConfig) GetForkCanonHashes() map[uint64]common.Hash {
 	}
 }
 
+func (c *ChainConfig) GetTrustedCheckpoint() *ctypes.TrustedCheckpoint {
+	return c.TrustedCheckpoint
+}
+
+func (c *ChainConfig) SetTrustedCheckpoint(cp *ctypes.TrustedCheckpoint) error {
+	c.TrustedCheckpoint = cp
+	return nil
+}
+
+func (c *ChainConfig) GetTrustedCheckpointOracle() *ctypes.CheckpointOracleConfig {
+	return c.TrustedCheckpointOracle
+}
+
+func (c *ChainConfig) SetTrustedCheckpointOracle(o *ctypes.CheckpointOracleConfig) error {
+	c.TrustedCheckpointOracle = o
+	return nil
+}
+
 func (c *ChainConfig) GetConsensusEngineType() ctypes.ConsensusEngineT {
 	if c.Clique != nil {
 		return ctypes.ConsensusEngineT_Clique
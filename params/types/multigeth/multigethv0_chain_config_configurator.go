@@ -427,6 +427,61 @@ func (c *ChainConfig) SetECBP1100Transition(n *uint64) error {
 	return ctypes.ErrUnsupportedConfigFatal
 }
 
+func (c *ChainConfig) GetEIP3529Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP3529Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (c *ChainConfig) GetEIP3541Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP3541Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (c *ChainConfig) GetEIP3651Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP3651Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (c *ChainConfig) GetEIP3855Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP3855Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (c *ChainConfig) GetEIP3860Transition() *uint64 {
+	return nil
+}
+
+func (c *ChainConfig) SetEIP3860Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
 func (c *ChainConfig) IsEnabled(fn func() *uint64, n *big.Int) bool {
 	f := fn()
 	if f == nil || n == nil {
@@ -459,6 +514,29 @@ func (c *ChainConfig) GetForkCanonHashes() map[uint64]common.Hash {
 	}
 }
 
+// Multi-geth chain configs do not carry light client checkpoints.
+func (c *ChainConfig) GetTrustedCheckpoint() *ctypes.TrustedCheckpoint {
+	return nil
+}
+
+func (c *ChainConfig) SetTrustedCheckpoint(cp *ctypes.TrustedCheckpoint) error {
+	if cp == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+func (c *ChainConfig) GetTrustedCheckpointOracle() *ctypes.CheckpointOracleConfig {
+	return nil
+}
+
+func (c *ChainConfig) SetTrustedCheckpointOracle(o *ctypes.CheckpointOracleConfig) error {
+	if o == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
 func (c *ChainConfig) GetConsensusEngineType() ctypes.ConsensusEngineT {
 	if c.Clique != nil {
 		return ctypes.ConsensusEngineT_Clique
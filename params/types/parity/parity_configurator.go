@@ -635,6 +635,57 @@ func (spec *ParityChainSpec) SetECBP1100Transition(n *uint64) error {
 	return ctypes.ErrUnsupportedConfigFatal
 }
 
+func (spec *ParityChainSpec) GetEIP3529Transition() *uint64 {
+	return spec.Params.EIP3529Transition.Uint64P()
+}
+
+func (spec *ParityChainSpec) SetEIP3529Transition(n *uint64) error {
+	spec.Params.EIP3529Transition = new(ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP3541Transition() *uint64 {
+	return spec.Params.EIP3541Transition.Uint64P()
+}
+
+func (spec *ParityChainSpec) SetEIP3541Transition(n *uint64) error {
+	spec.Params.EIP3541Transition = new(ParityU64).SetUint64(n)
+	return nil
+}
+
+func (spec *ParityChainSpec) GetEIP3651Transition() *uint64 {
+	return nil
+}
+
+func (spec *ParityChainSpec) SetEIP3651Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (spec *ParityChainSpec) GetEIP3855Transition() *uint64 {
+	return nil
+}
+
+func (spec *ParityChainSpec) SetEIP3855Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
+func (spec *ParityChainSpec) GetEIP3860Transition() *uint64 {
+	return nil
+}
+
+func (spec *ParityChainSpec) SetEIP3860Transition(n *uint64) error {
+	if n == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigFatal
+}
+
 func (spec *ParityChainSpec) IsEnabled(fn func() *uint64, n *big.Int) bool {
 	f := fn()
 	if f == nil || n == nil {
@@ -668,6 +719,29 @@ func (spec *ParityChainSpec) GetForkCanonHashes() map[uint64]common.Hash {
 	}
 }
 
+// Parity chain specs do not carry light client checkpoints.
+func (spec *ParityChainSpec) GetTrustedCheckpoint() *ctypes.TrustedCheckpoint {
+	return nil
+}
+
+func (spec *ParityChainSpec) SetTrustedCheckpoint(cp *ctypes.TrustedCheckpoint) error {
+	if cp == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
+func (spec *ParityChainSpec) GetTrustedCheckpointOracle() *ctypes.CheckpointOracleConfig {
+	return nil
+}
+
+func (spec *ParityChainSpec) SetTrustedCheckpointOracle(o *ctypes.CheckpointOracleConfig) error {
+	if o == nil {
+		return nil
+	}
+	return ctypes.ErrUnsupportedConfigNoop
+}
+
 // GetConsensusEngineType uses select indicator fields to determine if the
 // config is Clique or Ethash. This is an important logic! Read it!
 func (spec *ParityChainSpec) GetConsensusEngineType() ctypes.ConsensusEngineT {
@@ -71,6 +71,13 @@ var (
 	SstoreResetGasEIP2200             uint64 = 5000  // Once per SSTORE operation from clean non-zero to something else
 	SstoreClearsScheduleRefundEIP2200 uint64 = 15000 // Once per SSTORE operation for clearing an originally existing storage slot
 
+	// In EIP-3529: SSTORE_CLEARS_SCHEDULE is defined as SSTORE_RESET_GAS + ACCESS_LIST_STORAGE_KEY_COST
+	// Which becomes: 5000 - 2100 + 1900 = 4800
+	SstoreClearsScheduleRefundEIP3529 uint64 = 4800
+
+	RefundQuotient        uint64 = 2 // Maximum refund quotient; max gas refund is gasUsed / RefundQuotient
+	RefundQuotientEIP3529 uint64 = 5 // Maximum refund quotient after EIP-3529
+
 	JumpdestGas   uint64 = 1     // Once per JUMPDEST operation.
 	EpochDuration uint64 = 30000 // Duration between proof-of-work epochs.
 
@@ -119,7 +126,9 @@ var (
 	// Introduced in Tangerine Whistle (Eip 150)
 	CreateBySelfdestructGas uint64 = 25000
 
-	MaxCodeSize uint64 = 24576 // Maximum bytecode to permit for a contract
+	MaxCodeSize     uint64 = 24576           // Maximum bytecode to permit for a contract
+	MaxInitCodeSize uint64 = 2 * MaxCodeSize // Maximum initcode to permit in a creation transaction and create instructions (EIP-3860)
+	InitCodeWordGas uint64 = 2               // Once per word of the init code when creating a contract (EIP-3860)
 
 	// Precompiled contract gas prices
 
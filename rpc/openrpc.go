@@ -16,6 +16,23 @@
 
 package rpc
 
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// openRPCVersion is the version of the OpenRPC specification the generated documents conform to.
+const openRPCVersion = "1.2.6"
+
 type OpenRPCDiscoverSchemaT struct {
 	OpenRPC    string                   `json:"openrpc"`
 	Info       map[string]interface{}   `json:"info"`
@@ -23,3 +40,513 @@ type OpenRPCDiscoverSchemaT struct {
 	Methods    []map[string]interface{} `json:"methods"`
 	Components map[string]interface{}   `json:"components"`
 }
+
+const (
+	hexIntegerPattern = "^0x([1-9a-fA-F][0-9a-fA-F]*|0)$"
+	hexBytesPattern   = "^0x([0-9a-fA-F]{2})*$"
+)
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	hexIntegerSchema  = map[string]interface{}{"type": "string", "pattern": hexIntegerPattern}
+	blockNumberSchema = map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string", "enum": []interface{}{"earliest", "latest", "pending"}},
+			hexIntegerSchema,
+		},
+	}
+	hashSchema = map[string]interface{}{"type": "string", "pattern": "^0x[0-9a-fA-F]{64}$"}
+)
+
+// openRPCTypeSchemas are the JSON schemas of types whose JSON encoding can not be
+// derived from their Go definitions.
+var openRPCTypeSchemas = map[reflect.Type]map[string]interface{}{
+	reflect.TypeOf(hexutil.Big{}):     hexIntegerSchema,
+	reflect.TypeOf(hexutil.Uint64(0)): hexIntegerSchema,
+	reflect.TypeOf(hexutil.Uint(0)):   hexIntegerSchema,
+	reflect.TypeOf(hexutil.Bytes{}):   {"type": "string", "pattern": hexBytesPattern},
+	reflect.TypeOf(big.Int{}):         {"type": "integer"},
+	reflect.TypeOf(common.Hash{}):     hashSchema,
+	reflect.TypeOf(common.Address{}):  {"type": "string", "pattern": "^0x[0-9a-fA-F]{40}$"},
+	reflect.TypeOf(BlockNumber(0)):    blockNumberSchema,
+	reflect.TypeOf(BlockNumberOrHash{}): {
+		// Block hashes are also valid hex integers, but take precedence.
+		"anyOf": []interface{}{
+			blockNumberSchema,
+			hashSchema,
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"blockNumber":      blockNumberSchema,
+					"blockHash":        hashSchema,
+					"requireCanonical": map[string]interface{}{"type": "boolean"},
+				},
+			},
+		},
+	},
+	reflect.TypeOf(ID("")):                     {"type": "string"},
+	reflect.TypeOf(Subscription{}):             {"type": "string"},
+	reflect.TypeOf(json.RawMessage{}):          {},
+	reflect.TypeOf([]byte{}):                   {"type": "string", "contentEncoding": "base64"},
+	reflect.TypeOf((*interface{})(nil)).Elem(): {},
+}
+
+// openRPCGenerator builds the methods and component schemas of an OpenRPC document
+// from registered services.
+//
+// Schemas describe either the encoding of values (results), or the values accepted
+// when decoding (parameters). These differ for types with custom JSON decoding, and in
+// that struct fields are never required when decoding.
+type openRPCGenerator struct {
+	schemas map[string]interface{}  // component schemas, by name
+	names   map[componentKey]string // component schema names, by type
+	input   bool                    // whether schemas describe decoded values
+}
+
+type componentKey struct {
+	t     reflect.Type
+	input bool
+}
+
+func newOpenRPCGenerator() *openRPCGenerator {
+	return &openRPCGenerator{
+		schemas: make(map[string]interface{}),
+		names:   make(map[componentKey]string),
+	}
+}
+
+// inputSchema returns the JSON schema describing the values accepted when decoding
+// values of the given type.
+func (g *openRPCGenerator) inputSchema(t reflect.Type) map[string]interface{} {
+	g.input = true
+	defer func() { g.input = false }()
+	return g.schema(t)
+}
+
+// schema returns the JSON schema describing the JSON encoding of values of the given type.
+// Named struct types are added to the component schemas and referenced.
+func (g *openRPCGenerator) schema(t reflect.Type) map[string]interface{} {
+	if s, ok := openRPCTypeSchemas[t]; ok {
+		return s
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		if s, ok := openRPCTypeSchemas[t.Elem()]; ok {
+			return nullable(s)
+		}
+		return nullable(g.schema(t.Elem()))
+	case reflect.Interface:
+		return map[string]interface{}{}
+	}
+	// The encodings of types implementing custom JSON encoding can not be derived from
+	// their definitions, though structs generally retain their tagged field names.
+	jsonType, textType := jsonMarshalerType, textMarshalerType
+	if g.input {
+		jsonType, textType = jsonUnmarshalerType, textUnmarshalerType
+	}
+	// Types with text encodings (eg. time.Time) generally use them as JSON encoding as well.
+	if t.Implements(textType) || reflect.PtrTo(t).Implements(textType) {
+		return map[string]interface{}{"type": "string"}
+	}
+	if t.Implements(jsonType) || reflect.PtrTo(t).Implements(jsonType) {
+		if t.Kind() == reflect.Struct {
+			return g.component(t, func() map[string]interface{} {
+				return g.opaqueStruct(t)
+			})
+		}
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return nullable(map[string]interface{}{"type": "array", "items": g.schema(t.Elem())})
+	case reflect.Array:
+		return map[string]interface{}{
+			"type":     "array",
+			"items":    g.schema(t.Elem()),
+			"minItems": t.Len(),
+			"maxItems": t.Len(),
+		}
+	case reflect.Map:
+		return nullable(map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())})
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.component(t, func() map[string]interface{} {
+			return g.structSchema(t)
+		})
+	}
+	// Channels, functions and complex numbers can not be encoded.
+	return map[string]interface{}{}
+}
+
+// component adds the schema of a named type to the component schemas, returning a reference to it.
+func (g *openRPCGenerator) component(t reflect.Type, build func() map[string]interface{}) map[string]interface{} {
+	key := componentKey{t, g.input}
+	name, ok := g.names[key]
+	if !ok {
+		name = componentName(t)
+		if g.schemas[name] != nil {
+			// Distinguish the parameter and result schemas of a type.
+			if g.input {
+				name += "Input"
+			} else {
+				name += "Result"
+			}
+		}
+		for i := 2; g.schemas[name] != nil; i++ {
+			name = fmt.Sprintf("%s%d", componentName(t), i)
+		}
+		// Register the name before building the schema, allowing recursive types.
+		g.names[key] = name
+		g.schemas[name] = map[string]interface{}{}
+		g.schemas[name] = build()
+
+		// Share the component with the other direction if the schemas are equal.
+		if other, ok := g.names[componentKey{t, !g.input}]; ok && reflect.DeepEqual(g.schemas[other], g.schemas[name]) {
+			delete(g.schemas, name)
+			g.names[key] = other
+			name = other
+		}
+	}
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// componentName returns the package-qualified name of a type, eg. 'types.Header'.
+func componentName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if pkg == "" {
+		return t.Name()
+	}
+	return pkg + "." + t.Name()
+}
+
+// structSchema describes a struct type as encoded by encoding/json.
+func (g *openRPCGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []interface{}
+	g.structFields(t, func(name string, field reflect.StructField, omitEmpty, asString bool) {
+		var s map[string]interface{}
+		if asString {
+			s = map[string]interface{}{"type": "string"}
+		} else {
+			s = g.schema(field.Type)
+		}
+		properties[name] = s
+		if !omitEmpty && !g.input {
+			required = append(required, name)
+		}
+	})
+	s := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// opaqueStruct describes a struct type with a custom JSON encoding. Such encodings
+// generally retain the struct's tagged field names, but not its field types.
+func (g *openRPCGenerator) opaqueStruct(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	g.structFields(t, func(name string, field reflect.StructField, _, _ bool) {
+		if _, tagged := field.Tag.Lookup("json"); tagged {
+			properties[name] = map[string]interface{}{}
+		}
+	})
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// structFields calls fn for each field of a struct type encoded by encoding/json,
+// including the fields of embedded structs.
+func (g *openRPCGenerator) structFields(t reflect.Type, fn func(name string, field reflect.StructField, omitEmpty, asString bool)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		name := opts[0]
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.structFields(ft, fn)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		var omitEmpty, asString bool
+		for _, opt := range opts[1:] {
+			switch opt {
+			case "omitempty":
+				omitEmpty = true
+			case "string":
+				asString = true
+			}
+		}
+		fn(name, field, omitEmpty, asString)
+	}
+}
+
+// nullable extends a schema to also allow null values.
+func nullable(s map[string]interface{}) map[string]interface{} {
+	if len(s) == 0 {
+		return s
+	}
+	if typ, ok := s["type"].(string); ok {
+		ns := make(map[string]interface{}, len(s))
+		for k, v := range s {
+			ns[k] = v
+		}
+		ns["type"] = []interface{}{typ, "null"}
+		return ns
+	}
+	return map[string]interface{}{
+		"oneOf": []interface{}{s, map[string]interface{}{"type": "null"}},
+	}
+}
+
+// paramName derives a parameter name from its type, if it is a named non-builtin type.
+func paramName(t reflect.Type, index int) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := []rune(t.Name())
+	if len(name) == 0 || t.PkgPath() == "" {
+		return fmt.Sprintf("arg%d", index)
+	}
+	name[0] = unicode.ToLower(name[0])
+	return string(name)
+}
+
+// methods returns the OpenRPC method objects of all callbacks and subscriptions
+// of the service.
+func (g *openRPCGenerator) methods(svc service, docs map[string]map[string]interface{}) []map[string]interface{} {
+	var methods []map[string]interface{}
+	// Methods are generated in order, so that component names are deterministic.
+	names := make([]string, 0, len(svc.callbacks))
+	for name := range svc.callbacks {
+		// Subscription methods are handled by the server, shadowing any callbacks.
+		if name == subscribeMethodSuffix[1:] || name == unsubscribeMethodSuffix[1:] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		methods = append(methods, g.method(svc.name+"_"+name, svc.callbacks[name], docs))
+	}
+	if len(svc.subscriptions) > 0 {
+		var subscriptions []string
+		for name := range svc.subscriptions {
+			subscriptions = append(subscriptions, name)
+		}
+		sort.Strings(subscriptions)
+		enum := make([]interface{}, len(subscriptions))
+		for i, name := range subscriptions {
+			enum[i] = name
+		}
+		methods = append(methods, map[string]interface{}{
+			"name":    svc.name + subscribeMethodSuffix,
+			"summary": "Creates a subscription",
+			"params": []interface{}{
+				map[string]interface{}{
+					"name":     "subscriptionName",
+					"required": true,
+					"schema":   map[string]interface{}{"type": "string", "enum": enum},
+				},
+				map[string]interface{}{
+					"name":   "subscriptionOptions",
+					"schema": map[string]interface{}{},
+				},
+			},
+			"result": map[string]interface{}{
+				"name":   "subscriptionId",
+				"schema": map[string]interface{}{"type": "string"},
+			},
+		}, map[string]interface{}{
+			"name":    svc.name + unsubscribeMethodSuffix,
+			"summary": "Cancels a subscription",
+			"params": []interface{}{
+				map[string]interface{}{
+					"name":     "subscriptionId",
+					"required": true,
+					"schema":   map[string]interface{}{"type": "string"},
+				},
+			},
+			"result": map[string]interface{}{
+				"name":   "unsubscribed",
+				"schema": map[string]interface{}{"type": "boolean"},
+			},
+		})
+	}
+	return methods
+}
+
+// method returns the OpenRPC method object for a callback. Descriptive fields
+// (summaries, descriptions and names) are taken from the documentation for the method,
+// if available.
+func (g *openRPCGenerator) method(name string, cb *callback, docs map[string]map[string]interface{}) map[string]interface{} {
+	doc := docs[name]
+	var docParams []interface{}
+	if doc != nil {
+		docParams, _ = doc["params"].([]interface{})
+	}
+	params := make([]interface{}, len(cb.argTypes))
+	used := make(map[string]bool)
+	for i, t := range cb.argTypes {
+		param := map[string]interface{}{
+			"schema": g.inputSchema(t),
+			// Trailing arguments may be omitted if they are pointers.
+			"required": t.Kind() != reflect.Ptr,
+		}
+		if i < len(docParams) {
+			if dp, ok := docParams[i].(map[string]interface{}); ok {
+				copyDocFields(param, dp)
+			}
+		}
+		if param["name"] == nil {
+			param["name"] = paramName(t, i)
+		}
+		if pn := param["name"].(string); used[pn] {
+			param["name"] = fmt.Sprintf("%s%d", pn, i)
+		}
+		used[param["name"].(string)] = true
+		params[i] = param
+	}
+	result := map[string]interface{}{"name": "result"}
+	fntype := cb.fn.Type()
+	if cb.errPos != 0 && fntype.NumOut() > 0 {
+		result["schema"] = g.schema(fntype.Out(0))
+	} else {
+		result["schema"] = map[string]interface{}{"type": "null"}
+	}
+	if doc != nil {
+		if dr, ok := doc["result"].(map[string]interface{}); ok {
+			copyDocFields(result, dr)
+		}
+	}
+	method := map[string]interface{}{
+		"name":   name,
+		"params": params,
+		"result": result,
+	}
+	if doc != nil {
+		for _, k := range []string{"summary", "description"} {
+			if v, ok := doc[k]; ok {
+				method[k] = v
+			}
+		}
+	}
+	return method
+}
+
+func copyDocFields(dst, src map[string]interface{}) {
+	for _, k := range []string{"name", "summary", "description"} {
+		if v, ok := src[k].(string); ok {
+			dst[k] = v
+		}
+	}
+}
+
+// openRPCDocs returns the documentation of methods described by a (static) OpenRPC document,
+// by method name. Content descriptor references are resolved.
+func openRPCDocs(doc *OpenRPCDiscoverSchemaT) map[string]map[string]interface{} {
+	descriptors, _ := doc.Components["contentDescriptors"].(map[string]interface{})
+	resolve := func(v interface{}) interface{} {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return v
+		}
+		if d, ok := descriptors[strings.TrimPrefix(ref, "#/components/contentDescriptors/")]; ok {
+			return d
+		}
+		return v
+	}
+	docs := make(map[string]map[string]interface{}, len(doc.Methods))
+	for _, m := range doc.Methods {
+		name, _ := m["name"].(string)
+		if params, ok := m["params"].([]interface{}); ok {
+			for i := range params {
+				params[i] = resolve(params[i])
+			}
+		}
+		m["result"] = resolve(m["result"])
+		docs[name] = m
+	}
+	return docs
+}
+
+// openRPCDocument generates an OpenRPC document describing all methods of the services
+// registered with the server. The server's raw OpenRPC document, if any, is used for
+// the document info and for documentation of the methods.
+func (s *Server) openRPCDocument() (*OpenRPCDiscoverSchemaT, error) {
+	doc := &OpenRPCDiscoverSchemaT{
+		OpenRPC: openRPCVersion,
+		Info: map[string]interface{}{
+			"title":   "Ethereum JSON-RPC",
+			"version": "1.0.0",
+		},
+		Servers: make([]map[string]interface{}, 0),
+	}
+	docs := make(map[string]map[string]interface{})
+	if s.OpenRPCSchemaRaw != "" {
+		var static OpenRPCDiscoverSchemaT
+		if err := json.Unmarshal([]byte(s.OpenRPCSchemaRaw), &static); err != nil {
+			return nil, fmt.Errorf("%v: %v", errOpenRPCDiscoverSchemaInvalid, err)
+		}
+		if static.Info != nil {
+			doc.Info = static.Info
+		}
+		if static.Servers != nil {
+			doc.Servers = static.Servers
+		}
+		docs = openRPCDocs(&static)
+	}
+
+	g := newOpenRPCGenerator()
+	doc.Methods = []map[string]interface{}{}
+	s.services.mu.Lock()
+	names := make([]string, 0, len(s.services.services))
+	for name := range s.services.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		doc.Methods = append(doc.Methods, g.methods(s.services.services[name], docs)...)
+	}
+	s.services.mu.Unlock()
+	doc.Components = map[string]interface{}{"schemas": g.schemas}
+	return doc, nil
+}
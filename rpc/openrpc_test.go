@@ -0,0 +1,166 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func discoverMethod(t *testing.T, doc *OpenRPCDiscoverSchemaT, name string) map[string]interface{} {
+	for _, m := range doc.Methods {
+		if m["name"] == name {
+			return m
+		}
+	}
+	t.Fatalf("method %s not found", name)
+	return nil
+}
+
+func TestOpenRPCDiscover(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var doc *OpenRPCDiscoverSchemaT
+	if err := client.Call(&doc, "rpc_discover"); err != nil {
+		t.Fatal(err)
+	}
+	// Only registered methods are described.
+	for _, m := range doc.Methods {
+		if module, _, _ := elementizeMethodName(m["name"].(string)); module != "test" && module != "nftest" && module != MetadataApi {
+			t.Errorf("unexpected method %s", m["name"])
+		}
+	}
+
+	echo := discoverMethod(t, doc, "test_echo")
+	wantParams := `[
+		{"name":"arg0","required":true,"schema":{"type":"string"}},
+		{"name":"arg1","required":true,"schema":{"type":"integer"}},
+		{"name":"echoArgs","required":false,"schema":{"oneOf":[{"$ref":"#/components/schemas/rpc.echoArgs"},{"type":"null"}]}}
+	]`
+	assertJSONEqual(t, wantParams, echo["params"])
+	assertJSONEqual(t, `{"name":"result","schema":{"$ref":"#/components/schemas/rpc.echoResult"}}`, echo["result"])
+
+	schemas := doc.Components["schemas"].(map[string]interface{})
+	assertJSONEqual(t, `{
+		"type":"object",
+		"properties":{
+			"String":{"type":"string"},
+			"Int":{"type":"integer"},
+			"Args":{"oneOf":[{"$ref":"#/components/schemas/rpc.echoArgsResult"},{"type":"null"}]}
+		},
+		"required":["String","Int","Args"]
+	}`, schemas["rpc.echoResult"])
+
+	// Methods returning only errors have null results.
+	assertJSONEqual(t, `{"name":"result","schema":{"type":"null"}}`, discoverMethod(t, doc, "test_returnError")["result"])
+
+	// Subscriptions are described by the subscribe method of the service.
+	subscribe := discoverMethod(t, doc, "nftest_subscribe")
+	assertJSONEqual(t, `{"type":"string","enum":["hangSubscription","someSubscription"]}`,
+		subscribe["params"].([]interface{})[0].(map[string]interface{})["schema"])
+	discoverMethod(t, doc, "nftest_unsubscribe")
+}
+
+func TestOpenRPCDiscoverDocs(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	err := server.SetOpenRPCSchemaRaw(`{
+		"openrpc": "1.0.0",
+		"info": {"title": "test", "version": "1.0.0"},
+		"methods": [{
+			"name": "test_echo",
+			"summary": "echoes its arguments",
+			"params": [{"name": "str", "schema": {}}, {"$ref": "#/components/contentDescriptors/Int"}],
+			"result": {"name": "echo", "schema": {}}
+		}],
+		"components": {"contentDescriptors": {"Int": {"name": "int", "description": "an integer", "schema": {}}}}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := (&RPCService{server}).Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Info["title"] != "test" {
+		t.Errorf("wrong info title: %v", doc.Info["title"])
+	}
+	echo := discoverMethod(t, doc, "test_echo")
+	if echo["summary"] != "echoes its arguments" {
+		t.Errorf("wrong summary: %v", echo["summary"])
+	}
+	wantParams := `[
+		{"name":"str","required":true,"schema":{"type":"string"}},
+		{"name":"int","description":"an integer","required":true,"schema":{"type":"integer"}},
+		{"name":"echoArgs","required":false,"schema":{"oneOf":[{"$ref":"#/components/schemas/rpc.echoArgs"},{"type":"null"}]}}
+	]`
+	assertJSONEqual(t, wantParams, echo["params"])
+	assertJSONEqual(t, `{"name":"echo","schema":{"$ref":"#/components/schemas/rpc.echoResult"}}`, echo["result"])
+}
+
+func TestOpenRPCTypeSchemas(t *testing.T) {
+	type embedded struct {
+		Number hexutil.Uint64 `json:"number"`
+	}
+	type value struct {
+		embedded
+		Hash    common.Hash     `json:"hash"`
+		Balance *hexutil.Big    `json:"balance,omitempty"`
+		Nonce   uint64          `json:"nonce,string"`
+		Block   BlockNumber     `json:"block"`
+		Skipped string          `json:"-"`
+		Data    []hexutil.Bytes `json:"data"`
+	}
+	g := newOpenRPCGenerator()
+	assertJSONEqual(t, `{
+		"type":"object",
+		"properties":{
+			"number":{"type":"string","pattern":"^0x([1-9a-fA-F][0-9a-fA-F]*|0)$"},
+			"hash":{"type":"string","pattern":"^0x[0-9a-fA-F]{64}$"},
+			"balance":{"type":["string","null"],"pattern":"^0x([1-9a-fA-F][0-9a-fA-F]*|0)$"},
+			"nonce":{"type":"string"},
+			"block":{"oneOf":[{"type":"string","enum":["earliest","latest","pending"]},{"type":"string","pattern":"^0x([1-9a-fA-F][0-9a-fA-F]*|0)$"}]},
+			"data":{"type":["array","null"],"items":{"type":"string","pattern":"^0x([0-9a-fA-F]{2})*$"}}
+		},
+		"required":["number","hash","nonce","block","data"]
+	}`, g.structSchema(reflect.TypeOf(value{})))
+}
+
+func assertJSONEqual(t *testing.T, want string, got interface{}) {
+	t.Helper()
+	var w, g interface{}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(enc, &g); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(w, g) {
+		t.Errorf("JSON mismatch:\nwant %s\n got %s", want, enc)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"sync/atomic"
 
 	mapset "github.com/deckarep/golang-set"
@@ -32,11 +33,10 @@ const MetadataApi = "rpc"
 
 var (
 	// defaultOpenRPCSchemaRaw can be used to establish a default (package-wide) OpenRPC schema from raw JSON.
-	// Methods will be cross referenced with actual registered method names in order to serve
-	// only server-enabled methods, enabling user and on-the-fly server endpoint availability configuration.
+	// It documents the methods of the OpenRPC documents generated from the services registered with
+	// each server, so that only server-enabled methods are described.
 	defaultOpenRPCSchemaRaw string
 
-	errOpenRPCDiscoverUnavailable   = errors.New("openrpc discover data unavailable")
 	errOpenRPCDiscoverSchemaInvalid = errors.New("openrpc discover data invalid")
 )
 
@@ -60,6 +60,9 @@ type Server struct {
 	run              int32
 	codecs           mapset.Set
 	OpenRPCSchemaRaw string
+
+	openrpcMu  sync.Mutex
+	openrpcDoc *OpenRPCDiscoverSchemaT // generated OpenRPC document, nil if outdated
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -103,15 +106,27 @@ func (s *Server) SetOpenRPCSchemaRaw(schemaJSON string) error {
 		return err
 	}
 	s.OpenRPCSchemaRaw = schemaJSON
+	s.resetOpenRPCDocument()
 	return nil
 }
 
+// resetOpenRPCDocument discards the generated OpenRPC document, if any.
+func (s *Server) resetOpenRPCDocument() {
+	s.openrpcMu.Lock()
+	s.openrpcDoc = nil
+	s.openrpcMu.Unlock()
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either a RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
 // service collection this server provides to clients.
 func (s *Server) RegisterName(name string, receiver interface{}) error {
-	return s.services.registerName(name, receiver)
+	if err := s.services.registerName(name, receiver); err != nil {
+		return err
+	}
+	s.resetOpenRPCDocument()
+	return nil
 }
 
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
@@ -212,47 +227,19 @@ func (s *RPCService) methods() map[string][]string {
 	return methods
 }
 
-// Discover returns a configured schema that is audited for actual server availability.
-// Only methods that the server makes available are included in the 'methods' array of
-// the discover schema. Components are not audited.
+// Discover returns an OpenRPC document describing the methods made available by the server.
+// The document is generated from the registered services, with the parameter and result
+// schemas derived from the Go types of the methods. Descriptions of the methods are taken
+// from the configured OpenRPC schema, if any.
 func (s *RPCService) Discover() (schema *OpenRPCDiscoverSchemaT, err error) {
-	if s.server.OpenRPCSchemaRaw == "" {
-		return nil, errOpenRPCDiscoverUnavailable
-	}
-	schema = &OpenRPCDiscoverSchemaT{
-		Servers: make([]map[string]interface{}, 0),
-	}
-	err = json.Unmarshal([]byte(s.server.OpenRPCSchemaRaw), schema)
-	if err != nil {
-		log.Crit("openrpc json umarshal", "error", err)
-	}
+	s.server.openrpcMu.Lock()
+	defer s.server.openrpcMu.Unlock()
 
-	// Audit documented schema methods vs. actual server availability
-	// This removes methods described in the OpenRPC JSON schema document
-	// which are not currently exposed on the server's API.
-	// This is done on the fly (as opposed to at servre init or schema setting)
-	// because it's possible that exposed APIs could be modified in proc.
-	schemaMethodsAvailable := []map[string]interface{}{}
-	serverMethodsAvailable := s.methods()
-
-	for _, m := range schema.Methods {
-		module, path, err := elementizeMethodName(m["name"].(string))
-		if err != nil {
+	// The document is cached until the available services change.
+	if s.server.openrpcDoc == nil {
+		if s.server.openrpcDoc, err = s.server.openRPCDocument(); err != nil {
 			return nil, err
 		}
-		paths, ok := serverMethodsAvailable[module]
-		if !ok {
-			continue
-		}
-
-		// the module exists, does the path exist?
-		for _, pa := range paths {
-			if pa == path {
-				schemaMethodsAvailable = append(schemaMethodsAvailable, m)
-				break
-			}
-		}
 	}
-	schema.Methods = schemaMethodsAvailable
-	return
+	return s.server.openrpcDoc, nil
 }
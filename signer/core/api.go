@@ -43,7 +43,7 @@ const (
 	// ExternalAPIVersion -- see extapi_changelog.md
 	ExternalAPIVersion = "6.1.0"
 	// InternalAPIVersion -- see intapi_changelog.md
-	InternalAPIVersion = "7.0.1"
+	InternalAPIVersion = "7.1.0"
 )
 
 // ExternalAPI defines the external API through which signing requests are made.
@@ -95,6 +95,14 @@ type UIClientAPI interface {
 	RegisterUIServer(api *UIServerAPI)
 }
 
+// SignTxFailureListener is an optional interface of UIs which need to know when a
+// transaction request passed to ApproveTx ends up unsigned, because it was denied
+// or signing failed. UIs wrapping another one should pass the notification on.
+type SignTxFailureListener interface {
+	// OnSignTxFailed notifies the UI about a transaction request not having been signed.
+	OnSignTxFailed(request *SignTxRequest)
+}
+
 // Validator defines the methods required to validate a transaction against some
 // sanity defaults as well as any underlying 4byte method database.
 //
@@ -116,6 +124,7 @@ type SignerAPI struct {
 	validator   Validator
 	rejectMode  bool
 	credentials storage.Storage
+	simulator   Simulator
 }
 
 // Metadata about a request
@@ -221,9 +230,10 @@ func (m Metadata) String() string {
 type (
 	// SignTxRequest contains info about a Transaction to sign
 	SignTxRequest struct {
-		Transaction SendTxArgs       `json:"transaction"`
-		Callinfo    []ValidationInfo `json:"call_info"`
-		Meta        Metadata         `json:"meta"`
+		Transaction SendTxArgs        `json:"transaction"`
+		Callinfo    []ValidationInfo  `json:"call_info"`
+		Meta        Metadata          `json:"meta"`
+		Simulation  *SimulationResult `json:"simulation,omitempty"`
 	}
 	// SignTxResponse result from SignTxRequest
 	SignTxResponse struct {
@@ -283,12 +293,19 @@ func NewSignerAPI(am *accounts.Manager, chainID int64, noUSB bool, ui UIClientAP
 	if advancedMode {
 		log.Info("Clef is in advanced mode: will warn instead of reject")
 	}
-	signer := &SignerAPI{big.NewInt(chainID), am, ui, validator, !advancedMode, credentials}
+	signer := &SignerAPI{big.NewInt(chainID), am, ui, validator, !advancedMode, credentials, nil}
 	if !noUSB {
 		signer.startUSBListener()
 	}
 	return signer
 }
+
+// SetSimulator configures the simulator used to predict the outcome of
+// transactions before they are shown for approval.
+func (api *SignerAPI) SetSimulator(simulator Simulator) {
+	api.simulator = simulator
+}
+
 func (api *SignerAPI) openTrezor(url accounts.URL) {
 	resp, err := api.UI.OnInputRequired(UserInputRequest{
 		Prompt: "Pin required to open Trezor wallet\n" +
@@ -537,7 +554,23 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 		Meta:        MetadataFromContext(ctx),
 		Callinfo:    msgs.Messages,
 	}
-	// Process approval
+	// Simulate the transaction, a failure is shown as a warning but doesn't block signing
+	if api.simulator != nil {
+		simctx, cancel := context.WithTimeout(ctx, simulationTimeout)
+		req.Simulation, err = api.simulator.Simulate(simctx, &args)
+		cancel()
+		if err != nil {
+			log.Warn("Transaction simulation failed", "err", err)
+			req.Callinfo = append(req.Callinfo, ValidationInfo{WARN, fmt.Sprintf("Simulation failed: %v", err)})
+		}
+	}
+	// Process approval, letting the UI know if the request doesn't get signed
+	signed := false
+	defer func() {
+		if listener, ok := api.UI.(SignTxFailureListener); ok && !signed {
+			listener.OnSignTxFailed(&req)
+		}
+	}()
 	result, err = api.UI.ApproveTx(&req)
 	if err != nil {
 		return nil, err
@@ -578,6 +611,7 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 	response := ethapi.SignTransactionResult{Raw: rlpdata, Tx: signedTx}
 
 	// Finally, send the signed tx to the UI
+	signed = true
 	api.UI.OnApprovedTx(response)
 	// ...and to the external caller
 	return &response, nil
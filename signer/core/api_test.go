@@ -43,6 +43,7 @@ import (
 type headlessUi struct {
 	approveCh chan string // to send approve/deny
 	inputCh   chan string // to send password
+	failed    int         // number of unsigned transaction requests
 }
 
 func (ui *headlessUi) OnInputRequired(info core.UserInputRequest) (core.UserInputResponse, error) {
@@ -53,6 +54,7 @@ func (ui *headlessUi) OnInputRequired(info core.UserInputRequest) (core.UserInpu
 func (ui *headlessUi) OnSignerStartup(info core.StartupInfo)        {}
 func (ui *headlessUi) RegisterUIServer(api *core.UIServerAPI)       {}
 func (ui *headlessUi) OnApprovedTx(tx ethapi.SignTransactionResult) {}
+func (ui *headlessUi) OnSignTxFailed(request *core.SignTxRequest)   { ui.failed++ }
 
 func (ui *headlessUi) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
 
@@ -124,7 +126,7 @@ func setup(t *testing.T) (*core.SignerAPI, *headlessUi) {
 	if err != nil {
 		t.Fatal(err.Error())
 	}
-	ui := &headlessUi{make(chan string, 20), make(chan string, 20)}
+	ui := &headlessUi{approveCh: make(chan string, 20), inputCh: make(chan string, 20)}
 	am := core.StartClefAccountManager(tmpDirName(t), true, true, "")
 	api := core.NewSignerAPI(am, 1337, true, ui, db, true, &storage.NoStorage{})
 	return api, ui
@@ -277,6 +279,9 @@ func TestSignTx(t *testing.T) {
 	if err != core.ErrRequestDenied {
 		t.Errorf("Expected ErrRequestDenied! %v", err)
 	}
+	if control.failed != 2 {
+		t.Errorf("Expected 2 failure notifications, got %d", control.failed)
+	}
 	// Sign with correct password
 	control.approveCh <- "Y"
 	control.inputCh <- "a_long_password"
@@ -302,6 +307,9 @@ func TestSignTx(t *testing.T) {
 	if !bytes.Equal(res.Raw, res2.Raw) {
 		t.Error("Expected tx to be unmodified by UI")
 	}
+	if control.failed != 2 {
+		t.Errorf("Expected no failure notifications for signed transactions, got %d", control.failed-2)
+	}
 
 	//The tx is modified by the UI
 	control.approveCh <- "M"
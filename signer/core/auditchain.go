@@ -0,0 +1,332 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+// auditHeadKey is the storage key of the head of the audit log.
+const auditHeadKey = "audit_head"
+
+// AuditEntry is an entry of the audit log. Each entry commits to the previous
+// one by its hash, and is signed with the audit key of clef, so that entries
+// can't be modified, removed or reordered without breaking the chain.
+type AuditEntry struct {
+	Seq  uint64            `json:"seq"`
+	Time string            `json:"time"`
+	Msg  string            `json:"msg"`
+	Data map[string]string `json:"data"`
+	Prev common.Hash       `json:"prev"`
+	Hash common.Hash       `json:"hash"`          // Hash of the above fields
+	Sig  hexutil.Bytes     `json:"sig,omitempty"` // Signature of the hash with the audit key
+}
+
+// digest returns the hash of the entry, covering all fields but the hash itself
+// and the signature.
+func (e *AuditEntry) digest() common.Hash {
+	body, _ := json.Marshal(struct {
+		Seq  uint64            `json:"seq"`
+		Time string            `json:"time"`
+		Msg  string            `json:"msg"`
+		Data map[string]string `json:"data"`
+		Prev common.Hash       `json:"prev"`
+	}{e.Seq, e.Time, e.Msg, e.Data, e.Prev})
+	return crypto.Keccak256Hash(body)
+}
+
+// signer recovers the address of the audit key which signed the entry.
+func (e *AuditEntry) signer() (common.Address, error) {
+	pubkey, err := crypto.SigToPub(e.Hash[:], e.Sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// AuditHead identifies the last entry of an audit log.
+type AuditHead struct {
+	Seq  uint64
+	Hash common.Hash
+}
+
+func (h AuditHead) String() string {
+	return fmt.Sprintf("%d:%s", h.Seq, h.Hash.Hex())
+}
+
+// ParseAuditHead parses an audit log head in the "<seq>:<hash>" format.
+func ParseAuditHead(s string) (AuditHead, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return AuditHead{}, fmt.Errorf("invalid audit head %q", s)
+	}
+	seq, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return AuditHead{}, fmt.Errorf("invalid audit head sequence number: %v", err)
+	}
+	hash, err := hexutil.Decode(parts[1])
+	if err != nil || len(hash) != common.HashLength {
+		return AuditHead{}, fmt.Errorf("invalid audit head hash %q", parts[1])
+	}
+	return AuditHead{Seq: seq, Hash: common.BytesToHash(hash)}, nil
+}
+
+// StoredAuditHead returns the head of the audit log recorded in the storage, or
+// nil if none was recorded.
+func StoredAuditHead(store storage.Storage) (*AuditHead, error) {
+	stored, err := store.Get(auditHeadKey)
+	if err != nil {
+		return nil, nil
+	}
+	head, err := ParseAuditHead(stored)
+	if err != nil {
+		return nil, err
+	}
+	return &head, nil
+}
+
+// ReadAuditLog calls fn for every entry of the audit log at path, in order.
+func ReadAuditLog(path string, fn func(entry *AuditEntry) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(data) == 0 {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		entry := new(AuditEntry)
+		if err := json.Unmarshal(data, entry); err != nil {
+			return fmt.Errorf("line %d: invalid entry: %v", line, err)
+		}
+		if err := fn(entry); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+	}
+}
+
+// VerifyAuditLog checks the hash chain of the audit log at path, and that all
+// entries are signed by the audit key of the given auditor. If a previously known
+// head of the log is given, the log needs to contain it, which detects truncation.
+// The head of the verified log is returned.
+func VerifyAuditLog(path string, auditor common.Address, known *AuditHead) (*AuditHead, error) {
+	if auditor == (common.Address{}) {
+		return nil, errors.New("no auditor to verify the audit log against")
+	}
+	var (
+		head     *AuditHead
+		prev     common.Hash
+		expected uint64
+	)
+	err := ReadAuditLog(path, func(entry *AuditEntry) error {
+		if entry.Seq != expected {
+			return fmt.Errorf("sequence number %d, expected %d", entry.Seq, expected)
+		}
+		if entry.Prev != prev {
+			return fmt.Errorf("entry %d: previous hash %x, expected %x", entry.Seq, entry.Prev, prev)
+		}
+		if hash := entry.digest(); entry.Hash != hash {
+			return fmt.Errorf("entry %d: hash %x, expected %x", entry.Seq, entry.Hash, hash)
+		}
+		if len(entry.Sig) == 0 {
+			return fmt.Errorf("entry %d: not signed", entry.Seq)
+		}
+		addr, err := entry.signer()
+		if err != nil {
+			return fmt.Errorf("entry %d: invalid signature: %v", entry.Seq, err)
+		}
+		if addr != auditor {
+			return fmt.Errorf("entry %d: signed by %s, expected %s", entry.Seq, addr.Hex(), auditor.Hex())
+		}
+		if known != nil && known.Seq == entry.Seq && known.Hash != entry.Hash {
+			return fmt.Errorf("entry %d: hash %x diverges from known head %x", entry.Seq, entry.Hash, known.Hash)
+		}
+		head = &AuditHead{Seq: entry.Seq, Hash: entry.Hash}
+		prev, expected = entry.Hash, expected+1
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, errors.New("empty audit log")
+	}
+	if known != nil && known.Seq > head.Seq {
+		return nil, fmt.Errorf("log truncated: ends at entry %d, known head is entry %d", head.Seq, known.Seq)
+	}
+	return head, nil
+}
+
+// isLegacyAuditLog reports whether the log at path was written by a version of
+// clef predating the hash-chained audit log, as plain text records.
+func isLegacyAuditLog(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return false
+	}
+	return len(line) > 0 && json.Unmarshal(line, new(AuditEntry)) != nil
+}
+
+// auditHandler is a log.Handler writing records as hash-chained, signed audit
+// entries in JSON lines format.
+type auditHandler struct {
+	file  *os.File
+	key   *ecdsa.PrivateKey // Audit key signing the entries, nil if unsigned
+	store storage.Storage   // Storage of the head, to detect truncation on startup
+	seq   uint64            // Sequence number of the next entry
+	prev  common.Hash       // Hash of the last entry
+	lock  sync.Mutex
+}
+
+// newAuditHandler opens the audit log at path, continuing its chain if it
+// already exists. A plain text log of an earlier version of clef is moved
+// aside, and the new chain starts with an entry committing to its content.
+func newAuditHandler(path string, key *ecdsa.PrivateKey, store storage.Storage) (*auditHandler, error) {
+	h := &auditHandler{key: key, store: store}
+
+	var legacy []interface{} // Context of the entry referencing a rotated legacy log
+	if isLegacyAuditLog(path) {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rotated := fmt.Sprintf("%s.legacy-%d", path, time.Now().Unix())
+		if err := os.Rename(path, rotated); err != nil {
+			return nil, fmt.Errorf("failed to rotate legacy audit log %s: %v", path, err)
+		}
+		log.Warn("Rotated legacy audit log", "file", path, "rotated", rotated)
+		legacy = []interface{}{"file", rotated, "hash", crypto.Keccak256Hash(content)}
+	}
+	if _, err := os.Stat(path); err == nil {
+		err := ReadAuditLog(path, func(entry *AuditEntry) error {
+			h.seq, h.prev = entry.Seq+1, entry.Hash
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("existing audit log %s is not a valid hash-chained log: %v", path, err)
+		}
+	}
+	// Refuse to continue a log which is shorter than the last recorded head, as
+	// new entries would hide the truncation
+	if store != nil {
+		head, err := StoredAuditHead(store)
+		if err != nil {
+			return nil, err
+		}
+		if head != nil && h.seq <= head.Seq {
+			return nil, fmt.Errorf("audit log %s truncated: ends before entry %d", path, head.Seq)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	h.file = file
+
+	if legacy != nil {
+		record := &log.Record{Time: time.Now(), Lvl: log.LvlInfo, Msg: "RotatedLegacyLog", Ctx: legacy}
+		if err := h.Log(record); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// auditValue formats a log context value for an audit entry.
+func auditValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprintf("%+v", v)
+	}
+}
+
+func (h *auditHandler) Log(r *log.Record) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	entry := &AuditEntry{
+		Seq:  h.seq,
+		Time: r.Time.UTC().Format(time.RFC3339Nano),
+		Msg:  r.Msg,
+		Data: make(map[string]string),
+		Prev: h.prev,
+	}
+	for i := 0; i+1 < len(r.Ctx); i += 2 {
+		entry.Data[fmt.Sprint(r.Ctx[i])] = auditValue(r.Ctx[i+1])
+	}
+	entry.Hash = entry.digest()
+	if h.key != nil {
+		sig, err := crypto.Sign(entry.Hash[:], h.key)
+		if err != nil {
+			return err
+		}
+		entry.Sig = sig
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := h.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	h.seq, h.prev = entry.Seq+1, entry.Hash
+	if h.store != nil {
+		h.store.Put(auditHeadKey, AuditHead{Seq: entry.Seq, Hash: entry.Hash}.String())
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+// writeAuditLog writes n entries to the audit log at path.
+func writeAuditLog(t *testing.T, path string, n int, store storage.Storage) *AuditLogger {
+	l, err := NewAuditLogger(path, nil, mustKey(t), store)
+	if err != nil {
+		t.Fatalf("failed to create audit logger: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		l.Logger().Info("SignTransaction", "type", "request", "index", i)
+	}
+	return l
+}
+
+func TestAuditLogVerify(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "clef-audit-test")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	store := storage.NewEphemeralStorage()
+
+	// Write a log across two sessions, the second continues the chain
+	writeAuditLog(t, path, 3, store)
+	writeAuditLog(t, path, 2, store)
+
+	head, err := VerifyAuditLog(path, mustAuditor(t), nil)
+	if err != nil {
+		t.Fatalf("failed to verify log: %v", err)
+	}
+	if head.Seq != 6 {
+		t.Errorf("head sequence number mismatch: have %d, want %d", head.Seq, 6)
+	}
+	// The auditor must be given, the log does not vouch for itself
+	if _, err := VerifyAuditLog(path, common.Address{}, nil); err == nil {
+		t.Errorf("log verified without an auditor")
+	}
+	if stored, _ := store.Get(auditHeadKey); stored != head.String() {
+		t.Errorf("stored head mismatch: have %s, want %s", stored, head)
+	}
+	parsed, err := ParseAuditHead(head.String())
+	if err != nil || parsed != *head {
+		t.Errorf("head roundtrip failed: %v, %v", parsed, err)
+	}
+}
+
+func mustKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func mustAuditor(t *testing.T) common.Address {
+	return crypto.PubkeyToAddress(mustKey(t).PublicKey)
+}
+
+func TestAuditLogTampering(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "clef-audit-test")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	store := storage.NewEphemeralStorage()
+	writeAuditLog(t, path, 4, store)
+
+	original, _ := ioutil.ReadFile(path)
+	lines := bytes.SplitAfter(original, []byte("\n"))
+	head, err := VerifyAuditLog(path, mustAuditor(t), nil)
+	if err != nil {
+		t.Fatalf("failed to verify log: %v", err)
+	}
+	other, _ := crypto.GenerateKey()
+	otherAddr := crypto.PubkeyToAddress(other.PublicKey)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		expect string
+	}{
+		{"modified", bytes.Replace(original, []byte(`"index":"1"`), []byte(`"index":"9"`), 1), "hash"},
+		{"removed", append(append([]byte{}, lines[0]...), bytes.Join(lines[2:], nil)...), "sequence number"},
+		{"truncated", bytes.Join(lines[:3], nil), "truncated"},
+	}
+	for _, test := range tests {
+		ioutil.WriteFile(path, test.data, 0600)
+		if _, err := VerifyAuditLog(path, mustAuditor(t), head); err == nil || !strings.Contains(err.Error(), test.expect) {
+			t.Errorf("%s: have error %v, want %q", test.name, err, test.expect)
+		}
+	}
+	// A log signed by another key fails verification against the expected auditor
+	ioutil.WriteFile(path, original, 0600)
+	if _, err := VerifyAuditLog(path, otherAddr, nil); err == nil || !strings.Contains(err.Error(), "signed by") {
+		t.Errorf("wrong auditor: have error %v", err)
+	}
+	// A truncated log must not be continued
+	ioutil.WriteFile(path, bytes.Join(lines[:3], nil), 0600)
+	if _, err := NewAuditLogger(path, nil, mustKey(t), store); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("continuing truncated log: have error %v", err)
+	}
+}
+
+// TestAuditLogLegacy tests that a plain text log of an earlier version is moved
+// aside, and referenced by the first entry of the new chain.
+func TestAuditLogLegacy(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "clef-audit-test")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	legacy := []byte("t=2020-06-01T00:00:00+0000 lvl=info msg=Configured\n")
+	ioutil.WriteFile(path, legacy, 0600)
+	writeAuditLog(t, path, 2, nil)
+
+	if _, err := VerifyAuditLog(path, mustAuditor(t), nil); err != nil {
+		t.Fatalf("failed to verify log: %v", err)
+	}
+	var first *AuditEntry
+	ReadAuditLog(path, func(entry *AuditEntry) error {
+		if first == nil {
+			first = entry
+		}
+		return nil
+	})
+	rotated := first.Data["file"]
+	if content, err := ioutil.ReadFile(rotated); err != nil || !bytes.Equal(content, legacy) {
+		t.Fatalf("legacy log not retained: %v", err)
+	}
+	if first.Data["hash"] != crypto.Keccak256Hash(legacy).Hex() {
+		t.Errorf("legacy log hash mismatch: have %s", first.Data["hash"])
+	}
+}
+
+func TestAuditLogCorrupted(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "clef-audit-test")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	writeAuditLog(t, path, 2, nil)
+
+	// A broken chain is not mistaken for a legacy log
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("t=2020-06-01T00:00:00+0000 lvl=info msg=Configured\n")
+	f.Close()
+	if _, err := NewAuditLogger(path, nil, nil, nil); err == nil {
+		t.Fatalf("expected error continuing a corrupted log")
+	}
+}
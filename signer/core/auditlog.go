@@ -18,12 +18,15 @@ package core
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"encoding/json"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/storage"
 )
 
 type AuditLogger struct {
@@ -112,13 +115,26 @@ func (l *AuditLogger) Version(ctx context.Context) (string, error) {
 
 }
 
-func NewAuditLogger(path string, api ExternalAPI) (*AuditLogger, error) {
+// Logger returns the logger writing the audit log.
+func (l *AuditLogger) Logger() log.Logger {
+	return l.log
+}
+
+// NewAuditLogger creates an audit logger writing a hash-chained log to path. The
+// entries are signed with the given audit key, and the head of the log is kept
+// in the given storage to detect truncation. Both may be nil, which leaves the
+// entries unsigned or disables truncation detection on startup respectively.
+func NewAuditLogger(path string, api ExternalAPI, key *ecdsa.PrivateKey, store storage.Storage) (*AuditLogger, error) {
 	l := log.New("api", "signer")
-	handler, err := log.FileHandler(path, log.LogfmtFormat())
+	handler, err := newAuditHandler(path, key, store)
 	if err != nil {
 		return nil, err
 	}
 	l.SetHandler(handler)
-	l.Info("Configured", "audit log", path)
+	if key != nil {
+		l.Info("Configured", "audit log", path, "auditor", crypto.PubkeyToAddress(key.PublicKey))
+	} else {
+		l.Info("Configured", "audit log", path)
+	}
 	return &AuditLogger{l, api}, nil
 }
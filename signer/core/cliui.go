@@ -93,6 +93,25 @@ func sanitize(txt string, limit int) string {
 	return fmt.Sprintf("%q", txt)
 }
 
+func showSimulation(sim *SimulationResult) {
+	fmt.Printf("\nSimulation (block %d):\n", uint64(sim.Block))
+	if sim.Reverted {
+		if sim.RevertReason != "" {
+			fmt.Printf("  * WARNING : transaction will fail: %s (%s)\n", sim.Error, sim.RevertReason)
+		} else {
+			fmt.Printf("  * WARNING : transaction will fail: %s\n", sim.Error)
+		}
+	} else {
+		fmt.Printf("  gas estimate: %d\n", uint64(sim.GasEstimate))
+	}
+	for _, change := range sim.BalanceChanges {
+		fmt.Printf("  balance %v: %+d wei\n", change.Address.Hex(), change.Delta.ToInt())
+	}
+	for _, transfer := range sim.TokenTransfers {
+		fmt.Printf("  token %v: %v -> %v: %v\n", transfer.Token.Hex(), transfer.From.Hex(), transfer.To.Hex(), transfer.Value.ToInt())
+	}
+}
+
 func showMetadata(metadata Metadata) {
 	fmt.Printf("Request context:\n\t%v -> %v -> %v\n", metadata.Remote, metadata.Scheme, metadata.Local)
 	fmt.Printf("\nAdditional HTTP header data, provided by the external caller:\n")
@@ -132,6 +151,9 @@ func (ui *CommandlineUI) ApproveTx(request *SignTxRequest) (SignTxResponse, erro
 		fmt.Println()
 
 	}
+	if request.Simulation != nil {
+		showSimulation(request.Simulation)
+	}
 	fmt.Printf("\n")
 	showMetadata(request.Meta)
 	fmt.Printf("-------------------------------------------\n")
@@ -0,0 +1,355 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// approverClockSkew is the maximum age of the signed timestamp approvers
+// authenticate listing requests with.
+const approverClockSkew = 5 * time.Minute
+
+var (
+	errUnknownApproval  = errors.New("unknown or expired approval request")
+	errUnknownApprover  = errors.New("signer is not an approver")
+	errAlreadyDecided   = errors.New("approver already decided")
+	errInvalidTimestamp = errors.New("timestamp too far from current time")
+)
+
+// QuorumConfig configures the approvers of requests requiring a quorum.
+type QuorumConfig struct {
+	Approvers []common.Address // Addresses of the keys approvers authenticate with
+	Threshold int              // Number of approvals needed to sign a request
+	Timeout   time.Duration    // Time after which pending requests are rejected
+	Accounts  []common.Address // Accounts whose requests require a quorum, all if empty
+}
+
+// ApprovalRequest is a request awaiting the decisions of the approvers.
+type ApprovalRequest struct {
+	ID          string                  `json:"id"`
+	Transaction *SignTxRequest          `json:"transaction,omitempty"`
+	SignData    *SignDataRequest        `json:"signData,omitempty"`
+	Deadline    time.Time               `json:"deadline"`
+	Decisions   map[common.Address]bool `json:"decisions"`
+}
+
+// pendingApproval tracks the decisions on a request.
+type pendingApproval struct {
+	request   ApprovalRequest
+	approvals int
+	approved  bool
+	done      chan struct{} // Closed when the quorum is reached or can't be reached anymore
+}
+
+// QuorumUI is a UIClientAPI which requires a quorum of approvers to approve
+// transaction and data signing requests, in addition to the next UI. Approvers
+// are remote clients using the ApproverAPI, authenticated by signing their
+// decisions with their own key. All other requests are passed on to the next UI.
+type QuorumUI struct {
+	next      UIClientAPI
+	config    QuorumConfig
+	approvers map[common.Address]struct{}
+	accounts  map[common.Address]struct{}
+	audit     log.Logger
+
+	pending map[string]*pendingApproval
+	lock    sync.Mutex
+}
+
+// NewQuorumUI creates a UI requiring a quorum of approvers, passing requests not
+// covered by it on to next.
+func NewQuorumUI(next UIClientAPI, config QuorumConfig) (*QuorumUI, error) {
+	ui := &QuorumUI{
+		next:      next,
+		config:    config,
+		approvers: make(map[common.Address]struct{}),
+		accounts:  make(map[common.Address]struct{}),
+		audit:     log.Root(),
+		pending:   make(map[string]*pendingApproval),
+	}
+	for _, approver := range config.Approvers {
+		if _, ok := ui.approvers[approver]; ok {
+			return nil, fmt.Errorf("duplicate approver %s", approver.Hex())
+		}
+		ui.approvers[approver] = struct{}{}
+	}
+	if config.Threshold < 1 || config.Threshold > len(ui.approvers) {
+		return nil, fmt.Errorf("invalid quorum %d of %d approvers", config.Threshold, len(ui.approvers))
+	}
+	if config.Timeout <= 0 {
+		return nil, errors.New("approval timeout must be positive")
+	}
+	for _, account := range config.Accounts {
+		ui.accounts[account] = struct{}{}
+	}
+	return ui, nil
+}
+
+// SetAuditLog sets the logger recording the decisions of the approvers.
+func (ui *QuorumUI) SetAuditLog(audit log.Logger) {
+	ui.audit = audit
+}
+
+// covers reports whether requests of the account require a quorum.
+func (ui *QuorumUI) covers(account common.Address) bool {
+	if len(ui.accounts) == 0 {
+		return true
+	}
+	_, ok := ui.accounts[account]
+	return ok
+}
+
+// await registers the request and blocks until the approvers have decided on it
+// or the timeout expires.
+func (ui *QuorumUI) await(request ApprovalRequest) bool {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		log.Error("Failed to generate approval request id", "err", err)
+		return false
+	}
+	request.ID = hex.EncodeToString(id[:])
+	request.Deadline = time.Now().Add(ui.config.Timeout)
+	request.Decisions = make(map[common.Address]bool)
+
+	pending := &pendingApproval{request: request, done: make(chan struct{})}
+	ui.lock.Lock()
+	ui.pending[request.ID] = pending
+	ui.lock.Unlock()
+
+	ui.audit.Info("Approval", "type", "request", "id", request.ID, "quorum", fmt.Sprintf("%d/%d", ui.config.Threshold, len(ui.approvers)), "deadline", request.Deadline)
+	ui.next.ShowInfo(fmt.Sprintf("Request %s awaits the approval of %d of %d approvers", request.ID, ui.config.Threshold, len(ui.approvers)))
+
+	timer := time.NewTimer(ui.config.Timeout)
+	defer timer.Stop()
+
+	timeout := false
+	select {
+	case <-pending.done:
+	case <-timer.C:
+		timeout = true
+	}
+	ui.lock.Lock()
+	delete(ui.pending, request.ID)
+	approved, approvals := pending.approved, pending.approvals
+	ui.lock.Unlock()
+
+	ui.audit.Info("Approval", "type", "result", "id", request.ID, "approved", approved, "approvals", approvals, "timeout", timeout)
+	return approved
+}
+
+// Decide records the decision of an approver on a pending request.
+func (ui *QuorumUI) Decide(id string, approver common.Address, approve bool) error {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+
+	if _, ok := ui.approvers[approver]; !ok {
+		return errUnknownApprover
+	}
+	pending, ok := ui.pending[id]
+	if !ok {
+		return errUnknownApproval
+	}
+	if _, ok := pending.request.Decisions[approver]; ok {
+		return errAlreadyDecided
+	}
+	select {
+	case <-pending.done:
+		return errUnknownApproval
+	default:
+	}
+	pending.request.Decisions[approver] = approve
+	ui.audit.Info("Approval", "type", "decision", "id", id, "approver", approver, "approve", approve)
+
+	if approve {
+		pending.approvals++
+	}
+	rejections := len(pending.request.Decisions) - pending.approvals
+	switch {
+	case pending.approvals >= ui.config.Threshold:
+		pending.approved = true
+		close(pending.done)
+	case rejections > len(ui.approvers)-ui.config.Threshold:
+		close(pending.done)
+	}
+	return nil
+}
+
+// Pending returns the requests awaiting decisions.
+func (ui *QuorumUI) Pending() []ApprovalRequest {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+
+	requests := make([]ApprovalRequest, 0, len(ui.pending))
+	for _, pending := range ui.pending {
+		request := pending.request
+		request.Decisions = make(map[common.Address]bool, len(pending.request.Decisions))
+		for approver, approve := range pending.request.Decisions {
+			request.Decisions[approver] = approve
+		}
+		requests = append(requests, request)
+	}
+	return requests
+}
+
+// ApproveTx asks the next UI first, and then the approvers to approve the
+// transaction as it was approved, and possibly modified, by the next UI.
+func (ui *QuorumUI) ApproveTx(request *SignTxRequest) (SignTxResponse, error) {
+	resp, err := ui.next.ApproveTx(request)
+	if err != nil || !resp.Approved || !ui.covers(request.Transaction.From.Address()) {
+		return resp, err
+	}
+	approval := *request
+	approval.Transaction = resp.Transaction
+	resp.Approved = ui.await(ApprovalRequest{Transaction: &approval})
+	return resp, nil
+}
+
+// ApproveSignData asks the next UI first, and then the approvers to approve
+// signing the data.
+func (ui *QuorumUI) ApproveSignData(request *SignDataRequest) (SignDataResponse, error) {
+	resp, err := ui.next.ApproveSignData(request)
+	if err != nil || !resp.Approved || !ui.covers(request.Address.Address()) {
+		return resp, err
+	}
+	resp.Approved = ui.await(ApprovalRequest{SignData: request})
+	return resp, nil
+}
+
+func (ui *QuorumUI) ApproveListing(request *ListRequest) (ListResponse, error) {
+	return ui.next.ApproveListing(request)
+}
+
+func (ui *QuorumUI) ApproveNewAccount(request *NewAccountRequest) (NewAccountResponse, error) {
+	return ui.next.ApproveNewAccount(request)
+}
+
+func (ui *QuorumUI) ShowError(message string) {
+	ui.next.ShowError(message)
+}
+
+func (ui *QuorumUI) ShowInfo(message string) {
+	ui.next.ShowInfo(message)
+}
+
+func (ui *QuorumUI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	ui.next.OnApprovedTx(tx)
+}
+
+func (ui *QuorumUI) OnSignTxFailed(request *SignTxRequest) {
+	if listener, ok := ui.next.(SignTxFailureListener); ok {
+		listener.OnSignTxFailed(request)
+	}
+}
+
+func (ui *QuorumUI) OnSignerStartup(info StartupInfo) {
+	ui.next.OnSignerStartup(info)
+}
+
+func (ui *QuorumUI) OnInputRequired(info UserInputRequest) (UserInputResponse, error) {
+	return ui.next.OnInputRequired(info)
+}
+
+func (ui *QuorumUI) RegisterUIServer(api *UIServerAPI) {
+	ui.next.RegisterUIServer(api)
+}
+
+// ApproverAPI is the API used by approvers to list and decide on the requests
+// awaiting a quorum. Every call is authenticated by a signature of the approver
+// over a text message (as in personal_sign), so the API may be exposed on the
+// external endpoints of clef.
+type ApproverAPI struct {
+	ui *QuorumUI
+}
+
+// NewApproverAPI creates the API of the approvers of the given UI.
+func NewApproverAPI(ui *QuorumUI) *ApproverAPI {
+	return &ApproverAPI{ui}
+}
+
+// PendingMessage returns the message an approver signs to list the pending
+// requests at the given unix time.
+func PendingMessage(timestamp uint64) string {
+	return fmt.Sprintf("clef approver: list pending requests at %d", timestamp)
+}
+
+// DecisionMessage returns the message an approver signs to decide on a request.
+func DecisionMessage(id string, approve bool) string {
+	if approve {
+		return fmt.Sprintf("clef approver: approve request %s", id)
+	}
+	return fmt.Sprintf("clef approver: reject request %s", id)
+}
+
+// recoverApprover returns the address of the key which signed the text message.
+func recoverApprover(message string, sig hexutil.Bytes) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("signature must be %d bytes long", crypto.SignatureLength)
+	}
+	sig = common.CopyBytes(sig)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27 // Transform yellow paper V from 27/28 to 0/1
+	}
+	pubkey, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// Pending returns the requests awaiting decisions. The signature is made over
+// the PendingMessage of the given unix timestamp, which needs to be recent.
+// Example call
+// {"jsonrpc":"2.0","method":"approver_pending","params":[1592000000, "0x..."], "id":1}
+func (api *ApproverAPI) Pending(ctx context.Context, timestamp uint64, sig hexutil.Bytes) ([]ApprovalRequest, error) {
+	if diff := time.Since(time.Unix(int64(timestamp), 0)); diff > approverClockSkew || diff < -approverClockSkew {
+		return nil, errInvalidTimestamp
+	}
+	approver, err := recoverApprover(PendingMessage(timestamp), sig)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := api.ui.approvers[approver]; !ok {
+		return nil, errUnknownApprover
+	}
+	return api.ui.Pending(), nil
+}
+
+// Decide approves or rejects a pending request. The signature is made over the
+// DecisionMessage of the request and decision.
+// Example call
+// {"jsonrpc":"2.0","method":"approver_decide","params":["9c1f...", true, "0x..."], "id":2}
+func (api *ApproverAPI) Decide(ctx context.Context, id string, approve bool, sig hexutil.Bytes) error {
+	approver, err := recoverApprover(DecisionMessage(id, approve), sig)
+	if err != nil {
+		return err
+	}
+	return api.ui.Decide(id, approver, approve)
+}
@@ -0,0 +1,242 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+)
+
+// quorumTestUI is the next UI of the quorum, which approves or rejects everything.
+type quorumTestUI struct {
+	approve bool
+	calls   int
+}
+
+func (ui *quorumTestUI) ApproveTx(request *SignTxRequest) (SignTxResponse, error) {
+	ui.calls++
+	return SignTxResponse{Transaction: request.Transaction, Approved: ui.approve}, nil
+}
+func (ui *quorumTestUI) ApproveSignData(request *SignDataRequest) (SignDataResponse, error) {
+	ui.calls++
+	return SignDataResponse{Approved: ui.approve}, nil
+}
+func (ui *quorumTestUI) ApproveListing(request *ListRequest) (ListResponse, error) {
+	return ListResponse{}, nil
+}
+func (ui *quorumTestUI) ApproveNewAccount(request *NewAccountRequest) (NewAccountResponse, error) {
+	return NewAccountResponse{}, nil
+}
+func (ui *quorumTestUI) ShowError(message string)                     {}
+func (ui *quorumTestUI) ShowInfo(message string)                      {}
+func (ui *quorumTestUI) OnApprovedTx(tx ethapi.SignTransactionResult) {}
+func (ui *quorumTestUI) OnSignerStartup(info StartupInfo)             {}
+func (ui *quorumTestUI) OnInputRequired(info UserInputRequest) (UserInputResponse, error) {
+	return UserInputResponse{}, nil
+}
+func (ui *quorumTestUI) RegisterUIServer(api *UIServerAPI) {}
+
+func signApproval(t *testing.T, key *ecdsa.PrivateKey, message string) []byte {
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig
+}
+
+func newQuorumTest(t *testing.T, n, threshold int, timeout time.Duration) (*QuorumUI, *ApproverAPI, []*ecdsa.PrivateKey) {
+	var (
+		keys   []*ecdsa.PrivateKey
+		config = QuorumConfig{Threshold: threshold, Timeout: timeout}
+	)
+	for i := 0; i < n; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		config.Approvers = append(config.Approvers, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	ui, err := NewQuorumUI(&quorumTestUI{approve: true}, config)
+	if err != nil {
+		t.Fatalf("failed to create quorum: %v", err)
+	}
+	return ui, NewApproverAPI(ui), keys
+}
+
+// pendingID waits for a request to be pending and returns its id.
+func pendingID(t *testing.T, api *ApproverAPI, key *ecdsa.PrivateKey) string {
+	for i := 0; i < 100; i++ {
+		now := uint64(time.Now().Unix())
+		pending, err := api.Pending(context.Background(), now, signApproval(t, key, PendingMessage(now)))
+		if err != nil {
+			t.Fatalf("failed to list pending requests: %v", err)
+		}
+		if len(pending) > 0 {
+			return pending[0].ID
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no pending request")
+	return ""
+}
+
+func approveAsync(ui *QuorumUI) chan bool {
+	result := make(chan bool, 1)
+	go func() {
+		from := common.NewMixedcaseAddress(common.Address{0x01})
+		resp, _ := ui.ApproveTx(&SignTxRequest{Transaction: SendTxArgs{From: from}})
+		result <- resp.Approved
+	}()
+	return result
+}
+
+func TestQuorumApproval(t *testing.T) {
+	ui, api, keys := newQuorumTest(t, 3, 2, time.Minute)
+	result := approveAsync(ui)
+	id := pendingID(t, api, keys[0])
+
+	ctx := context.Background()
+	if err := api.Decide(ctx, id, true, signApproval(t, keys[0], DecisionMessage(id, true))); err != nil {
+		t.Fatalf("failed to approve: %v", err)
+	}
+	// A second decision of the same approver must not count
+	if err := api.Decide(ctx, id, true, signApproval(t, keys[0], DecisionMessage(id, true))); err != errAlreadyDecided {
+		t.Fatalf("repeated decision: have error %v, want %v", err, errAlreadyDecided)
+	}
+	// A signature over a different decision must not authenticate the approver
+	if err := api.Decide(ctx, id, true, signApproval(t, keys[1], DecisionMessage(id, false))); err != errUnknownApprover {
+		t.Fatalf("mismatched signature: have error %v, want %v", err, errUnknownApprover)
+	}
+	select {
+	case <-result:
+		t.Fatalf("request decided before quorum")
+	default:
+	}
+	if err := api.Decide(ctx, id, true, signApproval(t, keys[2], DecisionMessage(id, true))); err != nil {
+		t.Fatalf("failed to approve: %v", err)
+	}
+	if !<-result {
+		t.Fatalf("request not approved with quorum")
+	}
+	if len(ui.Pending()) != 0 {
+		t.Fatalf("decided request still pending")
+	}
+}
+
+func TestQuorumRejection(t *testing.T) {
+	ui, api, keys := newQuorumTest(t, 3, 2, time.Minute)
+	result := approveAsync(ui)
+	id := pendingID(t, api, keys[0])
+
+	// Two rejections make a quorum of two out of three impossible
+	for _, key := range keys[:2] {
+		if err := api.Decide(context.Background(), id, false, signApproval(t, key, DecisionMessage(id, false))); err != nil {
+			t.Fatalf("failed to reject: %v", err)
+		}
+	}
+	if <-result {
+		t.Fatalf("request approved without quorum")
+	}
+}
+
+func TestQuorumTimeout(t *testing.T) {
+	ui, api, keys := newQuorumTest(t, 2, 1, 50*time.Millisecond)
+	result := approveAsync(ui)
+	pendingID(t, api, keys[0])
+
+	if <-result {
+		t.Fatalf("request approved after timeout")
+	}
+}
+
+// TestQuorumNextRejection tests that requests rejected by the next UI are not
+// put to the approvers.
+func TestQuorumNextRejection(t *testing.T) {
+	next := new(quorumTestUI)
+	key, _ := crypto.GenerateKey()
+	ui, err := NewQuorumUI(next, QuorumConfig{
+		Approvers: []common.Address{crypto.PubkeyToAddress(key.PublicKey)},
+		Threshold: 1,
+		Timeout:   time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("failed to create quorum: %v", err)
+	}
+	resp, _ := ui.ApproveTx(&SignTxRequest{Transaction: SendTxArgs{From: common.NewMixedcaseAddress(common.Address{0x01})}})
+	if resp.Approved || next.calls != 1 {
+		t.Fatalf("request rejected by next UI approved: %v (calls %d)", resp.Approved, next.calls)
+	}
+	sresp, _ := ui.ApproveSignData(&SignDataRequest{Address: common.NewMixedcaseAddress(common.Address{0x01})})
+	if sresp.Approved || next.calls != 2 {
+		t.Fatalf("data rejected by next UI approved: %v (calls %d)", sresp.Approved, next.calls)
+	}
+	if len(ui.Pending()) != 0 {
+		t.Fatalf("request rejected by next UI awaits approvers")
+	}
+}
+
+func TestQuorumUncoveredAccount(t *testing.T) {
+	next := new(quorumTestUI)
+	key, _ := crypto.GenerateKey()
+	ui, err := NewQuorumUI(next, QuorumConfig{
+		Approvers: []common.Address{crypto.PubkeyToAddress(key.PublicKey)},
+		Threshold: 1,
+		Timeout:   time.Minute,
+		Accounts:  []common.Address{{0x02}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create quorum: %v", err)
+	}
+	ui.ApproveTx(&SignTxRequest{Transaction: SendTxArgs{From: common.NewMixedcaseAddress(common.Address{0x01})}})
+	if next.calls != 1 {
+		t.Fatalf("request of uncovered account not passed on")
+	}
+}
+
+func TestQuorumPendingAuth(t *testing.T) {
+	_, api, keys := newQuorumTest(t, 1, 1, time.Minute)
+	stranger, _ := crypto.GenerateKey()
+
+	now := uint64(time.Now().Unix())
+	if _, err := api.Pending(context.Background(), now, signApproval(t, stranger, PendingMessage(now))); err != errUnknownApprover {
+		t.Errorf("stranger: have error %v, want %v", err, errUnknownApprover)
+	}
+	old := now - uint64(time.Hour/time.Second)
+	if _, err := api.Pending(context.Background(), old, signApproval(t, keys[0], PendingMessage(old))); err != errInvalidTimestamp {
+		t.Errorf("old timestamp: have error %v, want %v", err, errInvalidTimestamp)
+	}
+}
+
+func TestQuorumConfig(t *testing.T) {
+	addr := common.Address{0x01}
+	for i, config := range []QuorumConfig{
+		{Approvers: []common.Address{addr}, Threshold: 2, Timeout: time.Minute},
+		{Approvers: []common.Address{addr}, Threshold: 0, Timeout: time.Minute},
+		{Approvers: []common.Address{addr, addr}, Threshold: 1, Timeout: time.Minute},
+		{Approvers: []common.Address{addr}, Threshold: 1},
+	} {
+		if _, err := NewQuorumUI(new(quorumTestUI), config); err == nil {
+			t.Errorf("config %d: expected error", i)
+		}
+	}
+}
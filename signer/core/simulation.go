@@ -0,0 +1,292 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// simulationTimeout is the maximum time spent simulating a transaction before
+// the request is shown to the user without the simulation.
+const simulationTimeout = 5 * time.Second
+
+// simulationTracer is the JavaScript tracer run by the node to simulate a
+// transaction. It collects the accounts whose balance may change, their balance
+// after the execution and the ERC-20 Transfer events emitted. The balances are
+// omitted if no code was executed, as the state is inaccessible to the tracer. Events of reverted
+// internal calls are not filtered out, as the tracer cannot observe call frames.
+const simulationTracer = `{
+	executed: false,
+	balances: {},
+	transfers: [],
+
+	touch: function(addr) {
+		this.balances[toHex(addr)] = true;
+	},
+
+	step: function(log, db) {
+		this.executed = true;
+		switch (log.op.toString()) {
+			case "CALL": case "CALLCODE":
+				this.touch(toAddress(log.stack.peek(1).toString(16)));
+				break;
+			case "CREATE":
+				var from = log.contract.getAddress();
+				this.touch(toContract(from, db.getNonce(from)));
+				break;
+			case "CREATE2":
+				var from = log.contract.getAddress();
+				var offset = log.stack.peek(1).valueOf();
+				var size = log.stack.peek(2).valueOf();
+				this.touch(toContract2(from, log.stack.peek(3).toString(16), log.memory.slice(offset, offset + size)));
+				break;
+			case "SELFDESTRUCT":
+				this.touch(log.contract.getAddress());
+				this.touch(toAddress(log.stack.peek(0).toString(16)));
+				break;
+			case "LOG3":
+				// Transfer(address indexed from, address indexed to, uint256 value)
+				if (log.stack.peek(2).toString(16) != "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef" || log.stack.peek(1).valueOf() != 32) {
+					break;
+				}
+				var offset = log.stack.peek(0).valueOf();
+				this.transfers.push({
+					token: toHex(log.contract.getAddress()),
+					from:  toHex(toAddress(log.stack.peek(3).toString(16))),
+					to:    toHex(toAddress(log.stack.peek(4).toString(16))),
+					value: toHex(log.memory.slice(offset, offset + 32))
+				});
+				break;
+		}
+	},
+
+	fault: function(log, db) {},
+
+	result: function(ctx, db) {
+		// The state is only accessible if code was executed
+		var balances = null;
+		if (this.executed) {
+			this.touch(ctx.from);
+			this.touch(ctx.to);
+
+			balances = {};
+			for (var addr in this.balances) {
+				balances[addr] = "0x" + db.getBalance(toAddress(addr)).toString(16);
+			}
+		}
+		return {
+			balances:  balances,
+			transfers: this.transfers,
+			gasUsed:   ctx.gasUsed,
+			output:    toHex(ctx.output),
+			error:     ctx.error
+		};
+	}
+}`
+
+// SimulationResult is the predicted outcome of executing a transaction on top
+// of the current state of the chain, shown to the user before approval.
+type SimulationResult struct {
+	Block          hexutil.Uint64  `json:"block"`                  // Block on top of which the transaction was simulated
+	GasEstimate    hexutil.Uint64  `json:"gasEstimate"`            // Estimated gas limit, zero if estimation failed
+	GasUsed        hexutil.Uint64  `json:"gasUsed"`                // Gas used by the executed code, excluding intrinsic gas
+	Reverted       bool            `json:"reverted"`               // Whether the execution failed
+	Error          string          `json:"error,omitempty"`        // Execution error, if any
+	RevertReason   string          `json:"revertReason,omitempty"` // Decoded revert reason, if any
+	BalanceChanges []BalanceChange `json:"balanceChanges"`         // Changes of ether balances, including fees
+	TokenTransfers []TokenTransfer `json:"tokenTransfers"`         // ERC-20 transfers emitted
+}
+
+// BalanceChange is the change of the ether balance of an account.
+type BalanceChange struct {
+	Address common.Address `json:"address"`
+	Before  *hexutil.Big   `json:"before"`
+	After   *hexutil.Big   `json:"after"`
+	Delta   *hexutil.Big   `json:"delta"`
+}
+
+// TokenTransfer is a decoded ERC-20 Transfer event.
+type TokenTransfer struct {
+	Token common.Address `json:"token"`
+	From  common.Address `json:"from"`
+	To    common.Address `json:"to"`
+	Value *hexutil.Big   `json:"value"`
+}
+
+// Simulator predicts the outcome of transactions before they are approved.
+type Simulator interface {
+	Simulate(ctx context.Context, args *SendTxArgs) (*SimulationResult, error)
+}
+
+// NodeSimulator simulates transactions through the debug_traceCall method of
+// a node, which needs to expose the debug and eth namespaces.
+type NodeSimulator struct {
+	client *rpc.Client
+}
+
+// NewNodeSimulator creates a simulator using the given node connection.
+func NewNodeSimulator(client *rpc.Client) *NodeSimulator {
+	return &NodeSimulator{client: client}
+}
+
+// callArgs converts the transaction into the arguments of a call.
+func (args *SendTxArgs) callArgs() map[string]interface{} {
+	call := map[string]interface{}{
+		"from":     args.From.Address(),
+		"gas":      args.Gas,
+		"gasPrice": args.GasPrice,
+		"value":    args.Value,
+	}
+	if args.To != nil {
+		call["to"] = args.To.Address()
+	}
+	if args.Data != nil {
+		call["data"] = args.Data
+	} else if args.Input != nil {
+		call["data"] = args.Input
+	}
+	return call
+}
+
+// tracerResult is the output of simulationTracer.
+type tracerResult struct {
+	Balances  map[common.Address]*hexutil.Big `json:"balances"`
+	Transfers []struct {
+		Token common.Address `json:"token"`
+		From  common.Address `json:"from"`
+		To    common.Address `json:"to"`
+		Value hexutil.Bytes  `json:"value"`
+	} `json:"transfers"`
+	GasUsed uint64        `json:"gasUsed"`
+	Output  hexutil.Bytes `json:"output"`
+	Error   string        `json:"error"`
+}
+
+// Simulate executes the transaction on top of the latest block of the node.
+func (s *NodeSimulator) Simulate(ctx context.Context, args *SendTxArgs) (*SimulationResult, error) {
+	// Pin the block, so that all queries see the same state
+	var head hexutil.Uint64
+	if err := s.client.CallContext(ctx, &head, "eth_blockNumber"); err != nil {
+		return nil, err
+	}
+	block := hexutil.EncodeUint64(uint64(head))
+
+	var (
+		call  = args.callArgs()
+		trace tracerResult
+	)
+	if err := s.client.CallContext(ctx, &trace, "debug_traceCall", call, block, map[string]interface{}{"tracer": simulationTracer}); err != nil {
+		return nil, fmt.Errorf("trace failed: %v", err)
+	}
+	result := &SimulationResult{
+		Block:          head,
+		GasUsed:        hexutil.Uint64(trace.GasUsed),
+		Reverted:       trace.Error != "",
+		Error:          trace.Error,
+		BalanceChanges: []BalanceChange{},
+		TokenTransfers: []TokenTransfer{},
+	}
+	if result.Reverted {
+		if reason, err := abi.UnpackRevert(trace.Output); err == nil {
+			result.RevertReason = reason
+		}
+	} else {
+		// The estimation fails for reverting transactions, only attempt for successful ones
+		if err := s.client.CallContext(ctx, &result.GasEstimate, "eth_estimateGas", call, block); err != nil {
+			return nil, fmt.Errorf("gas estimation failed: %v", err)
+		}
+	}
+	// Plain transfers execute no code, so the tracer has no access to the state.
+	// The balances after the transfer follow from the value and the fee instead,
+	// with the gas estimate being exact for plain transfers.
+	balances := trace.Balances
+	if balances == nil {
+		if args.To == nil {
+			return nil, errors.New("trace returned no balances")
+		}
+		from, to := args.From.Address(), args.To.Address()
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(uint64(result.GasEstimate)), args.GasPrice.ToInt())
+
+		deltas := map[common.Address]*big.Int{from: new(big.Int), to: new(big.Int)}
+		deltas[from].Sub(deltas[from], fee)
+		deltas[from].Sub(deltas[from], args.Value.ToInt())
+		deltas[to].Add(deltas[to], args.Value.ToInt())
+
+		balances = make(map[common.Address]*hexutil.Big)
+		for addr, delta := range deltas {
+			var before hexutil.Big
+			if err := s.client.CallContext(ctx, &before, "eth_getBalance", addr, block); err != nil {
+				return nil, err
+			}
+			balances[addr] = (*hexutil.Big)(delta.Add(delta, before.ToInt()))
+		}
+	}
+	// Compare the balances after the execution against the balances before
+	addrs := make([]common.Address, 0, len(balances))
+	for addr := range balances {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+	for _, addr := range addrs {
+		var before hexutil.Big
+		if err := s.client.CallContext(ctx, &before, "eth_getBalance", addr, block); err != nil {
+			return nil, err
+		}
+		after := balances[addr]
+		delta := new(big.Int).Sub(after.ToInt(), before.ToInt())
+		if delta.Sign() == 0 {
+			continue
+		}
+		result.BalanceChanges = append(result.BalanceChanges, BalanceChange{
+			Address: addr,
+			Before:  &before,
+			After:   after,
+			Delta:   (*hexutil.Big)(delta),
+		})
+	}
+	for _, transfer := range trace.Transfers {
+		result.TokenTransfers = append(result.TokenTransfers, TokenTransfer{
+			Token: transfer.Token,
+			From:  transfer.From,
+			To:    transfer.To,
+			Value: (*hexutil.Big)(new(big.Int).SetBytes(transfer.Value)),
+		})
+	}
+	return result, nil
+}
+
+// BalanceChange returns the predicted change of the balance of the account,
+// zero if it is not affected.
+func (r *SimulationResult) BalanceChange(addr common.Address) *big.Int {
+	for _, change := range r.BalanceChanges {
+		if change.Address == addr {
+			return new(big.Int).Set(change.Delta.ToInt())
+		}
+	}
+	return new(big.Int)
+}
@@ -0,0 +1,167 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	simFrom  = common.HexToAddress("0x000000000000000000000000000000000000dead")
+	simToken = common.HexToAddress("0x000000000000000000000000000000000000beef")
+)
+
+// fakeEthService serves the eth namespace methods used by the simulator.
+type fakeEthService struct {
+	estimated bool
+}
+
+func (s *fakeEthService) BlockNumber() hexutil.Uint64 { return 100 }
+
+func (s *fakeEthService) EstimateGas(args map[string]interface{}, block string) (hexutil.Uint64, error) {
+	if block != "0x64" {
+		return 0, errors.New("unexpected block")
+	}
+	s.estimated = true
+	return 50000, nil
+}
+
+func (s *fakeEthService) GetBalance(addr common.Address, block string) (*hexutil.Big, error) {
+	if block != "0x64" {
+		return nil, errors.New("unexpected block")
+	}
+	return (*hexutil.Big)(big.NewInt(1000)), nil
+}
+
+// fakeDebugService serves a canned trace result.
+type fakeDebugService struct {
+	result map[string]interface{}
+}
+
+func (s *fakeDebugService) TraceCall(args map[string]interface{}, block string, config map[string]interface{}) (map[string]interface{}, error) {
+	if config["tracer"] != simulationTracer {
+		return nil, errors.New("unexpected tracer")
+	}
+	return s.result, nil
+}
+
+func newTestSimulator(t *testing.T, trace map[string]interface{}) (*NodeSimulator, *fakeEthService) {
+	eth := new(fakeEthService)
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", eth); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("debug", &fakeDebugService{trace}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	return NewNodeSimulator(rpc.DialInProc(server)), eth
+}
+
+func TestNodeSimulator(t *testing.T) {
+	sim, eth := newTestSimulator(t, map[string]interface{}{
+		"balances": map[string]interface{}{
+			simFrom.Hex():  "0x3e6", // 998
+			simToken.Hex(): "0x3e8", // unchanged
+		},
+		"transfers": []interface{}{
+			map[string]interface{}{
+				"token": simToken.Hex(),
+				"from":  simFrom.Hex(),
+				"to":    simToken.Hex(),
+				"value": "0x" + common.Bytes2Hex(common.LeftPadBytes([]byte{5}, 32)),
+			},
+		},
+		"gasUsed": 42000,
+		"output":  "0x",
+	})
+	to := common.NewMixedcaseAddress(simToken)
+	result, err := sim.Simulate(context.Background(), &SendTxArgs{From: common.NewMixedcaseAddress(simFrom), To: &to})
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if !eth.estimated || result.GasEstimate != 50000 || result.GasUsed != 42000 || result.Reverted {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(result.BalanceChanges) != 1 || result.BalanceChange(simFrom).Int64() != -2 {
+		t.Errorf("unexpected balance changes: %+v", result.BalanceChanges)
+	}
+	if len(result.TokenTransfers) != 1 || result.TokenTransfers[0].Value.ToInt().Int64() != 5 {
+		t.Errorf("unexpected token transfers: %+v", result.TokenTransfers)
+	}
+}
+
+func TestNodeSimulatorRevert(t *testing.T) {
+	// Error(string) encoding of "nope"
+	output := "0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"6e6f706500000000000000000000000000000000000000000000000000000000"
+	sim, eth := newTestSimulator(t, map[string]interface{}{
+		"balances": map[string]interface{}{simFrom.Hex(): "0x3e8"},
+		"gasUsed":  21000,
+		"output":   output,
+		"error":    "execution reverted",
+	})
+	result, err := sim.Simulate(context.Background(), &SendTxArgs{From: common.NewMixedcaseAddress(simFrom)})
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if eth.estimated {
+		t.Errorf("gas estimated for reverting transaction")
+	}
+	if !result.Reverted || result.RevertReason != "nope" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(result.BalanceChanges) != 0 {
+		t.Errorf("unexpected balance changes: %+v", result.BalanceChanges)
+	}
+}
+
+func TestNodeSimulatorPlainTransfer(t *testing.T) {
+	// No code is executed, so the tracer returns no balances
+	sim, _ := newTestSimulator(t, map[string]interface{}{
+		"balances": nil,
+		"gasUsed":  0,
+		"output":   "0x",
+	})
+	to := common.NewMixedcaseAddress(simToken)
+	args := &SendTxArgs{
+		From:     common.NewMixedcaseAddress(simFrom),
+		To:       &to,
+		GasPrice: hexutil.Big(*big.NewInt(2)),
+		Value:    hexutil.Big(*big.NewInt(10)),
+	}
+	result, err := sim.Simulate(context.Background(), args)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	// The sender pays the value and 50000 gas at 2 wei, the recipient receives the value
+	if have := result.BalanceChange(simFrom).Int64(); have != -100010 {
+		t.Errorf("sender balance change mismatch: have %d, want %d", have, -100010)
+	}
+	if have := result.BalanceChange(simToken).Int64(); have != 10 {
+		t.Errorf("recipient balance change mismatch: have %d, want %d", have, 10)
+	}
+}
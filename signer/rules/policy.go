@@ -0,0 +1,618 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params/vars"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/storage"
+	"gopkg.in/yaml.v2"
+)
+
+// AnyMethod allows all methods of a contract in a policy.
+const AnyMethod = "*"
+
+// Policy is a declarative set of restrictions for automatically approving the
+// transactions of some accounts. Transactions of accounts not covered by the
+// policy are passed on to the next UI.
+type Policy struct {
+	ChainIDs []uint64                          `json:"chainIds,omitempty"` // Chain IDs the policy approves transactions on, any if empty
+	Accounts map[common.Address]*AccountPolicy `json:"accounts"`           // Policies of the covered accounts
+}
+
+// AccountPolicy is the set of restrictions for the transactions of an account.
+type AccountPolicy struct {
+	DailyLimit  *Amount                     `json:"dailyLimit,omitempty"`  // Maximum value sent per UTC day
+	WeeklyLimit *Amount                     `json:"weeklyLimit,omitempty"` // Maximum value sent per ISO week
+	MaxGasPrice *Amount                     `json:"maxGasPrice,omitempty"` // Gas price ceiling
+	Recipients  []common.Address            `json:"recipients,omitempty"`  // Allowed recipients of plain value transfers
+	Contracts   map[common.Address][]string `json:"contracts,omitempty"`   // Allowed method signatures or selectors per contract
+	Window      *TimeWindow                 `json:"window,omitempty"`      // Time of day transactions are approved in
+	Simulation  *SimulationPolicy           `json:"simulation,omitempty"`  // Restrictions on the simulated outcome
+}
+
+// SimulationPolicy restricts the predicted outcome of transactions, if clef is
+// configured to simulate them.
+type SimulationPolicy struct {
+	Required            bool    `json:"required,omitempty"`            // Reject transactions that were not simulated
+	RejectReverts       bool    `json:"rejectReverts,omitempty"`       // Reject transactions predicted to fail
+	MaxBalanceLoss      *Amount `json:"maxBalanceLoss,omitempty"`      // Maximum decrease of the sender's balance, including fees
+	AllowTokenTransfers bool    `json:"allowTokenTransfers,omitempty"` // Allow ERC-20 transfers out of the sender's account
+}
+
+// TimeWindow is a daily period of time, given as "15:04" clock times. The window
+// wraps around midnight if From is later than To.
+type TimeWindow struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Timezone string `json:"timezone,omitempty"` // IANA time zone name, UTC if empty
+}
+
+// Amount is an amount of wei, which is written as a number optionally followed
+// by a unit, e.g. "1.5 ether" or "20 gwei".
+type Amount big.Int
+
+var amountUnits = map[string]*big.Int{
+	"":      big.NewInt(1),
+	"wei":   big.NewInt(1),
+	"gwei":  big.NewInt(vars.GWei),
+	"ether": big.NewInt(vars.Ether),
+}
+
+// ParseAmount parses an amount of wei with an optional unit.
+func ParseAmount(s string) (*Amount, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	var unit string
+	if len(fields) == 2 {
+		unit = strings.ToLower(fields[1])
+	}
+	multiplier, ok := amountUnits[unit]
+	if !ok {
+		return nil, fmt.Errorf("invalid unit %q", fields[1])
+	}
+	value, ok := new(big.Rat).SetString(fields[0])
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	value.Mul(value, new(big.Rat).SetInt(multiplier))
+	if !value.IsInt() {
+		return nil, fmt.Errorf("amount %q is not a whole number of wei", s)
+	}
+	return (*Amount)(new(big.Int).Set(value.Num())), nil
+}
+
+// Int returns the amount in wei.
+func (a *Amount) Int() *big.Int {
+	return (*big.Int)(a)
+}
+
+// String returns the amount in wei.
+func (a *Amount) String() string {
+	return a.Int().String()
+}
+
+// UnmarshalJSON parses an amount from a JSON string or number.
+func (a *Amount) UnmarshalJSON(input []byte) error {
+	var s string
+	if err := json.Unmarshal(input, &s); err != nil {
+		s = string(input)
+	}
+	amount, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = *amount
+	return nil
+}
+
+// parseClock parses a "15:04" clock time into the minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid clock time %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains reports whether the time falls into the window.
+func (w *TimeWindow) contains(t time.Time) (bool, error) {
+	from, err := parseClock(w.From)
+	if err != nil {
+		return false, err
+	}
+	to, err := parseClock(w.To)
+	if err != nil {
+		return false, err
+	}
+	if w.Timezone != "" {
+		loc, err := time.LoadLocation(w.Timezone)
+		if err != nil {
+			return false, err
+		}
+		t = t.In(loc)
+	} else {
+		t = t.UTC()
+	}
+	now := t.Hour()*60 + t.Minute()
+	if from <= to {
+		return now >= from && now < to, nil
+	}
+	return now >= from || now < to, nil
+}
+
+// ParsePolicy parses a policy in YAML or JSON format. YAML documents are
+// converted to JSON first, so both formats share the same field names.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	blob, err := json.Marshal(yamlToJSON(doc))
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.DisallowUnknownFields()
+
+	policy := new(Policy)
+	if err := dec.Decode(policy); err != nil {
+		return nil, err
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// yamlToJSON converts the generic maps produced by the YAML decoder into maps
+// with string keys, which can be encoded as JSON.
+func yamlToJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = yamlToJSON(value)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = yamlToJSON(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// LoadPolicy reads a policy from a YAML or JSON file.
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicy(data)
+}
+
+// validate checks the policy for malformed entries, so they are reported when the
+// policy is loaded rather than when a transaction is evaluated.
+func (p *Policy) validate() error {
+	for addr, account := range p.Accounts {
+		if account == nil {
+			return fmt.Errorf("account %x: empty policy", addr)
+		}
+		if w := account.Window; w != nil {
+			if _, err := w.contains(time.Now()); err != nil {
+				return fmt.Errorf("account %x: %v", addr, err)
+			}
+		}
+		for contract, methods := range account.Contracts {
+			if len(methods) == 0 {
+				return fmt.Errorf("account %x: no methods allowed for contract %x", addr, contract)
+			}
+		}
+	}
+	return nil
+}
+
+// MethodDatabase resolves 4-byte method selectors to method signatures. Use a
+// fourbyte.Database as an implementation.
+type MethodDatabase interface {
+	Selector(id []byte) (string, error)
+}
+
+// Verdict is the outcome of evaluating a request against a policy.
+type Verdict int
+
+const (
+	VerdictManual  Verdict = iota // Request not covered by the policy
+	VerdictApprove                // Request allowed by the policy
+	VerdictReject                 // Request violating the policy
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case VerdictApprove:
+		return "approve"
+	case VerdictReject:
+		return "reject"
+	default:
+		return "manual"
+	}
+}
+
+// policyUI provides an implementation of UIClientAPI that approves or rejects
+// transactions according to a declarative policy, passing everything else to
+// the next UI.
+type policyUI struct {
+	next    core.UIClientAPI
+	policy  *Policy
+	chainID *big.Int
+	methods MethodDatabase
+	storage storage.Storage  // Persists the value sent per account and period
+	now     func() time.Time // Clock, overridable for testing
+
+	reserved map[reservationKey]reservation // Value of approved transactions not signed yet
+	lock     sync.Mutex                     // Serializes the limit checks and the value tracking
+}
+
+// reservationKey identifies an approved transaction awaiting its signature.
+type reservationKey struct {
+	from  common.Address
+	nonce uint64
+}
+
+// reservation is the value of an approved transaction, counted towards the
+// limits of the sender until it is signed or fails.
+type reservation struct {
+	value *big.Int
+	at    time.Time
+}
+
+// NewPolicyEvaluator creates a UI evaluating transactions against the policy
+// before passing them on to next. The value sent by the covered accounts is
+// tracked in the given storage. Methods are resolved through the given database,
+// which may be nil if the policy only contains selectors.
+func NewPolicyEvaluator(next core.UIClientAPI, policy *Policy, chainID *big.Int, methods MethodDatabase, backend storage.Storage) (*policyUI, error) {
+	if policy == nil {
+		return nil, errors.New("no policy given")
+	}
+	return &policyUI{
+		next:    next,
+		policy:  policy,
+		chainID: chainID,
+		methods: methods,
+		storage: backend,
+		now:     time.Now,
+
+		reserved: make(map[reservationKey]reservation),
+	}, nil
+}
+
+// spentKeys returns the storage keys of the value sent by an account in the day
+// and week of the given time.
+func spentKeys(addr common.Address, t time.Time) (day, week string) {
+	t = t.UTC()
+	year, weekNum := t.ISOWeek()
+	day = fmt.Sprintf("policy/%x/day/%s", addr, t.Format("2006-01-02"))
+	week = fmt.Sprintf("policy/%x/week/%d-W%02d", addr, year, weekNum)
+	return day, week
+}
+
+// spent returns the value stored under a key, zero if missing.
+func (p *policyUI) spent(key string) *big.Int {
+	value, ok := new(big.Int), false
+	if s, err := p.storage.Get(key); err == nil {
+		value, ok = value.SetString(s, 10)
+	}
+	if !ok {
+		return new(big.Int)
+	}
+	return value
+}
+
+// record adds the value sent by an account to its daily and weekly totals. A
+// negative value is taken off the totals. The caller must hold the lock.
+func (p *policyUI) record(addr common.Address, value *big.Int, at time.Time) {
+	if _, ok := p.policy.Accounts[addr]; !ok || value.Sign() == 0 {
+		return
+	}
+	day, week := spentKeys(addr, at)
+	for _, key := range []string{day, week} {
+		total := new(big.Int).Add(p.spent(key), value)
+		if total.Sign() < 0 {
+			total.SetUint64(0)
+		}
+		p.storage.Put(key, total.String())
+	}
+}
+
+// reserve counts the value of an approved transaction towards the limits of the
+// sender until it is either signed or fails. The caller must hold the lock.
+func (p *policyUI) reserve(args *core.SendTxArgs, at time.Time) {
+	key := reservationKey{args.From.Address(), uint64(args.Nonce)}
+	p.release(key)
+
+	value := new(big.Int).Set(args.Value.ToInt())
+	p.record(key.from, value, at)
+	p.reserved[key] = reservation{value: value, at: at}
+}
+
+// release takes the value of an approved transaction off the totals of the
+// sender, if it was reserved. The caller must hold the lock.
+func (p *policyUI) release(key reservationKey) {
+	if r, ok := p.reserved[key]; ok {
+		delete(p.reserved, key)
+		p.record(key.from, new(big.Int).Neg(r.value), r.at)
+	}
+}
+
+// methodAllowed reports whether the call data invokes one of the given methods.
+func (p *policyUI) methodAllowed(methods []string, data []byte) (bool, string) {
+	if len(data) < 4 {
+		return false, "call data without method selector"
+	}
+	selector := hexutil.Encode(data[:4])
+	var signature string
+	if p.methods != nil {
+		signature, _ = p.methods.Selector(data[:4])
+	}
+	for _, method := range methods {
+		method = strings.Replace(method, " ", "", -1)
+		if method == AnyMethod || strings.EqualFold(method, selector) || (signature != "" && method == signature) {
+			return true, ""
+		}
+	}
+	if signature != "" {
+		return false, fmt.Sprintf("method %s not allowed", signature)
+	}
+	return false, fmt.Sprintf("method %s not allowed", selector)
+}
+
+// Evaluate checks a transaction request against the policy, returning the reason
+// of a rejection.
+func (p *policyUI) Evaluate(request *core.SignTxRequest) (Verdict, string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.evaluate(request, p.now())
+}
+
+// DryRun checks a transaction request against the policy as if it was sent at
+// the given time, and records its value as spent if approved. It is meant for
+// trying out a policy against sample transactions.
+func (p *policyUI) DryRun(request *core.SignTxRequest, at time.Time) (Verdict, string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	verdict, reason := p.evaluate(request, at)
+	if verdict == VerdictApprove {
+		args := &request.Transaction
+		p.record(args.From.Address(), args.Value.ToInt(), at)
+	}
+	return verdict, reason
+}
+
+// evaluate checks a transaction request against the policy at the given time.
+// The caller must hold the lock.
+func (p *policyUI) evaluate(request *core.SignTxRequest, now time.Time) (Verdict, string) {
+	args := &request.Transaction
+	account, ok := p.policy.Accounts[args.From.Address()]
+	if !ok {
+		return VerdictManual, ""
+	}
+	if len(p.policy.ChainIDs) > 0 {
+		allowed := false
+		for _, id := range p.policy.ChainIDs {
+			if p.chainID != nil && p.chainID.IsUint64() && p.chainID.Uint64() == id {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return VerdictReject, fmt.Sprintf("chain ID %v not allowed", p.chainID)
+		}
+	}
+	if account.Window != nil {
+		inside, err := account.Window.contains(now)
+		if err != nil {
+			return VerdictReject, err.Error()
+		}
+		if !inside {
+			return VerdictReject, fmt.Sprintf("outside of time window %s-%s", account.Window.From, account.Window.To)
+		}
+	}
+	if account.MaxGasPrice != nil && args.GasPrice.ToInt().Cmp(account.MaxGasPrice.Int()) > 0 {
+		return VerdictReject, fmt.Sprintf("gas price %v above ceiling %v", args.GasPrice.ToInt(), account.MaxGasPrice)
+	}
+	// Check the recipient and the invoked contract method
+	if args.To == nil {
+		return VerdictReject, "contract creation not allowed"
+	}
+	var data []byte
+	if args.Data != nil {
+		data = *args.Data
+	} else if args.Input != nil {
+		data = *args.Input
+	}
+	to := args.To.Address()
+	if len(data) == 0 {
+		allowed := false
+		for _, recipient := range account.Recipients {
+			allowed = allowed || recipient == to
+		}
+		if !allowed {
+			return VerdictReject, fmt.Sprintf("recipient %s not allowed", to.Hex())
+		}
+	} else {
+		methods, ok := account.Contracts[to]
+		if !ok {
+			return VerdictReject, fmt.Sprintf("contract %s not allowed", to.Hex())
+		}
+		if allowed, reason := p.methodAllowed(methods, data); !allowed {
+			return VerdictReject, reason
+		}
+	}
+	// Check the value limits, including the value of this transaction
+	value := args.Value.ToInt()
+	day, week := spentKeys(args.From.Address(), now)
+	if limit := account.DailyLimit; limit != nil {
+		if total := new(big.Int).Add(p.spent(day), value); total.Cmp(limit.Int()) > 0 {
+			return VerdictReject, fmt.Sprintf("daily limit %v exceeded", limit)
+		}
+	}
+	if limit := account.WeeklyLimit; limit != nil {
+		if total := new(big.Int).Add(p.spent(week), value); total.Cmp(limit.Int()) > 0 {
+			return VerdictReject, fmt.Sprintf("weekly limit %v exceeded", limit)
+		}
+	}
+	// Check the predicted outcome of the transaction
+	if account.Simulation != nil {
+		if reason := account.Simulation.check(args.From.Address(), request.Simulation); reason != "" {
+			return VerdictReject, reason
+		}
+	}
+	return VerdictApprove, ""
+}
+
+// check returns the reason why the simulation result violates the policy, or
+// an empty string if it doesn't.
+func (s *SimulationPolicy) check(from common.Address, sim *core.SimulationResult) string {
+	if sim == nil {
+		if s.Required {
+			return "transaction not simulated"
+		}
+		return ""
+	}
+	if s.RejectReverts && sim.Reverted {
+		return fmt.Sprintf("transaction predicted to fail: %s", sim.Error)
+	}
+	if s.MaxBalanceLoss != nil {
+		if loss := new(big.Int).Neg(sim.BalanceChange(from)); loss.Cmp(s.MaxBalanceLoss.Int()) > 0 {
+			return fmt.Sprintf("predicted balance loss %v above maximum %v", loss, s.MaxBalanceLoss)
+		}
+	}
+	if !s.AllowTokenTransfers {
+		for _, transfer := range sim.TokenTransfers {
+			if transfer.From == from {
+				return fmt.Sprintf("token transfer of %v from sender not allowed", transfer.Token.Hex())
+			}
+		}
+	}
+	return ""
+}
+
+func (p *policyUI) RegisterUIServer(api *core.UIServerAPI) {
+	p.next.RegisterUIServer(api)
+}
+
+// ApproveTx evaluates the transaction request against the policy. The value of
+// approved transactions is reserved within the same check of the limits, so
+// that concurrent requests can't exceed them together.
+func (p *policyUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	p.lock.Lock()
+	now := p.now()
+	verdict, reason := p.evaluate(request, now)
+	if verdict == VerdictApprove {
+		p.reserve(&request.Transaction, now)
+	}
+	p.lock.Unlock()
+
+	switch verdict {
+	case VerdictApprove:
+		log.Info("Policy approved transaction", "from", request.Transaction.From.Address())
+		return core.SignTxResponse{Transaction: request.Transaction, Approved: true}, nil
+	case VerdictReject:
+		log.Warn("Policy rejected transaction", "from", request.Transaction.From.Address(), "reason", reason)
+		return core.SignTxResponse{Approved: false}, nil
+	default:
+		return p.next.ApproveTx(request)
+	}
+}
+
+func (p *policyUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	return p.next.ApproveSignData(request)
+}
+
+func (p *policyUI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	return p.next.ApproveListing(request)
+}
+
+func (p *policyUI) ApproveNewAccount(request *core.NewAccountRequest) (core.NewAccountResponse, error) {
+	return p.next.ApproveNewAccount(request)
+}
+
+func (p *policyUI) OnInputRequired(info core.UserInputRequest) (core.UserInputResponse, error) {
+	return p.next.OnInputRequired(info)
+}
+
+func (p *policyUI) ShowError(message string) {
+	p.next.ShowError(message)
+}
+
+func (p *policyUI) ShowInfo(message string) {
+	p.next.ShowInfo(message)
+}
+
+func (p *policyUI) OnSignerStartup(info core.StartupInfo) {
+	p.next.OnSignerStartup(info)
+}
+
+// OnSignTxFailed releases the value reserved for an approved transaction which
+// was not signed in the end.
+func (p *policyUI) OnSignTxFailed(request *core.SignTxRequest) {
+	p.lock.Lock()
+	p.release(reservationKey{request.Transaction.From.Address(), uint64(request.Transaction.Nonce)})
+	p.lock.Unlock()
+
+	if listener, ok := p.next.(core.SignTxFailureListener); ok {
+		listener.OnSignTxFailed(request)
+	}
+}
+
+// OnApprovedTx tracks the value sent by the accounts covered by the policy,
+// regardless of how the transaction was approved. The value reserved when the
+// policy approved it is replaced by the value actually signed.
+func (p *policyUI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	if tx.Tx != nil && p.chainID != nil {
+		if from, err := types.NewEIP155Signer(p.chainID).Sender(tx.Tx); err == nil {
+			p.lock.Lock()
+			p.release(reservationKey{from, tx.Tx.Nonce()})
+			p.record(from, tx.Tx.Value(), p.now())
+			p.lock.Unlock()
+		} else {
+			log.Warn("Failed to recover transaction sender", "err", err)
+		}
+	}
+	p.next.OnApprovedTx(tx)
+}
@@ -0,0 +1,342 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package rules
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+const testPolicy = `
+chainIds: [61]
+accounts:
+  "%s":
+    dailyLimit: 1 ether
+    weeklyLimit: 1.5 ether
+    maxGasPrice: 20 gwei
+    recipients:
+      - "0x000000000000000000000000000000000000dead"
+    contracts:
+      "0x000000000000000000000000000000000000beef":
+        - transfer(address,uint256)
+        - "0x095ea7b3"
+    window:
+      from: "08:00"
+      to: "18:00"
+`
+
+type fakeMethods map[string]string
+
+func (f fakeMethods) Selector(id []byte) (string, error) {
+	if sig, ok := f[hexutil.Encode(id)]; ok {
+		return sig, nil
+	}
+	return "", errors.New("not found")
+}
+
+func newTestPolicyUI(t *testing.T, from common.Address, chainID int64) *policyUI {
+	policy, err := ParsePolicy([]byte(strings.Replace(testPolicy, "%s", from.Hex(), 1)))
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+	methods := fakeMethods{"0xa9059cbb": "transfer(address,uint256)"}
+	ui, err := NewPolicyEvaluator(&dummyUI{}, policy, big.NewInt(chainID), methods, storage.NewEphemeralStorage())
+	if err != nil {
+		t.Fatalf("failed to create evaluator: %v", err)
+	}
+	ui.now = func() time.Time { return time.Date(2020, 6, 3, 12, 0, 0, 0, time.UTC) }
+	return ui
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1", "1"},
+		{"20 gwei", "20000000000"},
+		{"1.5 ether", "1500000000000000000"},
+		{"1e18", "1000000000000000000"},
+	}
+	for _, test := range tests {
+		amount, err := ParseAmount(test.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.input, err)
+			continue
+		}
+		if amount.String() != test.want {
+			t.Errorf("%q: have %v, want %v", test.input, amount, test.want)
+		}
+	}
+	for _, input := range []string{"", "-1", "0.5", "1 finney", "1 2 3"} {
+		if _, err := ParseAmount(input); err == nil {
+			t.Errorf("%q: expected error", input)
+		}
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	from := common.HexToAddress("0x000000000000000000000000000000000000dead")
+	ui := newTestPolicyUI(t, from, 61)
+
+	ether := func(f float64) hexutil.Big {
+		v, _ := new(big.Float).Mul(big.NewFloat(f), big.NewFloat(1e18)).Int(nil)
+		return hexutil.Big(*v)
+	}
+	call := func(to string, data string) func(req *core.SendTxArgs) {
+		return func(req *core.SendTxArgs) {
+			addr, _ := mixAddr(to)
+			input := hexutil.Bytes(common.FromHex(data))
+			req.To, req.Data = addr, &input
+		}
+	}
+	tests := []struct {
+		name    string
+		value   hexutil.Big
+		modify  func(req *core.SendTxArgs)
+		verdict Verdict
+	}{
+		{"transfer", ether(0.5), nil, VerdictApprove},
+		{"daily limit", ether(1.1), nil, VerdictReject},
+		{"gas price", ether(0.1), func(req *core.SendTxArgs) { req.GasPrice = hexutil.Big(*big.NewInt(21e9)) }, VerdictReject},
+		{"recipient", ether(0.1), func(req *core.SendTxArgs) { req.To, _ = mixAddr("0x000000000000000000000000000000000000cafe") }, VerdictReject},
+		{"creation", ether(0), func(req *core.SendTxArgs) { req.To = nil }, VerdictReject},
+		{"method signature", ether(0), call("0x000000000000000000000000000000000000beef", "0xa9059cbb00"), VerdictApprove},
+		{"method selector", ether(0), call("0x000000000000000000000000000000000000beef", "0x095ea7b300"), VerdictApprove},
+		{"unknown method", ether(0), call("0x000000000000000000000000000000000000beef", "0x23b872dd00"), VerdictReject},
+		{"unknown contract", ether(0), call("0x000000000000000000000000000000000000cafe", "0xa9059cbb00"), VerdictReject},
+	}
+	for _, test := range tests {
+		req := dummyTx(test.value)
+		if test.modify != nil {
+			test.modify(&req.Transaction)
+		}
+		if verdict, reason := ui.Evaluate(req); verdict != test.verdict {
+			t.Errorf("%s: have verdict %v (%s), want %v", test.name, verdict, reason, test.verdict)
+		}
+	}
+	// Transactions outside the time window and chain are rejected
+	ui.now = func() time.Time { return time.Date(2020, 6, 3, 20, 0, 0, 0, time.UTC) }
+	if verdict, _ := ui.Evaluate(dummyTx(ether(0.1))); verdict != VerdictReject {
+		t.Errorf("window: have verdict %v, want %v", verdict, VerdictReject)
+	}
+	ui = newTestPolicyUI(t, from, 1)
+	if verdict, _ := ui.Evaluate(dummyTx(ether(0.1))); verdict != VerdictReject {
+		t.Errorf("chain ID: have verdict %v, want %v", verdict, VerdictReject)
+	}
+}
+
+func TestPolicyLimits(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	ui := newTestPolicyUI(t, from, 61)
+	signer := types.NewEIP155Signer(big.NewInt(61))
+
+	// 0.4 ether per transaction: two fit into the daily limit
+	value := new(big.Int).Mul(big.NewInt(4), big.NewInt(1e17))
+	send := func() bool {
+		req := dummyTx(hexutil.Big(*value))
+		req.Transaction.From = common.NewMixedcaseAddress(from)
+		resp, err := ui.ApproveTx(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Approved {
+			tx, _ := types.SignTx(dummySigned(value), signer, key)
+			ui.OnApprovedTx(ethapi.SignTransactionResult{Tx: tx})
+		}
+		return resp.Approved
+	}
+	for i, want := range []bool{true, true, false} {
+		if have := send(); have != want {
+			t.Fatalf("day 1, tx %d: have approved %v, want %v", i, have, want)
+		}
+	}
+	// The next day the weekly limit allows just one more
+	ui.now = func() time.Time { return time.Date(2020, 6, 4, 12, 0, 0, 0, time.UTC) }
+	for i, want := range []bool{true, false} {
+		if have := send(); have != want {
+			t.Fatalf("day 2, tx %d: have approved %v, want %v", i, have, want)
+		}
+	}
+	// A new week resets the limits
+	ui.now = func() time.Time { return time.Date(2020, 6, 8, 12, 0, 0, 0, time.UTC) }
+	if !send() {
+		t.Fatalf("new week: transaction not approved")
+	}
+}
+
+// Tests that concurrent requests can't exceed the limits together, as approved
+// values are reserved until signed, and released if signing fails.
+func TestPolicyConcurrentLimits(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	ui := newTestPolicyUI(t, from, 61)
+	signer := types.NewEIP155Signer(big.NewInt(61))
+
+	// 0.4 ether per transaction: two fit into the daily limit
+	value := new(big.Int).Mul(big.NewInt(4), big.NewInt(1e17))
+	request := func(nonce uint64) *core.SignTxRequest {
+		req := dummyTx(hexutil.Big(*value))
+		req.Transaction.From = common.NewMixedcaseAddress(from)
+		req.Transaction.Nonce = hexutil.Uint64(nonce)
+		return req
+	}
+	approve := func(req *core.SignTxRequest) bool {
+		resp, err := ui.ApproveTx(req)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return resp.Approved
+	}
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		approved []*core.SignTxRequest
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(nonce uint64) {
+			defer wg.Done()
+			if req := request(nonce); approve(req) {
+				lock.Lock()
+				approved = append(approved, req)
+				lock.Unlock()
+			}
+		}(uint64(i))
+	}
+	wg.Wait()
+	if len(approved) != 2 {
+		t.Fatalf("approved count mismatch: have %d, want 2", len(approved))
+	}
+	// A failed signing releases its reservation for another request
+	ui.OnSignTxFailed(approved[0])
+	if !approve(request(100)) {
+		t.Fatal("transaction not approved after released reservation")
+	}
+	if approve(request(101)) {
+		t.Fatal("transaction approved above daily limit")
+	}
+	// Signing a reserved transaction must not count its value twice
+	tx, _ := types.SignTx(types.NewTransaction(uint64(approved[1].Transaction.Nonce), common.HexToAddress("0xdead"), value, 21000, big.NewInt(2000000), nil), signer, key)
+	ui.OnApprovedTx(ethapi.SignTransactionResult{Tx: tx})
+	ui.OnSignTxFailed(request(100))
+	if !approve(request(102)) {
+		t.Fatal("transaction not approved after signed reservation")
+	}
+}
+
+func TestPolicyForwarding(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`{"accounts": {}}`))
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+	next := &dummyUI{}
+	ui, _ := NewPolicyEvaluator(next, policy, big.NewInt(61), nil, storage.NewEphemeralStorage())
+	ui.ApproveTx(dummyTxWithV(1))
+	ui.ApproveListing(nil)
+	ui.ApproveNewAccount(nil)
+	ui.ApproveSignData(nil)
+	ui.OnApprovedTx(ethapi.SignTransactionResult{})
+	if len(next.calls) != 5 {
+		t.Errorf("expected 5 forwarded calls, got %d: %v", len(next.calls), next.calls)
+	}
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+	for _, input := range []string{
+		`{"accounts": {}, "unknown": 1}`,
+		`accounts: {"0x000000000000000000000000000000000000dead": {dailyLimit: "1 finney"}}`,
+		`accounts: {"0x000000000000000000000000000000000000dead": {window: {from: "25:00", to: "08:00"}}}`,
+		`accounts: {"0x000000000000000000000000000000000000dead": {contracts: {"0x000000000000000000000000000000000000beef": []}}}`,
+	} {
+		if _, err := ParsePolicy([]byte(input)); err == nil {
+			t.Errorf("expected error for %s", input)
+		}
+	}
+}
+
+func TestPolicyDryRun(t *testing.T) {
+	from := common.HexToAddress("0x000000000000000000000000000000000000dead")
+	ui := newTestPolicyUI(t, from, 61)
+
+	value := hexutil.Big(*new(big.Int).Mul(big.NewInt(6), big.NewInt(1e17)))
+	at := time.Date(2020, 6, 3, 9, 0, 0, 0, time.UTC)
+	if verdict, reason := ui.DryRun(dummyTx(value), at); verdict != VerdictApprove {
+		t.Fatalf("first: have verdict %v (%s), want %v", verdict, reason, VerdictApprove)
+	}
+	if verdict, _ := ui.DryRun(dummyTx(value), at); verdict != VerdictReject {
+		t.Fatalf("second: have verdict %v, want %v", verdict, VerdictReject)
+	}
+	if verdict, reason := ui.DryRun(dummyTx(value), at.Add(24*time.Hour)); verdict != VerdictApprove {
+		t.Fatalf("next day: have verdict %v (%s), want %v", verdict, reason, VerdictApprove)
+	}
+	if verdict, reason := ui.DryRun(dummyTx(value), at.Add(48*time.Hour)); verdict != VerdictReject || !strings.Contains(reason, "weekly") {
+		t.Fatalf("day after: have verdict %v (%s), want weekly limit rejection", verdict, reason)
+	}
+}
+
+func TestPolicySimulation(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`
+accounts:
+  "0x000000000000000000000000000000000000dead":
+    recipients: ["0x000000000000000000000000000000000000dead"]
+    simulation:
+      required: true
+      rejectReverts: true
+      maxBalanceLoss: 1 ether
+`))
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+	ui, _ := NewPolicyEvaluator(&dummyUI{}, policy, big.NewInt(61), nil, storage.NewEphemeralStorage())
+
+	from := common.HexToAddress("0x000000000000000000000000000000000000dead")
+	loss := func(wei int64) []core.BalanceChange {
+		return []core.BalanceChange{{Address: from, Delta: (*hexutil.Big)(big.NewInt(-wei))}}
+	}
+	tests := []struct {
+		name    string
+		sim     *core.SimulationResult
+		verdict Verdict
+	}{
+		{"missing", nil, VerdictReject},
+		{"success", &core.SimulationResult{BalanceChanges: loss(1e18)}, VerdictApprove},
+		{"reverted", &core.SimulationResult{Reverted: true, Error: "execution reverted"}, VerdictReject},
+		{"balance loss", &core.SimulationResult{BalanceChanges: loss(2e18)}, VerdictReject},
+		{"token transfer", &core.SimulationResult{TokenTransfers: []core.TokenTransfer{{From: from}}}, VerdictReject},
+	}
+	for _, test := range tests {
+		req := dummyTxWithV(1)
+		req.Simulation = test.sim
+		if verdict, reason := ui.Evaluate(req); verdict != test.verdict {
+			t.Errorf("%s: have verdict %v (%s), want %v", test.name, verdict, reason, test.verdict)
+		}
+	}
+}
@@ -625,3 +625,35 @@ function ApproveSignData(r){
 		t.Fatalf("Expected approved")
 	}
 }
+
+func TestSimulationResult(t *testing.T) {
+	js := `function ApproveTx(r){
+    if (r.simulation && !r.simulation.reverted && r.simulation.tokenTransfers.length == 0) {
+        return "Approve"
+    }
+    return "Reject"
+}`
+	r, err := initRuleEngine(js)
+	if err != nil {
+		t.Fatalf("Couldn't create evaluator %v", err)
+	}
+	for i, test := range []struct {
+		sim      *core.SimulationResult
+		approved bool
+	}{
+		{nil, false},
+		{&core.SimulationResult{BalanceChanges: []core.BalanceChange{}, TokenTransfers: []core.TokenTransfer{}}, true},
+		{&core.SimulationResult{Reverted: true, BalanceChanges: []core.BalanceChange{}, TokenTransfers: []core.TokenTransfer{}}, false},
+		{&core.SimulationResult{BalanceChanges: []core.BalanceChange{}, TokenTransfers: []core.TokenTransfer{{}}}, false},
+	} {
+		req := dummyTxWithV(0)
+		req.Simulation = test.sim
+		resp, err := r.ApproveTx(req)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error %v", i, err)
+		}
+		if resp.Approved != test.approved {
+			t.Errorf("test %d: have approved %v, want %v", i, resp.Approved, test.approved)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package statediff
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// PublicStateDiffAPI provides access to the state diffs of blocks.
+type PublicStateDiffAPI struct {
+	service *Service
+}
+
+// Stream subscribes to the state diffs of newly imported blocks. Diffs are
+// delivered for all imported blocks, including those not (or no longer) part of
+// the canonical chain. Subscribers falling too far behind stop receiving diffs,
+// use the file sink if every diff is needed.
+func (api *PublicStateDiffAPI) Stream(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		diffs := make(chan *StateDiff, stateDiffChanSize)
+		sub := api.service.SubscribeStateDiffs(diffs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case diff := <-diffs:
+				notifier.Notify(rpcSub.ID, diff)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case err := <-sub.Err():
+				if err != nil {
+					log.Warn("Dropped state diff subscription", "id", rpcSub.ID, "err", err)
+				}
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// StateDiffAt returns the state diff of the given block, recomputing it by
+// re-executing the block on the state of its parent, which must be available.
+func (api *PublicStateDiffAPI) StateDiffAt(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*StateDiff, error) {
+	var block *types.Block
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block = api.service.chain.GetBlockByHash(hash)
+	} else if number, ok := blockNrOrHash.Number(); ok {
+		if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+			block = api.service.chain.CurrentBlock()
+		} else {
+			block = api.service.chain.GetBlockByNumber(uint64(number))
+		}
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	if block.NumberU64() == 0 {
+		return nil, fmt.Errorf("genesis is not diffable")
+	}
+	return api.service.StateDiffAt(block)
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package statediff
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// StateDiff is the set of state changes made by a block.
+type StateDiff struct {
+	BlockNumber hexutil.Uint64      `json:"blockNumber"`
+	BlockHash   common.Hash         `json:"blockHash"`
+	ParentHash  common.Hash         `json:"parentHash"`
+	StateRoot   common.Hash         `json:"stateRoot"`
+	Accounts    []state.AccountDiff `json:"accounts"`
+	Nodes       []TrieNode          `json:"nodes,omitempty"` // Only present if intermediate nodes are requested
+}
+
+// TrieNode is a trie node created by a block.
+type TrieNode struct {
+	Address *common.Address `json:"address,omitempty"` // Owner of the storage trie, nil for account trie nodes
+	Path    hexutil.Bytes   `json:"path"`
+	Hash    common.Hash     `json:"hash"`
+	Value   hexutil.Bytes   `json:"value"`
+}
+
+func newStateDiff(block *types.Block, accounts []state.AccountDiff) *StateDiff {
+	if accounts == nil {
+		accounts = []state.AccountDiff{}
+	}
+	return &StateDiff{
+		BlockNumber: hexutil.Uint64(block.NumberU64()),
+		BlockHash:   block.Hash(),
+		ParentHash:  block.ParentHash(),
+		StateRoot:   block.Root(),
+		Accounts:    accounts,
+	}
+}
+
+// computeStateDiff re-executes the block on top of the state of its parent,
+// returning the state changes it made.
+func computeStateDiff(chain blockChain, block *types.Block) (*StateDiff, error) {
+	parent := chain.GetBlockByHash(block.ParentHash())
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, err := state.New(parent.Root(), chain.StateCache(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("state of parent %#x not available: %v", parent.Hash(), err)
+	}
+	if _, _, _, err := chain.Processor().Process(block, statedb, vm.Config{}); err != nil {
+		return nil, fmt.Errorf("processing block %#x failed: %v", block.Hash(), err)
+	}
+	config := chain.Config()
+	if root := statedb.IntermediateRoot(config.IsEnabled(config.GetEIP161dTransition, block.Number())); root != block.Root() {
+		return nil, fmt.Errorf("state root mismatch for block %#x: have %x, want %x", block.Hash(), root, block.Root())
+	}
+	accounts, err := statedb.StateDiff()
+	if err != nil {
+		return nil, err
+	}
+	return newStateDiff(block, accounts), nil
+}
+
+// addNodes adds the account and storage trie nodes which were created by the
+// block to the diff. Both the state of the block and of its parent need to be
+// available.
+func addNodes(db state.Database, parentRoot common.Hash, diff *StateDiff) error {
+	oldTrie, err := db.OpenTrie(parentRoot)
+	if err != nil {
+		return err
+	}
+	newTrie, err := db.OpenTrie(diff.StateRoot)
+	if err != nil {
+		return err
+	}
+	nodes, err := trieDiffNodes(db, oldTrie, newTrie, nil)
+	if err != nil {
+		return err
+	}
+	for _, account := range diff.Accounts {
+		if account.After == nil || (account.Before != nil && account.Before.Root == account.After.Root) {
+			continue
+		}
+		var (
+			address  = account.Address
+			addrHash = crypto.Keccak256Hash(address[:])
+			oldRoot  = types.EmptyRootHash
+		)
+		if account.Before != nil {
+			oldRoot = account.Before.Root
+		}
+		oldStorage, err := db.OpenStorageTrie(addrHash, oldRoot)
+		if err != nil {
+			return err
+		}
+		newStorage, err := db.OpenStorageTrie(addrHash, account.After.Root)
+		if err != nil {
+			return err
+		}
+		storageNodes, err := trieDiffNodes(db, oldStorage, newStorage, &address)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, storageNodes...)
+	}
+	diff.Nodes = nodes
+	return nil
+}
+
+// trieDiffNodes returns the nodes of the new trie which are not part of the old one.
+func trieDiffNodes(db state.Database, oldTrie, newTrie state.Trie, owner *common.Address) ([]TrieNode, error) {
+	var nodes []TrieNode
+	it, _ := trie.NewDifferenceIterator(oldTrie.NodeIterator(nil), newTrie.NodeIterator(nil))
+	// The old trie is already stepped into on construction, bail out before
+	// walking the new one if it could not be resolved.
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	for it.Next(true) {
+		hash := it.Hash()
+		if hash == (common.Hash{}) {
+			continue // Leaf values and nodes embedded in their parent
+		}
+		blob, err := db.TrieDB().Node(hash)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, TrieNode{
+			Address: owner,
+			Path:    common.CopyBytes(it.Path()),
+			Hash:    hash,
+			Value:   blob,
+		})
+	}
+	return nodes, it.Error()
+}
@@ -0,0 +1,216 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package statediff implements a service streaming the state changes made by
+// each imported block.
+package statediff
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// stateDiffChanSize is the size of channel listening to StateDiffEvent.
+const stateDiffChanSize = 64
+
+// errSlowSubscriber is returned to subscribers dropped for not keeping up with
+// the published diffs.
+var errSlowSubscriber = errors.New("state diff subscriber too slow")
+
+// Config contains the settings of the state diff service.
+type Config struct {
+	File              string // File to append the diffs of all imported blocks to, as JSON lines
+	IntermediateNodes bool   // Whether to include the created trie nodes in the diffs
+}
+
+// blockChain is the chain functionality needed by the state diff service.
+type blockChain interface {
+	Config() ctypes.ChainConfigurator
+	CurrentBlock() *types.Block
+	GetBlockByHash(hash common.Hash) *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	StateCache() state.Database
+	Processor() core.Processor
+	SubscribeStateDiffEvent(ch chan<- core.StateDiffEvent) event.Subscription
+}
+
+// Service captures the state diffs of imported blocks, publishing them to RPC
+// subscribers and writing them to a file sink.
+type Service struct {
+	chain  blockChain
+	config Config
+
+	feed  event.Feed
+	scope event.SubscriptionScope
+
+	sub  event.Subscription
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates the state diff service and registers it with the node.
+func New(stack *node.Node, chain blockChain, config Config) *Service {
+	s := &Service{
+		chain:  chain,
+		config: config,
+		quit:   make(chan struct{}),
+	}
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "statediff",
+		Version:   "1.0",
+		Service:   &PublicStateDiffAPI{s},
+		Public:    true,
+	}})
+	stack.RegisterLifecycle(s)
+	return s
+}
+
+// Start implements node.Lifecycle, starting to capture state diffs.
+func (s *Service) Start() error {
+	var sink *os.File
+	if s.config.File != "" {
+		f, err := os.OpenFile(s.config.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		sink = f
+	}
+	events := make(chan core.StateDiffEvent, stateDiffChanSize)
+	s.sub = s.chain.SubscribeStateDiffEvent(events)
+
+	s.wg.Add(1)
+	go s.loop(events, sink)
+
+	log.Info("State diff service started", "file", s.config.File, "nodes", s.config.IntermediateNodes)
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the service.
+func (s *Service) Stop() error {
+	s.sub.Unsubscribe()
+	close(s.quit)
+	s.wg.Wait()
+	s.scope.Close()
+
+	log.Info("State diff service stopped")
+	return nil
+}
+
+// loop builds the diffs of the captured state changes, publishing them until
+// the service is stopped.
+func (s *Service) loop(events chan core.StateDiffEvent, sink *os.File) {
+	defer s.wg.Done()
+
+	var writer *bufio.Writer
+	if sink != nil {
+		defer sink.Close()
+		writer = bufio.NewWriter(sink)
+	}
+	for {
+		select {
+		case ev := <-events:
+			diff := newStateDiff(ev.Block, ev.Accounts)
+			if s.config.IntermediateNodes {
+				if err := addNodes(s.chain.StateCache(), s.parentRoot(ev.Block), diff); err != nil {
+					log.Warn("Failed to collect state diff trie nodes", "number", ev.Block.Number(), "hash", ev.Block.Hash(), "err", err)
+				}
+			}
+			// Write the diff to the sink first, publishing never blocks on
+			// subscribers, but the sink must not miss any diff
+			if writer != nil {
+				if err := writeDiff(writer, diff); err != nil {
+					log.Error("Failed to write state diff", "file", s.config.File, "number", ev.Block.Number(), "err", err)
+				}
+			}
+			s.feed.Send(diff)
+		case <-s.sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *Service) parentRoot(block *types.Block) common.Hash {
+	if parent := s.chain.GetBlockByHash(block.ParentHash()); parent != nil {
+		return parent.Root()
+	}
+	return common.Hash{}
+}
+
+// writeDiff appends the diff to the sink as a single line of JSON.
+func writeDiff(w *bufio.Writer, diff *StateDiff) error {
+	enc, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(enc, '\n')); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// SubscribeStateDiffs registers a subscription to the diffs of imported blocks.
+// Diffs are never waited for to be received, a subscriber whose channel is full
+// is dropped with errSlowSubscriber instead.
+func (s *Service) SubscribeStateDiffs(ch chan<- *StateDiff) event.Subscription {
+	return s.scope.Track(event.NewSubscription(func(quit <-chan struct{}) error {
+		diffs := make(chan *StateDiff, stateDiffChanSize)
+		sub := s.feed.Subscribe(diffs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case diff := <-diffs:
+				select {
+				case ch <- diff:
+				default:
+					return errSlowSubscriber
+				}
+			case <-quit:
+				return nil
+			}
+		}
+	}))
+}
+
+// StateDiffAt recomputes the diff of the given block by re-executing it on the
+// state of its parent.
+func (s *Service) StateDiffAt(block *types.Block) (*StateDiff, error) {
+	diff, err := computeStateDiff(s.chain, block)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.IntermediateNodes {
+		if err := addNodes(s.chain.StateCache(), s.parentRoot(block), diff); err != nil {
+			return nil, err
+		}
+	}
+	return diff, nil
+}
@@ -0,0 +1,287 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package statediff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/types/genesisT"
+)
+
+var (
+	testKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr     = crypto.PubkeyToAddress(testKey.PublicKey)
+	testFunds    = big.NewInt(1000000000000000)
+	testCoinbase = common.Address{1}
+	testReceiver = common.Address{2}
+
+	// The contract stores 0x2a in slot 1.
+	testContract = common.HexToAddress("0xc0de")
+	testCode     = []byte{byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0x01, byte(vm.SSTORE)}
+)
+
+// newTestChain creates a chain with a block calling the storage contract, and
+// a block transferring funds to a new account.
+func newTestChain(t *testing.T) (*core.BlockChain, []*types.Block) {
+	gspec := &genesisT.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: genesisT.GenesisAlloc{
+			testAddr: {Balance: testFunds},
+			testContract: {
+				Code:    testCode,
+				Balance: big.NewInt(0),
+				Storage: map[common.Hash]common.Hash{common.HexToHash("01"): common.HexToHash("01")},
+			},
+		},
+	}
+	var (
+		engine  = ethash.NewFaker()
+		db      = rawdb.NewMemoryDatabase()
+		genesis = core.MustCommitGenesis(db, gspec)
+		signer  = types.NewEIP155Signer(gspec.Config.GetChainID())
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, engine, db, 2, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(testCoinbase)
+		var tx *types.Transaction
+		switch i {
+		case 0:
+			tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(testAddr), testContract, big.NewInt(0), 100000, big.NewInt(1), nil), signer, testKey)
+		case 1:
+			tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(testAddr), testReceiver, big.NewInt(1000), 21000, big.NewInt(1), nil), signer, testKey)
+		}
+		b.AddTx(tx)
+	})
+	diskdb := rawdb.NewMemoryDatabase()
+	core.MustCommitGenesis(diskdb, gspec)
+	chain, err := core.NewBlockChain(diskdb, nil, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	return chain, blocks
+}
+
+func findAccount(diff *StateDiff, addr common.Address) *state.AccountDiff {
+	for i := range diff.Accounts {
+		if diff.Accounts[i].Address == addr {
+			return &diff.Accounts[i]
+		}
+	}
+	return nil
+}
+
+func TestStateDiffService(t *testing.T) {
+	chain, blocks := newTestChain(t)
+	defer chain.Stop()
+
+	dir, err := ioutil.TempDir("", "statediff-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "diffs.jsonl")
+	service := &Service{
+		chain:  chain,
+		config: Config{File: file, IntermediateNodes: true},
+		quit:   make(chan struct{}),
+	}
+	if err := service.Start(); err != nil {
+		t.Fatalf("failed to start service: %v", err)
+	}
+	diffs := make(chan *StateDiff, len(blocks))
+	sub := service.SubscribeStateDiffs(diffs)
+	defer sub.Unsubscribe()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	var streamed []*StateDiff
+	for range blocks {
+		select {
+		case diff := <-diffs:
+			streamed = append(streamed, diff)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for state diff")
+		}
+	}
+	service.Stop()
+
+	// Check the diff of the contract call
+	diff := streamed[0]
+	if diff.BlockHash != blocks[0].Hash() {
+		t.Fatalf("diff block mismatch: have %x, want %x", diff.BlockHash, blocks[0].Hash())
+	}
+	sender := findAccount(diff, testAddr)
+	if sender == nil || sender.Before == nil || sender.After == nil {
+		t.Fatalf("sender diff missing or incomplete: %+v", sender)
+	}
+	if sender.Before.Nonce != 0 || sender.After.Nonce != 1 {
+		t.Errorf("sender nonce mismatch: have %d -> %d, want 0 -> 1", sender.Before.Nonce, sender.After.Nonce)
+	}
+	if findAccount(diff, testCoinbase) == nil {
+		t.Errorf("coinbase diff missing")
+	}
+	contract := findAccount(diff, testContract)
+	if contract == nil {
+		t.Fatalf("contract diff missing")
+	}
+	want := []state.StorageDiff{{Key: common.HexToHash("01"), Before: common.HexToHash("01"), After: common.HexToHash("2a")}}
+	if !reflect.DeepEqual(contract.Storage, want) {
+		t.Errorf("contract storage diff mismatch: have %+v, want %+v", contract.Storage, want)
+	}
+	if contract.Code != nil {
+		t.Errorf("unchanged contract code included in diff")
+	}
+	if len(diff.Nodes) == 0 {
+		t.Errorf("intermediate nodes missing")
+	}
+	// Check the diff of the transfer, creating a new account
+	receiver := findAccount(streamed[1], testReceiver)
+	if receiver == nil || receiver.Before != nil || receiver.After == nil {
+		t.Fatalf("receiver diff mismatch: %+v", receiver)
+	}
+	if receiver.After.Balance.ToInt().Int64() != 1000 {
+		t.Errorf("receiver balance mismatch: have %v, want 1000", receiver.After.Balance)
+	}
+	if findAccount(streamed[1], testContract) != nil {
+		t.Errorf("untouched contract included in diff")
+	}
+	// Recomputed diffs should match the streamed ones
+	for i, block := range blocks {
+		have, err := service.StateDiffAt(block)
+		if err != nil {
+			t.Fatalf("block %d: failed to recompute diff: %v", i, err)
+		}
+		haveJSON, _ := json.Marshal(have)
+		wantJSON, _ := json.Marshal(streamed[i])
+		if string(haveJSON) != string(wantJSON) {
+			t.Errorf("block %d: recomputed diff mismatch:\nhave %s\nwant %s", i, haveJSON, wantJSON)
+		}
+	}
+	// Check the file sink
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines int
+	for scanner := bufio.NewScanner(f); scanner.Scan(); lines++ {
+		var diff StateDiff
+		if err := json.Unmarshal(scanner.Bytes(), &diff); err != nil {
+			t.Fatalf("line %d: invalid diff: %v", lines, err)
+		}
+		if diff.BlockHash != blocks[lines].Hash() {
+			t.Errorf("line %d: block mismatch: have %x, want %x", lines, diff.BlockHash, blocks[lines].Hash())
+		}
+	}
+	if lines != len(blocks) {
+		t.Errorf("file sink line count mismatch: have %d, want %d", lines, len(blocks))
+	}
+}
+
+func TestStateDiffLaggingSubscriber(t *testing.T) {
+	chain, blocks := newTestChain(t)
+	defer chain.Stop()
+
+	// Subscribe without ever reading, the import must not wait for it
+	sub := chain.SubscribeStateDiffEvent(make(chan core.StateDiffEvent))
+	defer sub.Unsubscribe()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := chain.InsertChain(blocks)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("failed to insert into chain: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("chain import blocked on state diff subscriber")
+	}
+}
+
+func TestStateDiffSlowSubscriber(t *testing.T) {
+	chain, blocks := newTestChain(t)
+	defer chain.Stop()
+
+	dir, err := ioutil.TempDir("", "statediff-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "diffs.jsonl")
+	service := &Service{
+		chain:  chain,
+		config: Config{File: file},
+		quit:   make(chan struct{}),
+	}
+	if err := service.Start(); err != nil {
+		t.Fatalf("failed to start service: %v", err)
+	}
+	defer service.Stop()
+
+	// Subscribe without ever reading, the subscriber must be dropped instead of
+	// holding up the file sink
+	sub := service.SubscribeStateDiffs(make(chan *StateDiff))
+	defer sub.Unsubscribe()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	select {
+	case err := <-sub.Err():
+		if err != errSlowSubscriber {
+			t.Fatalf("subscription error mismatch: have %v, want %v", err, errSlowSubscriber)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow subscriber not dropped")
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		blob, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := bytes.Count(blob, []byte("\n")); lines == len(blocks) {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("file sink line count mismatch: have %d, want %d", lines, len(blocks))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
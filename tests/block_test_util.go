@@ -101,7 +101,17 @@ func (t *BlockTest) Run(snapshotter bool) error {
 	if !ok {
 		return UnsupportedForkError{t.json.Network}
 	}
+	return t.RunWithConfig(config, snapshotter)
+}
+
+// Network returns the name of the fork configuration the test was filled with.
+func (t *BlockTest) Network() string {
+	return t.json.Network
+}
 
+// RunWithConfig runs the test using the given chain configuration in place of
+// the configuration named by the test network.
+func (t *BlockTest) RunWithConfig(config ctypes.ChainConfigurator, snapshotter bool) error {
 	// import pre accounts & construct test genesis block & state root
 	db := rawdb.NewMemoryDatabase()
 	gblock, err := core.CommitGenesis(t.genesis(config), db)
@@ -16,6 +16,17 @@
 
 package tests
 
+import (
+	"crypto/sha1"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params/confp"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+)
+
 // writeDifficultyTestsReferencePairs defines pairs for test generation.
 // TODO: move to more accessible api?
 var writeDifficultyTestsReferencePairs = map[string]string{
@@ -23,3 +34,67 @@ var writeDifficultyTestsReferencePairs = map[string]string{
 	"Constantinople": "ETC_Agharta",
 	"EIP2384":        "ETC_Phoenix",
 }
+
+var (
+	// difficultyGenParentTimestamp is the parent block timestamp used for generated difficulty tests.
+	difficultyGenParentTimestamp = uint64(1_000_000)
+	// difficultyGenParentDifficulty is the parent block difficulty used for generated difficulty tests.
+	difficultyGenParentDifficulty = big.NewInt(0x20000000000)
+	// difficultyGenTimeDeltas are the block time deltas used for generated difficulty tests.
+	// They are chosen to hit each branch of the adjustment algorithms (including the -99 floor).
+	difficultyGenTimeDeltas = []uint64{1, 9, 10, 17, 20, 1000}
+	// difficultyGenUncleHashes are the parent uncle hashes used for generated difficulty tests;
+	// any value besides the empty uncle hash is treated as the parent having uncles.
+	difficultyGenUncleHashes = map[string]common.Hash{
+		"nouncles": types.EmptyUncleHash,
+		"uncles":   common.HexToHash("0x01"),
+	}
+)
+
+// Fill calculates the difficulty of the test's current block using the given
+// configuration, and sets it as the expected difficulty.
+func (test *DifficultyTest) Fill(config ctypes.ChainConfigurator) {
+	test.CurrentDifficulty = test.calcDifficulty(config)
+}
+
+// SetChainspec sets the reference to the chain configuration file the test was generated with.
+func (test *DifficultyTest) SetChainspec(filename string, content []byte) {
+	sum := sha1.Sum(content)
+	test.Chainspec = chainspecRef{
+		Filename: filename,
+		Sha1Sum:  sum[:],
+	}
+}
+
+// GenerateDifficultyTests generates ethereum/tests-compatible difficulty tests for the configuration,
+// keyed by test name. Tests are generated for the blocks around each of the configuration's forks,
+// with block time deltas and parent uncles chosen to exercise each branch of the
+// configured difficulty rules.
+func GenerateDifficultyTests(config ctypes.ChainConfigurator, name string) map[string]*DifficultyTest {
+	numbers := map[uint64]struct{}{1: {}}
+	for _, f := range confp.Forks(config) {
+		for _, n := range []uint64{f - 1, f, f + 1} {
+			if n > 0 {
+				numbers[n] = struct{}{}
+			}
+		}
+	}
+	out := make(map[string]*DifficultyTest)
+	for n := range numbers {
+		for _, dt := range difficultyGenTimeDeltas {
+			for uncles, uncleHash := range difficultyGenUncleHashes {
+				test := &DifficultyTest{
+					ParentTimestamp:    difficultyGenParentTimestamp,
+					ParentDifficulty:   new(big.Int).Set(difficultyGenParentDifficulty),
+					UncleHash:          uncleHash,
+					CurrentTimestamp:   difficultyGenParentTimestamp + dt,
+					CurrentBlockNumber: n,
+					Name:               fmt.Sprintf("%s_block%d_delta%d_%s", name, n, dt, uncles),
+				}
+				test.Fill(config)
+				out[test.Name] = test
+			}
+		}
+	}
+	return out
+}
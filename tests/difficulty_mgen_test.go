@@ -28,6 +28,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/params/vars"
 )
 
@@ -194,3 +195,33 @@ func mustAppendTestToFile(t *testing.T, test *DifficultyTest, filep string) {
 		t.Fatal(err)
 	}
 }
+
+func TestGenerateDifficultyTests(t *testing.T) {
+	generated := GenerateDifficultyTests(params.ClassicChainConfig, "classic")
+	if len(generated) == 0 {
+		t.Fatal("no tests generated")
+	}
+	for name, test := range generated {
+		// Round trip the test through its JSON encoding.
+		b, err := json.Marshal(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dec DifficultyTest
+		if err := json.Unmarshal(b, &dec); err != nil {
+			t.Fatal(err)
+		}
+		if err := dec.Run(params.ClassicChainConfig); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	// ECIP-1010 pauses the bomb at the pause block's period.
+	paused := generated["classic_block3000001_delta10_nouncles"]
+	continued := generated["classic_block5000001_delta10_nouncles"]
+	if paused == nil || continued == nil {
+		t.Fatal("missing ECIP1010 transition tests")
+	}
+	if paused.CurrentDifficulty.Cmp(continued.CurrentDifficulty) != 0 {
+		t.Errorf("ECIP1010 bomb mismatch: paused %v, continued %v", paused.CurrentDifficulty, continued.CurrentDifficulty)
+	}
+}
@@ -152,7 +152,7 @@ func (t *DifficultyTest) String() string {
 	return string(b)
 }
 
-func (test *DifficultyTest) Run(config ctypes.ChainConfigurator) error {
+func (test *DifficultyTest) calcDifficulty(config ctypes.ChainConfigurator) *big.Int {
 	parentNumber := big.NewInt(int64(test.CurrentBlockNumber - 1))
 	parent := &types.Header{
 		Difficulty: test.ParentDifficulty,
@@ -160,8 +160,11 @@ func (test *DifficultyTest) Run(config ctypes.ChainConfigurator) error {
 		Number:     parentNumber,
 		UncleHash:  test.UncleHash,
 	}
+	return ethash.CalcDifficulty(config, test.CurrentTimestamp, parent)
+}
 
-	actual := ethash.CalcDifficulty(config, test.CurrentTimestamp, parent)
+func (test *DifficultyTest) Run(config ctypes.ChainConfigurator) error {
+	actual := test.calcDifficulty(config)
 	exp := test.CurrentDifficulty
 
 	b, _ := json.Marshal(config)
@@ -0,0 +1,224 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package tests
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/params/confp"
+	"github.com/ethereum/go-ethereum/params/types/coregeth"
+	"github.com/ethereum/go-ethereum/params/types/ctypes"
+)
+
+// This file holds logic for mapping the fork names used by test fixtures
+// to arbitrary ChainConfigurators, allowing fixtures to be run
+// against any chain configuration which composes an equivalent set of features.
+
+// featureIgnoredPrefixes define transitions which are not considered
+// when comparing feature sets.
+// Consensus engine transitions (difficulty, block rewards, DAG sizes) are chain-specific
+// and are validated by the tests themselves rather than used for fork matching.
+var featureIgnoredPrefixes = []string{
+	"GetEthash",
+	"GetClique",
+}
+
+// projectionSkipTransitions are transitions which describe irregular, single-block
+// state changes. These are never projected onto the genesis block of a derived configuration.
+var projectionSkipTransitions = map[string]bool{
+	"GetEthashEIP779Transition": true, // DAO
+}
+
+// TransitionFeatureName returns the human-readable feature name for a
+// ChainConfigurator transition method name, eg. GetEthashECIP1017Transition => ECIP1017.
+func TransitionFeatureName(transitionName string) string {
+	s := strings.TrimPrefix(transitionName, "Get")
+	s = strings.TrimSuffix(s, "Transition")
+	return strings.TrimPrefix(s, "Ethash")
+}
+
+// ActiveFeatures returns the sorted names of the features enabled for the configuration
+// at the given block number.
+// Features which have been disabled by a corresponding *DisableTransition are not included,
+// nor are consensus engine features or cross-compatible (eg. ECBP) features.
+func ActiveFeatures(conf ctypes.ChainConfigurator, n uint64) []string {
+	fns, names := confp.Transitions(conf)
+	active := make(map[string]bool)
+	for i, fn := range fns {
+		if !isFeatureTransition(names[i]) {
+			continue
+		}
+		if v := fn(); v != nil && *v <= n {
+			active[TransitionFeatureName(names[i])] = true
+		}
+	}
+	for name := range active {
+		if strings.HasSuffix(name, "Disable") {
+			delete(active, name)
+			delete(active, strings.TrimSuffix(name, "Disable"))
+		}
+	}
+	features := make([]string, 0, len(active))
+	for name := range active {
+		features = append(features, name)
+	}
+	sort.Strings(features)
+	return features
+}
+
+func isFeatureTransition(name string) bool {
+	if confp.NameSignalsCompatibility(name) {
+		return false
+	}
+	for _, p := range featureIgnoredPrefixes {
+		if strings.HasPrefix(name, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// featureHeights returns the genesis block and all fork blocks of a configuration.
+func featureHeights(conf ctypes.ChainConfigurator) []uint64 {
+	return append([]uint64{0}, confp.Forks(conf)...)
+}
+
+// featureEpochs returns the heights at which the feature set of the configuration changes,
+// along with the feature set in effect from each height.
+func featureEpochs(conf ctypes.ChainConfigurator) (heights []uint64, sets [][]string) {
+	for _, h := range featureHeights(conf) {
+		set := ActiveFeatures(conf, h)
+		if len(sets) > 0 && featuresEqual(sets[len(sets)-1], set) {
+			continue
+		}
+		heights = append(heights, h)
+		sets = append(sets, set)
+	}
+	return heights, sets
+}
+
+func featuresEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ConfiguratorForFork returns a configuration derived from conf which is feature-equivalent to the
+// fixture fork named by forkName (eg. 'Istanbul', or 'ByzantiumToConstantinopleFixAt5').
+//
+// The feature epochs of the reference fork configuration are matched against a contiguous
+// sequence of feature epochs of conf. The transitions of conf are then projected onto the block
+// numbers used by the reference fork, so that conf's first matching epoch begins at genesis.
+// All non-transition values of conf (chain id, block reward schedules, ECIPs, etc.) are retained.
+//
+// The block number in conf at which the match began is returned as well.
+// If conf has no feature-equivalent sequence of epochs, an UnsupportedForkError is returned.
+func ConfiguratorForFork(conf ctypes.ChainConfigurator, forkName string) (ctypes.ChainConfigurator, uint64, error) {
+	ref, ok := Forks[forkName]
+	if !ok {
+		return nil, 0, UnsupportedForkError{forkName}
+	}
+	refHeights, refSets := featureEpochs(ref)
+	confHeights, confSets := featureEpochs(conf)
+
+	start := -1
+outer:
+	for i := 0; i+len(refSets) <= len(confSets); i++ {
+		for j := range refSets {
+			if !featuresEqual(refSets[j], confSets[i+j]) {
+				continue outer
+			}
+		}
+		start = i
+		break
+	}
+	if start < 0 {
+		return nil, 0, UnsupportedForkError{forkName}
+	}
+
+	// projection maps conf epoch heights to the reference fork epoch heights.
+	projection := make(map[uint64]uint64, len(refHeights))
+	for j, h := range refHeights {
+		projection[confHeights[start+j]] = h
+	}
+	first := confHeights[start]
+	last := confHeights[start+len(refHeights)-1]
+
+	derived := &coregeth.CoreGethChainConfig{}
+	if err := confp.Convert(conf, derived); err != nil {
+		return nil, 0, err
+	}
+
+	fns, names := confp.Transitions(conf)
+	var deferred []int
+	for i := range fns {
+		// Continue-type transitions (ie ECIP1010) are defined relative to their Pause counterparts,
+		// so they must be set after them.
+		if strings.Contains(names[i], "Continue") {
+			deferred = append(deferred, i)
+			continue
+		}
+		if err := projectTransition(derived, names[i], fns[i](), first, last, projection); err != nil {
+			return nil, 0, err
+		}
+	}
+	for _, i := range deferred {
+		if err := projectTransition(derived, names[i], fns[i](), first, last, projection); err != nil {
+			return nil, 0, err
+		}
+	}
+	return derived, first, nil
+}
+
+func projectTransition(conf ctypes.ChainConfigurator, name string, v *uint64, first, last uint64, projection map[uint64]uint64) error {
+	var next *uint64
+	switch {
+	case v == nil || *v == math.MaxUint64:
+	case projectionSkipTransitions[name]:
+	case *v <= first:
+		zero := uint64(0)
+		next = &zero
+	case *v <= last:
+		// Transitions falling between epoch heights (eg. consensus engine transitions)
+		// are assigned to the next epoch at or above them.
+		for h, p := range projection {
+			if h >= *v && (next == nil || p < *next) {
+				pp := p
+				next = &pp
+			}
+		}
+	}
+	setter := reflect.ValueOf(conf).MethodByName("Set" + strings.TrimPrefix(name, "Get"))
+	if !setter.IsValid() {
+		return fmt.Errorf("missing setter for transition %s", name)
+	}
+	out := setter.Call([]reflect.Value{reflect.ValueOf(next)})
+	if err, _ := out[0].Interface().(error); err != nil && err != ctypes.ErrUnsupportedConfigNoop {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
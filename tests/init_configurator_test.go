@@ -0,0 +1,71 @@
+// Copyright 2020 The core-geth Authors
+// This file is part of the core-geth library.
+//
+// The core-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The core-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the core-geth library. If not, see <http://www.gnu.org/licenses/>.
+
+package tests
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestConfiguratorForFork(t *testing.T) {
+	cases := []struct {
+		fork  string
+		first uint64
+		fail  bool
+	}{
+		{"Byzantium", 8772000, false},
+		{"ETC_Atlantis", 8772000, false},
+		{"ConstantinopleFix", 9573000, false},
+		{"ETC_Agharta", 9573000, false},
+		{"Istanbul", 10500839, false},
+		{"ETC_Phoenix", 10500839, false},
+		{"ByzantiumToConstantinopleFixAt5", 8772000, false},
+		{"Constantinople", 0, true}, // Classic never enabled EIP1283.
+		{"Berlin", 0, true},
+	}
+	for _, c := range cases {
+		conf, first, err := ConfiguratorForFork(params.ClassicChainConfig, c.fork)
+		if c.fail {
+			if err == nil {
+				t.Errorf("%s: expected error, got none", c.fork)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.fork, err)
+			continue
+		}
+		if first != c.first {
+			t.Errorf("%s: first block mismatch: got %d, want %d", c.fork, first, c.first)
+		}
+		ref := Forks[c.fork]
+		for _, n := range []uint64{0, 4, 5, 6} {
+			if got, want := ActiveFeatures(conf, n), ActiveFeatures(ref, n); !featuresEqual(got, want) {
+				t.Errorf("%s: block %d: feature mismatch: got %v, want %v", c.fork, n, got, want)
+			}
+		}
+		// Chain-specific values are retained.
+		if conf.GetChainID().Cmp(big.NewInt(61)) != 0 {
+			t.Errorf("%s: chain id mismatch: got %v", c.fork, conf.GetChainID())
+		}
+		if conf.GetEthashECIP1017Transition() == nil {
+			t.Errorf("%s: missing ECIP1017", c.fork)
+		}
+	}
+}
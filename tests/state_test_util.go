@@ -144,25 +144,36 @@ type stTransactionMarshaling struct {
 // - a plain forkname, e.g. `Byzantium`,
 // - a fork basename, and a list of EIPs to enable; e.g. `Byzantium+1884+1283`.
 func GetChainConfig(forkString string) (baseConfig ctypes.ChainConfigurator, eips []int, err error) {
-	var (
-		splitForks            = strings.Split(forkString, "+")
-		ok                    bool
-		baseName, eipsStrings = splitForks[0], splitForks[1:]
-	)
+	baseName, eips, err := GetChainConfigName(forkString)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ok bool
 	if baseConfig, ok = Forks[baseName]; !ok {
 		return nil, nil, UnsupportedForkError{baseName}
 	}
+	return baseConfig, eips, nil
+}
+
+// GetChainConfigName takes a fork definition (see GetChainConfig) and returns
+// the fork basename and the list of EIPs to enable.
+func GetChainConfigName(forkString string) (baseName string, eips []int, err error) {
+	var (
+		splitForks  = strings.Split(forkString, "+")
+		eipsStrings = splitForks[1:]
+	)
+	baseName = splitForks[0]
 	for _, eip := range eipsStrings {
 		if eipNum, err := strconv.Atoi(eip); err != nil {
-			return nil, nil, fmt.Errorf("syntax error, invalid eip number %v", eipNum)
+			return "", nil, fmt.Errorf("syntax error, invalid eip number %v", eipNum)
 		} else {
 			if !vm.ValidEip(eipNum) {
-				return nil, nil, fmt.Errorf("syntax error, invalid eip number %v", eipNum)
+				return "", nil, fmt.Errorf("syntax error, invalid eip number %v", eipNum)
 			}
 			eips = append(eips, eipNum)
 		}
 	}
-	return baseConfig, eips, nil
+	return baseName, eips, nil
 }
 
 // Subtests returns all valid subtests of the test.
@@ -188,16 +199,30 @@ func (t *StateTest) Run(subtest StateSubtest, vmconfig vm.Config, snapshotter bo
 	if err != nil {
 		return snaps, statedb, err
 	}
+	return snaps, statedb, t.verify(subtest, statedb, root)
+}
+
+// RunWithConfig executes a specific subtest using the given chain configuration in place
+// of the configuration named by the subtest fork, and verifies the post-state and logs.
+func (t *StateTest) RunWithConfig(subtest StateSubtest, config ctypes.ChainConfigurator, vmconfig vm.Config, snapshotter bool) (*snapshot.Tree, *state.StateDB, error) {
+	snaps, statedb, root, err := t.RunNoVerifyWithConfig(subtest, config, vmconfig, snapshotter)
+	if err != nil {
+		return snaps, statedb, err
+	}
+	return snaps, statedb, t.verify(subtest, statedb, root)
+}
+
+func (t *StateTest) verify(subtest StateSubtest, statedb *state.StateDB, root common.Hash) error {
 	post := t.json.Post[subtest.Fork][subtest.Index]
 	// N.B: We need to do this in a two-step process, because the first Commit takes care
 	// of suicides, and we need to touch the coinbase _after_ it has potentially suicided.
 	if root != common.Hash(post.Root) {
-		return snaps, statedb, fmt.Errorf("post state root mismatch: got %x, want %x", root, post.Root)
+		return fmt.Errorf("post state root mismatch: got %x, want %x", root, post.Root)
 	}
 	if logs := rlpHash(statedb.Logs()); logs != common.Hash(post.Logs) {
-		return snaps, statedb, fmt.Errorf("post state logs hash mismatch: got %x, want %x", logs, post.Logs)
+		return fmt.Errorf("post state logs hash mismatch: got %x, want %x", logs, post.Logs)
 	}
-	return snaps, statedb, nil
+	return nil
 }
 
 // RunNoVerify runs a specific subtest and returns the statedb and post-state root
@@ -207,6 +232,12 @@ func (t *StateTest) RunNoVerify(subtest StateSubtest, vmconfig vm.Config, snapsh
 		return nil, nil, common.Hash{}, UnsupportedForkError{subtest.Fork}
 	}
 	vmconfig.ExtraEips = eips
+	return t.RunNoVerifyWithConfig(subtest, config, vmconfig, snapshotter)
+}
+
+// RunNoVerifyWithConfig runs a specific subtest using the given chain configuration
+// and returns the statedb and post-state root.
+func (t *StateTest) RunNoVerifyWithConfig(subtest StateSubtest, config ctypes.ChainConfigurator, vmconfig vm.Config, snapshotter bool) (*snapshot.Tree, *state.StateDB, common.Hash, error) {
 	block := core.GenesisToBlock(t.genesis(config), nil)
 	snaps, statedb := MakePreState(rawdb.NewMemoryDatabase(), t.json.Pre, snapshotter)
 
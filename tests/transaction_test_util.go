@@ -24,6 +24,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params/types/ctypes"
+	"github.com/ethereum/go-ethereum/params/vars"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -37,6 +38,7 @@ type TransactionTest struct {
 	EIP158         ttFork
 	Frontier       ttFork
 	Homestead      ttFork
+	Shanghai       *ttFork
 }
 
 type ttFork struct {
@@ -46,7 +48,7 @@ type ttFork struct {
 
 func (tt *TransactionTest) Run(config ctypes.ChainConfigurator) error {
 
-	validateTx := func(rlpData hexutil.Bytes, signer types.Signer, isEIP2F bool, isEIP2028F bool) (*common.Address, *common.Hash, error) {
+	validateTx := func(rlpData hexutil.Bytes, signer types.Signer, isEIP2F bool, isEIP2028F bool, isEIP3860F bool) (*common.Address, *common.Hash, error) {
 		tx := new(types.Transaction)
 		if err := rlp.DecodeBytes(rlpData, tx); err != nil {
 			return nil, nil, err
@@ -55,8 +57,12 @@ func (tt *TransactionTest) Run(config ctypes.ChainConfigurator) error {
 		if err != nil {
 			return nil, nil, err
 		}
+		// Init code size
+		if isEIP3860F && tx.To() == nil && uint64(len(tx.Data())) > vars.MaxInitCodeSize {
+			return nil, nil, fmt.Errorf("%w: code size %v limit %v", core.ErrMaxInitCodeSizeExceeded, len(tx.Data()), vars.MaxInitCodeSize)
+		}
 		// Intrinsic gas
-		requiredGas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, isEIP2F, isEIP2028F)
+		requiredGas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, isEIP2F, isEIP2028F, isEIP3860F)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -70,19 +76,25 @@ func (tt *TransactionTest) Run(config ctypes.ChainConfigurator) error {
 	for _, testcase := range []struct {
 		name        string
 		signer      types.Signer
-		fork        ttFork
+		fork        *ttFork
 		isHomestead bool
 		isIstanbul  bool
+		isEIP3860   bool
 	}{
-		{"Frontier", types.FrontierSigner{}, tt.Frontier, false, false},
-		{"Homestead", types.HomesteadSigner{}, tt.Homestead, true, false},
-		{"EIP150", types.HomesteadSigner{}, tt.EIP150, true, false},
-		{"EIP158", types.NewEIP155Signer(config.GetChainID()), tt.EIP158, true, false},
-		{"Byzantium", types.NewEIP155Signer(config.GetChainID()), tt.Byzantium, true, false},
-		{"Constantinople", types.NewEIP155Signer(config.GetChainID()), tt.Constantinople, true, false},
-		{"Istanbul", types.NewEIP155Signer(config.GetChainID()), tt.Istanbul, true, true},
+		{"Frontier", types.FrontierSigner{}, &tt.Frontier, false, false, false},
+		{"Homestead", types.HomesteadSigner{}, &tt.Homestead, true, false, false},
+		{"EIP150", types.HomesteadSigner{}, &tt.EIP150, true, false, false},
+		{"EIP158", types.NewEIP155Signer(config.GetChainID()), &tt.EIP158, true, false, false},
+		{"Byzantium", types.NewEIP155Signer(config.GetChainID()), &tt.Byzantium, true, false, false},
+		{"Constantinople", types.NewEIP155Signer(config.GetChainID()), &tt.Constantinople, true, false, false},
+		{"Istanbul", types.NewEIP155Signer(config.GetChainID()), &tt.Istanbul, true, true, false},
+		{"Shanghai", types.NewEIP155Signer(config.GetChainID()), tt.Shanghai, true, true, config.GetEIP3860Transition() != nil},
 	} {
-		sender, txhash, err := validateTx(tt.RLP, testcase.signer, testcase.isHomestead, testcase.isIstanbul)
+		// Fixtures predating Shanghai carry no expectations for it
+		if testcase.fork == nil {
+			continue
+		}
+		sender, txhash, err := validateTx(tt.RLP, testcase.signer, testcase.isHomestead, testcase.isIstanbul, testcase.isEIP3860)
 
 		if testcase.fork.Sender == (common.UnprefixedAddress{}) {
 			if err == nil {